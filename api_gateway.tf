@@ -5,10 +5,10 @@ resource "aws_apigatewayv2_api" "books_api" {
 
   cors_configuration {
     allow_credentials = false
-    allow_headers     = ["content-type", "x-amz-date", "authorization", "x-api-key", "x-amz-security-token", "x-amz-user-agent"]
+    allow_headers     = ["content-type", "x-amz-date", "authorization", "x-api-key", "x-amz-security-token", "x-amz-user-agent", "if-match", "idempotency-key"]
     allow_methods     = ["*"]
     allow_origins     = ["*"]
-    expose_headers    = ["date", "keep-alive"]
+    expose_headers    = ["date", "keep-alive", "etag", "accept-patch", "idempotent-replayed"]
     max_age           = 86400
   }
 }
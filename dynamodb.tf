@@ -15,14 +15,49 @@ resource "aws_dynamodb_table" "books" {
   }
 
   attribute {
-    name = "status"
+    name = "GSI1PK"
     type = "S"
   }
 
+  attribute {
+    name = "GSI1SK"
+    type = "S"
+  }
+
+  attribute {
+    name = "GSI2PK"
+    type = "S"
+  }
+
+  attribute {
+    name = "GSI2SK"
+    type = "S"
+  }
+
+  # Items carrying expires_at are removed once it passes:
+  #   IDEMPOTENCY#<key>  after 24 hours
+  #   TRASH#<book id>    30 days after the book was deleted
+  #   IMPORT#<job id>    7 days after the import was created
+  #   EXPORT#<job id>    7 days after the export was created, as its file is
+  # Books and HIST# entries have no expires_at and are kept.
+  ttl {
+    attribute_name = "expires_at"
+    enabled        = true
+  }
+
+  # USER#<id> / STATUS#<status>#<date>#<book id>
+  global_secondary_index {
+    name            = "status-index"
+    hash_key        = "GSI1PK"
+    range_key       = "GSI1SK"
+    projection_type = "ALL"
+  }
+
+  # USER#<id> / AUTHOR#<author>#<book id>
   global_secondary_index {
-    name            = "status-gsi"
-    hash_key        = "status"
-    range_key       = "PK"
+    name            = "author-index"
+    hash_key        = "GSI2PK"
+    range_key       = "GSI2SK"
     projection_type = "ALL"
   }
-} 
\ No newline at end of file
+}
@@ -12,6 +12,10 @@ resource "aws_dynamodb_table_item" "the_way_of_kings" {
     Series    = { "S" = "The Stormlight Archive" }
     status    = { "S" = "READ" }
     thumbnail = { "S" = "https://books.google.com/books/content?id=X_x_AAAAQBAJ&printsec=frontcover&img=1&zoom=1&source=gbs_api" }
+    GSI1PK    = { "S" = "USER#a4f88448-9071-7026-f5af-ee3f8bf3627f" }
+    GSI1SK    = { "S" = "STATUS#READ##a1b2c3d4-e5f6-7890-1234-567890abcdef" }
+    GSI2PK    = { "S" = "USER#a4f88448-9071-7026-f5af-ee3f8bf3627f" }
+    GSI2SK    = { "S" = "AUTHOR#brandon sanderson#a1b2c3d4-e5f6-7890-1234-567890abcdef" }
   })
 }
 
@@ -31,6 +35,10 @@ resource "aws_dynamodb_table_item" "words_of_radiance" {
     rating    = { "N" = "8" }
     thumbnail = { "S" = "http://books.google.com/books/content?id=cZbQAgAAQBAJ&printsec=frontcover&img=1&zoom=1&edge=curl&source=gbs_api" }
     type      = { "S" = "audiobook" }
+    GSI1PK    = { "S" = "USER#a4f88448-9071-7026-f5af-ee3f8bf3627f" }
+    GSI1SK    = { "S" = "STATUS#READ##b2c3d4e5-f6a7-8901-2345-67890abcdef1" }
+    GSI2PK    = { "S" = "USER#a4f88448-9071-7026-f5af-ee3f8bf3627f" }
+    GSI2SK    = { "S" = "AUTHOR#brandon sanderson#b2c3d4e5-f6a7-8901-2345-67890abcdef1" }
   })
 }
 
@@ -48,6 +56,10 @@ resource "aws_dynamodb_table_item" "oathbringer" {
     Series    = { "S" = "The Stormlight Archive" }
     status    = { "S" = "WANT_TO_READ" }
     thumbnail = { "S" = "https://books.google.com/books/content?id=VsT3DQAAQBAJ&printsec=frontcover&img=1&zoom=1&edge=curl&source=gbs_api" }
+    GSI1PK    = { "S" = "USER#a4f88448-9071-7026-f5af-ee3f8bf3627f" }
+    GSI1SK    = { "S" = "STATUS#WANT_TO_READ##c3d4e5f6-a7b8-9012-3456-7890abcdef12" }
+    GSI2PK    = { "S" = "USER#a4f88448-9071-7026-f5af-ee3f8bf3627f" }
+    GSI2SK    = { "S" = "AUTHOR#brandon sanderson#c3d4e5f6-a7b8-9012-3456-7890abcdef12" }
   })
 }
 
@@ -65,6 +77,10 @@ resource "aws_dynamodb_table_item" "rhythm_of_war" {
     Series    = { "S" = "The Stormlight Archive" }
     status    = { "S" = "WANT_TO_READ" }
     thumbnail = { "S" = "https://books.google.com/books/content?id=QCPBDwAAQBAJ&printsec=frontcover&img=1&zoom=1&edge=curl&source=gbs_api" }
+    GSI1PK    = { "S" = "USER#a4f88448-9071-7026-f5af-ee3f8bf3627f" }
+    GSI1SK    = { "S" = "STATUS#WANT_TO_READ##d4e5f6a7-b8c9-0123-4567-890abcdef123" }
+    GSI2PK    = { "S" = "USER#a4f88448-9071-7026-f5af-ee3f8bf3627f" }
+    GSI2SK    = { "S" = "AUTHOR#brandon sanderson#d4e5f6a7-b8c9-0123-4567-890abcdef123" }
   })
 }
 
@@ -82,6 +98,10 @@ resource "aws_dynamodb_table_item" "wind_and_truth" {
     Series    = { "S" = "The Stormlight Archive" }
     status    = { "S" = "WANT_TO_READ" }
     thumbnail = { "S" = "https://books.google.com/books/content?id=GInoEAAAQBAJ&printsec=frontcover&img=1&zoom=1&edge=curl&source=gbs_api" }
+    GSI1PK    = { "S" = "USER#a4f88448-9071-7026-f5af-ee3f8bf3627f" }
+    GSI1SK    = { "S" = "STATUS#WANT_TO_READ##e5f6a7b8-c9d0-1234-5678-90abcdef1234" }
+    GSI2PK    = { "S" = "USER#a4f88448-9071-7026-f5af-ee3f8bf3627f" }
+    GSI2SK    = { "S" = "AUTHOR#brandon sanderson#e5f6a7b8-c9d0-1234-5678-90abcdef1234" }
   })
 }
 
@@ -99,6 +119,10 @@ resource "aws_dynamodb_table_item" "the_eye_of_the_world" {
     Series    = { "S" = "The Wheel of Time" }
     status    = { "S" = "WANT_TO_READ" }
     thumbnail = { "S" = "https://books.google.com/books/content?id=PmJuDwAAQBAJ&printsec=frontcover&img=1&zoom=1&source=gbs_api" }
+    GSI1PK    = { "S" = "USER#a4f88448-9071-7026-f5af-ee3f8bf3627f" }
+    GSI1SK    = { "S" = "STATUS#WANT_TO_READ##f6a7b8c9-d0e1-2345-6789-0abcdef12345" }
+    GSI2PK    = { "S" = "USER#a4f88448-9071-7026-f5af-ee3f8bf3627f" }
+    GSI2SK    = { "S" = "AUTHOR#robert jordan#f6a7b8c9-d0e1-2345-6789-0abcdef12345" }
   })
 }
 
@@ -116,5 +140,9 @@ resource "aws_dynamodb_table_item" "the_great_hunt" {
     Series    = { "S" = "The Wheel of Time" }
     status    = { "S" = "WANT_TO_READ" }
     thumbnail = { "S" = "https://books.google.com/books/content?id=yngEsxEO4QYC&printsec=frontcover&img=1&zoom=1&edge=curl&source=gbs_api" }
+    GSI1PK    = { "S" = "USER#a4f88448-9071-7026-f5af-ee3f8bf3627f" }
+    GSI1SK    = { "S" = "STATUS#WANT_TO_READ##a7b8c9d0-e1f2-3456-7890-bcdef123456" }
+    GSI2PK    = { "S" = "USER#a4f88448-9071-7026-f5af-ee3f8bf3627f" }
+    GSI2SK    = { "S" = "AUTHOR#robert jordan#a7b8c9d0-e1f2-3456-7890-bcdef123456" }
   })
 }
@@ -1,7 +1,7 @@
 locals {
   delete_book_lambda_source_dir = "${path.module}/lambdas/delete-book"
   delete_book_go_files_for_hash = fileset(local.delete_book_lambda_source_dir, "**/*.go")
-  delete_book_source_hash       = sha1(join("", [for f in local.delete_book_go_files_for_hash : filesha1("${local.delete_book_lambda_source_dir}/${f}")]))
+  delete_book_source_hash       = sha1(join("", concat([for f in local.delete_book_go_files_for_hash : filesha1("${local.delete_book_lambda_source_dir}/${f}")], [local.bookshelf_shared_source_hash])))
 }
 
 resource "null_resource" "build_delete_book_lambda" {
@@ -33,6 +33,7 @@ data "aws_iam_policy_document" "delete_book_dynamodb_policy" {
   statement {
     actions = [
       "dynamodb:GetItem",
+      "dynamodb:PutItem",
       "dynamodb:DeleteItem"
     ]
     resources = ["*"]
@@ -41,7 +42,7 @@ data "aws_iam_policy_document" "delete_book_dynamodb_policy" {
 
 resource "aws_iam_policy" "delete_book_dynamodb_policy" {
   name        = "DeleteBookDynamoDBPolicy"
-  description = "Policy to allow getting and deleting an item in the Books DynamoDB table"
+  description = "Policy to allow moving an item to the trash in the Books DynamoDB table"
   policy      = data.aws_iam_policy_document.delete_book_dynamodb_policy.json
 }
 
@@ -1,7 +1,7 @@
 locals {
   get_book_lambda_source_dir = "${path.module}/lambdas/get-book"
   get_book_go_files_for_hash = fileset(local.get_book_lambda_source_dir, "**/*.go")
-  get_book_source_hash       = sha1(join("", [for f in local.get_book_go_files_for_hash : filesha1("${local.get_book_lambda_source_dir}/${f}")]))
+  get_book_source_hash       = sha1(join("", concat([for f in local.get_book_go_files_for_hash : filesha1("${local.get_book_lambda_source_dir}/${f}")], [local.bookshelf_shared_source_hash])))
 }
 
 resource "null_resource" "build_get_book_lambda" {
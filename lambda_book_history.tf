@@ -0,0 +1,104 @@
+locals {
+  book_history_lambda_source_dir = "${path.module}/lambdas/book-history"
+  book_history_go_files_for_hash = fileset(local.book_history_lambda_source_dir, "**/*.go")
+  book_history_source_hash       = sha1(join("", concat([for f in local.book_history_go_files_for_hash : filesha1("${local.book_history_lambda_source_dir}/${f}")], [local.bookshelf_shared_source_hash])))
+}
+
+resource "null_resource" "build_book_history_lambda" {
+  triggers = {
+    source_hash = local.book_history_source_hash
+  }
+
+  provisioner "local-exec" {
+    command = "cd ${local.book_history_lambda_source_dir} && make zip"
+  }
+}
+
+data "aws_iam_policy_document" "book_history_lambda_assume_role_policy" {
+  statement {
+    actions = ["sts:AssumeRole"]
+    principals {
+      type        = "Service"
+      identifiers = ["lambda.amazonaws.com"]
+    }
+  }
+}
+
+resource "aws_iam_role" "book_history_lambda_exec_role" {
+  name               = "book-history-lambda-exec-role"
+  assume_role_policy = data.aws_iam_policy_document.book_history_lambda_assume_role_policy.json
+}
+
+data "aws_iam_policy_document" "book_history_dynamodb_policy" {
+  statement {
+    actions = [
+      "dynamodb:GetItem",
+      "dynamodb:Query"
+    ]
+    resources = ["*"]
+  }
+}
+
+resource "aws_iam_policy" "book_history_dynamodb_policy" {
+  name        = "BookHistoryDynamoDBPolicy"
+  description = "Policy to allow reading the history of an item in the Books DynamoDB table"
+  policy      = data.aws_iam_policy_document.book_history_dynamodb_policy.json
+}
+
+resource "aws_iam_role_policy_attachment" "book_history_lambda_dynamodb_read" {
+  role       = aws_iam_role.book_history_lambda_exec_role.name
+  policy_arn = aws_iam_policy.book_history_dynamodb_policy.arn
+}
+
+resource "aws_iam_role_policy_attachment" "book_history_lambda_basic_execution" {
+  role       = aws_iam_role.book_history_lambda_exec_role.name
+  policy_arn = "arn:aws:iam::aws:policy/service-role/AWSLambdaBasicExecutionRole"
+}
+
+resource "aws_cloudwatch_log_group" "book_history_lambda_log_group" {
+  name              = "/aws/lambda/book-history"
+  retention_in_days = 7
+}
+
+resource "aws_lambda_function" "book_history_lambda" {
+  function_name = "book-history"
+  role          = aws_iam_role.book_history_lambda_exec_role.arn
+  handler       = "bootstrap"
+  runtime       = "provided.al2"
+
+  filename         = "${local.book_history_lambda_source_dir}/dist/book-history.zip"
+  source_code_hash = local.book_history_source_hash
+
+  depends_on = [
+    aws_iam_role_policy_attachment.book_history_lambda_basic_execution,
+    aws_iam_role_policy_attachment.book_history_lambda_dynamodb_read,
+    null_resource.build_book_history_lambda,
+    aws_cloudwatch_log_group.book_history_lambda_log_group,
+  ]
+}
+
+resource "aws_apigatewayv2_integration" "book_history_lambda_integration" {
+  api_id           = aws_apigatewayv2_api.books_api.id
+  integration_type = "AWS_PROXY"
+
+  integration_uri        = aws_lambda_function.book_history_lambda.invoke_arn
+  payload_format_version = "2.0"
+}
+
+resource "aws_apigatewayv2_route" "book_history_route" {
+  api_id    = aws_apigatewayv2_api.books_api.id
+  route_key = "GET /books/{id}/history"
+  target    = "integrations/${aws_apigatewayv2_integration.book_history_lambda_integration.id}"
+
+  authorization_type = "JWT"
+  authorizer_id      = aws_apigatewayv2_authorizer.cognito_authorizer.id
+}
+
+resource "aws_lambda_permission" "book_history_api_gateway_permission" {
+  statement_id  = "AllowAPIGatewayInvokeBookHistory"
+  action        = "lambda:InvokeFunction"
+  function_name = aws_lambda_function.book_history_lambda.function_name
+  principal     = "apigateway.amazonaws.com"
+
+  source_arn = "${aws_apigatewayv2_api.books_api.execution_arn}/*/*"
+} 
\ No newline at end of file
@@ -1,7 +1,7 @@
 locals {
   create_book_lambda_source_dir = "${path.module}/lambdas/create-book"
   create_book_go_files_for_hash = fileset(local.create_book_lambda_source_dir, "**/*.go")
-  create_book_source_hash       = sha1(join("", [for f in local.create_book_go_files_for_hash : filesha1("${local.create_book_lambda_source_dir}/${f}")]))
+  create_book_source_hash       = sha1(join("", concat([for f in local.create_book_go_files_for_hash : filesha1("${local.create_book_lambda_source_dir}/${f}")], [local.bookshelf_shared_source_hash])))
 }
 
 resource "null_resource" "build_create_book_lambda" {
@@ -31,14 +31,18 @@ resource "aws_iam_role" "create_book_lambda_exec_role" {
 
 data "aws_iam_policy_document" "create_book_dynamodb_policy" {
   statement {
-    actions   = ["dynamodb:PutItem"]
+    actions = [
+      "dynamodb:GetItem",
+      "dynamodb:PutItem",
+      "dynamodb:Query"
+    ]
     resources = ["*"]
   }
 }
 
 resource "aws_iam_policy" "create_book_dynamodb_policy" {
   name        = "CreateBookDynamoDBPolicy"
-  description = "Policy to allow putting an item into the Books DynamoDB table"
+  description = "Policy to allow checking for duplicates and putting books and idempotency records into the Books DynamoDB table"
   policy      = data.aws_iam_policy_document.create_book_dynamodb_policy.json
 }
 
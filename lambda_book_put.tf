@@ -1,7 +1,7 @@
 locals {
   update_book_lambda_source_dir = "${path.module}/lambdas/update-book"
   update_book_go_files_for_hash = fileset(local.update_book_lambda_source_dir, "**/*.go")
-  update_book_source_hash       = sha1(join("", [for f in local.update_book_go_files_for_hash : filesha1("${local.update_book_lambda_source_dir}/${f}")]))
+  update_book_source_hash       = sha1(join("", concat([for f in local.update_book_go_files_for_hash : filesha1("${local.update_book_lambda_source_dir}/${f}")], [local.bookshelf_shared_source_hash])))
 }
 
 resource "null_resource" "build_update_book_lambda" {
@@ -1,7 +1,7 @@
 locals {
   lambda_source_dir = "${path.module}/lambdas/list-books"
   go_files_for_hash = fileset(local.lambda_source_dir, "**/*.go")
-  source_hash       = sha1(join("", [for f in local.go_files_for_hash : filesha1("${local.lambda_source_dir}/${f}")]))
+  source_hash       = sha1(join("", concat([for f in local.go_files_for_hash : filesha1("${local.lambda_source_dir}/${f}")], [local.bookshelf_shared_source_hash])))
 }
 
 resource "null_resource" "build_list_books_lambda" {
@@ -63,6 +63,11 @@ resource "aws_cloudwatch_log_group" "list_books_lambda_log_group" {
   retention_in_days = 7
 }
 
+# Key used to seal GET /books pagination cursors.
+resource "random_id" "list_books_cursor_key" {
+  byte_length = 32
+}
+
 resource "aws_lambda_function" "list_books_lambda" {
   function_name = "list-books"
   role          = aws_iam_role.list_books_lambda_exec_role.arn
@@ -72,6 +77,12 @@ resource "aws_lambda_function" "list_books_lambda" {
   filename         = "${local.lambda_source_dir}/dist/list-books.zip"
   source_code_hash = local.source_hash
 
+  environment {
+    variables = {
+      CURSOR_KEY = random_id.list_books_cursor_key.b64_std
+    }
+  }
+
   depends_on = [
     aws_iam_role_policy_attachment.list_books_lambda_basic_execution,
     aws_iam_role_policy_attachment.list_books_lambda_dynamodb_read,
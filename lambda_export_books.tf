@@ -7,8 +7,9 @@ resource "null_resource" "export_books_build" {
 
   triggers = {
     # Rebuild when source files change
-    source_hash = filesha256("${path.module}/lambdas/export-books/main.go")
+    source_hash = sha1(join("", [for f in fileset("${path.module}/lambdas/export-books", "**/*.go") : filesha1("${path.module}/lambdas/export-books/${f}")]))
     go_mod_hash = filesha256("${path.module}/lambdas/export-books/go.mod")
+    shared_hash = local.bookshelf_shared_source_hash
   }
 }
 
@@ -26,7 +27,8 @@ resource "aws_lambda_function" "export_books" {
 
   environment {
     variables = {
-      EXPORTS_BUCKET_NAME = aws_s3_bucket.exports.bucket
+      EXPORTS_BUCKET_NAME         = aws_s3_bucket.exports.bucket
+      EXPORT_WORKER_FUNCTION_NAME = aws_lambda_function.process_export_lambda.function_name
     }
   }
 
@@ -76,7 +78,8 @@ resource "aws_iam_role_policy" "export_books_dynamodb" {
       {
         Effect = "Allow"
         Action = [
-          "dynamodb:Query"
+          "dynamodb:Query",
+          "dynamodb:PutItem"
         ]
         Resource = [
           aws_dynamodb_table.books.arn,
@@ -99,6 +102,7 @@ resource "aws_iam_role_policy" "export_books_s3" {
         Effect = "Allow"
         Action = [
           "s3:PutObject",
+          "s3:AbortMultipartUpload",
           "s3:GetObject",
           "s3:DeleteObject"
         ]
@@ -106,4 +110,23 @@ resource "aws_iam_role_policy" "export_books_s3" {
       }
     ]
   })
-}
\ No newline at end of file
+}
+
+# IAM policy for starting asynchronous exports
+resource "aws_iam_role_policy" "export_books_invoke_worker" {
+  name = "bookshelf-export-books-invoke-worker-policy"
+  role = aws_iam_role.export_books_lambda.id
+
+  policy = jsonencode({
+    Version = "2012-10-17"
+    Statement = [
+      {
+        Effect = "Allow"
+        Action = [
+          "lambda:InvokeFunction"
+        ]
+        Resource = aws_lambda_function.process_export_lambda.arn
+      }
+    ]
+  })
+}
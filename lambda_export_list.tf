@@ -0,0 +1,130 @@
+locals {
+  list_exports_lambda_source_dir = "${path.module}/lambdas/list-exports"
+  list_exports_go_files_for_hash = fileset(local.list_exports_lambda_source_dir, "**/*.go")
+  list_exports_source_hash       = sha1(join("", concat([for f in local.list_exports_go_files_for_hash : filesha1("${local.list_exports_lambda_source_dir}/${f}")], [local.bookshelf_shared_source_hash])))
+}
+
+resource "null_resource" "build_list_exports_lambda" {
+  triggers = {
+    source_hash = local.list_exports_source_hash
+  }
+
+  provisioner "local-exec" {
+    command = "cd ${local.list_exports_lambda_source_dir} && make zip"
+  }
+}
+
+data "aws_iam_policy_document" "list_exports_lambda_assume_role_policy" {
+  statement {
+    actions = ["sts:AssumeRole"]
+    principals {
+      type        = "Service"
+      identifiers = ["lambda.amazonaws.com"]
+    }
+  }
+}
+
+resource "aws_iam_role" "list_exports_lambda_exec_role" {
+  name               = "list-exports-lambda-exec-role"
+  assume_role_policy = data.aws_iam_policy_document.list_exports_lambda_assume_role_policy.json
+}
+
+data "aws_iam_policy_document" "list_exports_dynamodb_policy" {
+  statement {
+    actions = [
+      "dynamodb:Query"
+    ]
+    resources = ["*"]
+  }
+}
+
+resource "aws_iam_policy" "list_exports_dynamodb_policy" {
+  name        = "ListExportsDynamoDBPolicy"
+  description = "Policy to allow listing export jobs in the Books DynamoDB table"
+  policy      = data.aws_iam_policy_document.list_exports_dynamodb_policy.json
+}
+
+resource "aws_iam_role_policy_attachment" "list_exports_lambda_dynamodb_exports" {
+  role       = aws_iam_role.list_exports_lambda_exec_role.name
+  policy_arn = aws_iam_policy.list_exports_dynamodb_policy.arn
+}
+
+data "aws_iam_policy_document" "list_exports_s3_policy" {
+  statement {
+    actions = [
+      "s3:ListBucket"
+    ]
+    resources = [aws_s3_bucket.exports.arn]
+  }
+}
+
+resource "aws_iam_policy" "list_exports_s3_policy" {
+  name        = "ListExportsS3Policy"
+  description = "Policy to allow listing export files in the exports bucket"
+  policy      = data.aws_iam_policy_document.list_exports_s3_policy.json
+}
+
+resource "aws_iam_role_policy_attachment" "list_exports_lambda_s3_exports" {
+  role       = aws_iam_role.list_exports_lambda_exec_role.name
+  policy_arn = aws_iam_policy.list_exports_s3_policy.arn
+}
+
+resource "aws_iam_role_policy_attachment" "list_exports_lambda_basic_execution" {
+  role       = aws_iam_role.list_exports_lambda_exec_role.name
+  policy_arn = "arn:aws:iam::aws:policy/service-role/AWSLambdaBasicExecutionRole"
+}
+
+resource "aws_cloudwatch_log_group" "list_exports_lambda_log_group" {
+  name              = "/aws/lambda/list-exports"
+  retention_in_days = 7
+}
+
+resource "aws_lambda_function" "list_exports_lambda" {
+  function_name = "list-exports"
+  role          = aws_iam_role.list_exports_lambda_exec_role.arn
+  handler       = "bootstrap"
+  runtime       = "provided.al2"
+
+  environment {
+    variables = {
+      EXPORTS_BUCKET_NAME = aws_s3_bucket.exports.bucket
+    }
+  }
+
+  filename         = "${local.list_exports_lambda_source_dir}/dist/list-exports.zip"
+  source_code_hash = local.list_exports_source_hash
+
+  depends_on = [
+    aws_iam_role_policy_attachment.list_exports_lambda_basic_execution,
+    aws_iam_role_policy_attachment.list_exports_lambda_dynamodb_exports,
+    aws_iam_role_policy_attachment.list_exports_lambda_s3_exports,
+    null_resource.build_list_exports_lambda,
+    aws_cloudwatch_log_group.list_exports_lambda_log_group,
+  ]
+}
+
+resource "aws_apigatewayv2_integration" "list_exports_lambda_integration" {
+  api_id           = aws_apigatewayv2_api.books_api.id
+  integration_type = "AWS_PROXY"
+
+  integration_uri        = aws_lambda_function.list_exports_lambda.invoke_arn
+  payload_format_version = "2.0"
+}
+
+resource "aws_apigatewayv2_route" "list_exports_route" {
+  api_id    = aws_apigatewayv2_api.books_api.id
+  route_key = "GET /exports"
+  target    = "integrations/${aws_apigatewayv2_integration.list_exports_lambda_integration.id}"
+
+  authorization_type = "JWT"
+  authorizer_id      = aws_apigatewayv2_authorizer.cognito_authorizer.id
+}
+
+resource "aws_lambda_permission" "list_exports_api_gateway_permission" {
+  statement_id  = "AllowAPIGatewayInvokeListExports"
+  action        = "lambda:InvokeFunction"
+  function_name = aws_lambda_function.list_exports_lambda.function_name
+  principal     = "apigateway.amazonaws.com"
+
+  source_arn = "${aws_apigatewayv2_api.books_api.execution_arn}/*/*"
+}
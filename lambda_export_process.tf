@@ -0,0 +1,118 @@
+locals {
+  process_export_lambda_source_dir = "${path.module}/lambdas/process-export"
+  process_export_go_files_for_hash = fileset(local.process_export_lambda_source_dir, "**/*.go")
+  process_export_source_hash       = sha1(join("", concat([for f in local.process_export_go_files_for_hash : filesha1("${local.process_export_lambda_source_dir}/${f}")], [local.bookshelf_shared_source_hash])))
+}
+
+resource "null_resource" "build_process_export_lambda" {
+  triggers = {
+    source_hash = local.process_export_source_hash
+  }
+
+  provisioner "local-exec" {
+    command = "cd ${local.process_export_lambda_source_dir} && make zip"
+  }
+}
+
+data "aws_iam_policy_document" "process_export_lambda_assume_role_policy" {
+  statement {
+    actions = ["sts:AssumeRole"]
+    principals {
+      type        = "Service"
+      identifiers = ["lambda.amazonaws.com"]
+    }
+  }
+}
+
+resource "aws_iam_role" "process_export_lambda_exec_role" {
+  name               = "process-export-lambda-exec-role"
+  assume_role_policy = data.aws_iam_policy_document.process_export_lambda_assume_role_policy.json
+}
+
+data "aws_iam_policy_document" "process_export_dynamodb_policy" {
+  statement {
+    actions = [
+      "dynamodb:GetItem",
+      "dynamodb:PutItem",
+      "dynamodb:UpdateItem",
+      "dynamodb:Query"
+    ]
+    resources = ["*"]
+  }
+}
+
+resource "aws_iam_policy" "process_export_dynamodb_policy" {
+  name        = "ProcessExportDynamoDBPolicy"
+  description = "Policy to allow exporting books and tracking export jobs in the Books DynamoDB table"
+  policy      = data.aws_iam_policy_document.process_export_dynamodb_policy.json
+}
+
+resource "aws_iam_role_policy_attachment" "process_export_lambda_dynamodb_exports" {
+  role       = aws_iam_role.process_export_lambda_exec_role.name
+  policy_arn = aws_iam_policy.process_export_dynamodb_policy.arn
+}
+
+data "aws_iam_policy_document" "process_export_s3_policy" {
+  statement {
+    actions = [
+      "s3:PutObject",
+      "s3:AbortMultipartUpload"
+    ]
+    resources = ["${aws_s3_bucket.exports.arn}/exports/*"]
+  }
+}
+
+resource "aws_iam_policy" "process_export_s3_policy" {
+  name        = "ProcessExportS3Policy"
+  description = "Policy to allow writing export files to the exports bucket"
+  policy      = data.aws_iam_policy_document.process_export_s3_policy.json
+}
+
+resource "aws_iam_role_policy_attachment" "process_export_lambda_s3_exports" {
+  role       = aws_iam_role.process_export_lambda_exec_role.name
+  policy_arn = aws_iam_policy.process_export_s3_policy.arn
+}
+
+resource "aws_iam_role_policy_attachment" "process_export_lambda_basic_execution" {
+  role       = aws_iam_role.process_export_lambda_exec_role.name
+  policy_arn = "arn:aws:iam::aws:policy/service-role/AWSLambdaBasicExecutionRole"
+}
+
+resource "aws_cloudwatch_log_group" "process_export_lambda_log_group" {
+  name              = "/aws/lambda/process-export"
+  retention_in_days = 7
+}
+
+resource "aws_lambda_function" "process_export_lambda" {
+  function_name = "process-export"
+  role          = aws_iam_role.process_export_lambda_exec_role.arn
+  handler       = "bootstrap"
+  runtime       = "provided.al2"
+  timeout       = 900
+  memory_size   = 512
+
+  environment {
+    variables = {
+      EXPORTS_BUCKET_NAME = aws_s3_bucket.exports.bucket
+    }
+  }
+
+  filename         = "${local.process_export_lambda_source_dir}/dist/process-export.zip"
+  source_code_hash = local.process_export_source_hash
+
+  depends_on = [
+    aws_iam_role_policy_attachment.process_export_lambda_basic_execution,
+    aws_iam_role_policy_attachment.process_export_lambda_dynamodb_exports,
+    aws_iam_role_policy_attachment.process_export_lambda_s3_exports,
+    null_resource.build_process_export_lambda,
+    aws_cloudwatch_log_group.process_export_lambda_log_group,
+  ]
+}
+
+# Background exports are retried for an hour at most; export jobs still
+# pending after that are reported failed (exporter.JobMaxEventAge)
+resource "aws_lambda_function_event_invoke_config" "process_export_lambda" {
+  function_name                = aws_lambda_function.process_export_lambda.function_name
+  maximum_event_age_in_seconds = 3600
+  maximum_retry_attempts       = 2
+}
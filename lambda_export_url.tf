@@ -0,0 +1,138 @@
+locals {
+  get_export_url_lambda_source_dir = "${path.module}/lambdas/get-export-url"
+  get_export_url_go_files_for_hash = fileset(local.get_export_url_lambda_source_dir, "**/*.go")
+  get_export_url_source_hash       = sha1(join("", concat([for f in local.get_export_url_go_files_for_hash : filesha1("${local.get_export_url_lambda_source_dir}/${f}")], [local.bookshelf_shared_source_hash])))
+}
+
+resource "null_resource" "build_get_export_url_lambda" {
+  triggers = {
+    source_hash = local.get_export_url_source_hash
+  }
+
+  provisioner "local-exec" {
+    command = "cd ${local.get_export_url_lambda_source_dir} && make zip"
+  }
+}
+
+data "aws_iam_policy_document" "get_export_url_lambda_assume_role_policy" {
+  statement {
+    actions = ["sts:AssumeRole"]
+    principals {
+      type        = "Service"
+      identifiers = ["lambda.amazonaws.com"]
+    }
+  }
+}
+
+resource "aws_iam_role" "get_export_url_lambda_exec_role" {
+  name               = "get-export-url-lambda-exec-role"
+  assume_role_policy = data.aws_iam_policy_document.get_export_url_lambda_assume_role_policy.json
+}
+
+data "aws_iam_policy_document" "get_export_url_dynamodb_policy" {
+  statement {
+    actions = [
+      "dynamodb:GetItem"
+    ]
+    resources = ["*"]
+  }
+}
+
+resource "aws_iam_policy" "get_export_url_dynamodb_policy" {
+  name        = "GetExportUrlDynamoDBPolicy"
+  description = "Policy to allow reading export jobs from the Books DynamoDB table"
+  policy      = data.aws_iam_policy_document.get_export_url_dynamodb_policy.json
+}
+
+resource "aws_iam_role_policy_attachment" "get_export_url_lambda_dynamodb_exports" {
+  role       = aws_iam_role.get_export_url_lambda_exec_role.name
+  policy_arn = aws_iam_policy.get_export_url_dynamodb_policy.arn
+}
+
+data "aws_iam_policy_document" "get_export_url_s3_policy" {
+  statement {
+    actions = [
+      "s3:GetObject"
+    ]
+    resources = ["${aws_s3_bucket.exports.arn}/exports/*"]
+  }
+
+  # Lets HeadObject report a missing file as 404 rather than 403
+  statement {
+    actions = [
+      "s3:ListBucket"
+    ]
+    resources = [aws_s3_bucket.exports.arn]
+  }
+}
+
+resource "aws_iam_policy" "get_export_url_s3_policy" {
+  name        = "GetExportUrlS3Policy"
+  description = "Policy to allow signing download URLs for export files in the exports bucket"
+  policy      = data.aws_iam_policy_document.get_export_url_s3_policy.json
+}
+
+resource "aws_iam_role_policy_attachment" "get_export_url_lambda_s3_exports" {
+  role       = aws_iam_role.get_export_url_lambda_exec_role.name
+  policy_arn = aws_iam_policy.get_export_url_s3_policy.arn
+}
+
+resource "aws_iam_role_policy_attachment" "get_export_url_lambda_basic_execution" {
+  role       = aws_iam_role.get_export_url_lambda_exec_role.name
+  policy_arn = "arn:aws:iam::aws:policy/service-role/AWSLambdaBasicExecutionRole"
+}
+
+resource "aws_cloudwatch_log_group" "get_export_url_lambda_log_group" {
+  name              = "/aws/lambda/get-export-url"
+  retention_in_days = 7
+}
+
+resource "aws_lambda_function" "get_export_url_lambda" {
+  function_name = "get-export-url"
+  role          = aws_iam_role.get_export_url_lambda_exec_role.arn
+  handler       = "bootstrap"
+  runtime       = "provided.al2"
+
+  environment {
+    variables = {
+      EXPORTS_BUCKET_NAME = aws_s3_bucket.exports.bucket
+    }
+  }
+
+  filename         = "${local.get_export_url_lambda_source_dir}/dist/get-export-url.zip"
+  source_code_hash = local.get_export_url_source_hash
+
+  depends_on = [
+    aws_iam_role_policy_attachment.get_export_url_lambda_basic_execution,
+    aws_iam_role_policy_attachment.get_export_url_lambda_dynamodb_exports,
+    aws_iam_role_policy_attachment.get_export_url_lambda_s3_exports,
+    null_resource.build_get_export_url_lambda,
+    aws_cloudwatch_log_group.get_export_url_lambda_log_group,
+  ]
+}
+
+resource "aws_apigatewayv2_integration" "get_export_url_lambda_integration" {
+  api_id           = aws_apigatewayv2_api.books_api.id
+  integration_type = "AWS_PROXY"
+
+  integration_uri        = aws_lambda_function.get_export_url_lambda.invoke_arn
+  payload_format_version = "2.0"
+}
+
+resource "aws_apigatewayv2_route" "get_export_url_route" {
+  api_id    = aws_apigatewayv2_api.books_api.id
+  route_key = "GET /exports/{id}/url"
+  target    = "integrations/${aws_apigatewayv2_integration.get_export_url_lambda_integration.id}"
+
+  authorization_type = "JWT"
+  authorizer_id      = aws_apigatewayv2_authorizer.cognito_authorizer.id
+}
+
+resource "aws_lambda_permission" "get_export_url_api_gateway_permission" {
+  statement_id  = "AllowAPIGatewayInvokeGetExportUrl"
+  action        = "lambda:InvokeFunction"
+  function_name = aws_lambda_function.get_export_url_lambda.function_name
+  principal     = "apigateway.amazonaws.com"
+
+  source_arn = "${aws_apigatewayv2_api.books_api.execution_arn}/*/*"
+}
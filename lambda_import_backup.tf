@@ -0,0 +1,109 @@
+locals {
+  import_backup_lambda_source_dir = "${path.module}/lambdas/import-backup"
+  import_backup_go_files_for_hash = fileset(local.import_backup_lambda_source_dir, "**/*.go")
+  import_backup_source_hash       = sha1(join("", concat([for f in local.import_backup_go_files_for_hash : filesha1("${local.import_backup_lambda_source_dir}/${f}")], [local.bookshelf_shared_source_hash])))
+}
+
+resource "null_resource" "build_import_backup_lambda" {
+  triggers = {
+    source_hash = local.import_backup_source_hash
+  }
+
+  provisioner "local-exec" {
+    command = "cd ${local.import_backup_lambda_source_dir} && make zip"
+  }
+}
+
+data "aws_iam_policy_document" "import_backup_lambda_assume_role_policy" {
+  statement {
+    actions = ["sts:AssumeRole"]
+    principals {
+      type        = "Service"
+      identifiers = ["lambda.amazonaws.com"]
+    }
+  }
+}
+
+resource "aws_iam_role" "import_backup_lambda_exec_role" {
+  name               = "import-backup-lambda-exec-role"
+  assume_role_policy = data.aws_iam_policy_document.import_backup_lambda_assume_role_policy.json
+}
+
+data "aws_iam_policy_document" "import_backup_dynamodb_policy" {
+  statement {
+    actions = [
+      "dynamodb:Query",
+      "dynamodb:GetItem",
+      "dynamodb:PutItem",
+      "dynamodb:DeleteItem",
+      "dynamodb:BatchWriteItem"
+    ]
+    resources = ["*"]
+  }
+}
+
+resource "aws_iam_policy" "import_backup_dynamodb_policy" {
+  name        = "ImportBackupDynamoDBPolicy"
+  description = "Policy to allow restoring backups into the Books DynamoDB table"
+  policy      = data.aws_iam_policy_document.import_backup_dynamodb_policy.json
+}
+
+resource "aws_iam_role_policy_attachment" "import_backup_lambda_dynamodb_import" {
+  role       = aws_iam_role.import_backup_lambda_exec_role.name
+  policy_arn = aws_iam_policy.import_backup_dynamodb_policy.arn
+}
+
+resource "aws_iam_role_policy_attachment" "import_backup_lambda_basic_execution" {
+  role       = aws_iam_role.import_backup_lambda_exec_role.name
+  policy_arn = "arn:aws:iam::aws:policy/service-role/AWSLambdaBasicExecutionRole"
+}
+
+resource "aws_cloudwatch_log_group" "import_backup_lambda_log_group" {
+  name              = "/aws/lambda/import-backup"
+  retention_in_days = 7
+}
+
+resource "aws_lambda_function" "import_backup_lambda" {
+  function_name = "import-backup"
+  role          = aws_iam_role.import_backup_lambda_exec_role.arn
+  handler       = "bootstrap"
+  runtime       = "provided.al2"
+  timeout       = 60
+  memory_size   = 256
+
+  filename         = "${local.import_backup_lambda_source_dir}/dist/import-backup.zip"
+  source_code_hash = local.import_backup_source_hash
+
+  depends_on = [
+    aws_iam_role_policy_attachment.import_backup_lambda_basic_execution,
+    aws_iam_role_policy_attachment.import_backup_lambda_dynamodb_import,
+    null_resource.build_import_backup_lambda,
+    aws_cloudwatch_log_group.import_backup_lambda_log_group,
+  ]
+}
+
+resource "aws_apigatewayv2_integration" "import_backup_lambda_integration" {
+  api_id           = aws_apigatewayv2_api.books_api.id
+  integration_type = "AWS_PROXY"
+
+  integration_uri        = aws_lambda_function.import_backup_lambda.invoke_arn
+  payload_format_version = "2.0"
+}
+
+resource "aws_apigatewayv2_route" "import_backup_route" {
+  api_id    = aws_apigatewayv2_api.books_api.id
+  route_key = "POST /import/backup"
+  target    = "integrations/${aws_apigatewayv2_integration.import_backup_lambda_integration.id}"
+
+  authorization_type = "JWT"
+  authorizer_id      = aws_apigatewayv2_authorizer.cognito_authorizer.id
+}
+
+resource "aws_lambda_permission" "import_backup_api_gateway_permission" {
+  statement_id  = "AllowAPIGatewayInvokeImportBackup"
+  action        = "lambda:InvokeFunction"
+  function_name = aws_lambda_function.import_backup_lambda.function_name
+  principal     = "apigateway.amazonaws.com"
+
+  source_arn = "${aws_apigatewayv2_api.books_api.execution_arn}/*/*"
+} 
\ No newline at end of file
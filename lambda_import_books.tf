@@ -0,0 +1,106 @@
+locals {
+  import_books_lambda_source_dir = "${path.module}/lambdas/import-books"
+  import_books_go_files_for_hash = fileset(local.import_books_lambda_source_dir, "**/*.go")
+  import_books_source_hash       = sha1(join("", concat([for f in local.import_books_go_files_for_hash : filesha1("${local.import_books_lambda_source_dir}/${f}")], [local.bookshelf_shared_source_hash])))
+}
+
+resource "null_resource" "build_import_books_lambda" {
+  triggers = {
+    source_hash = local.import_books_source_hash
+  }
+
+  provisioner "local-exec" {
+    command = "cd ${local.import_books_lambda_source_dir} && make zip"
+  }
+}
+
+data "aws_iam_policy_document" "import_books_lambda_assume_role_policy" {
+  statement {
+    actions = ["sts:AssumeRole"]
+    principals {
+      type        = "Service"
+      identifiers = ["lambda.amazonaws.com"]
+    }
+  }
+}
+
+resource "aws_iam_role" "import_books_lambda_exec_role" {
+  name               = "import-books-lambda-exec-role"
+  assume_role_policy = data.aws_iam_policy_document.import_books_lambda_assume_role_policy.json
+}
+
+data "aws_iam_policy_document" "import_books_dynamodb_policy" {
+  statement {
+    actions = [
+      "dynamodb:Query",
+      "dynamodb:BatchWriteItem"
+    ]
+    resources = ["*"]
+  }
+}
+
+resource "aws_iam_policy" "import_books_dynamodb_policy" {
+  name        = "ImportBooksDynamoDBPolicy"
+  description = "Policy to allow reading and batch writing items in the Books DynamoDB table"
+  policy      = data.aws_iam_policy_document.import_books_dynamodb_policy.json
+}
+
+resource "aws_iam_role_policy_attachment" "import_books_lambda_dynamodb_import" {
+  role       = aws_iam_role.import_books_lambda_exec_role.name
+  policy_arn = aws_iam_policy.import_books_dynamodb_policy.arn
+}
+
+resource "aws_iam_role_policy_attachment" "import_books_lambda_basic_execution" {
+  role       = aws_iam_role.import_books_lambda_exec_role.name
+  policy_arn = "arn:aws:iam::aws:policy/service-role/AWSLambdaBasicExecutionRole"
+}
+
+resource "aws_cloudwatch_log_group" "import_books_lambda_log_group" {
+  name              = "/aws/lambda/import-books"
+  retention_in_days = 7
+}
+
+resource "aws_lambda_function" "import_books_lambda" {
+  function_name = "import-books"
+  role          = aws_iam_role.import_books_lambda_exec_role.arn
+  handler       = "bootstrap"
+  runtime       = "provided.al2"
+  timeout       = 60
+  memory_size   = 256
+
+  filename         = "${local.import_books_lambda_source_dir}/dist/import-books.zip"
+  source_code_hash = local.import_books_source_hash
+
+  depends_on = [
+    aws_iam_role_policy_attachment.import_books_lambda_basic_execution,
+    aws_iam_role_policy_attachment.import_books_lambda_dynamodb_import,
+    null_resource.build_import_books_lambda,
+    aws_cloudwatch_log_group.import_books_lambda_log_group,
+  ]
+}
+
+resource "aws_apigatewayv2_integration" "import_books_lambda_integration" {
+  api_id           = aws_apigatewayv2_api.books_api.id
+  integration_type = "AWS_PROXY"
+
+  integration_uri        = aws_lambda_function.import_books_lambda.invoke_arn
+  payload_format_version = "2.0"
+}
+
+resource "aws_apigatewayv2_route" "import_books_route" {
+  api_id    = aws_apigatewayv2_api.books_api.id
+  route_key = "POST /import"
+  target    = "integrations/${aws_apigatewayv2_integration.import_books_lambda_integration.id}"
+
+  authorization_type = "JWT"
+  authorizer_id      = aws_apigatewayv2_authorizer.cognito_authorizer.id
+}
+
+resource "aws_lambda_permission" "import_books_api_gateway_permission" {
+  statement_id  = "AllowAPIGatewayInvokeImportBooks"
+  action        = "lambda:InvokeFunction"
+  function_name = aws_lambda_function.import_books_lambda.function_name
+  principal     = "apigateway.amazonaws.com"
+
+  source_arn = "${aws_apigatewayv2_api.books_api.execution_arn}/*/*"
+} 
\ No newline at end of file
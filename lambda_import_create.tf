@@ -0,0 +1,130 @@
+locals {
+  create_import_lambda_source_dir = "${path.module}/lambdas/create-import"
+  create_import_go_files_for_hash = fileset(local.create_import_lambda_source_dir, "**/*.go")
+  create_import_source_hash       = sha1(join("", concat([for f in local.create_import_go_files_for_hash : filesha1("${local.create_import_lambda_source_dir}/${f}")], [local.bookshelf_shared_source_hash])))
+}
+
+resource "null_resource" "build_create_import_lambda" {
+  triggers = {
+    source_hash = local.create_import_source_hash
+  }
+
+  provisioner "local-exec" {
+    command = "cd ${local.create_import_lambda_source_dir} && make zip"
+  }
+}
+
+data "aws_iam_policy_document" "create_import_lambda_assume_role_policy" {
+  statement {
+    actions = ["sts:AssumeRole"]
+    principals {
+      type        = "Service"
+      identifiers = ["lambda.amazonaws.com"]
+    }
+  }
+}
+
+resource "aws_iam_role" "create_import_lambda_exec_role" {
+  name               = "create-import-lambda-exec-role"
+  assume_role_policy = data.aws_iam_policy_document.create_import_lambda_assume_role_policy.json
+}
+
+data "aws_iam_policy_document" "create_import_dynamodb_policy" {
+  statement {
+    actions = [
+      "dynamodb:PutItem"
+    ]
+    resources = ["*"]
+  }
+}
+
+resource "aws_iam_policy" "create_import_dynamodb_policy" {
+  name        = "CreateImportDynamoDBPolicy"
+  description = "Policy to allow creating import jobs in the Books DynamoDB table"
+  policy      = data.aws_iam_policy_document.create_import_dynamodb_policy.json
+}
+
+resource "aws_iam_role_policy_attachment" "create_import_lambda_dynamodb_write" {
+  role       = aws_iam_role.create_import_lambda_exec_role.name
+  policy_arn = aws_iam_policy.create_import_dynamodb_policy.arn
+}
+
+data "aws_iam_policy_document" "create_import_s3_policy" {
+  statement {
+    actions = [
+      "s3:PutObject"
+    ]
+    resources = ["${aws_s3_bucket.imports.arn}/imports/*"]
+  }
+}
+
+resource "aws_iam_policy" "create_import_s3_policy" {
+  name        = "CreateImportS3Policy"
+  description = "Policy to allow uploading import files to the imports bucket"
+  policy      = data.aws_iam_policy_document.create_import_s3_policy.json
+}
+
+resource "aws_iam_role_policy_attachment" "create_import_lambda_s3_imports" {
+  role       = aws_iam_role.create_import_lambda_exec_role.name
+  policy_arn = aws_iam_policy.create_import_s3_policy.arn
+}
+
+resource "aws_iam_role_policy_attachment" "create_import_lambda_basic_execution" {
+  role       = aws_iam_role.create_import_lambda_exec_role.name
+  policy_arn = "arn:aws:iam::aws:policy/service-role/AWSLambdaBasicExecutionRole"
+}
+
+resource "aws_cloudwatch_log_group" "create_import_lambda_log_group" {
+  name              = "/aws/lambda/create-import"
+  retention_in_days = 7
+}
+
+resource "aws_lambda_function" "create_import_lambda" {
+  function_name = "create-import"
+  role          = aws_iam_role.create_import_lambda_exec_role.arn
+  handler       = "bootstrap"
+  runtime       = "provided.al2"
+
+  environment {
+    variables = {
+      IMPORTS_BUCKET_NAME = aws_s3_bucket.imports.bucket
+    }
+  }
+
+  filename         = "${local.create_import_lambda_source_dir}/dist/create-import.zip"
+  source_code_hash = local.create_import_source_hash
+
+  depends_on = [
+    aws_iam_role_policy_attachment.create_import_lambda_basic_execution,
+    aws_iam_role_policy_attachment.create_import_lambda_dynamodb_write,
+    aws_iam_role_policy_attachment.create_import_lambda_s3_imports,
+    null_resource.build_create_import_lambda,
+    aws_cloudwatch_log_group.create_import_lambda_log_group,
+  ]
+}
+
+resource "aws_apigatewayv2_integration" "create_import_lambda_integration" {
+  api_id           = aws_apigatewayv2_api.books_api.id
+  integration_type = "AWS_PROXY"
+
+  integration_uri        = aws_lambda_function.create_import_lambda.invoke_arn
+  payload_format_version = "2.0"
+}
+
+resource "aws_apigatewayv2_route" "create_import_route" {
+  api_id    = aws_apigatewayv2_api.books_api.id
+  route_key = "POST /imports"
+  target    = "integrations/${aws_apigatewayv2_integration.create_import_lambda_integration.id}"
+
+  authorization_type = "JWT"
+  authorizer_id      = aws_apigatewayv2_authorizer.cognito_authorizer.id
+}
+
+resource "aws_lambda_permission" "create_import_api_gateway_permission" {
+  statement_id  = "AllowAPIGatewayInvokeCreateImport"
+  action        = "lambda:InvokeFunction"
+  function_name = aws_lambda_function.create_import_lambda.function_name
+  principal     = "apigateway.amazonaws.com"
+
+  source_arn = "${aws_apigatewayv2_api.books_api.execution_arn}/*/*"
+} 
\ No newline at end of file
@@ -0,0 +1,103 @@
+locals {
+  get_import_lambda_source_dir = "${path.module}/lambdas/get-import"
+  get_import_go_files_for_hash = fileset(local.get_import_lambda_source_dir, "**/*.go")
+  get_import_source_hash       = sha1(join("", concat([for f in local.get_import_go_files_for_hash : filesha1("${local.get_import_lambda_source_dir}/${f}")], [local.bookshelf_shared_source_hash])))
+}
+
+resource "null_resource" "build_get_import_lambda" {
+  triggers = {
+    source_hash = local.get_import_source_hash
+  }
+
+  provisioner "local-exec" {
+    command = "cd ${local.get_import_lambda_source_dir} && make zip"
+  }
+}
+
+data "aws_iam_policy_document" "get_import_lambda_assume_role_policy" {
+  statement {
+    actions = ["sts:AssumeRole"]
+    principals {
+      type        = "Service"
+      identifiers = ["lambda.amazonaws.com"]
+    }
+  }
+}
+
+resource "aws_iam_role" "get_import_lambda_exec_role" {
+  name               = "get-import-lambda-exec-role"
+  assume_role_policy = data.aws_iam_policy_document.get_import_lambda_assume_role_policy.json
+}
+
+data "aws_iam_policy_document" "get_import_dynamodb_policy" {
+  statement {
+    actions = [
+      "dynamodb:GetItem"
+    ]
+    resources = ["*"]
+  }
+}
+
+resource "aws_iam_policy" "get_import_dynamodb_policy" {
+  name        = "GetImportDynamoDBPolicy"
+  description = "Policy to allow reading import jobs from the Books DynamoDB table"
+  policy      = data.aws_iam_policy_document.get_import_dynamodb_policy.json
+}
+
+resource "aws_iam_role_policy_attachment" "get_import_lambda_dynamodb_read" {
+  role       = aws_iam_role.get_import_lambda_exec_role.name
+  policy_arn = aws_iam_policy.get_import_dynamodb_policy.arn
+}
+
+resource "aws_iam_role_policy_attachment" "get_import_lambda_basic_execution" {
+  role       = aws_iam_role.get_import_lambda_exec_role.name
+  policy_arn = "arn:aws:iam::aws:policy/service-role/AWSLambdaBasicExecutionRole"
+}
+
+resource "aws_cloudwatch_log_group" "get_import_lambda_log_group" {
+  name              = "/aws/lambda/get-import"
+  retention_in_days = 7
+}
+
+resource "aws_lambda_function" "get_import_lambda" {
+  function_name = "get-import"
+  role          = aws_iam_role.get_import_lambda_exec_role.arn
+  handler       = "bootstrap"
+  runtime       = "provided.al2"
+
+  filename         = "${local.get_import_lambda_source_dir}/dist/get-import.zip"
+  source_code_hash = local.get_import_source_hash
+
+  depends_on = [
+    aws_iam_role_policy_attachment.get_import_lambda_basic_execution,
+    aws_iam_role_policy_attachment.get_import_lambda_dynamodb_read,
+    null_resource.build_get_import_lambda,
+    aws_cloudwatch_log_group.get_import_lambda_log_group,
+  ]
+}
+
+resource "aws_apigatewayv2_integration" "get_import_lambda_integration" {
+  api_id           = aws_apigatewayv2_api.books_api.id
+  integration_type = "AWS_PROXY"
+
+  integration_uri        = aws_lambda_function.get_import_lambda.invoke_arn
+  payload_format_version = "2.0"
+}
+
+resource "aws_apigatewayv2_route" "get_import_route" {
+  api_id    = aws_apigatewayv2_api.books_api.id
+  route_key = "GET /imports/{id}"
+  target    = "integrations/${aws_apigatewayv2_integration.get_import_lambda_integration.id}"
+
+  authorization_type = "JWT"
+  authorizer_id      = aws_apigatewayv2_authorizer.cognito_authorizer.id
+}
+
+resource "aws_lambda_permission" "get_import_api_gateway_permission" {
+  statement_id  = "AllowAPIGatewayInvokeGetImport"
+  action        = "lambda:InvokeFunction"
+  function_name = aws_lambda_function.get_import_lambda.function_name
+  principal     = "apigateway.amazonaws.com"
+
+  source_arn = "${aws_apigatewayv2_api.books_api.execution_arn}/*/*"
+} 
\ No newline at end of file
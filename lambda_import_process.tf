@@ -0,0 +1,119 @@
+locals {
+  process_import_lambda_source_dir = "${path.module}/lambdas/process-import"
+  process_import_go_files_for_hash = fileset(local.process_import_lambda_source_dir, "**/*.go")
+  process_import_source_hash       = sha1(join("", concat([for f in local.process_import_go_files_for_hash : filesha1("${local.process_import_lambda_source_dir}/${f}")], [local.bookshelf_shared_source_hash])))
+}
+
+resource "null_resource" "build_process_import_lambda" {
+  triggers = {
+    source_hash = local.process_import_source_hash
+  }
+
+  provisioner "local-exec" {
+    command = "cd ${local.process_import_lambda_source_dir} && make zip"
+  }
+}
+
+data "aws_iam_policy_document" "process_import_lambda_assume_role_policy" {
+  statement {
+    actions = ["sts:AssumeRole"]
+    principals {
+      type        = "Service"
+      identifiers = ["lambda.amazonaws.com"]
+    }
+  }
+}
+
+resource "aws_iam_role" "process_import_lambda_exec_role" {
+  name               = "process-import-lambda-exec-role"
+  assume_role_policy = data.aws_iam_policy_document.process_import_lambda_assume_role_policy.json
+}
+
+data "aws_iam_policy_document" "process_import_dynamodb_policy" {
+  statement {
+    actions = [
+      "dynamodb:GetItem",
+      "dynamodb:PutItem",
+      "dynamodb:UpdateItem",
+      "dynamodb:Query",
+      "dynamodb:BatchWriteItem"
+    ]
+    resources = ["*"]
+  }
+}
+
+resource "aws_iam_policy" "process_import_dynamodb_policy" {
+  name        = "ProcessImportDynamoDBPolicy"
+  description = "Policy to allow importing books and tracking import jobs in the Books DynamoDB table"
+  policy      = data.aws_iam_policy_document.process_import_dynamodb_policy.json
+}
+
+resource "aws_iam_role_policy_attachment" "process_import_lambda_dynamodb_import" {
+  role       = aws_iam_role.process_import_lambda_exec_role.name
+  policy_arn = aws_iam_policy.process_import_dynamodb_policy.arn
+}
+
+data "aws_iam_policy_document" "process_import_s3_policy" {
+  statement {
+    actions = [
+      "s3:GetObject"
+    ]
+    resources = ["${aws_s3_bucket.imports.arn}/imports/*"]
+  }
+}
+
+resource "aws_iam_policy" "process_import_s3_policy" {
+  name        = "ProcessImportS3Policy"
+  description = "Policy to allow reading uploaded import files from the imports bucket"
+  policy      = data.aws_iam_policy_document.process_import_s3_policy.json
+}
+
+resource "aws_iam_role_policy_attachment" "process_import_lambda_s3_imports" {
+  role       = aws_iam_role.process_import_lambda_exec_role.name
+  policy_arn = aws_iam_policy.process_import_s3_policy.arn
+}
+
+resource "aws_iam_role_policy_attachment" "process_import_lambda_basic_execution" {
+  role       = aws_iam_role.process_import_lambda_exec_role.name
+  policy_arn = "arn:aws:iam::aws:policy/service-role/AWSLambdaBasicExecutionRole"
+}
+
+resource "aws_cloudwatch_log_group" "process_import_lambda_log_group" {
+  name              = "/aws/lambda/process-import"
+  retention_in_days = 7
+}
+
+resource "aws_lambda_function" "process_import_lambda" {
+  function_name = "process-import"
+  role          = aws_iam_role.process_import_lambda_exec_role.arn
+  handler       = "bootstrap"
+  runtime       = "provided.al2"
+  timeout       = 900
+  memory_size   = 512
+
+  environment {
+    variables = {
+      IMPORTS_BUCKET_NAME = aws_s3_bucket.imports.bucket
+    }
+  }
+
+  filename         = "${local.process_import_lambda_source_dir}/dist/process-import.zip"
+  source_code_hash = local.process_import_source_hash
+
+  depends_on = [
+    aws_iam_role_policy_attachment.process_import_lambda_basic_execution,
+    aws_iam_role_policy_attachment.process_import_lambda_dynamodb_import,
+    aws_iam_role_policy_attachment.process_import_lambda_s3_imports,
+    null_resource.build_process_import_lambda,
+    aws_cloudwatch_log_group.process_import_lambda_log_group,
+  ]
+}
+
+resource "aws_lambda_permission" "process_import_s3_permission" {
+  statement_id  = "AllowS3InvokeProcessImport"
+  action        = "lambda:InvokeFunction"
+  function_name = aws_lambda_function.process_import_lambda.function_name
+  principal     = "s3.amazonaws.com"
+
+  source_arn = aws_s3_bucket.imports.arn
+}
\ No newline at end of file
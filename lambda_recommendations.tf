@@ -1,7 +1,7 @@
 locals {
   recommendations_lambda_source_dir = "${path.module}/lambdas/recommendations"
   recommendations_go_files_for_hash = fileset(local.recommendations_lambda_source_dir, "**/*.go")
-  recommendations_source_hash       = sha1(join("", [for f in local.recommendations_go_files_for_hash : filesha1("${local.recommendations_lambda_source_dir}/${f}")]))
+  recommendations_source_hash       = sha1(join("", concat([for f in local.recommendations_go_files_for_hash : filesha1("${local.recommendations_lambda_source_dir}/${f}")], [local.bookshelf_shared_source_hash])))
 }
 
 resource "null_resource" "build_recommendations_lambda" {
@@ -0,0 +1,8 @@
+# Shared Go packages imported by the Lambda functions. Their sources, and the
+# templates embedded in them, are folded into each function's source hash so
+# a change here rebuilds every Lambda.
+locals {
+  bookshelf_shared_source_dir        = "${path.module}/lambdas/internal"
+  bookshelf_shared_go_files_for_hash = fileset(local.bookshelf_shared_source_dir, "**/*.{go,tmpl}")
+  bookshelf_shared_source_hash       = sha1(join("", [for f in local.bookshelf_shared_go_files_for_hash : filesha1("${local.bookshelf_shared_source_dir}/${f}")]))
+}
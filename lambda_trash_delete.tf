@@ -0,0 +1,101 @@
+locals {
+  purge_book_lambda_source_dir = "${path.module}/lambdas/purge-book"
+  purge_book_go_files_for_hash = fileset(local.purge_book_lambda_source_dir, "**/*.go")
+  purge_book_source_hash       = sha1(join("", concat([for f in local.purge_book_go_files_for_hash : filesha1("${local.purge_book_lambda_source_dir}/${f}")], [local.bookshelf_shared_source_hash])))
+}
+
+resource "null_resource" "build_purge_book_lambda" {
+  triggers = {
+    source_hash = local.purge_book_source_hash
+  }
+
+  provisioner "local-exec" {
+    command = "cd ${local.purge_book_lambda_source_dir} && make zip"
+  }
+}
+
+data "aws_iam_policy_document" "purge_book_lambda_assume_role_policy" {
+  statement {
+    actions = ["sts:AssumeRole"]
+    principals {
+      type        = "Service"
+      identifiers = ["lambda.amazonaws.com"]
+    }
+  }
+}
+
+resource "aws_iam_role" "purge_book_lambda_exec_role" {
+  name               = "purge-book-lambda-exec-role"
+  assume_role_policy = data.aws_iam_policy_document.purge_book_lambda_assume_role_policy.json
+}
+
+data "aws_iam_policy_document" "purge_book_dynamodb_policy" {
+  statement {
+    actions   = ["dynamodb:DeleteItem"]
+    resources = ["*"]
+  }
+}
+
+resource "aws_iam_policy" "purge_book_dynamodb_policy" {
+  name        = "PurgeBookDynamoDBPolicy"
+  description = "Policy to allow purging an item from the trash in the Books DynamoDB table"
+  policy      = data.aws_iam_policy_document.purge_book_dynamodb_policy.json
+}
+
+resource "aws_iam_role_policy_attachment" "purge_book_lambda_dynamodb_delete" {
+  role       = aws_iam_role.purge_book_lambda_exec_role.name
+  policy_arn = aws_iam_policy.purge_book_dynamodb_policy.arn
+}
+
+resource "aws_iam_role_policy_attachment" "purge_book_lambda_basic_execution" {
+  role       = aws_iam_role.purge_book_lambda_exec_role.name
+  policy_arn = "arn:aws:iam::aws:policy/service-role/AWSLambdaBasicExecutionRole"
+}
+
+resource "aws_cloudwatch_log_group" "purge_book_lambda_log_group" {
+  name              = "/aws/lambda/purge-book"
+  retention_in_days = 7
+}
+
+resource "aws_lambda_function" "purge_book_lambda" {
+  function_name = "purge-book"
+  role          = aws_iam_role.purge_book_lambda_exec_role.arn
+  handler       = "bootstrap"
+  runtime       = "provided.al2"
+
+  filename         = "${local.purge_book_lambda_source_dir}/dist/purge-book.zip"
+  source_code_hash = local.purge_book_source_hash
+
+  depends_on = [
+    aws_iam_role_policy_attachment.purge_book_lambda_basic_execution,
+    aws_iam_role_policy_attachment.purge_book_lambda_dynamodb_delete,
+    null_resource.build_purge_book_lambda,
+    aws_cloudwatch_log_group.purge_book_lambda_log_group,
+  ]
+}
+
+resource "aws_apigatewayv2_integration" "purge_book_lambda_integration" {
+  api_id           = aws_apigatewayv2_api.books_api.id
+  integration_type = "AWS_PROXY"
+
+  integration_uri        = aws_lambda_function.purge_book_lambda.invoke_arn
+  payload_format_version = "2.0"
+}
+
+resource "aws_apigatewayv2_route" "purge_book_route" {
+  api_id    = aws_apigatewayv2_api.books_api.id
+  route_key = "DELETE /trash/{id}"
+  target    = "integrations/${aws_apigatewayv2_integration.purge_book_lambda_integration.id}"
+
+  authorization_type = "JWT"
+  authorizer_id      = aws_apigatewayv2_authorizer.cognito_authorizer.id
+}
+
+resource "aws_lambda_permission" "purge_book_api_gateway_permission" {
+  statement_id  = "AllowAPIGatewayInvokePurgeBook"
+  action        = "lambda:InvokeFunction"
+  function_name = aws_lambda_function.purge_book_lambda.function_name
+  principal     = "apigateway.amazonaws.com"
+
+  source_arn = "${aws_apigatewayv2_api.books_api.execution_arn}/*/*"
+} 
\ No newline at end of file
@@ -0,0 +1,101 @@
+locals {
+  list_trash_lambda_source_dir = "${path.module}/lambdas/list-trash"
+  list_trash_go_files_for_hash = fileset(local.list_trash_lambda_source_dir, "**/*.go")
+  list_trash_source_hash       = sha1(join("", concat([for f in local.list_trash_go_files_for_hash : filesha1("${local.list_trash_lambda_source_dir}/${f}")], [local.bookshelf_shared_source_hash])))
+}
+
+resource "null_resource" "build_list_trash_lambda" {
+  triggers = {
+    source_hash = local.list_trash_source_hash
+  }
+
+  provisioner "local-exec" {
+    command = "cd ${local.list_trash_lambda_source_dir} && make zip"
+  }
+}
+
+data "aws_iam_policy_document" "list_trash_lambda_assume_role_policy" {
+  statement {
+    actions = ["sts:AssumeRole"]
+    principals {
+      type        = "Service"
+      identifiers = ["lambda.amazonaws.com"]
+    }
+  }
+}
+
+resource "aws_iam_role" "list_trash_lambda_exec_role" {
+  name               = "list-trash-lambda-exec-role"
+  assume_role_policy = data.aws_iam_policy_document.list_trash_lambda_assume_role_policy.json
+}
+
+data "aws_iam_policy_document" "list_trash_dynamodb_policy" {
+  statement {
+    actions   = ["dynamodb:Query"]
+    resources = ["*"]
+  }
+}
+
+resource "aws_iam_policy" "list_trash_dynamodb_policy" {
+  name        = "ListTrashDynamoDBPolicy"
+  description = "Policy to allow querying the trash in the Books DynamoDB table"
+  policy      = data.aws_iam_policy_document.list_trash_dynamodb_policy.json
+}
+
+resource "aws_iam_role_policy_attachment" "list_trash_lambda_dynamodb_read" {
+  role       = aws_iam_role.list_trash_lambda_exec_role.name
+  policy_arn = aws_iam_policy.list_trash_dynamodb_policy.arn
+}
+
+resource "aws_iam_role_policy_attachment" "list_trash_lambda_basic_execution" {
+  role       = aws_iam_role.list_trash_lambda_exec_role.name
+  policy_arn = "arn:aws:iam::aws:policy/service-role/AWSLambdaBasicExecutionRole"
+}
+
+resource "aws_cloudwatch_log_group" "list_trash_lambda_log_group" {
+  name              = "/aws/lambda/list-trash"
+  retention_in_days = 7
+}
+
+resource "aws_lambda_function" "list_trash_lambda" {
+  function_name = "list-trash"
+  role          = aws_iam_role.list_trash_lambda_exec_role.arn
+  handler       = "bootstrap"
+  runtime       = "provided.al2"
+
+  filename         = "${local.list_trash_lambda_source_dir}/dist/list-trash.zip"
+  source_code_hash = local.list_trash_source_hash
+
+  depends_on = [
+    aws_iam_role_policy_attachment.list_trash_lambda_basic_execution,
+    aws_iam_role_policy_attachment.list_trash_lambda_dynamodb_read,
+    null_resource.build_list_trash_lambda,
+    aws_cloudwatch_log_group.list_trash_lambda_log_group,
+  ]
+}
+
+resource "aws_apigatewayv2_integration" "list_trash_lambda_integration" {
+  api_id           = aws_apigatewayv2_api.books_api.id
+  integration_type = "AWS_PROXY"
+
+  integration_uri        = aws_lambda_function.list_trash_lambda.invoke_arn
+  payload_format_version = "2.0"
+}
+
+resource "aws_apigatewayv2_route" "list_trash_route" {
+  api_id    = aws_apigatewayv2_api.books_api.id
+  route_key = "GET /trash"
+  target    = "integrations/${aws_apigatewayv2_integration.list_trash_lambda_integration.id}"
+
+  authorization_type = "JWT"
+  authorizer_id      = aws_apigatewayv2_authorizer.cognito_authorizer.id
+}
+
+resource "aws_lambda_permission" "list_trash_api_gateway_permission" {
+  statement_id  = "AllowAPIGatewayInvokeListTrash"
+  action        = "lambda:InvokeFunction"
+  function_name = aws_lambda_function.list_trash_lambda.function_name
+  principal     = "apigateway.amazonaws.com"
+
+  source_arn = "${aws_apigatewayv2_api.books_api.execution_arn}/*/*"
+} 
\ No newline at end of file
@@ -0,0 +1,92 @@
+// Package handler implements GET /books/{id}/history.
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/bookshelf"
+)
+
+// Handler serves GET /books/{id}/history against an injected book
+// repository.
+type Handler struct {
+	Books bookshelf.BookRepository
+}
+
+// New returns a Handler backed by books.
+func New(books bookshelf.BookRepository) *Handler {
+	return &Handler{Books: books}
+}
+
+// Handle is the Lambda function handler.
+func (h *Handler) Handle(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Extract user ID from JWT claims
+	userID, err := bookshelf.UserID(request)
+	if err != nil {
+		log.Printf("Error extracting user ID: %v", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusUnauthorized,
+			Body:       "Unauthorized: Could not extract user ID",
+		}, nil
+	}
+
+	// Get the book ID from path parameters
+	bookID := request.PathParameters["id"]
+	if bookID == "" {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Body:       "Book ID is required",
+		}, nil
+	}
+
+	// Query the book's history, newest first
+	entries, err := h.Books.History(ctx, userID, bookID)
+	if err != nil {
+		log.Printf("Error querying history: %v", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Body:       "Internal Server Error",
+		}, nil
+	}
+
+	// A book stored before history was recorded has none yet; anything else
+	// without history does not exist
+	if len(entries) == 0 {
+		_, err := h.Books.Get(ctx, userID, bookID)
+		if errors.Is(err, bookshelf.ErrNotFound) {
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusNotFound,
+				Body:       "Book not found",
+			}, nil
+		}
+		if err != nil {
+			log.Printf("Error getting book: %v", err)
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusInternalServerError,
+				Body:       "Internal Server Error",
+			}, nil
+		}
+	}
+
+	body, err := json.Marshal(bookshelf.ToAPIHistory(entries))
+	if err != nil {
+		log.Printf("Error marshalling JSON: %v", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Body:       "Internal Server Error",
+		}, nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Headers: map[string]string{
+			"Content-Type": "application/json",
+		},
+		Body: string(body),
+	}, nil
+}
@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/bookshelf"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/handlertest"
+)
+
+// legacyBooks wraps a repository whose books were all stored before
+// history was recorded.
+type legacyBooks struct {
+	*bookshelf.MemoryRepository
+}
+
+func (legacyBooks) History(ctx context.Context, userID, bookID string) ([]bookshelf.HistoryEntry, error) {
+	return nil, nil
+}
+
+func TestHandle(t *testing.T) {
+	ctx := context.Background()
+	books := handlertest.Books(t, bookshelf.APIBook{ID: "book-1", Title: "Dune", Author: "Frank Herbert", Status: bookshelf.StatusReading})
+	book, err := books.Get(ctx, handlertest.UserID, "book-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	book.Status = bookshelf.StatusRead
+	if _, err := books.Update(bookshelf.WithRequestID(ctx, "request-2"), handlertest.UserID, book); err != nil {
+		t.Fatal(err)
+	}
+	deleted := bookshelf.NewBook(handlertest.UserID, "book-3", bookshelf.APIBook{Title: "Emma", Author: "Jane Austen", Status: bookshelf.StatusRead})
+	if err := books.Put(ctx, handlertest.UserID, deleted); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := books.Trash(ctx, handlertest.UserID, deleted); err != nil {
+		t.Fatal(err)
+	}
+	if err := books.Purge(ctx, handlertest.UserID, "book-3"); err != nil {
+		t.Fatal(err)
+	}
+	history := func(id string) events.APIGatewayProxyRequest {
+		return handlertest.Request(events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": id}})
+	}
+
+	tests := []struct {
+		name        string
+		books       bookshelf.BookRepository
+		request     events.APIGatewayProxyRequest
+		wantStatus  int
+		wantBody    string   // contained in the body
+		wantActions []string // of the entries, newest first
+		wantLatest  *bookshelf.APIHistoryEntry
+	}{
+		{
+			name:       "no claims",
+			books:      books,
+			request:    events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "book-1"}},
+			wantStatus: 401,
+			wantBody:   "Unauthorized",
+		},
+		{
+			name:       "no ID",
+			books:      books,
+			request:    history(""),
+			wantStatus: 400,
+			wantBody:   "Book ID is required",
+		},
+		{
+			name:       "not found",
+			books:      books,
+			request:    history("book-2"),
+			wantStatus: 404,
+			wantBody:   "Book not found",
+		},
+		{
+			name:        "history",
+			books:       books,
+			request:     history("book-1"),
+			wantStatus:  200,
+			wantActions: []string{bookshelf.HistoryUpdate, bookshelf.HistoryCreate},
+			wantLatest: &bookshelf.APIHistoryEntry{
+				BookID:    "book-1",
+				Action:    bookshelf.HistoryUpdate,
+				Version:   2,
+				RequestID: "request-2",
+				Changes:   []bookshelf.FieldChange{{Field: "status", From: bookshelf.StatusReading, To: bookshelf.StatusRead}},
+			},
+		},
+		{
+			name:        "purged book",
+			books:       books,
+			request:     history("book-3"),
+			wantStatus:  200,
+			wantActions: []string{bookshelf.HistoryDelete, bookshelf.HistoryCreate},
+		},
+		{
+			name:        "book stored before history",
+			books:       legacyBooks{books},
+			request:     history("book-1"),
+			wantStatus:  200,
+			wantBody:    "[]",
+			wantActions: []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := New(tt.books).Handle(ctx, tt.request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.wantStatus || !strings.Contains(resp.Body, tt.wantBody) {
+				t.Errorf("Handle = %d %s, want %d containing %q", resp.StatusCode, resp.Body, tt.wantStatus, tt.wantBody)
+			}
+			if tt.wantActions == nil {
+				return
+			}
+			var entries []bookshelf.APIHistoryEntry
+			if err := json.Unmarshal([]byte(resp.Body), &entries); err != nil {
+				t.Fatal(err)
+			}
+			actions := []string{}
+			for _, entry := range entries {
+				actions = append(actions, entry.Action)
+			}
+			if strings.Join(actions, ",") != strings.Join(tt.wantActions, ",") {
+				t.Errorf("actions = %v, want %v", actions, tt.wantActions)
+			}
+			if tt.wantLatest != nil {
+				latest := entries[0]
+				latest.Timestamp = ""
+				if !reflect.DeepEqual(latest, *tt.wantLatest) {
+					t.Errorf("latest entry = %+v, want %+v", latest, *tt.wantLatest)
+				}
+			}
+		})
+	}
+}
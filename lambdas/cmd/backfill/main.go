@@ -0,0 +1,116 @@
+// Command backfill writes the status-index and author-index keys onto book
+// items stored before those indexes existed, or before their keys changed,
+// as when author-index keys were normalized. It scans the whole table, so
+// run it once after deploying the indexes or a change to their keys; items
+// that already have current keys are left alone, making it safe to re-run.
+//
+//	cd lambdas/cmd/backfill && go run . -dry-run
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/bookshelf"
+)
+
+func main() {
+	table := flag.String("table", bookshelf.TableName, "DynamoDB table to backfill")
+	dryRun := flag.Bool("dry-run", false, "report items that need keys without writing them")
+	flag.Parse()
+
+	ctx := context.Background()
+
+	// Adaptive retries slow the scan down when DynamoDB throttles instead of
+	// failing part way through.
+	cfg, err := config.LoadDefaultConfig(ctx,
+		config.WithRetryMode(aws.RetryModeAdaptive),
+		config.WithRetryMaxAttempts(10),
+	)
+	if err != nil {
+		log.Fatalf("unable to load SDK config, %v", err)
+	}
+	client := dynamodb.NewFromConfig(cfg)
+
+	var scanned, updated, current, skipped int
+	paginator := dynamodb.NewScanPaginator(client, &dynamodb.ScanInput{
+		TableName: aws.String(*table),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			log.Fatalf("failed to scan %s: %v", *table, err)
+		}
+
+		for _, item := range page.Items {
+			scanned++
+
+			var book bookshelf.Book
+			if err := attributevalue.UnmarshalMap(item, &book); err != nil {
+				log.Fatalf("failed to unmarshal item: %v", err)
+			}
+			userID, isUser := bookshelf.UserIDFromPK(book.PK)
+			_, isBook := bookshelf.BookIDFromSK(book.SK)
+			if !isUser || !isBook {
+				skipped++
+				continue
+			}
+
+			want := book
+			want.SetKeys(userID)
+			if want.GSI1PK == book.GSI1PK && want.GSI1SK == book.GSI1SK &&
+				want.GSI2PK == book.GSI2PK && want.GSI2SK == book.GSI2SK {
+				current++
+				continue
+			}
+
+			if *dryRun {
+				log.Printf("would update %s %s: GSI1SK=%q GSI2SK=%q", book.PK, book.SK, want.GSI1SK, want.GSI2SK)
+				updated++
+				continue
+			}
+			if err := setKeys(ctx, client, *table, want); err != nil {
+				log.Fatalf("failed to update %s %s: %v", book.PK, book.SK, err)
+			}
+			updated++
+		}
+	}
+
+	verb := "Updated"
+	if *dryRun {
+		verb = "Would update"
+	}
+	log.Printf("Scanned %d items. %s %d, %d already current, %d skipped (not books).", scanned, verb, updated, current, skipped)
+}
+
+// setKeys writes book's index keys without touching its other attributes.
+// The condition stops a book deleted since the scan from being recreated.
+func setKeys(ctx context.Context, client *dynamodb.Client, table string, book bookshelf.Book) error {
+	_, err := client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(table),
+		Key: map[string]types.AttributeValue{
+			"PK": &types.AttributeValueMemberS{Value: book.PK},
+			"SK": &types.AttributeValueMemberS{Value: book.SK},
+		},
+		UpdateExpression:    aws.String("SET GSI1PK = :gsi1pk, GSI1SK = :gsi1sk, GSI2PK = :gsi2pk, GSI2SK = :gsi2sk"),
+		ConditionExpression: aws.String("attribute_exists(PK)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":gsi1pk": &types.AttributeValueMemberS{Value: book.GSI1PK},
+			":gsi1sk": &types.AttributeValueMemberS{Value: book.GSI1SK},
+			":gsi2pk": &types.AttributeValueMemberS{Value: book.GSI2PK},
+			":gsi2sk": &types.AttributeValueMemberS{Value: book.GSI2SK},
+		},
+	})
+	var ccf *types.ConditionalCheckFailedException
+	if errors.As(err, &ccf) {
+		return nil
+	}
+	return err
+}
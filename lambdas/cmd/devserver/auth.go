@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// claims returns the JWT claims API Gateway would pass to the Lambda. The
+// bearer token's payload is decoded without verifying its signature, so the
+// front end can act as whichever user it signed in as. Requests without a
+// usable token are attributed to defaultUserID.
+func claims(r *http.Request, defaultUserID string) map[string]interface{} {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if ok {
+		parts := strings.Split(token, ".")
+		if len(parts) == 3 {
+			payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+			var c map[string]interface{}
+			if err == nil && json.Unmarshal(payload, &c) == nil {
+				if _, ok := c["sub"].(string); ok {
+					return c
+				}
+			}
+		}
+	}
+	return map[string]interface{}{"sub": defaultUserID}
+}
+
+// fakeToken builds an unsigned JWT for userID that the front end accepts as a
+// Cognito token.
+func fakeToken(userID, email string) string {
+	header, _ := json.Marshal(map[string]string{"alg": "none", "typ": "JWT"})
+	payload, _ := json.Marshal(map[string]interface{}{
+		"sub":   userID,
+		"email": email,
+		"exp":   time.Now().Add(365 * 24 * time.Hour).Unix(),
+	})
+	enc := base64.RawURLEncoding
+	return enc.EncodeToString(header) + "." + enc.EncodeToString(payload) + ".local"
+}
+
+var devLoginPage = template.Must(template.New("dev-login").Parse(`<!DOCTYPE html>
+<html>
+<head><title>Local sign in</title></head>
+<body>
+<script>
+  localStorage.setItem('accessToken', {{.Token}});
+  localStorage.setItem('idToken', {{.Token}});
+  localStorage.setItem('refreshToken', 'local');
+  localStorage.setItem('userEmail', {{.Email}});
+  window.location.replace('/');
+</script>
+</body>
+</html>
+`))
+
+// devLogin stores fake Cognito tokens for userID in the browser and redirects
+// to the app, bypassing the Cognito sign-in page.
+func devLogin(userID string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		email := fmt.Sprintf("%s@localhost", userID)
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		devLoginPage.Execute(w, map[string]string{
+			"Token": fakeToken(userID, email),
+			"Email": email,
+		})
+	}
+}
@@ -0,0 +1,91 @@
+module github.com/ericdahl/bookshelf-aws/lambdas/cmd/devserver
+
+go 1.24.4
+
+require (
+	github.com/aws/aws-lambda-go v1.49.0
+	github.com/ericdahl/bookshelf-aws/lambdas/book-history v0.0.0-00010101000000-000000000000
+	github.com/ericdahl/bookshelf-aws/lambdas/create-book v0.0.0-00010101000000-000000000000
+	github.com/ericdahl/bookshelf-aws/lambdas/create-import v0.0.0-00010101000000-000000000000
+	github.com/ericdahl/bookshelf-aws/lambdas/delete-book v0.0.0-00010101000000-000000000000
+	github.com/ericdahl/bookshelf-aws/lambdas/export-books v0.0.0-00010101000000-000000000000
+	github.com/ericdahl/bookshelf-aws/lambdas/get-book v0.0.0-00010101000000-000000000000
+	github.com/ericdahl/bookshelf-aws/lambdas/get-export v0.0.0-00010101000000-000000000000
+	github.com/ericdahl/bookshelf-aws/lambdas/get-export-url v0.0.0-00010101000000-000000000000
+	github.com/ericdahl/bookshelf-aws/lambdas/get-import v0.0.0-00010101000000-000000000000
+	github.com/ericdahl/bookshelf-aws/lambdas/import-backup v0.0.0-00010101000000-000000000000
+	github.com/ericdahl/bookshelf-aws/lambdas/import-books v0.0.0-00010101000000-000000000000
+	github.com/ericdahl/bookshelf-aws/lambdas/internal v0.0.0-00010101000000-000000000000
+	github.com/ericdahl/bookshelf-aws/lambdas/list-books v0.0.0-00010101000000-000000000000
+	github.com/ericdahl/bookshelf-aws/lambdas/list-exports v0.0.0-00010101000000-000000000000
+	github.com/ericdahl/bookshelf-aws/lambdas/list-trash v0.0.0-00010101000000-000000000000
+	github.com/ericdahl/bookshelf-aws/lambdas/merge-book v0.0.0-00010101000000-000000000000
+	github.com/ericdahl/bookshelf-aws/lambdas/patch-book v0.0.0-00010101000000-000000000000
+	github.com/ericdahl/bookshelf-aws/lambdas/process-export v0.0.0-00010101000000-000000000000
+	github.com/ericdahl/bookshelf-aws/lambdas/process-import v0.0.0-00010101000000-000000000000
+	github.com/ericdahl/bookshelf-aws/lambdas/purge-book v0.0.0-00010101000000-000000000000
+	github.com/ericdahl/bookshelf-aws/lambdas/recommendations v0.0.0-00010101000000-000000000000
+	github.com/ericdahl/bookshelf-aws/lambdas/restore-book v0.0.0-00010101000000-000000000000
+	github.com/ericdahl/bookshelf-aws/lambdas/revert-book v0.0.0-00010101000000-000000000000
+	github.com/ericdahl/bookshelf-aws/lambdas/search-books v0.0.0-00010101000000-000000000000
+	github.com/ericdahl/bookshelf-aws/lambdas/update-book v0.0.0-00010101000000-000000000000
+)
+
+require (
+	github.com/aws/aws-sdk-go-v2 v1.36.6 // indirect
+	github.com/aws/aws-sdk-go-v2/aws/protocol/eventstream v1.6.11 // indirect
+	github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue v1.19.6 // indirect
+	github.com/aws/aws-sdk-go-v2/internal/configsources v1.3.37 // indirect
+	github.com/aws/aws-sdk-go-v2/internal/endpoints/v2 v2.6.37 // indirect
+	github.com/aws/aws-sdk-go-v2/internal/v4a v1.3.25 // indirect
+	github.com/aws/aws-sdk-go-v2/service/bedrockruntime v1.20.2 // indirect
+	github.com/aws/aws-sdk-go-v2/service/dynamodb v1.44.1 // indirect
+	github.com/aws/aws-sdk-go-v2/service/dynamodbstreams v1.26.1 // indirect
+	github.com/aws/aws-sdk-go-v2/service/internal/accept-encoding v1.12.4 // indirect
+	github.com/aws/aws-sdk-go-v2/service/internal/checksum v1.4.6 // indirect
+	github.com/aws/aws-sdk-go-v2/service/internal/endpoint-discovery v1.10.18 // indirect
+	github.com/aws/aws-sdk-go-v2/service/internal/presigned-url v1.12.17 // indirect
+	github.com/aws/aws-sdk-go-v2/service/internal/s3shared v1.18.6 // indirect
+	github.com/aws/aws-sdk-go-v2/service/lambda v1.73.0 // indirect
+	github.com/aws/aws-sdk-go-v2/service/s3 v1.70.0 // indirect
+	github.com/aws/smithy-go v1.22.4 // indirect
+	github.com/go-pdf/fpdf v0.9.0 // indirect
+	github.com/google/uuid v1.6.0 // indirect
+	github.com/mohae/deepcopy v0.0.0-20170929034955-c48cc78d4826 // indirect
+	github.com/richardlehane/mscfb v1.0.4 // indirect
+	github.com/richardlehane/msoleps v1.0.4 // indirect
+	github.com/xuri/efp v0.0.0-20240408161823-9ad904a10d6d // indirect
+	github.com/xuri/excelize/v2 v2.9.0 // indirect
+	github.com/xuri/nfp v0.0.0-20240318013403-ab9948c2c4a7 // indirect
+	golang.org/x/crypto v0.28.0 // indirect
+	golang.org/x/net v0.30.0 // indirect
+	golang.org/x/text v0.19.0 // indirect
+)
+
+replace (
+	github.com/ericdahl/bookshelf-aws/lambdas/book-history => ../../book-history
+	github.com/ericdahl/bookshelf-aws/lambdas/create-book => ../../create-book
+	github.com/ericdahl/bookshelf-aws/lambdas/create-import => ../../create-import
+	github.com/ericdahl/bookshelf-aws/lambdas/delete-book => ../../delete-book
+	github.com/ericdahl/bookshelf-aws/lambdas/export-books => ../../export-books
+	github.com/ericdahl/bookshelf-aws/lambdas/get-book => ../../get-book
+	github.com/ericdahl/bookshelf-aws/lambdas/get-export => ../../get-export
+	github.com/ericdahl/bookshelf-aws/lambdas/get-export-url => ../../get-export-url
+	github.com/ericdahl/bookshelf-aws/lambdas/get-import => ../../get-import
+	github.com/ericdahl/bookshelf-aws/lambdas/import-backup => ../../import-backup
+	github.com/ericdahl/bookshelf-aws/lambdas/import-books => ../../import-books
+	github.com/ericdahl/bookshelf-aws/lambdas/internal => ../../internal
+	github.com/ericdahl/bookshelf-aws/lambdas/list-books => ../../list-books
+	github.com/ericdahl/bookshelf-aws/lambdas/list-exports => ../../list-exports
+	github.com/ericdahl/bookshelf-aws/lambdas/list-trash => ../../list-trash
+	github.com/ericdahl/bookshelf-aws/lambdas/merge-book => ../../merge-book
+	github.com/ericdahl/bookshelf-aws/lambdas/patch-book => ../../patch-book
+	github.com/ericdahl/bookshelf-aws/lambdas/process-export => ../../process-export
+	github.com/ericdahl/bookshelf-aws/lambdas/process-import => ../../process-import
+	github.com/ericdahl/bookshelf-aws/lambdas/purge-book => ../../purge-book
+	github.com/ericdahl/bookshelf-aws/lambdas/recommendations => ../../recommendations
+	github.com/ericdahl/bookshelf-aws/lambdas/restore-book => ../../restore-book
+	github.com/ericdahl/bookshelf-aws/lambdas/revert-book => ../../revert-book
+	github.com/ericdahl/bookshelf-aws/lambdas/search-books => ../../search-books
+	github.com/ericdahl/bookshelf-aws/lambdas/update-book => ../../update-book
+)
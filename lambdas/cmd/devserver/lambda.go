@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// lambdaHandler is the signature shared by every Lambda handler.
+type lambdaHandler func(context.Context, events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error)
+
+// adapt serves a Lambda handler over net/http. Each request is translated into
+// the event API Gateway would send, including JWT authorizer claims taken from
+// the bearer token (or the default user when there is none).
+func adapt(h lambdaHandler, defaultUserID string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, "failed to read request body", http.StatusBadRequest)
+			return
+		}
+
+		request := events.APIGatewayProxyRequest{
+			Resource:                        r.Pattern,
+			Path:                            r.URL.Path,
+			HTTPMethod:                      r.Method,
+			Headers:                         map[string]string{},
+			MultiValueHeaders:               map[string][]string{},
+			QueryStringParameters:           map[string]string{},
+			MultiValueQueryStringParameters: map[string][]string{},
+			PathParameters:                  map[string]string{},
+			Body:                            string(body),
+			RequestContext: events.APIGatewayProxyRequestContext{
+				RequestID:  requestID(),
+				HTTPMethod: r.Method,
+				Path:       r.URL.Path,
+				Authorizer: map[string]interface{}{
+					"jwt": map[string]interface{}{
+						"claims": claims(r, defaultUserID),
+					},
+				},
+			},
+		}
+		for name, values := range r.Header {
+			name = strings.ToLower(name)
+			request.Headers[name] = strings.Join(values, ",")
+			request.MultiValueHeaders[name] = values
+		}
+		for name, values := range r.URL.Query() {
+			request.QueryStringParameters[name] = values[0]
+			request.MultiValueQueryStringParameters[name] = values
+		}
+		if id := r.PathValue("id"); id != "" {
+			request.PathParameters["id"] = id
+		}
+
+		response, err := h(r.Context(), request)
+		if err != nil {
+			log.Printf("Handler error: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		writeResponse(w, response)
+	})
+}
+
+// writeResponse copies a Lambda proxy response onto w.
+func writeResponse(w http.ResponseWriter, response events.APIGatewayProxyResponse) {
+	for name, value := range response.Headers {
+		w.Header().Set(name, value)
+	}
+	for name, values := range response.MultiValueHeaders {
+		for _, value := range values {
+			w.Header().Add(name, value)
+		}
+	}
+
+	body := []byte(response.Body)
+	if response.IsBase64Encoded {
+		decoded, err := base64.StdEncoding.DecodeString(response.Body)
+		if err != nil {
+			log.Printf("Invalid base64 response body: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		body = decoded
+	}
+
+	status := response.StatusCode
+	if status == 0 {
+		status = http.StatusOK
+	}
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+func requestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
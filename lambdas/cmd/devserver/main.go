@@ -0,0 +1,173 @@
+// Command devserver runs the whole bookshelf app on a laptop. It mounts every
+// Lambda handler behind net/http using the same routes as API Gateway, keeps
+// books in memory, keeps exports and uploaded imports in local directories
+// and serves the web/ front end, so no AWS account is needed. seed.json, next
+// to this file, is a small sample library to start from:
+//
+//	cd lambdas/cmd/devserver && go run . -seed seed.json
+//
+// Then open http://localhost:8080/dev-login to sign in as the local user.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/bookshelf"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/exporter"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/importer"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/objectstore"
+
+	bookhistory "github.com/ericdahl/bookshelf-aws/lambdas/book-history/handler"
+	createbook "github.com/ericdahl/bookshelf-aws/lambdas/create-book/handler"
+	createimport "github.com/ericdahl/bookshelf-aws/lambdas/create-import/handler"
+	deletebook "github.com/ericdahl/bookshelf-aws/lambdas/delete-book/handler"
+	exportbooks "github.com/ericdahl/bookshelf-aws/lambdas/export-books/handler"
+	getbook "github.com/ericdahl/bookshelf-aws/lambdas/get-book/handler"
+	getexporturl "github.com/ericdahl/bookshelf-aws/lambdas/get-export-url/handler"
+	getexport "github.com/ericdahl/bookshelf-aws/lambdas/get-export/handler"
+	getimport "github.com/ericdahl/bookshelf-aws/lambdas/get-import/handler"
+	importbackup "github.com/ericdahl/bookshelf-aws/lambdas/import-backup/handler"
+	importbooks "github.com/ericdahl/bookshelf-aws/lambdas/import-books/handler"
+	listbooks "github.com/ericdahl/bookshelf-aws/lambdas/list-books/handler"
+	listexports "github.com/ericdahl/bookshelf-aws/lambdas/list-exports/handler"
+	listtrash "github.com/ericdahl/bookshelf-aws/lambdas/list-trash/handler"
+	mergebook "github.com/ericdahl/bookshelf-aws/lambdas/merge-book/handler"
+	patchbook "github.com/ericdahl/bookshelf-aws/lambdas/patch-book/handler"
+	processexport "github.com/ericdahl/bookshelf-aws/lambdas/process-export/handler"
+	processimport "github.com/ericdahl/bookshelf-aws/lambdas/process-import/handler"
+	purgebook "github.com/ericdahl/bookshelf-aws/lambdas/purge-book/handler"
+	recommendations "github.com/ericdahl/bookshelf-aws/lambdas/recommendations/handler"
+	restorebook "github.com/ericdahl/bookshelf-aws/lambdas/restore-book/handler"
+	revertbook "github.com/ericdahl/bookshelf-aws/lambdas/revert-book/handler"
+	searchbooks "github.com/ericdahl/bookshelf-aws/lambdas/search-books/handler"
+	updatebook "github.com/ericdahl/bookshelf-aws/lambdas/update-book/handler"
+)
+
+// exportsPath is where the local export store is served, standing in for the
+// pre-signed S3 URLs returned in AWS.
+const exportsPath = "/local-exports/"
+
+// importsPath is where uploads to the local import store are accepted,
+// standing in for the pre-signed S3 upload URLs returned in AWS.
+const importsPath = "/local-imports/"
+
+func main() {
+	addr := flag.String("addr", "localhost:8080", "address to listen on")
+	webDir := flag.String("web", "../../../web", "directory holding the static front end")
+	exportsDir := flag.String("exports", filepath.Join(os.TempDir(), "bookshelf-exports"), "directory export files are written to")
+	importsDir := flag.String("imports", filepath.Join(os.TempDir(), "bookshelf-imports"), "directory uploaded import files are written to")
+	userID := flag.String("user", "local-user", "user ID used when a request carries no bearer token")
+	seed := flag.String("seed", "", "optional JSON file of books to load for -user at startup")
+	flag.Parse()
+
+	books := bookshelf.NewMemoryRepository()
+	if *seed != "" {
+		n, err := loadSeed(context.Background(), books, *userID, *seed)
+		if err != nil {
+			log.Fatalf("failed to load seed file: %v", err)
+		}
+		log.Printf("Loaded %d books from %s for user %s", n, *seed, *userID)
+	}
+
+	cursors, err := bookshelf.CursorCodecFromEnv()
+	if err != nil {
+		log.Fatalf("failed to create cursor codec: %v", err)
+	}
+	store := objectstore.NewFileStore(*exportsDir, "http://"+*addr+exportsPath)
+	importStore := objectstore.NewFileStore(*importsDir, "http://"+*addr+importsPath)
+	jobs := importer.NewMemoryJobRepository()
+	exportJobs := exporter.NewMemoryJobRepository()
+	exportQueue := goQueue(processexport.New(books, exportJobs, store).Handle)
+
+	mux := http.NewServeMux()
+	routes := map[string]lambdaHandler{
+		"GET /books":               listbooks.New(books, cursors).Handle,
+		"POST /books":              createbook.New(books).Handle,
+		"GET /books/{id}":          getbook.New(books).Handle,
+		"PUT /books/{id}":          updatebook.New(books).Handle,
+		"PATCH /books/{id}":        patchbook.New(books).Handle,
+		"POST /books/{id}/merge":   mergebook.New(books).Handle,
+		"GET /books/{id}/history":  bookhistory.New(books).Handle,
+		"POST /books/{id}/revert":  revertbook.New(books).Handle,
+		"DELETE /books/{id}":       deletebook.New(books).Handle,
+		"GET /trash":               listtrash.New(books).Handle,
+		"POST /trash/{id}/restore": restorebook.New(books).Handle,
+		"DELETE /trash/{id}":       purgebook.New(books).Handle,
+		"GET /search":              searchbooks.New(http.DefaultClient).Handle,
+		"GET /recommendations":     recommendations.New(books, nil).Handle,
+		"POST /export":             exportbooks.New(books, store, exportJobs, exportQueue).Handle,
+		"GET /exports":             listexports.New(exportJobs, store).Handle,
+		"GET /exports/{id}":        getexport.New(exportJobs, store).Handle,
+		"GET /exports/{id}/url":    getexporturl.New(exportJobs, store).Handle,
+		"POST /import":             importbooks.New(books).Handle,
+		"POST /import/backup":      importbackup.New(books).Handle,
+		"POST /imports":            createimport.New(jobs, importStore).Handle,
+		"GET /imports/{id}":        getimport.New(jobs).Handle,
+	}
+	for pattern, h := range routes {
+		// API Gateway exposes every route both bare and under /api for CloudFront.
+		mux.Handle(pattern, adapt(h, *userID))
+		mux.Handle(withPrefix(pattern, "/api"), adapt(h, *userID))
+	}
+
+	mux.HandleFunc("GET /dev-login", devLogin(*userID))
+	mux.Handle(exportsPath, http.StripPrefix(exportsPath, http.FileServer(http.Dir(*exportsDir))))
+	mux.Handle("PUT "+importsPath+"{key...}", upload(importStore, "local-imports", processimport.New(books, jobs, importStore).Handle))
+	mux.Handle("/", http.FileServer(http.Dir(*webDir)))
+
+	log.Printf("Serving %s and the API on http://%s (sign in at /dev-login)", *webDir, *addr)
+	log.Fatal(http.ListenAndServe(*addr, logRequests(mux)))
+}
+
+// withPrefix inserts prefix into a "METHOD /path" mux pattern.
+func withPrefix(pattern, prefix string) string {
+	var method, path string
+	fmt.Sscan(pattern, &method, &path)
+	return method + " " + prefix + path
+}
+
+// loadSeed stores the books in the JSON file at path for userID.
+func loadSeed(ctx context.Context, books bookshelf.BookRepository, userID, path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	var seed []bookshelf.APIBook
+	if err := json.Unmarshal(data, &seed); err != nil {
+		return 0, fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+
+	for _, book := range seed {
+		if err := books.Put(ctx, userID, bookshelf.NewBook(userID, book.ID, book)); err != nil {
+			return 0, err
+		}
+	}
+	return len(seed), nil
+}
+
+// logRequests logs the method, path and status of every request.
+func logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		log.Printf("%s %s %d", r.Method, r.URL.RequestURI(), rec.status)
+	})
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/objectstore"
+)
+
+// s3EventHandler is the signature of a Lambda handler for S3 event
+// notifications.
+type s3EventHandler func(context.Context, events.S3Event) error
+
+// upload accepts PUTs to the upload URLs handed out by a FileStore, standing
+// in for S3. The body is stored under the path's key and then, like an S3
+// event notification, h is invoked in the background with an
+// ObjectCreated:Put event for it.
+func upload(store objectstore.Store, bucket string, h s3EventHandler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.PathValue("key")
+		if err := store.Put(r.Context(), key, r.Body, nil); err != nil {
+			log.Printf("Upload error: %v", err)
+			http.Error(w, "failed to store upload", http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+
+		event := events.S3Event{
+			Records: []events.S3EventRecord{{
+				EventSource: "aws:s3",
+				EventTime:   time.Now().UTC(),
+				EventName:   "ObjectCreated:Put",
+				S3: events.S3Entity{
+					Bucket: events.S3Bucket{Name: bucket},
+					Object: events.S3Object{Key: key, URLDecodedKey: key},
+				},
+			}},
+		}
+		go func() {
+			if err := h(context.Background(), event); err != nil {
+				log.Printf("Upload handler error: %v", err)
+			}
+		}()
+	})
+}
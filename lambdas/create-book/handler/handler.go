@@ -0,0 +1,234 @@
+// Package handler implements POST /books.
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/bookshelf"
+	"github.com/google/uuid"
+)
+
+// Handler serves POST /books against an injected book repository.
+type Handler struct {
+	Books bookshelf.BookRepository
+}
+
+// New returns a Handler backed by books.
+func New(books bookshelf.BookRepository) *Handler {
+	return &Handler{Books: books}
+}
+
+// Handle is the Lambda function handler.
+func (h *Handler) Handle(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Extract user ID from JWT claims
+	userID, err := bookshelf.UserID(request)
+	if err != nil {
+		log.Printf("Error extracting user ID: %v", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusUnauthorized,
+			Body:       "Unauthorized: Could not extract user ID",
+		}, nil
+	}
+
+	// Record the request ID with the change in the book's history
+	ctx = bookshelf.WithRequestID(ctx, request.RequestContext.RequestID)
+
+	// Retries carrying the same Idempotency-Key get the original response
+	idempotencyKey, err := bookshelf.ParseIdempotencyKey(request)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Body:       err.Error(),
+		}, nil
+	}
+
+	// force=true skips the duplicate check
+	force := false
+	if value, ok := request.QueryStringParameters["force"]; ok {
+		if force, err = strconv.ParseBool(value); err != nil {
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusBadRequest,
+				Body:       "Invalid force. Must be true or false",
+			}, nil
+		}
+	}
+
+	// A retry of a request that already created a book gets the original
+	// response before the book can be mistaken for a duplicate of itself
+	if idempotencyKey != "" {
+		existing, err := h.Books.GetIdempotencyRecord(ctx, userID, idempotencyKey)
+		if err != nil {
+			log.Printf("Error getting idempotency record: %v", err)
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusInternalServerError,
+				Body:       "Internal Server Error",
+			}, nil
+		}
+		if existing != nil {
+			return replay(*existing, request.Body), nil
+		}
+	}
+
+	// Parse the request body
+	var bookRequest bookshelf.APIBook
+	if err := json.Unmarshal([]byte(request.Body), &bookRequest); err != nil {
+		log.Printf("Error parsing request body: %v", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Body:       "Invalid request body",
+		}, nil
+	}
+
+	// Validate required fields
+	if bookRequest.Title == "" {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Body:       "Title is required",
+		}, nil
+	}
+
+	if bookRequest.Author == "" {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Body:       "Author is required",
+		}, nil
+	}
+
+	// Validate status
+	if bookRequest.Status == "" {
+		bookRequest.Status = bookshelf.StatusWantToRead // Default status
+	} else if !bookshelf.ValidStatus(bookRequest.Status) {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Body:       bookshelf.InvalidStatusMessage,
+		}, nil
+	}
+
+	// Refuse likely duplicates unless the client insists. A title can only
+	// match among the author's books, one partition of AuthorIndex, but an
+	// ISBN or volume can match a book filed under any author, so a book
+	// with either is checked against the whole library.
+	if !force {
+		candidate := bookshelf.NewBook(userID, "", bookRequest)
+		var books []bookshelf.Book
+		var err error
+		if candidate.ISBN != "" || candidate.VolumeID != "" {
+			books, err = bookshelf.ListAll(ctx, h.Books, userID, bookshelf.ListOptions{})
+		} else {
+			books, err = h.Books.ListByAuthor(ctx, userID, candidate.Author)
+		}
+		if err != nil {
+			log.Printf("Error listing books: %v", err)
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusInternalServerError,
+				Body:       "Internal Server Error",
+			}, nil
+		}
+		if duplicate, ok := bookshelf.FindDuplicate(books, candidate); ok {
+			// The duplicate may be the book of a concurrent request with
+			// the same key that was stored after the key was looked up
+			if idempotencyKey != "" {
+				existing, err := h.Books.GetIdempotencyRecord(ctx, userID, idempotencyKey)
+				if err != nil {
+					log.Printf("Error getting idempotency record: %v", err)
+					return events.APIGatewayProxyResponse{
+						StatusCode: http.StatusInternalServerError,
+						Body:       "Internal Server Error",
+					}, nil
+				}
+				if existing != nil {
+					return replay(*existing, request.Body), nil
+				}
+			}
+			return duplicateResponse(duplicate), nil
+		}
+	}
+
+	// Generate UUID for the book
+	bookID := uuid.New().String()
+
+	// Create the book record
+	book := bookshelf.NewBook(userID, bookID, bookRequest)
+	book.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+
+	body, err := json.Marshal(book.ToAPI())
+	if err != nil {
+		log.Printf("Error marshalling JSON: %v", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Body:       "Internal Server Error",
+		}, nil
+	}
+
+	response := events.APIGatewayProxyResponse{
+		StatusCode: http.StatusCreated,
+		Headers: map[string]string{
+			"Content-Type": "application/json",
+			"ETag":         bookshelf.ETag(book.Version),
+		},
+		Body: string(body),
+	}
+
+	// Store the book
+	if idempotencyKey == "" {
+		err = h.Books.Put(ctx, userID, book)
+	} else {
+		var existing *bookshelf.IdempotencyRecord
+		record := bookshelf.NewIdempotencyRecord(idempotencyKey, request.Body, response)
+		existing, err = h.Books.PutIdempotent(ctx, userID, book, record)
+		if err == nil && existing != nil {
+			return replay(*existing, request.Body), nil
+		}
+	}
+	if err != nil {
+		log.Printf("Error storing book: %v", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Body:       "Internal Server Error",
+		}, nil
+	}
+
+	return response, nil
+}
+
+// replay answers a request whose Idempotency-Key has been seen before: with
+// the original response if it is a retry, or 422 if the key was reused for a
+// different request.
+func replay(record bookshelf.IdempotencyRecord, body string) events.APIGatewayProxyResponse {
+	if !record.Matches(body) {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusUnprocessableEntity,
+			Body:       "Idempotency-Key has already been used with a different request body",
+		}
+	}
+	return record.Response()
+}
+
+// duplicateResponse reports a likely duplicate of the book being created,
+// returning the existing book so the client can offer to open it, merge into
+// it or retry with force=true.
+func duplicateResponse(duplicate bookshelf.Book) events.APIGatewayProxyResponse {
+	body, err := json.Marshal(duplicate.ToAPI())
+	if err != nil {
+		log.Printf("Error marshalling JSON: %v", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Body:       "Internal Server Error",
+		}
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusConflict,
+		Headers: map[string]string{
+			"Content-Type": "application/json",
+			"Location":     "/books/" + duplicate.ID,
+		},
+		Body: string(body),
+	}
+}
@@ -0,0 +1,327 @@
+package handler
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/bookshelf"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/handlertest"
+)
+
+var (
+	dune   = bookshelf.APIBook{ID: "book-1", Title: "Dune", Author: "Frank Herbert", Status: bookshelf.StatusRead, ISBN: "0441013597", VolumeID: "B1hSG45JCX4C"}
+	hobbit = bookshelf.APIBook{ID: "book-2", Title: "The Hobbit", Author: "J.R.R. Tolkien", Status: bookshelf.StatusRead}
+)
+
+// libraryReads counts the pages of the whole library read, which the
+// duplicate check only needs for a book with an ISBN or volume.
+type libraryReads struct {
+	bookshelf.BookRepository
+	pages int
+}
+
+func (r *libraryReads) ListPage(ctx context.Context, userID string, opts bookshelf.PageOptions) (bookshelf.Page, error) {
+	r.pages++
+	return r.BookRepository.ListPage(ctx, userID, opts)
+}
+
+func TestHandle(t *testing.T) {
+	post := func(body string, query map[string]string) events.APIGatewayProxyRequest {
+		return handlertest.Request(events.APIGatewayProxyRequest{Body: body, QueryStringParameters: query})
+	}
+
+	tests := []struct {
+		name       string
+		request    events.APIGatewayProxyRequest
+		wantStatus int
+		wantBody   string // contained in the body
+		wantBooks  int    // the user has after the request
+		wantRead   bool   // the whole library, rather than the author's books
+	}{
+		{
+			name:       "no claims",
+			request:    events.APIGatewayProxyRequest{Body: `{"title":"Emma","author":"Jane Austen"}`},
+			wantStatus: 401,
+			wantBody:   "Unauthorized",
+			wantBooks:  2,
+		},
+		{
+			name:       "invalid JSON",
+			request:    post(`{"title":`, nil),
+			wantStatus: 400,
+			wantBody:   "Invalid request body",
+			wantBooks:  2,
+		},
+		{
+			name:       "no title",
+			request:    post(`{"author":"Jane Austen"}`, nil),
+			wantStatus: 400,
+			wantBody:   "Title is required",
+			wantBooks:  2,
+		},
+		{
+			name:       "no author",
+			request:    post(`{"title":"Emma"}`, nil),
+			wantStatus: 400,
+			wantBody:   "Author is required",
+			wantBooks:  2,
+		},
+		{
+			name:       "invalid status",
+			request:    post(`{"title":"Emma","author":"Jane Austen","status":"DONE"}`, nil),
+			wantStatus: 400,
+			wantBody:   bookshelf.InvalidStatusMessage,
+			wantBooks:  2,
+		},
+		{
+			name:       "invalid force",
+			request:    post(`{"title":"Emma","author":"Jane Austen"}`, map[string]string{"force": "maybe"}),
+			wantStatus: 400,
+			wantBody:   "Invalid force",
+			wantBooks:  2,
+		},
+		{
+			name: "empty Idempotency-Key",
+			request: handlertest.Request(events.APIGatewayProxyRequest{
+				Headers: map[string]string{"Idempotency-Key": ""},
+				Body:    `{"title":"Emma","author":"Jane Austen"}`,
+			}),
+			wantStatus: 400,
+			wantBooks:  2,
+		},
+		{
+			name:       "duplicate",
+			request:    post(`{"title":"dune","author":"Frank Herbert"}`, nil),
+			wantStatus: 409,
+			wantBody:   `"id":"book-1"`,
+			wantBooks:  2,
+		},
+		{
+			name:       "duplicate title ignoring case and punctuation",
+			request:    post(`{"title":"The Dune.","author":"frank herbert "}`, nil),
+			wantStatus: 409,
+			wantBody:   `"id":"book-1"`,
+			wantBooks:  2,
+		},
+		{
+			name:       "duplicate ISBN",
+			request:    post(`{"title":"Dune (Deluxe Edition)","author":"Frank Herbert","isbn":"978-0-441-01359-3"}`, nil),
+			wantStatus: 409,
+			wantBody:   `"id":"book-1"`,
+			wantBooks:  2,
+			wantRead:   true,
+		},
+		{
+			name:       "duplicate ISBN under another author",
+			request:    post(`{"title":"Dune","author":"Herbert, Frank","isbn":"0441013597"}`, nil),
+			wantStatus: 409,
+			wantBody:   `"id":"book-1"`,
+			wantBooks:  2,
+			wantRead:   true,
+		},
+		{
+			name:       "duplicate volume",
+			request:    post(`{"title":"Dune: Book One","author":"FRANK HERBERT","google_volume_id":"B1hSG45JCX4C"}`, nil),
+			wantStatus: 409,
+			wantBody:   `"id":"book-1"`,
+			wantBooks:  2,
+			wantRead:   true,
+		},
+		{
+			name:       "duplicate volume under another author",
+			request:    post(`{"title":"Dune","author":"F. Herbert","google_volume_id":"B1hSG45JCX4C"}`, nil),
+			wantStatus: 409,
+			wantBody:   `"id":"book-1"`,
+			wantBooks:  2,
+			wantRead:   true,
+		},
+		{
+			name:       "duplicate author punctuated differently",
+			request:    post(`{"title":"The Hobbit","author":"JRR Tolkien"}`, nil),
+			wantStatus: 409,
+			wantBody:   `"id":"book-2"`,
+			wantBooks:  2,
+		},
+		{
+			name:       "same title by an author with other initials",
+			request:    post(`{"title":"The Hobbit","author":"J. R. Tolkien"}`, nil),
+			wantStatus: 201,
+			wantBooks:  3,
+		},
+		{
+			name:       "same title by another author",
+			request:    post(`{"title":"Dune","author":"Brian Herbert"}`, nil),
+			wantStatus: 201,
+			wantBody:   `"author":"Brian Herbert"`,
+			wantBooks:  3,
+		},
+		{
+			name:       "duplicate with force",
+			request:    post(`{"title":"dune","author":"Frank Herbert"}`, map[string]string{"force": "true"}),
+			wantStatus: 201,
+			wantBody:   `"title":"dune"`,
+			wantBooks:  3,
+		},
+		{
+			name:       "new book",
+			request:    post(`{"title":"Emma","author":"Jane Austen"}`, nil),
+			wantStatus: 201,
+			wantBody:   `"status":"WANT_TO_READ"`,
+			wantBooks:  3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			books := handlertest.Books(t, dune, hobbit)
+			reads := &libraryReads{BookRepository: books}
+			resp, err := New(reads).Handle(context.Background(), tt.request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.wantStatus || !strings.Contains(resp.Body, tt.wantBody) {
+				t.Errorf("Handle = %d %s, want %d containing %q", resp.StatusCode, resp.Body, tt.wantStatus, tt.wantBody)
+			}
+			stored, err := books.List(context.Background(), handlertest.UserID, bookshelf.ListOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(stored) != tt.wantBooks {
+				t.Errorf("user has %d books, want %d", len(stored), tt.wantBooks)
+			}
+			if read := reads.pages > 0; read != tt.wantRead {
+				t.Errorf("read the whole library = %v, want %v", read, tt.wantRead)
+			}
+		})
+	}
+}
+
+// racing hides the writes of a concurrent first request with the same key:
+// its idempotency record from the first lookup, as if it was stored just
+// after, and with books every book from the duplicate check, as if this
+// request read before the first wrote anything.
+type racing struct {
+	bookshelf.BookRepository
+	books   bool
+	lookups int
+}
+
+func (r *racing) GetIdempotencyRecord(ctx context.Context, userID, key string) (*bookshelf.IdempotencyRecord, error) {
+	r.lookups++
+	if r.lookups == 1 {
+		return nil, nil
+	}
+	return r.BookRepository.GetIdempotencyRecord(ctx, userID, key)
+}
+
+func (r *racing) ListByAuthor(ctx context.Context, userID, author string) ([]bookshelf.Book, error) {
+	if r.books {
+		return nil, nil
+	}
+	return r.BookRepository.ListByAuthor(ctx, userID, author)
+}
+
+func TestHandleIdempotencyKey(t *testing.T) {
+	request := func(key, body string) events.APIGatewayProxyRequest {
+		return handlertest.Request(events.APIGatewayProxyRequest{
+			Headers: map[string]string{"Idempotency-Key": key},
+			Body:    body,
+		})
+	}
+	emma := `{"title":"Emma","author":"Jane Austen"}`
+	otherUser := request("key-1", emma)
+	otherUser.RequestContext.Authorizer = map[string]interface{}{
+		"jwt": map[string]interface{}{"claims": map[string]interface{}{"sub": "user-2"}},
+	}
+
+	tests := []struct {
+		name       string
+		request    events.APIGatewayProxyRequest
+		racing     *racing // hides the first request's writes
+		wantReplay bool    // of the first request's response
+		wantStatus int
+		wantBody   string // contained in the body when not replayed
+	}{
+		{name: "retry", request: request("key-1", emma), wantReplay: true},
+		{name: "retry racing the first request's record", request: request("key-1", emma), racing: &racing{}, wantReplay: true},
+		{name: "retry racing the first request's book", request: request("key-1", emma), racing: &racing{books: true}, wantReplay: true},
+		{name: "lower-case header", request: handlertest.Request(events.APIGatewayProxyRequest{
+			Headers: map[string]string{"idempotency-key": "key-1"},
+			Body:    emma,
+		}), wantReplay: true},
+		{
+			name:       "key reused for another book",
+			request:    request("key-1", `{"title":"Persuasion","author":"Jane Austen"}`),
+			wantStatus: 422,
+			wantBody:   "Idempotency-Key has already been used",
+		},
+		{
+			name:       "key reused racing the first request",
+			request:    request("key-1", `{"title":"Persuasion","author":"Jane Austen"}`),
+			racing:     &racing{books: true},
+			wantStatus: 422,
+			wantBody:   "Idempotency-Key has already been used",
+		},
+		{
+			// Without the key the retry is only caught as a duplicate
+			name:       "another key",
+			request:    request("key-2", emma),
+			wantStatus: 409,
+			wantBody:   `"title":"Emma"`,
+		},
+		{
+			name:       "key too long",
+			request:    request(strings.Repeat("k", bookshelf.MaxIdempotencyKeyLength+1), emma),
+			wantStatus: 400,
+			wantBody:   "Invalid Idempotency-Key",
+		},
+		{
+			name:       "same key from another user",
+			request:    otherUser,
+			wantStatus: 201,
+			wantBody:   `"title":"Emma"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			books := handlertest.Books(t)
+			first, err := New(books).Handle(ctx, request("key-1", emma))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if first.StatusCode != 201 {
+				t.Fatalf("first request = %d %s, want 201", first.StatusCode, first.Body)
+			}
+
+			var repo bookshelf.BookRepository = books
+			if tt.racing != nil {
+				tt.racing.BookRepository = books
+				repo = tt.racing
+			}
+			resp, err := New(repo).Handle(ctx, tt.request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			replayed := resp.Headers["Idempotent-Replayed"] == "true"
+			if tt.wantReplay {
+				if !replayed || resp.StatusCode != first.StatusCode || resp.Body != first.Body || resp.Headers["ETag"] != first.Headers["ETag"] {
+					t.Errorf("Handle = %d %v %s, want the first response %d %v %s replayed",
+						resp.StatusCode, resp.Headers, resp.Body, first.StatusCode, first.Headers, first.Body)
+				}
+			} else if replayed || resp.StatusCode != tt.wantStatus || !strings.Contains(resp.Body, tt.wantBody) {
+				t.Errorf("Handle = %d %v %s, want %d containing %q", resp.StatusCode, resp.Headers, resp.Body, tt.wantStatus, tt.wantBody)
+			}
+
+			// The book is only created once
+			stored, err := books.List(ctx, handlertest.UserID, bookshelf.ListOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(stored) != 1 {
+				t.Errorf("user has %d books, want the 1 created once", len(stored))
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+// Package handler implements POST /imports.
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/bookshelf"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/importer"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/objectstore"
+	"github.com/google/uuid"
+)
+
+// UploadURLExpiry is how long the client has to start uploading the file.
+const UploadURLExpiry = 15 * time.Minute
+
+type CreateImportRequest struct {
+	Format string `json:"format"`
+}
+
+// CreateImportResponse is the new job, with where to upload its file.
+type CreateImportResponse struct {
+	importer.APIJob
+	UploadURL       string `json:"upload_url"`
+	UploadExpiresAt string `json:"upload_expires_at"`
+}
+
+// Handler serves POST /imports, recording jobs in an injected repository
+// and handing out upload URLs for an injected object store.
+type Handler struct {
+	Jobs  importer.JobRepository
+	Store objectstore.Store
+}
+
+// New returns a Handler recording jobs in jobs and uploading to store.
+func New(jobs importer.JobRepository, store objectstore.Store) *Handler {
+	return &Handler{Jobs: jobs, Store: store}
+}
+
+// Handle is the Lambda function handler.
+func (h *Handler) Handle(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Extract user ID from JWT claims
+	userID, err := bookshelf.UserID(request)
+	if err != nil {
+		log.Printf("Error extracting user ID: %v", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusUnauthorized,
+			Body:       "Unauthorized: Could not extract user ID",
+		}, nil
+	}
+
+	// Parse request body
+	var req CreateImportRequest
+	if err := json.Unmarshal([]byte(request.Body), &req); err != nil {
+		log.Printf("Error parsing request body: %v", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Body:       "Invalid request body",
+		}, nil
+	}
+	if _, ok := importer.Lookup(req.Format); !ok {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Body:       importer.InvalidFormatMessage(),
+		}, nil
+	}
+
+	if h.Store == nil {
+		log.Printf("Error creating import: no import store configured")
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Body:       "Internal Server Error",
+		}, nil
+	}
+
+	// Record the pending job; uploading its file starts the import
+	now := time.Now()
+	job := importer.NewJob(userID, uuid.NewString(), req.Format, now)
+	if err := h.Jobs.Put(ctx, userID, job); err != nil {
+		log.Printf("Error creating import job: %v", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Body:       "Internal Server Error",
+		}, nil
+	}
+
+	uploadURL, err := h.Store.PresignPut(ctx, importer.JobKey(userID, job.ID), UploadURLExpiry)
+	if err != nil {
+		log.Printf("Error generating upload URL: %v", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Body:       "Internal Server Error",
+		}, nil
+	}
+
+	body, err := json.Marshal(CreateImportResponse{
+		APIJob:          job.ToAPI(),
+		UploadURL:       uploadURL,
+		UploadExpiresAt: now.Add(UploadURLExpiry).UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		log.Printf("Error marshalling JSON: %v", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Body:       "Internal Server Error",
+		}, nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusCreated,
+		Headers: map[string]string{
+			"Content-Type": "application/json",
+			"Location":     "/imports/" + job.ID,
+		},
+		Body: string(body),
+	}, nil
+}
@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/handlertest"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/importer"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/objectstore"
+)
+
+func TestHandle(t *testing.T) {
+	create := func(body string) events.APIGatewayProxyRequest {
+		return handlertest.Request(events.APIGatewayProxyRequest{Body: body})
+	}
+
+	tests := []struct {
+		name       string
+		request    events.APIGatewayProxyRequest
+		wantStatus int
+		wantBody   string // contained in the body
+		noStore    bool   // no import bucket is configured
+	}{
+		{
+			name:       "no claims",
+			request:    events.APIGatewayProxyRequest{Body: `{"format":"goodreads"}`},
+			wantStatus: 401,
+			wantBody:   "Unauthorized",
+		},
+		{
+			name:       "invalid JSON",
+			request:    create(`{"format":`),
+			wantStatus: 400,
+			wantBody:   "Invalid request body",
+		},
+		{
+			name:       "no format",
+			request:    create(`{}`),
+			wantStatus: 400,
+			wantBody:   importer.InvalidFormatMessage(),
+		},
+		{
+			name:       "invalid format",
+			request:    create(`{"format":"kindle"}`),
+			wantStatus: 400,
+			wantBody:   importer.InvalidFormatMessage(),
+		},
+		{
+			name:       "no import store",
+			request:    create(`{"format":"storygraph"}`),
+			noStore:    true,
+			wantStatus: 500,
+			wantBody:   "Internal Server Error",
+		},
+		{
+			name:       "created",
+			request:    create(`{"format":"storygraph"}`),
+			wantStatus: 201,
+			wantBody:   `"status":"pending"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			jobs := importer.NewMemoryJobRepository()
+			h := New(jobs, objectstore.NewFileStore(t.TempDir(), "https://imports.example.com"))
+			if tt.noStore {
+				h.Store = nil
+			}
+			resp, err := h.Handle(ctx, tt.request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.wantStatus || !strings.Contains(resp.Body, tt.wantBody) {
+				t.Errorf("Handle = %d %s, want %d containing %q", resp.StatusCode, resp.Body, tt.wantStatus, tt.wantBody)
+			}
+			if resp.StatusCode != 201 {
+				return
+			}
+
+			var response CreateImportResponse
+			if err := json.Unmarshal([]byte(resp.Body), &response); err != nil {
+				t.Fatal(err)
+			}
+			if location := resp.Headers["Location"]; location != "/imports/"+response.ID {
+				t.Errorf("Location = %q, want /imports/%s", location, response.ID)
+			}
+			if want := "https://imports.example.com/" + importer.JobKey(handlertest.UserID, response.ID); response.UploadURL != want {
+				t.Errorf("upload_url = %q, want %q", response.UploadURL, want)
+			}
+			job, err := jobs.Get(ctx, handlertest.UserID, response.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if job.Status != importer.JobPending || job.Format != "storygraph" {
+				t.Errorf("job = %s %s, want pending storygraph", job.Status, job.Format)
+			}
+			if !reflect.DeepEqual(response.APIJob, job.ToAPI()) {
+				t.Errorf("response job = %+v, want the stored %+v", response.APIJob, job.ToAPI())
+			}
+			created, err := time.Parse(time.RFC3339, response.CreatedAt)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := created.Add(UploadURLExpiry).Format(time.RFC3339); response.UploadExpiresAt != want {
+				t.Errorf("upload_expires_at = %s, want %s, 15 minutes after the job was created", response.UploadExpiresAt, want)
+			}
+		})
+	}
+}
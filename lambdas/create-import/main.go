@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/ericdahl/bookshelf-aws/lambdas/create-import/handler"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/bookshelf"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/importer"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/objectstore"
+)
+
+var (
+	ddbClient  *dynamodb.Client
+	s3Client   *s3.Client
+	bucketName = os.Getenv("IMPORTS_BUCKET_NAME")
+)
+
+func init() {
+	cfg, err := config.LoadDefaultConfig(context.TODO())
+	if err != nil {
+		log.Fatalf("unable to load SDK config, %v", err)
+	}
+	ddbClient = dynamodb.NewFromConfig(cfg)
+	s3Client = s3.NewFromConfig(cfg)
+}
+
+func main() {
+	var store objectstore.Store
+	if bucketName != "" {
+		store = objectstore.NewS3Store(s3Client, s3.NewPresignClient(s3Client), bucketName)
+	} else {
+		log.Printf("IMPORTS_BUCKET_NAME environment variable not set")
+	}
+	h := handler.New(importer.NewDynamoJobRepository(ddbClient, bookshelf.TableName), store)
+
+	if os.Getenv("LAMBDA_TASK_ROOT") == "" {
+		// Local testing
+		fmt.Println("--- Local execution mode ---")
+		fmt.Println("Set IMPORTS_BUCKET_NAME environment variable for S3 operations")
+
+		// Create a test request
+		testReq := events.APIGatewayProxyRequest{
+			Body: `{"format": "goodreads"}`,
+			RequestContext: events.APIGatewayProxyRequestContext{
+				Authorizer: map[string]interface{}{
+					"jwt": map[string]interface{}{
+						"claims": map[string]interface{}{
+							"sub": "test-user-id",
+						},
+					},
+				},
+			},
+		}
+
+		response, err := h.Handle(context.Background(), testReq)
+		if err != nil {
+			log.Fatalf("FATAL: handler failed: %v", err)
+		}
+
+		fmt.Printf("Status Code: %d\n", response.StatusCode)
+		fmt.Printf("Response Body: %s\n", response.Body)
+	} else {
+		lambda.Start(h.Handle)
+	}
+}
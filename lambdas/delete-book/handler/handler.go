@@ -0,0 +1,112 @@
+// Package handler implements DELETE /books/{id}, which moves the book to
+// the user's trash.
+package handler
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/bookshelf"
+)
+
+// maxAttempts bounds the retries for a request without If-Match that keeps
+// racing other writers.
+const maxAttempts = 3
+
+// Handler serves DELETE /books/{id} against an injected book repository.
+type Handler struct {
+	Books bookshelf.BookRepository
+}
+
+// New returns a Handler backed by books.
+func New(books bookshelf.BookRepository) *Handler {
+	return &Handler{Books: books}
+}
+
+// Handle is the Lambda function handler.
+func (h *Handler) Handle(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Extract user ID from JWT claims
+	userID, err := bookshelf.UserID(request)
+	if err != nil {
+		log.Printf("Error extracting user ID: %v", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusUnauthorized,
+			Body:       "Unauthorized: Could not extract user ID",
+		}, nil
+	}
+
+	// Record the request ID with the change in the book's history
+	ctx = bookshelf.WithRequestID(ctx, request.RequestContext.RequestID)
+
+	// Get the book ID from path parameters
+	bookID := request.PathParameters["id"]
+	if bookID == "" {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Body:       "Book ID is required",
+		}, nil
+	}
+
+	// Move the book to the trash only if it is still at the version that was
+	// read. With If-Match the client's version must match; without it, a
+	// concurrent change is retried against the new version.
+	ifMatch := bookshelf.ParseIfMatch(request)
+	for attempt := 1; ; attempt++ {
+		current, err := h.Books.Get(ctx, userID, bookID)
+		if errors.Is(err, bookshelf.ErrNotFound) {
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusNotFound,
+				Body:       "Book not found",
+			}, nil
+		}
+		if err != nil {
+			log.Printf("Error getting book: %v", err)
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusInternalServerError,
+				Body:       "Internal Server Error",
+			}, nil
+		}
+		if !ifMatch.Matches(current.Version) {
+			return bookshelf.ConflictResponse(http.StatusPreconditionFailed, current), nil
+		}
+
+		_, err = h.Books.Trash(ctx, userID, current)
+		var conflict *bookshelf.ConflictError
+		if errors.As(err, &conflict) {
+			if ifMatch.Present {
+				return bookshelf.ConflictResponse(http.StatusPreconditionFailed, conflict.Current), nil
+			}
+			if attempt < maxAttempts {
+				continue
+			}
+			return bookshelf.ConflictResponse(http.StatusConflict, conflict.Current), nil
+		}
+		if errors.Is(err, bookshelf.ErrNotFound) {
+			// The book was deleted between the read and the write.
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusNotFound,
+				Body:       "Book not found",
+			}, nil
+		}
+		if err != nil {
+			log.Printf("Error moving book to trash: %v", err)
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusInternalServerError,
+				Body:       "Internal Server Error",
+			}, nil
+		}
+		break
+	}
+
+	// Return 204 No Content on successful deletion
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusNoContent,
+		Headers: map[string]string{
+			"Content-Type": "application/json",
+		},
+		Body: "",
+	}, nil
+}
@@ -0,0 +1,148 @@
+package handler
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/bookshelf"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/handlertest"
+)
+
+func TestHandle(t *testing.T) {
+	remove := func(id, ifMatch string) events.APIGatewayProxyRequest {
+		request := events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": id}}
+		if ifMatch != "" {
+			request.Headers = map[string]string{"If-Match": ifMatch}
+		}
+		return handlertest.Request(request)
+	}
+
+	tests := []struct {
+		name        string
+		request     events.APIGatewayProxyRequest
+		racing      bool // another writer changes the book before every write
+		racesOnce   bool // another writer changes the book before the first write
+		wantStatus  int
+		wantBody    string // contained in the body
+		wantTrashed bool
+	}{
+		{
+			name:       "no claims",
+			request:    events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "book-1"}},
+			wantStatus: 401,
+			wantBody:   "Unauthorized",
+		},
+		{
+			name:       "no ID",
+			request:    remove("", ""),
+			wantStatus: 400,
+			wantBody:   "Book ID is required",
+		},
+		{
+			name:       "not found",
+			request:    remove("book-2", ""),
+			wantStatus: 404,
+			wantBody:   "Book not found",
+		},
+		{
+			name:       "stale If-Match",
+			request:    remove("book-1", bookshelf.ETag(2)),
+			wantStatus: 412,
+			wantBody:   `"version":1`,
+		},
+		{
+			name:       "If-Match losing a race",
+			request:    remove("book-1", bookshelf.ETag(1)),
+			racing:     true,
+			wantStatus: 412,
+			wantBody:   `"version":2`,
+		},
+		{
+			name:       "losing every retry",
+			request:    remove("book-1", ""),
+			racing:     true,
+			wantStatus: 409,
+			wantBody:   `"version":4`,
+		},
+		{
+			name:        "retried after a concurrent change",
+			request:     remove("book-1", ""),
+			racesOnce:   true,
+			wantStatus:  204,
+			wantTrashed: true,
+		},
+		{
+			name:        "trashed",
+			request:     remove("book-1", ""),
+			wantStatus:  204,
+			wantTrashed: true,
+		},
+		{
+			name:        "trashed at the version in If-Match",
+			request:     remove("book-1", bookshelf.ETag(1)),
+			wantStatus:  204,
+			wantTrashed: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			memory := handlertest.Books(t, bookshelf.APIBook{ID: "book-1", Title: "Dune", Author: "Frank Herbert", Status: bookshelf.StatusRead})
+			var books bookshelf.BookRepository = memory
+			switch {
+			case tt.racing:
+				books = handlertest.Racing{MemoryRepository: memory}
+			case tt.racesOnce:
+				books = &handlertest.RacingOnce{MemoryRepository: memory}
+			}
+			resp, err := New(books).Handle(ctx, tt.request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.wantStatus || !strings.Contains(resp.Body, tt.wantBody) {
+				t.Errorf("Handle = %d %s, want %d containing %q", resp.StatusCode, resp.Body, tt.wantStatus, tt.wantBody)
+			}
+
+			trash, err := memory.ListTrash(ctx, handlertest.UserID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if trashed := len(trash) == 1; trashed != tt.wantTrashed {
+				t.Fatalf("book trashed = %v, want %v", trashed, tt.wantTrashed)
+			}
+			if !tt.wantTrashed {
+				return
+			}
+
+			// The book leaves the shelf for the trash as it was last stored,
+			// to be purged after TrashTTL
+			listed, err := memory.List(ctx, handlertest.UserID, bookshelf.ListOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(listed) != 0 {
+				t.Errorf("listed %v, want no books", listed)
+			}
+			stored, err := memory.History(ctx, handlertest.UserID, "book-1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if trash[0].Version != stored[0].Version || trash[0].Title != "Dune" {
+				t.Errorf("trashed %+v, want Dune at version %d", trash[0].Book.ToAPI(), stored[0].Version)
+			}
+			deleted, err := time.Parse(time.RFC3339, trash[0].DeletedAt)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if expires := time.Unix(trash[0].ExpiresAt, 0); !expires.Equal(deleted.Add(bookshelf.TrashTTL)) {
+				t.Errorf("expires at %v, want TrashTTL after %v", expires, deleted)
+			}
+			if stored[0].Action != bookshelf.HistoryDelete {
+				t.Errorf("history starts with %q, want %q", stored[0].Action, bookshelf.HistoryDelete)
+			}
+		})
+	}
+}
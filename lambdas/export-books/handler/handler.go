@@ -0,0 +1,270 @@
+// Package handler implements POST /export, which writes the export file
+// while the client waits or, with "async": true, starts an export job that
+// writes it in the background.
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/bookshelf"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/exporter"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/objectstore"
+)
+
+type ExportRequest struct {
+	Format  string            `json:"format"`
+	Filters map[string]string `json:"filters,omitempty"`
+	// CSVOptions choose the columns and formatting of CSV exports.
+	exporter.CSVOptions
+	// Async starts an export job instead of writing the file before
+	// responding. Zip backups always start one.
+	Async bool `json:"async,omitempty"`
+}
+
+type ExportResponse struct {
+	// ID names the export in GET /exports/{id}/url, which hands out a fresh
+	// download URL once this one expires.
+	ID          string `json:"id"`
+	DownloadURL string `json:"download_url"`
+	Format      string `json:"format"`
+	Filename    string `json:"filename"`
+	ExpiresAt   string `json:"expires_at"`
+	// BookCount is the exact number of books written to the export file.
+	BookCount int `json:"book_count"`
+}
+
+// Handler serves POST /export, reading books from an injected repository and
+// writing the export file to an injected object store. Asynchronous exports
+// are recorded in an injected job repository and handed to an injected
+// queue.
+type Handler struct {
+	Books bookshelf.BookRepository
+	Store objectstore.Store
+	Jobs  exporter.JobRepository
+	Queue exporter.Queue
+}
+
+// New returns a Handler reading from books and writing exports to store,
+// starting export jobs in jobs and queue.
+func New(books bookshelf.BookRepository, store objectstore.Store, jobs exporter.JobRepository, queue exporter.Queue) *Handler {
+	return &Handler{Books: books, Store: store, Jobs: jobs, Queue: queue}
+}
+
+// upload streams the export file write writes to the store under key and
+// returns a pre-signed download URL.
+func (h *Handler) upload(ctx context.Context, key string, write func(io.Writer) error) (string, error) {
+	if h.Store == nil {
+		return "", fmt.Errorf("no export store configured")
+	}
+
+	if _, err := exporter.Upload(ctx, h.Store, key, write); err != nil {
+		return "", err
+	}
+
+	return h.Store.PresignGet(ctx, key, exporter.DownloadURLExpiry)
+}
+
+// enqueue records a pending job for the export and hands it to the queue.
+func (h *Handler) enqueue(ctx context.Context, userID string, exportReq ExportRequest) (exporter.Job, error) {
+	if h.Jobs == nil || h.Queue == nil {
+		return exporter.Job{}, fmt.Errorf("no export queue configured")
+	}
+
+	now := time.Now()
+	job := exporter.NewJob(userID, exporter.NewID(exportReq.Format, now), exportReq.Format, exportReq.Filters, exportReq.CSVOptions, now)
+	if err := h.Jobs.Put(ctx, userID, job); err != nil {
+		return exporter.Job{}, err
+	}
+	if err := h.Queue.Enqueue(ctx, exporter.Task{UserID: userID, ExportID: job.ID}); err != nil {
+		// Leave a record of the export that never started
+		job.Fail("The export could not be started")
+		if err := h.Jobs.Put(ctx, userID, job); err != nil {
+			log.Printf("Error failing export job %s: %v", job.ID, err)
+		}
+		return exporter.Job{}, err
+	}
+	return job, nil
+}
+
+// Handle is the Lambda function handler.
+func (h *Handler) Handle(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Extract user ID from JWT claims
+	userID, err := bookshelf.UserID(request)
+	if err != nil {
+		log.Printf("Error extracting user ID: %v", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusUnauthorized,
+			Headers: map[string]string{
+				"Content-Type": "application/json",
+			},
+			Body: `{"error": "Unauthorized: Could not extract user ID"}`,
+		}, nil
+	}
+
+	// Parse request body
+	var exportReq ExportRequest
+	if request.Body != "" {
+		if err := json.Unmarshal([]byte(request.Body), &exportReq); err != nil {
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusBadRequest,
+				Headers: map[string]string{
+					"Content-Type": "application/json",
+				},
+				Body: `{"error": "Invalid JSON in request body"}`,
+			}, nil
+		}
+	}
+
+	// Default format to CSV if not specified
+	if exportReq.Format == "" {
+		exportReq.Format = "csv"
+	}
+
+	// Validate format
+	if !exporter.ValidFormat(exportReq.Format) {
+		body, _ := json.Marshal(map[string]string{"error": exporter.InvalidFormatMessage()})
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Headers: map[string]string{
+				"Content-Type": "application/json",
+			},
+			Body: string(body),
+		}, nil
+	}
+
+	// Validate the CSV layout before starting a job that would fail on it
+	if err := exportReq.CSVOptions.Validate(exportReq.Format); err != nil {
+		body, _ := json.Marshal(map[string]string{"error": err.Error()})
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Headers: map[string]string{
+				"Content-Type": "application/json",
+			},
+			Body: string(body),
+		}, nil
+	}
+
+	if exportReq.Async || exporter.RequiresJob(exportReq.Format) {
+		return h.handleAsync(ctx, userID, exportReq)
+	}
+
+	log.Printf("Exporting books for user %s as %s", userID, exportReq.Format)
+
+	// Read the books and generate the export file while uploading it to the
+	// export store, and get signed URL
+	now := time.Now().UTC()
+	exportID := exporter.NewID(exportReq.Format, now)
+	var bookCount int
+	var generateErr error
+	downloadURL, err := h.upload(ctx, exporter.Key(userID, exportID), func(w io.Writer) error {
+		bookCount, generateErr = exporter.New(h.Books, nil).Export(ctx, w, userID, exportReq.Format, exportReq.Filters, exportReq.CSVOptions, now)
+		return generateErr
+	})
+	if errors.Is(err, exporter.ErrListBooks) {
+		log.Printf("Error getting user books: %v", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Headers: map[string]string{
+				"Content-Type": "application/json",
+			},
+			Body: `{"error": "Failed to retrieve books"}`,
+		}, nil
+	}
+	if err != nil && errors.Is(err, generateErr) {
+		log.Printf("Error generating export data: %v", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Headers: map[string]string{
+				"Content-Type": "application/json",
+			},
+			Body: `{"error": "Failed to generate export data"}`,
+		}, nil
+	}
+	if err != nil {
+		log.Printf("Error uploading export: %v", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Headers: map[string]string{
+				"Content-Type": "application/json",
+			},
+			Body: `{"error": "Failed to upload export file"}`,
+		}, nil
+	}
+
+	// Prepare response
+	response := ExportResponse{
+		ID:          exportID,
+		DownloadURL: downloadURL,
+		Format:      exportReq.Format,
+		Filename:    exportID,
+		ExpiresAt:   now.Add(exporter.DownloadURLExpiry).Format(time.RFC3339),
+		BookCount:   bookCount,
+	}
+
+	responseBody, err := json.Marshal(response)
+	if err != nil {
+		log.Printf("Error marshalling response: %v", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Headers: map[string]string{
+				"Content-Type": "application/json",
+			},
+			Body: `{"error": "Failed to generate response"}`,
+		}, nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Headers: map[string]string{
+			"Content-Type": "application/json",
+		},
+		Body: string(responseBody),
+	}, nil
+}
+
+// handleAsync starts an export job and responds with it while the file is
+// written in the background. Clients poll GET /exports/{id} until it
+// completes and then fetch GET /exports/{id}/url.
+func (h *Handler) handleAsync(ctx context.Context, userID string, exportReq ExportRequest) (events.APIGatewayProxyResponse, error) {
+	job, err := h.enqueue(ctx, userID, exportReq)
+	if err != nil {
+		log.Printf("Error starting export job: %v", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Headers: map[string]string{
+				"Content-Type": "application/json",
+			},
+			Body: `{"error": "Failed to start export"}`,
+		}, nil
+	}
+	log.Printf("Started export %s for user %s as %s", job.ID, userID, exportReq.Format)
+
+	responseBody, err := json.Marshal(job.ToAPI(objectstore.Object{}))
+	if err != nil {
+		log.Printf("Error marshalling response: %v", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Headers: map[string]string{
+				"Content-Type": "application/json",
+			},
+			Body: `{"error": "Failed to generate response"}`,
+		}, nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusAccepted,
+		Headers: map[string]string{
+			"Content-Type": "application/json",
+			"Location":     "/exports/" + job.ID,
+		},
+		Body: string(responseBody),
+	}, nil
+}
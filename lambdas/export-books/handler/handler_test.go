@@ -0,0 +1,244 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/bookshelf"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/exporter"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/handlertest"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/objectstore"
+)
+
+// fakeQueue records the tasks it is handed, or fails with err.
+type fakeQueue struct {
+	tasks []exporter.Task
+	err   error
+}
+
+func (q *fakeQueue) Enqueue(ctx context.Context, task exporter.Task) error {
+	if q.err != nil {
+		return q.err
+	}
+	q.tasks = append(q.tasks, task)
+	return nil
+}
+
+func TestHandle(t *testing.T) {
+	books := handlertest.Books(t,
+		bookshelf.APIBook{ID: "book-1", Title: "Dune", Author: "Frank Herbert", Status: bookshelf.StatusRead},
+		bookshelf.APIBook{ID: "book-2", Title: "Emma", Author: "Jane Austen", Status: bookshelf.StatusWantToRead},
+	)
+	export := func(body string) events.APIGatewayProxyRequest {
+		return handlertest.Request(events.APIGatewayProxyRequest{Body: body})
+	}
+
+	tests := []struct {
+		name       string
+		request    events.APIGatewayProxyRequest
+		queueErr   error
+		wantStatus int
+		wantBody   string             // contained in the body
+		wantCount  int                // books in a file written before responding
+		wantFile   string             // contained in that file
+		wantOmits  string             // not contained in that file
+		wantJob    string             // status of the job started, if any
+		wantExport exporter.APIExport // format, filters and options of the job
+	}{
+		{
+			name:       "no claims",
+			request:    events.APIGatewayProxyRequest{Body: `{"format":"csv"}`},
+			wantStatus: 401,
+			wantBody:   "Unauthorized",
+		},
+		{
+			name:       "invalid JSON",
+			request:    export(`{"format":`),
+			wantStatus: 400,
+			wantBody:   "Invalid JSON",
+		},
+		{
+			name:       "invalid format",
+			request:    export(`{"format":"docx"}`),
+			wantStatus: 400,
+			wantBody:   "Invalid format",
+		},
+		{
+			name:       "CSV options for JSON",
+			request:    export(`{"format":"json","delimiter":"tab"}`),
+			wantStatus: 400,
+			wantBody:   "only apply to the csv format",
+		},
+		{
+			name:       "invalid CSV options",
+			request:    export(`{"format":"csv","fields":["colour"]}`),
+			wantStatus: 400,
+			wantBody:   "colour",
+		},
+		{
+			name:       "invalid delimiter",
+			request:    export(`{"format":"csv","delimiter":"|"}`),
+			wantStatus: 400,
+			wantBody:   "Invalid delimiter",
+		},
+		{
+			name:       "invalid date format",
+			request:    export(`{"format":"csv","date_format":"D/M/YY"}`),
+			wantStatus: 400,
+			wantBody:   "Invalid date_format",
+		},
+		{
+			name:       "invalid timezone",
+			request:    export(`{"format":"csv","timezone":"CEST"}`),
+			wantStatus: 400,
+			wantBody:   "Invalid timezone",
+		},
+		{
+			name:       "invalid bom",
+			request:    export(`{"format":"csv","bom":"yes"}`),
+			wantStatus: 400,
+			wantBody:   "Invalid JSON",
+		},
+		{
+			name:       "CSV options",
+			request:    export(`{"format":"csv","fields":["title","status"],"delimiter":"semicolon","date_format":"DD.MM.YYYY","timezone":"Europe/Berlin","bom":true}`),
+			wantStatus: 200,
+			wantBody:   `"format":"csv"`,
+			wantCount:  2,
+			wantFile:   "\ufeffTitle;Status\nDune;READ\nEmma;WANT_TO_READ\n",
+		},
+		{
+			name:       "default format",
+			request:    export(""),
+			wantStatus: 200,
+			wantBody:   `"format":"csv"`,
+			wantCount:  2,
+			wantFile:   "Title,Author",
+		},
+		{
+			name:       "filtered",
+			request:    export(`{"format":"json","filters":{"status":"READ"}}`),
+			wantStatus: 200,
+			wantBody:   `"download_url":"https://exports.example.com/`,
+			wantCount:  1,
+			wantFile:   `"title": "Dune"`,
+			wantOmits:  `"title": "Emma"`,
+		},
+		{
+			name:       "async",
+			request:    export(`{"format":"xlsx","async":true}`),
+			wantStatus: 202,
+			wantBody:   `"status":"pending"`,
+			wantJob:    exporter.JobPending,
+			wantExport: exporter.APIExport{Format: "xlsx"},
+		},
+		{
+			// Kept with the job for the worker to export with
+			name:       "async with filters and CSV options",
+			request:    export(`{"format":"csv","async":true,"filters":{"status":"READ"},"fields":["title"],"delimiter":"tab"}`),
+			wantStatus: 202,
+			wantJob:    exporter.JobPending,
+			wantExport: exporter.APIExport{
+				Format:  "csv",
+				Filters: map[string]string{"status": "READ"},
+				Options: &exporter.CSVOptions{Fields: []string{"title"}, Delimiter: "tab"},
+			},
+		},
+		{
+			name:       "zip backup",
+			request:    export(`{"format":"zip"}`),
+			wantStatus: 202,
+			wantBody:   `"format":"zip"`,
+			wantJob:    exporter.JobPending,
+			wantExport: exporter.APIExport{Format: "zip"},
+		},
+		{
+			name:       "queue unavailable",
+			request:    export(`{"format":"zip"}`),
+			queueErr:   errors.New("throttled"),
+			wantStatus: 500,
+			wantBody:   "Failed to start export",
+			wantJob:    exporter.JobFailed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := objectstore.NewFileStore(t.TempDir(), "https://exports.example.com")
+			jobs := exporter.NewMemoryJobRepository()
+			queue := &fakeQueue{err: tt.queueErr}
+			resp, err := New(books, store, jobs, queue).Handle(ctx, tt.request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.wantStatus || !strings.Contains(resp.Body, tt.wantBody) {
+				t.Errorf("Handle = %d %s, want %d containing %q", resp.StatusCode, resp.Body, tt.wantStatus, tt.wantBody)
+			}
+
+			switch resp.StatusCode {
+			case 200:
+				var response ExportResponse
+				if err := json.Unmarshal([]byte(resp.Body), &response); err != nil {
+					t.Fatal(err)
+				}
+				if response.BookCount != tt.wantCount {
+					t.Errorf("book_count = %d, want %d", response.BookCount, tt.wantCount)
+				}
+				file, err := store.Get(ctx, exporter.Key(handlertest.UserID, response.ID))
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer file.Close()
+				data, err := io.ReadAll(file)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !strings.Contains(string(data), tt.wantFile) || (tt.wantOmits != "" && strings.Contains(string(data), tt.wantOmits)) {
+					t.Errorf("export file %q, want it containing %q and not %q", data, tt.wantFile, tt.wantOmits)
+				}
+			case 202:
+				var response exporter.APIExport
+				if err := json.Unmarshal([]byte(resp.Body), &response); err != nil {
+					t.Fatal(err)
+				}
+				if location := resp.Headers["Location"]; location != "/exports/"+response.ID {
+					t.Errorf("Location = %q, want /exports/%s", location, response.ID)
+				}
+				want := exporter.Task{UserID: handlertest.UserID, ExportID: response.ID}
+				if len(queue.tasks) != 1 || queue.tasks[0] != want {
+					t.Errorf("queued %v, want [%v]", queue.tasks, want)
+				}
+				job, err := jobs.Get(ctx, handlertest.UserID, response.ID)
+				if err != nil {
+					t.Fatal(err)
+				}
+				stored := job.ToAPI(objectstore.Object{})
+				if !reflect.DeepEqual(stored, response) {
+					t.Errorf("response = %+v, want the stored job %+v", response, stored)
+				}
+				if stored.Format != tt.wantExport.Format || !reflect.DeepEqual(stored.Filters, tt.wantExport.Filters) || !reflect.DeepEqual(stored.Options, tt.wantExport.Options) {
+					t.Errorf("job = %s %v %+v, want %s %v %+v", stored.Format, stored.Filters, stored.Options,
+						tt.wantExport.Format, tt.wantExport.Filters, tt.wantExport.Options)
+				}
+			}
+
+			started, err := jobs.List(ctx, handlertest.UserID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var statuses []string
+			for _, job := range started {
+				statuses = append(statuses, job.Status)
+			}
+			if got := strings.Join(statuses, ","); got != tt.wantJob {
+				t.Errorf("jobs = %v, want %q", statuses, tt.wantJob)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+// Package handler implements GET /books/{id}.
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/bookshelf"
+)
+
+// Handler serves GET /books/{id} from an injected book repository.
+type Handler struct {
+	Books bookshelf.BookRepository
+}
+
+// New returns a Handler reading from books.
+func New(books bookshelf.BookRepository) *Handler {
+	return &Handler{Books: books}
+}
+
+// Handle is the Lambda function handler.
+func (h *Handler) Handle(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Extract user ID from JWT claims
+	userID, err := bookshelf.UserID(request)
+	if err != nil {
+		log.Printf("Error extracting user ID: %v", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusUnauthorized,
+			Body:       "Unauthorized: Could not extract user ID",
+		}, nil
+	}
+
+	id := request.PathParameters["id"]
+	if id == "" {
+		return events.APIGatewayProxyResponse{StatusCode: 400, Body: "book ID is required"}, nil
+	}
+
+	book, err := h.Books.Get(ctx, userID, id)
+	if errors.Is(err, bookshelf.ErrNotFound) {
+		return events.APIGatewayProxyResponse{StatusCode: 404, Body: "book not found"}, nil
+	}
+	if err != nil {
+		log.Printf("failed to get item, %v", err)
+		return events.APIGatewayProxyResponse{StatusCode: 500}, nil
+	}
+
+	body, err := json.Marshal(book.ToAPI())
+	if err != nil {
+		log.Printf("failed to marshal book, %v", err)
+		return events.APIGatewayProxyResponse{StatusCode: 500}, nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: 200,
+		Headers: map[string]string{
+			"Content-Type": "application/json",
+			"ETag":         bookshelf.ETag(book.Version),
+		},
+		Body: string(body),
+	}, nil
+}
@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/bookshelf"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/handlertest"
+)
+
+func TestHandle(t *testing.T) {
+	books := handlertest.Books(t, bookshelf.APIBook{ID: "book-1", Title: "Dune", Author: "Frank Herbert", Status: bookshelf.StatusRead})
+	edited := bookshelf.NewBook(handlertest.UserID, "book-3", bookshelf.APIBook{Title: "Emma", Author: "Jane Austen", Status: bookshelf.StatusRead})
+	edited.Version = 3
+	if err := books.Put(context.Background(), handlertest.UserID, edited); err != nil {
+		t.Fatal(err)
+	}
+	h := New(books)
+	otherUser := handlertest.Request(events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "book-1"}})
+	otherUser.RequestContext.Authorizer = map[string]interface{}{
+		"jwt": map[string]interface{}{"claims": map[string]interface{}{"sub": "user-2"}},
+	}
+
+	tests := []struct {
+		name       string
+		request    events.APIGatewayProxyRequest
+		wantStatus int
+		wantBody   string // contained in the body
+		wantETag   string
+		wantBook   string // ID of the stored book served
+	}{
+		{
+			name:       "no claims",
+			request:    events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "book-1"}},
+			wantStatus: 401,
+			wantBody:   "Unauthorized",
+		},
+		{
+			name:       "no ID",
+			request:    handlertest.Request(events.APIGatewayProxyRequest{}),
+			wantStatus: 400,
+			wantBody:   "book ID is required",
+		},
+		{
+			name:       "not found",
+			request:    handlertest.Request(events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "book-2"}}),
+			wantStatus: 404,
+			wantBody:   "book not found",
+		},
+		{
+			name:       "another user's book",
+			request:    otherUser,
+			wantStatus: 404,
+			wantBody:   "book not found",
+		},
+		{
+			name:       "found",
+			request:    handlertest.Request(events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "book-1"}}),
+			wantStatus: 200,
+			wantBody:   `"title":"Dune"`,
+			wantETag:   `"1"`,
+			wantBook:   "book-1",
+		},
+		{
+			name:       "edited",
+			request:    handlertest.Request(events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "book-3"}}),
+			wantStatus: 200,
+			wantBody:   `"version":3`,
+			wantETag:   `"3"`,
+			wantBook:   "book-3",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := h.Handle(context.Background(), tt.request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.wantStatus || !strings.Contains(resp.Body, tt.wantBody) {
+				t.Errorf("Handle = %d %s, want %d containing %q", resp.StatusCode, resp.Body, tt.wantStatus, tt.wantBody)
+			}
+			if resp.Headers["ETag"] != tt.wantETag {
+				t.Errorf("ETag = %q, want %q", resp.Headers["ETag"], tt.wantETag)
+			}
+			if tt.wantBook == "" {
+				return
+			}
+
+			stored, err := books.Get(context.Background(), handlertest.UserID, tt.wantBook)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got bookshelf.APIBook
+			if err := json.Unmarshal([]byte(resp.Body), &got); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, stored.ToAPI()) {
+				t.Errorf("book = %+v, want the stored %+v", got, stored.ToAPI())
+			}
+		})
+	}
+}
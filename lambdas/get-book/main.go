@@ -5,162 +5,30 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
-	"net/http"
 	"os"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
-	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/ericdahl/bookshelf-aws/lambdas/get-book/handler"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/bookshelf"
 )
 
-type Book struct {
-	PK         string   `dynamodbav:"PK"`
-	SK         string   `dynamodbav:"SK"`
-	ID         string   `dynamodbav:"id"`
-	Title      string   `dynamodbav:"Title"`
-	Author     string   `dynamodbav:"Author"`
-	Series     string   `dynamodbav:"Series,omitempty"`
-	Status     string   `dynamodbav:"status"`
-	Rating     *int     `dynamodbav:"rating,omitempty"`
-	Review     string   `dynamodbav:"review,omitempty"`
-	Tags       []string `dynamodbav:"tags,omitempty"`
-	StartedAt  string   `dynamodbav:"started_at,omitempty"`
-	FinishedAt string   `dynamodbav:"finished_at,omitempty"`
-	Thumbnail  string   `dynamodbav:"thumbnail,omitempty"`
-	Type       string   `dynamodbav:"type,omitempty"`
-	Comments   string   `dynamodbav:"comments,omitempty"`
-}
-
-type APIBook struct {
-	ID         string   `json:"id"`
-	Title      string   `json:"title"`
-	Author     string   `json:"author"`
-	Series     string   `json:"series,omitempty"`
-	Status     string   `json:"status"`
-	Rating     *int     `json:"rating,omitempty"`
-	Review     string   `json:"review,omitempty"`
-	Tags       []string `json:"tags,omitempty"`
-	StartedAt  string   `json:"started_at,omitempty"`
-	FinishedAt string   `json:"finished_at,omitempty"`
-	Thumbnail  string   `json:"thumbnail"`
-	Type       string   `json:"type,omitempty"`
-	Comments   string   `json:"comments,omitempty"`
-}
-
-// getUserID extracts the user ID from the JWT claims in the request context
-func getUserID(request events.APIGatewayProxyRequest) (string, error) {
-	// For HTTP API with JWT authorizer, the claims are nested under jwt.claims
-	jwt, ok := request.RequestContext.Authorizer["jwt"].(map[string]interface{})
-	if !ok {
-		log.Printf("Authorizer context: %+v", request.RequestContext.Authorizer)
-		return "", fmt.Errorf("no jwt found in authorizer context")
-	}
-	
-	claims, ok := jwt["claims"].(map[string]interface{})
-	if !ok {
-		log.Printf("JWT context: %+v", jwt)
-		return "", fmt.Errorf("no claims found in jwt context")
-	}
-	
-	// Try accessing the 'sub' claim first (standard JWT subject claim)
-	if sub, ok := claims["sub"].(string); ok {
-		return sub, nil
-	}
-	
-	// Try cognito:username as fallback
-	if cognitoUsername, ok := claims["cognito:username"].(string); ok {
-		return cognitoUsername, nil
-	}
-	
-	// Debug: log the actual claims structure if we can't find the user ID
-	log.Printf("Claims: %+v", claims)
-	
-	return "", fmt.Errorf("no user ID found in JWT claims")
-}
-
-func HandleRequest(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
-	// Extract user ID from JWT claims
-	userID, err := getUserID(request)
-	if err != nil {
-		log.Printf("Error extracting user ID: %v", err)
-		return events.APIGatewayProxyResponse{
-			StatusCode: http.StatusUnauthorized,
-			Body:       "Unauthorized: Could not extract user ID",
-		}, nil
-	}
-
-	id := request.PathParameters["id"]
-	if id == "" {
-		return events.APIGatewayProxyResponse{StatusCode: 400, Body: "book ID is required"}, nil
-	}
-
-	cfg, err := config.LoadDefaultConfig(ctx)
-	if err != nil {
-		log.Printf("failed to load configuration, %v", err)
-		return events.APIGatewayProxyResponse{StatusCode: 500}, nil
-	}
-
-	client := dynamodb.NewFromConfig(cfg)
-
-	input := &dynamodb.GetItemInput{
-		TableName: &[]string{"books"}[0],
-		Key: map[string]types.AttributeValue{
-			"PK": &types.AttributeValueMemberS{Value: "USER#" + userID},
-			"SK": &types.AttributeValueMemberS{Value: "BOOK#" + id},
-		},
-	}
-
-	result, err := client.GetItem(ctx, input)
-	if err != nil {
-		log.Printf("failed to get item, %v", err)
-		return events.APIGatewayProxyResponse{StatusCode: 500}, nil
-	}
-
-	if result.Item == nil {
-		return events.APIGatewayProxyResponse{StatusCode: 404, Body: "book not found"}, nil
-	}
-
-	var book Book
-	err = attributevalue.UnmarshalMap(result.Item, &book)
-	if err != nil {
-		log.Printf("Error unmarshalling book: %v", err)
-		return events.APIGatewayProxyResponse{StatusCode: 500, Body: "Internal Server Error"}, nil
-	}
+// ddbClient is the DynamoDB client.
+var ddbClient *dynamodb.Client
 
-	// Create the API response
-	apiBook := APIBook{
-		ID:         book.ID,
-		Title:      book.Title,
-		Author:     book.Author,
-		Series:     book.Series,
-		Status:     book.Status,
-		Rating:     book.Rating,
-		Review:     book.Review,
-		Tags:       book.Tags,
-		StartedAt:  book.StartedAt,
-		FinishedAt: book.FinishedAt,
-		Thumbnail:  book.Thumbnail,
-		Type:       book.Type,
-		Comments:   book.Comments,
-	}
-
-	body, err := json.Marshal(apiBook)
+func init() {
+	cfg, err := config.LoadDefaultConfig(context.TODO())
 	if err != nil {
-		log.Printf("failed to marshal book, %v", err)
-		return events.APIGatewayProxyResponse{StatusCode: 500}, nil
+		log.Fatalf("unable to load SDK config, %v", err)
 	}
-
-	return events.APIGatewayProxyResponse{
-		StatusCode: 200,
-		Headers:    map[string]string{"Content-Type": "application/json"},
-		Body:       string(body),
-	}, nil
+	ddbClient = dynamodb.NewFromConfig(cfg)
 }
 
 func main() {
+	h := handler.New(bookshelf.NewDynamoRepository(ddbClient, bookshelf.TableName))
+
 	// If the LAMBDA_TASK_ROOT environment variable is not set, we're running locally.
 	if os.Getenv("LAMBDA_TASK_ROOT") == "" {
 		// Create a dummy request for local testing with a sample book ID.
@@ -171,7 +39,7 @@ func main() {
 		}
 
 		// Call the handler directly.
-		response, err := HandleRequest(context.Background(), request)
+		response, err := h.Handle(context.Background(), request)
 		if err != nil {
 			log.Fatalf("FATAL: handler failed: %v", err)
 		}
@@ -191,6 +59,6 @@ func main() {
 
 	} else {
 		// Start the Lambda handler in the AWS environment.
-		lambda.Start(HandleRequest)
+		lambda.Start(h.Handle)
 	}
 }
@@ -0,0 +1,131 @@
+// Package handler implements GET /exports/{id}/url.
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/bookshelf"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/exporter"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/objectstore"
+)
+
+// ExportURLResponse is a fresh download URL for an export file.
+type ExportURLResponse struct {
+	ID          string `json:"id"`
+	DownloadURL string `json:"download_url"`
+	Format      string `json:"format"`
+	Filename    string `json:"filename"`
+	ExpiresAt   string `json:"expires_at"`
+}
+
+// Handler serves GET /exports/{id}/url against an injected job repository
+// and the injected object store export files are written to.
+type Handler struct {
+	Jobs  exporter.JobRepository
+	Store objectstore.Store
+}
+
+// New returns a Handler signing URLs for the exports in jobs and store.
+func New(jobs exporter.JobRepository, store objectstore.Store) *Handler {
+	return &Handler{Jobs: jobs, Store: store}
+}
+
+// Handle is the Lambda function handler. An export whose job has not
+// written its file yet, or failed to, is a 409.
+func (h *Handler) Handle(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Extract user ID from JWT claims
+	userID, err := bookshelf.UserID(request)
+	if err != nil {
+		log.Printf("Error extracting user ID: %v", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusUnauthorized,
+			Body:       "Unauthorized: Could not extract user ID",
+		}, nil
+	}
+
+	// Get the export ID from path parameters
+	exportID := request.PathParameters["id"]
+	if exportID == "" {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Body:       "Export ID is required",
+		}, nil
+	}
+
+	if h.Store == nil {
+		log.Printf("Error getting export URL: no export store configured")
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Body:       "Internal Server Error",
+		}, nil
+	}
+
+	export, err := exporter.Lookup(ctx, h.Jobs, h.Store, userID, exportID)
+	if errors.Is(err, exporter.ErrExportNotFound) {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusNotFound,
+			Body:       "Export not found",
+		}, nil
+	}
+	if err != nil {
+		log.Printf("Error getting export: %v", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Body:       "Internal Server Error",
+		}, nil
+	}
+	switch export.Status {
+	case exporter.JobCompleted:
+	case exporter.JobFailed:
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusConflict,
+			Body:       "Export failed: " + export.Error,
+		}, nil
+	default:
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusConflict,
+			Body:       "Export is not ready yet",
+		}, nil
+	}
+
+	now := time.Now()
+	downloadURL, err := h.Store.PresignGet(ctx, exporter.Key(userID, export.ID), exporter.DownloadURLExpiry)
+	if err != nil {
+		log.Printf("Error generating download URL: %v", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Body:       "Internal Server Error",
+		}, nil
+	}
+
+	body, err := json.Marshal(ExportURLResponse{
+		ID:          export.ID,
+		DownloadURL: downloadURL,
+		Format:      export.Format,
+		Filename:    export.ID,
+		ExpiresAt:   now.Add(exporter.DownloadURLExpiry).UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		log.Printf("Error marshalling JSON: %v", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Body:       "Internal Server Error",
+		}, nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Headers: map[string]string{
+			"Content-Type": "application/json",
+			// Every request signs a new URL
+			"Cache-Control": "no-store",
+		},
+		Body: string(body),
+	}, nil
+}
@@ -0,0 +1,145 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/exporter"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/exporter/exportertest"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/handlertest"
+)
+
+func TestHandle(t *testing.T) {
+	exports := exportertest.New(t)
+	h := New(exports.Jobs, exports.Store)
+	get := func(id string) events.APIGatewayProxyRequest {
+		return handlertest.Request(events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": id}})
+	}
+	downloadURL := func(id string) string {
+		return `"download_url":"https://exports.example.com/` + exporter.Key(handlertest.UserID, id) + `"`
+	}
+	// otherUser asks for handlertest.UserID's export as user-2
+	otherUser := func(id string) events.APIGatewayProxyRequest {
+		request := events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": id}}
+		request.RequestContext.Authorizer = map[string]interface{}{
+			"jwt": map[string]interface{}{"claims": map[string]interface{}{"sub": "user-2"}},
+		}
+		return request
+	}
+
+	tests := []struct {
+		name       string
+		request    events.APIGatewayProxyRequest
+		wantStatus int
+		wantBody   string // contained in the body
+		wantFormat string // of the export whose URL is signed
+	}{
+		{
+			name:       "no claims",
+			request:    events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": exports.Completed}},
+			wantStatus: 401,
+			wantBody:   "Unauthorized",
+		},
+		{
+			name:       "no ID",
+			request:    get(""),
+			wantStatus: 400,
+			wantBody:   "Export ID is required",
+		},
+		{
+			name:       "not found",
+			request:    get("books-20250301-120000-3f9a1c.csv"),
+			wantStatus: 404,
+			wantBody:   "Export not found",
+		},
+		{
+			name:       "file expired",
+			request:    get(exports.Expired),
+			wantStatus: 404,
+			wantBody:   "Export not found",
+		},
+		{
+			name:       "another user's export",
+			request:    otherUser(exports.Completed),
+			wantStatus: 404,
+			wantBody:   "Export not found",
+		},
+		{
+			name:       "another user's file",
+			request:    otherUser(exports.File),
+			wantStatus: 404,
+			wantBody:   "Export not found",
+		},
+		{
+			name:       "pending",
+			request:    get(exports.Pending),
+			wantStatus: 409,
+			wantBody:   "Export is not ready yet",
+		},
+		{
+			name:       "failed",
+			request:    get(exports.Failed),
+			wantStatus: 409,
+			wantBody:   "Export failed: The books could not be read",
+		},
+		{
+			name:       "stalled",
+			request:    get(exports.Stalled),
+			wantStatus: 409,
+			wantBody:   "Export failed: " + exporter.JobTimedOut,
+		},
+		{
+			name:       "completed",
+			request:    get(exports.Completed),
+			wantStatus: 200,
+			wantBody:   downloadURL(exports.Completed),
+			wantFormat: "xlsx",
+		},
+		{
+			name:       "written without a job",
+			request:    get(exports.File),
+			wantStatus: 200,
+			wantBody:   downloadURL(exports.File),
+			wantFormat: "markdown",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signed := time.Now().UTC().Truncate(time.Second)
+			resp, err := h.Handle(context.Background(), tt.request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.wantStatus || !strings.Contains(resp.Body, tt.wantBody) {
+				t.Errorf("Handle = %d %s, want %d containing %q", resp.StatusCode, resp.Body, tt.wantStatus, tt.wantBody)
+			}
+			if tt.wantFormat == "" {
+				return
+			}
+
+			var response ExportURLResponse
+			if err := json.Unmarshal([]byte(resp.Body), &response); err != nil {
+				t.Fatal(err)
+			}
+			id := tt.request.PathParameters["id"]
+			if response.ID != id || response.Filename != id || response.Format != tt.wantFormat {
+				t.Errorf("export = %s %s %s, want %s named for its ID", response.ID, response.Filename, response.Format, tt.wantFormat)
+			}
+			// The URL is signed for DownloadURLExpiry from the request
+			expires, err := time.Parse(time.RFC3339, response.ExpiresAt)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := signed.Add(exporter.DownloadURLExpiry); expires.Before(want) || expires.After(want.Add(time.Minute)) {
+				t.Errorf("expires_at = %s, want %s", response.ExpiresAt, want.Format(time.RFC3339))
+			}
+			if cache := resp.Headers["Cache-Control"]; cache != "no-store" {
+				t.Errorf("Cache-Control = %q, want no-store", cache)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+// Package handler implements GET /exports/{id}.
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/bookshelf"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/exporter"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/objectstore"
+)
+
+// Handler serves GET /exports/{id} against an injected job repository and
+// the injected object store export files are written to.
+type Handler struct {
+	Jobs  exporter.JobRepository
+	Store objectstore.Store
+}
+
+// New returns a Handler looking exports up in jobs and store.
+func New(jobs exporter.JobRepository, store objectstore.Store) *Handler {
+	return &Handler{Jobs: jobs, Store: store}
+}
+
+// Handle is the Lambda function handler.
+func (h *Handler) Handle(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Extract user ID from JWT claims
+	userID, err := bookshelf.UserID(request)
+	if err != nil {
+		log.Printf("Error extracting user ID: %v", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusUnauthorized,
+			Body:       "Unauthorized: Could not extract user ID",
+		}, nil
+	}
+
+	// Get the export ID from path parameters
+	exportID := request.PathParameters["id"]
+	if exportID == "" {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Body:       "Export ID is required",
+		}, nil
+	}
+
+	if h.Store == nil {
+		log.Printf("Error getting export: no export store configured")
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Body:       "Internal Server Error",
+		}, nil
+	}
+
+	export, err := exporter.Lookup(ctx, h.Jobs, h.Store, userID, exportID)
+	if errors.Is(err, exporter.ErrExportNotFound) {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusNotFound,
+			Body:       "Export not found",
+		}, nil
+	}
+	if err != nil {
+		log.Printf("Error getting export: %v", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Body:       "Internal Server Error",
+		}, nil
+	}
+
+	body, err := json.Marshal(export)
+	if err != nil {
+		log.Printf("Error marshalling JSON: %v", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Body:       "Internal Server Error",
+		}, nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Headers: map[string]string{
+			"Content-Type": "application/json",
+			// Status changes while the export runs
+			"Cache-Control": "no-store",
+		},
+		Body: string(body),
+	}, nil
+}
@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/exporter"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/exporter/exportertest"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/handlertest"
+)
+
+func TestHandle(t *testing.T) {
+	exports := exportertest.New(t)
+	h := New(exports.Jobs, exports.Store)
+	get := func(id string) events.APIGatewayProxyRequest {
+		return handlertest.Request(events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": id}})
+	}
+	// otherUser asks for handlertest.UserID's export as user-2
+	otherUser := func(id string) events.APIGatewayProxyRequest {
+		request := events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": id}}
+		request.RequestContext.Authorizer = map[string]interface{}{
+			"jwt": map[string]interface{}{"claims": map[string]interface{}{"sub": "user-2"}},
+		}
+		return request
+	}
+
+	tests := []struct {
+		name       string
+		request    events.APIGatewayProxyRequest
+		wantStatus int
+		wantBody   []string // contained in the body
+	}{
+		{
+			name:       "no claims",
+			request:    events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": exports.Pending}},
+			wantStatus: 401,
+			wantBody:   []string{"Unauthorized"},
+		},
+		{
+			name:       "no ID",
+			request:    get(""),
+			wantStatus: 400,
+			wantBody:   []string{"Export ID is required"},
+		},
+		{
+			name:       "invalid ID",
+			request:    get("../" + exports.File),
+			wantStatus: 404,
+			wantBody:   []string{"Export not found"},
+		},
+		{
+			name:       "not found",
+			request:    get("books-20250301-120000-3f9a1c.csv"),
+			wantStatus: 404,
+			wantBody:   []string{"Export not found"},
+		},
+		{
+			name:       "file expired",
+			request:    get(exports.Expired),
+			wantStatus: 404,
+			wantBody:   []string{"Export not found"},
+		},
+		{
+			name:       "another user's export",
+			request:    otherUser(exports.Completed),
+			wantStatus: 404,
+			wantBody:   []string{"Export not found"},
+		},
+		{
+			name:       "another user's file",
+			request:    otherUser(exports.File),
+			wantStatus: 404,
+			wantBody:   []string{"Export not found"},
+		},
+		{
+			name:       "pending",
+			request:    get(exports.Pending),
+			wantStatus: 200,
+			wantBody:   []string{`"status":"pending"`, `"format":"csv"`},
+		},
+		{
+			name:       "failed",
+			request:    get(exports.Failed),
+			wantStatus: 200,
+			wantBody:   []string{`"status":"failed"`, `"error":"The books could not be read"`},
+		},
+		{
+			name:       "stalled",
+			request:    get(exports.Stalled),
+			wantStatus: 200,
+			wantBody:   []string{`"status":"failed"`, `"error":"` + exporter.JobTimedOut + `"`},
+		},
+		{
+			name:       "completed",
+			request:    get(exports.Completed),
+			wantStatus: 200,
+			wantBody:   []string{`"status":"completed"`, `"book_count":2`, `"size":7`, `"expires_at"`},
+		},
+		{
+			name:       "written without a job",
+			request:    get(exports.File),
+			wantStatus: 200,
+			wantBody:   []string{`"status":"completed"`, `"format":"markdown"`, `"size":7`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := h.Handle(context.Background(), tt.request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("Handle = %d %s, want %d", resp.StatusCode, resp.Body, tt.wantStatus)
+			}
+			for _, want := range tt.wantBody {
+				if !strings.Contains(resp.Body, want) {
+					t.Errorf("body %s does not contain %q", resp.Body, want)
+				}
+			}
+		})
+	}
+}
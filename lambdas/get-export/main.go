@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/ericdahl/bookshelf-aws/lambdas/get-export/handler"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/bookshelf"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/exporter"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/objectstore"
+)
+
+var (
+	ddbClient  *dynamodb.Client
+	s3Client   *s3.Client
+	bucketName = os.Getenv("EXPORTS_BUCKET_NAME")
+)
+
+func init() {
+	cfg, err := config.LoadDefaultConfig(context.TODO())
+	if err != nil {
+		log.Fatalf("unable to load SDK config, %v", err)
+	}
+	ddbClient = dynamodb.NewFromConfig(cfg)
+	s3Client = s3.NewFromConfig(cfg)
+}
+
+func main() {
+	var store objectstore.Store
+	if bucketName != "" {
+		store = objectstore.NewS3Store(s3Client, s3.NewPresignClient(s3Client), bucketName)
+	} else {
+		log.Printf("EXPORTS_BUCKET_NAME environment variable not set")
+	}
+	h := handler.New(exporter.NewDynamoJobRepository(ddbClient, bookshelf.TableName), store)
+
+	// If the LAMBDA_TASK_ROOT environment variable is not set, we're running locally.
+	if os.Getenv("LAMBDA_TASK_ROOT") == "" {
+		// Create a dummy request for local testing.
+		request := events.APIGatewayProxyRequest{
+			PathParameters: map[string]string{
+				"id": "books-20260101-000000-000000.csv",
+			},
+		}
+
+		// Call the handler directly.
+		response, err := h.Handle(context.Background(), request)
+		if err != nil {
+			log.Fatalf("FATAL: handler failed: %v", err)
+		}
+
+		// Print the response details to stdout.
+		fmt.Println("--- Local execution ---")
+		fmt.Printf("Status Code: %d\n", response.StatusCode)
+		fmt.Printf("Response Body: %s\n", response.Body)
+	} else {
+		// Start the Lambda handler in the AWS environment.
+		lambda.Start(h.Handle)
+	}
+}
@@ -0,0 +1,80 @@
+// Package handler implements GET /imports/{id}.
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/bookshelf"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/importer"
+)
+
+// Handler serves GET /imports/{id} against an injected job repository.
+type Handler struct {
+	Jobs importer.JobRepository
+}
+
+// New returns a Handler backed by jobs.
+func New(jobs importer.JobRepository) *Handler {
+	return &Handler{Jobs: jobs}
+}
+
+// Handle is the Lambda function handler.
+func (h *Handler) Handle(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Extract user ID from JWT claims
+	userID, err := bookshelf.UserID(request)
+	if err != nil {
+		log.Printf("Error extracting user ID: %v", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusUnauthorized,
+			Body:       "Unauthorized: Could not extract user ID",
+		}, nil
+	}
+
+	// Get the job ID from path parameters
+	jobID := request.PathParameters["id"]
+	if jobID == "" {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Body:       "Import ID is required",
+		}, nil
+	}
+
+	job, err := h.Jobs.Get(ctx, userID, jobID)
+	if errors.Is(err, importer.ErrJobNotFound) {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusNotFound,
+			Body:       "Import not found",
+		}, nil
+	}
+	if err != nil {
+		log.Printf("Error getting import job: %v", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Body:       "Internal Server Error",
+		}, nil
+	}
+
+	body, err := json.Marshal(job.ToAPI())
+	if err != nil {
+		log.Printf("Error marshalling JSON: %v", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Body:       "Internal Server Error",
+		}, nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Headers: map[string]string{
+			"Content-Type": "application/json",
+			// Progress changes while the import runs
+			"Cache-Control": "no-store",
+		},
+		Body: string(body),
+	}, nil
+}
@@ -0,0 +1,164 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/handlertest"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/importer"
+)
+
+func TestHandle(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	jobs := importer.NewMemoryJobRepository()
+	pending := importer.NewJob(handlertest.UserID, "import-1", "goodreads", now)
+	completed := importer.NewJob(handlertest.UserID, "import-2", "librarything", now)
+	completed.Total = 2
+	completed.Progress(importer.Report{Created: 1, Skipped: 1, Rows: []importer.RowResult{
+		{Row: 2, Title: "Dune", Status: importer.RowCreated, BookID: "book-1"},
+		{Row: 3, Title: "Dune", Status: importer.RowSkipped, BookID: "book-1"},
+	}})
+	completed.Complete()
+	failed := importer.NewJob(handlertest.UserID, "import-3", "goodreads", now)
+	failed.Fail(`Invalid goodreads file: missing column "Author"`)
+	expired := importer.NewJob(handlertest.UserID, "import-4", "goodreads", now.Add(-2*importer.JobTTL))
+	// import-5 is half way through more failed rows than a job keeps
+	processing := importer.NewJob(handlertest.UserID, "import-5", "storygraph", now)
+	processing.Status, processing.StartedAt, processing.Total = importer.JobProcessing, processing.CreatedAt, 2*importer.MaxJobErrors
+	var report importer.Report
+	for i := range importer.MaxJobErrors + 1 {
+		report.Rows = append(report.Rows, importer.RowResult{Row: i + 2, Title: "Untitled", Status: importer.RowFailed, Error: "Author is required"})
+		report.Failed++
+	}
+	processing.Progress(report)
+	// import-6's worker died before the Lambda timeout could be recorded
+	dead := importer.NewJob(handlertest.UserID, "import-6", "goodreads", now.Add(-time.Hour))
+	dead.Status, dead.StartedAt = importer.JobProcessing, dead.CreatedAt
+	for _, job := range []importer.Job{pending, completed, failed, expired, processing, dead} {
+		if err := jobs.Put(ctx, handlertest.UserID, job); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := jobs.Put(ctx, "user-2", importer.NewJob("user-2", "import-7", "goodreads", now)); err != nil {
+		t.Fatal(err)
+	}
+	h := New(jobs)
+	get := func(id string) events.APIGatewayProxyRequest {
+		return handlertest.Request(events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": id}})
+	}
+	// api returns job as it is served, changed by change
+	api := func(job importer.Job, change func(*importer.APIJob)) *importer.APIJob {
+		want := job.ToAPI()
+		change(&want)
+		return &want
+	}
+
+	tests := []struct {
+		name       string
+		request    events.APIGatewayProxyRequest
+		wantStatus int
+		wantBody   string // contained in the body
+		wantJob    *importer.APIJob
+	}{
+		{
+			name:       "no claims",
+			request:    events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "import-1"}},
+			wantStatus: 401,
+			wantBody:   "Unauthorized",
+		},
+		{
+			name:       "no ID",
+			request:    get(""),
+			wantStatus: 400,
+			wantBody:   "Import ID is required",
+		},
+		{
+			name:       "not found",
+			request:    get("import-8"),
+			wantStatus: 404,
+			wantBody:   "Import not found",
+		},
+		{
+			name:       "another user's import",
+			request:    get("import-7"),
+			wantStatus: 404,
+			wantBody:   "Import not found",
+		},
+		{
+			name:       "expired",
+			request:    get("import-4"),
+			wantStatus: 404,
+			wantBody:   "Import not found",
+		},
+		{
+			name:       "pending",
+			request:    get("import-1"),
+			wantStatus: 200,
+			wantBody:   `"status":"pending","total":0,"processed":0,"created":0,"skipped":0,"failed":0,"errors":[]`,
+			wantJob:    api(pending, func(j *importer.APIJob) {}),
+		},
+		{
+			name:       "processing",
+			request:    get("import-5"),
+			wantStatus: 200,
+			wantJob: api(processing, func(j *importer.APIJob) {
+				j.Processed, j.Failed, j.ErrorsTruncated = importer.MaxJobErrors+1, importer.MaxJobErrors+1, true
+				j.Errors = report.Rows[:importer.MaxJobErrors]
+			}),
+		},
+		{
+			name:       "completed",
+			request:    get("import-2"),
+			wantStatus: 200,
+			wantBody:   `"status":"completed","total":2,"processed":2,"created":1,"skipped":1,"failed":0,"errors":[]`,
+			wantJob:    api(completed, func(j *importer.APIJob) {}),
+		},
+		{
+			name:       "failed",
+			request:    get("import-3"),
+			wantStatus: 200,
+			wantBody:   `"error":"Invalid goodreads file: missing column \"Author\""`,
+			wantJob:    api(failed, func(j *importer.APIJob) {}),
+		},
+		{
+			// Served as failed so that clients stop polling it
+			name:       "worker died",
+			request:    get("import-6"),
+			wantStatus: 200,
+			wantJob: api(dead, func(j *importer.APIJob) {
+				j.Status, j.Error = importer.JobFailed, importer.JobTimedOut
+			}),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := h.Handle(ctx, tt.request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.wantStatus || !strings.Contains(resp.Body, tt.wantBody) {
+				t.Errorf("Handle = %d %s, want %d containing %q", resp.StatusCode, resp.Body, tt.wantStatus, tt.wantBody)
+			}
+			if tt.wantJob == nil {
+				return
+			}
+
+			if cache := resp.Headers["Cache-Control"]; cache != "no-store" {
+				t.Errorf("Cache-Control = %q, want no-store while the import runs", cache)
+			}
+			var job importer.APIJob
+			if err := json.Unmarshal([]byte(resp.Body), &job); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(job, *tt.wantJob) {
+				t.Errorf("job = %+v, want %+v", job, *tt.wantJob)
+			}
+		})
+	}
+}
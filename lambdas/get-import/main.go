@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/ericdahl/bookshelf-aws/lambdas/get-import/handler"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/bookshelf"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/importer"
+)
+
+// ddbClient is the DynamoDB client.
+var ddbClient *dynamodb.Client
+
+func init() {
+	cfg, err := config.LoadDefaultConfig(context.TODO())
+	if err != nil {
+		log.Fatalf("unable to load SDK config, %v", err)
+	}
+	ddbClient = dynamodb.NewFromConfig(cfg)
+}
+
+func main() {
+	h := handler.New(importer.NewDynamoJobRepository(ddbClient, bookshelf.TableName))
+
+	// If the LAMBDA_TASK_ROOT environment variable is not set, we're running locally.
+	if os.Getenv("LAMBDA_TASK_ROOT") == "" {
+		// Create a dummy request for local testing.
+		request := events.APIGatewayProxyRequest{
+			PathParameters: map[string]string{
+				"id": "test-import-id",
+			},
+		}
+
+		// Call the handler directly.
+		response, err := h.Handle(context.Background(), request)
+		if err != nil {
+			log.Fatalf("FATAL: handler failed: %v", err)
+		}
+
+		// Print the response details to stdout.
+		fmt.Println("--- Local execution ---")
+		fmt.Printf("Status Code: %d\n", response.StatusCode)
+		fmt.Printf("Response Body: %s\n", response.Body)
+	} else {
+		// Start the Lambda handler in the AWS environment.
+		lambda.Start(h.Handle)
+	}
+}
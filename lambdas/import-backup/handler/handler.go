@@ -0,0 +1,121 @@
+// Package handler implements POST /import/backup.
+package handler
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/bookshelf"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/importer"
+)
+
+// MaxBooks is the most books one request may restore, so the restore
+// finishes well within the Lambda timeout.
+const MaxBooks = 5000
+
+// Handler serves POST /import/backup against an injected book repository.
+type Handler struct {
+	Books bookshelf.BookRepository
+}
+
+// New returns a Handler backed by books.
+func New(books bookshelf.BookRepository) *Handler {
+	return &Handler{Books: books}
+}
+
+// Handle is the Lambda function handler.
+func (h *Handler) Handle(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Extract user ID from JWT claims
+	userID, err := bookshelf.UserID(request)
+	if err != nil {
+		log.Printf("Error extracting user ID: %v", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusUnauthorized,
+			Body:       "Unauthorized: Could not extract user ID",
+		}, nil
+	}
+
+	// Record the request ID with the restored books in their history
+	ctx = bookshelf.WithRequestID(ctx, request.RequestContext.RequestID)
+
+	// Merge unless the client asks for a replace
+	mode := request.QueryStringParameters["mode"]
+	if mode == "" {
+		mode = importer.RestoreMerge
+	}
+	if !importer.ValidRestoreMode(mode) {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Body:       fmt.Sprintf("Invalid mode. Must be one of: %s, %s", importer.RestoreMerge, importer.RestoreReplace),
+		}, nil
+	}
+
+	// The backup is the raw request body, base64-encoded by API Gateway
+	// when it is sent as binary
+	body := request.Body
+	if request.IsBase64Encoded {
+		decoded, err := base64.StdEncoding.DecodeString(body)
+		if err != nil {
+			log.Printf("Error decoding request body: %v", err)
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusBadRequest,
+				Body:       "Invalid request body",
+			}, nil
+		}
+		body = string(decoded)
+	}
+
+	backup, err := importer.ParseBackup(strings.NewReader(body))
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Body:       fmt.Sprintf("Invalid backup file: %v", err),
+		}, nil
+	}
+	if len(backup.Books) > MaxBooks {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusRequestEntityTooLarge,
+			Body:       fmt.Sprintf("Too many books. At most %d books can be restored at once", MaxBooks),
+		}, nil
+	}
+
+	// Store the backup's books and report on every book
+	report, err := importer.Restore(ctx, h.Books, userID, backup, mode)
+	if errors.Is(err, importer.ErrFilteredReplace) {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Body:       "A filtered export cannot be restored in replace mode",
+		}, nil
+	}
+	if err != nil {
+		log.Printf("Error restoring books: %v", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Body:       "Internal Server Error",
+		}, nil
+	}
+
+	responseBody, err := json.Marshal(report)
+	if err != nil {
+		log.Printf("Error marshalling JSON: %v", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Body:       "Internal Server Error",
+		}, nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Headers: map[string]string{
+			"Content-Type": "application/json",
+		},
+		Body: string(responseBody),
+	}, nil
+}
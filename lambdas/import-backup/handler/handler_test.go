@@ -0,0 +1,252 @@
+package handler
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/bookshelf"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/handlertest"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/importer"
+)
+
+// backup returns a JSON export of books taken with filters.
+func backup(t *testing.T, filters map[string]string, books ...bookshelf.APIBook) string {
+	t.Helper()
+	data, err := json.Marshal(bookshelf.Export{
+		SchemaVersion: bookshelf.ExportSchemaVersion,
+		ExportedAt:    "2025-03-01T12:00:00Z",
+		Filters:       filters,
+		BookCount:     len(books),
+		Books:         books,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestHandle(t *testing.T) {
+	dune := bookshelf.APIBook{ID: "book-1", Title: "Dune", Author: "Frank Herbert", Status: bookshelf.StatusRead, Version: 1}
+	emma := bookshelf.APIBook{ID: "book-2", Title: "Emma", Author: "Jane Austen", Status: bookshelf.StatusWantToRead, Version: 1}
+	piranesi := bookshelf.APIBook{ID: "book-3", Title: "Piranesi", Author: "Susanna Clarke", Status: bookshelf.StatusReading, Version: 1}
+	restore := func(mode, body string) events.APIGatewayProxyRequest {
+		request := events.APIGatewayProxyRequest{Body: body}
+		if mode != "" {
+			request.QueryStringParameters = map[string]string{"mode": mode}
+		}
+		return handlertest.Request(request)
+	}
+	full := backup(t, nil, dune, piranesi)
+	encoded := restore("", base64.StdEncoding.EncodeToString([]byte(full)))
+	encoded.IsBase64Encoded = true
+	// rated is dune rated since the shelf was backed up, and restored4 is
+	// piranesi backed up at version 4
+	rating := 8
+	rated := dune
+	rated.Rating = &rating
+	restored4 := piranesi
+	restored4.Version = 4
+	unnamed := emma
+	unnamed.ID, unnamed.Author = "book-4", ""
+	tooMany := make([]bookshelf.APIBook, MaxBooks+1)
+	for i := range tooMany {
+		tooMany[i] = bookshelf.APIBook{ID: fmt.Sprintf("book-%d", i), Title: "Dune", Author: "Frank Herbert", Status: bookshelf.StatusRead, Version: 1}
+	}
+
+	tests := []struct {
+		name       string
+		request    events.APIGatewayProxyRequest
+		wantStatus int
+		wantBody   string   // contained in the body
+		wantShelf  []string // IDs of the books on the shelf afterwards
+		wantReport *importer.RestoreReport
+		wantBooks  []bookshelf.APIBook // stored, of those restored
+		wantTrash  []string            // IDs of the books moved to the trash
+	}{
+		{
+			name:       "no claims",
+			request:    events.APIGatewayProxyRequest{Body: full},
+			wantStatus: 401,
+			wantBody:   "Unauthorized",
+			wantShelf:  []string{"book-1", "book-2"},
+		},
+		{
+			name:       "invalid mode",
+			request:    restore("overwrite", full),
+			wantStatus: 400,
+			wantBody:   "Invalid mode",
+			wantShelf:  []string{"book-1", "book-2"},
+		},
+		{
+			name: "invalid base64",
+			request: handlertest.Request(events.APIGatewayProxyRequest{
+				Body:            "not base64!",
+				IsBase64Encoded: true,
+			}),
+			wantStatus: 400,
+			wantBody:   "Invalid request body",
+			wantShelf:  []string{"book-1", "book-2"},
+		},
+		{
+			name:       "malformed backup",
+			request:    restore("", `{"schema_version":`),
+			wantStatus: 400,
+			wantBody:   "Invalid backup file: malformed JSON",
+			wantShelf:  []string{"book-1", "book-2"},
+		},
+		{
+			name:       "truncated backup",
+			request:    restore("", strings.Replace(full, `"book_count":2`, `"book_count":3`, 1)),
+			wantStatus: 400,
+			wantBody:   "Invalid backup file: book_count is 3",
+			wantShelf:  []string{"book-1", "book-2"},
+		},
+		{
+			name:       "too many books",
+			request:    restore("", backup(t, nil, tooMany...)),
+			wantStatus: 413,
+			wantBody:   "Too many books",
+			wantShelf:  []string{"book-1", "book-2"},
+		},
+		{
+			name:       "filtered replace",
+			request:    restore(importer.RestoreReplace, backup(t, map[string]string{"status": "READ"}, dune)),
+			wantStatus: 400,
+			wantBody:   "A filtered export cannot be restored in replace mode",
+			wantShelf:  []string{"book-1", "book-2"},
+		},
+		{
+			name:       "merged by default",
+			request:    restore("", full),
+			wantStatus: 200,
+			wantShelf:  []string{"book-1", "book-2", "book-3"},
+			wantReport: &importer.RestoreReport{Mode: importer.RestoreMerge, Created: 1, Unchanged: 1, Books: []importer.RestoreResult{
+				{Entry: 1, BookID: "book-1", Title: "Dune", Status: importer.BookUnchanged},
+				{Entry: 2, BookID: "book-3", Title: "Piranesi", Status: importer.BookCreated},
+			}},
+			wantBooks: []bookshelf.APIBook{dune, piranesi},
+		},
+		{
+			name:       "merged from binary",
+			request:    encoded,
+			wantStatus: 200,
+			wantBody:   `"mode":"merge","created":1,`,
+			wantShelf:  []string{"book-1", "book-2", "book-3"},
+			wantBooks:  []bookshelf.APIBook{dune, piranesi},
+		},
+		{
+			// A changed book replaces the one on the shelf as its next
+			// version, and a new one keeps the version it was backed up at
+			name:       "merged versions",
+			request:    restore("", backup(t, nil, rated, restored4)),
+			wantStatus: 200,
+			wantShelf:  []string{"book-1", "book-2", "book-3"},
+			wantReport: &importer.RestoreReport{Mode: importer.RestoreMerge, Created: 1, Updated: 1, Books: []importer.RestoreResult{
+				{Entry: 1, BookID: "book-1", Title: "Dune", Status: importer.BookUpdated},
+				{Entry: 2, BookID: "book-3", Title: "Piranesi", Status: importer.BookCreated},
+			}},
+			wantBooks: func() []bookshelf.APIBook {
+				updated := rated
+				updated.Version = 2
+				return []bookshelf.APIBook{updated, restored4}
+			}(),
+		},
+		{
+			name:       "invalid books",
+			request:    restore("", backup(t, nil, piranesi, unnamed, piranesi)),
+			wantStatus: 200,
+			wantShelf:  []string{"book-1", "book-2", "book-3"},
+			wantReport: &importer.RestoreReport{Mode: importer.RestoreMerge, Created: 1, Failed: 2, Books: []importer.RestoreResult{
+				{Entry: 1, BookID: "book-3", Title: "Piranesi", Status: importer.BookCreated},
+				{Entry: 2, BookID: "book-4", Title: "Emma", Status: importer.BookFailed, Error: "Author is required"},
+				{Entry: 3, BookID: "book-3", Title: "Piranesi", Status: importer.BookFailed, Error: "Duplicate book ID"},
+			}},
+			wantBooks: []bookshelf.APIBook{piranesi},
+		},
+		{
+			name:       "filtered merge",
+			request:    restore(importer.RestoreMerge, backup(t, map[string]string{"status": "READING"}, piranesi)),
+			wantStatus: 200,
+			wantBody:   `"created":1,`,
+			wantShelf:  []string{"book-1", "book-2", "book-3"},
+		},
+		{
+			// Emma, added since the backup, can be restored from the trash
+			name:       "replaced",
+			request:    restore(importer.RestoreReplace, full),
+			wantStatus: 200,
+			wantShelf:  []string{"book-1", "book-3"},
+			wantReport: &importer.RestoreReport{Mode: importer.RestoreReplace, Created: 1, Unchanged: 1, Removed: 1, Books: []importer.RestoreResult{
+				{Entry: 1, BookID: "book-1", Title: "Dune", Status: importer.BookUnchanged},
+				{Entry: 2, BookID: "book-3", Title: "Piranesi", Status: importer.BookCreated},
+				{BookID: "book-2", Title: "Emma", Status: importer.BookRemoved},
+			}},
+			wantBooks: []bookshelf.APIBook{dune, piranesi},
+			wantTrash: []string{"book-2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			books := handlertest.Books(t, dune, emma)
+			resp, err := New(books).Handle(ctx, tt.request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.wantStatus || !strings.Contains(resp.Body, tt.wantBody) {
+				t.Errorf("Handle = %d %s, want %d containing %q", resp.StatusCode, resp.Body, tt.wantStatus, tt.wantBody)
+			}
+
+			shelf, err := books.List(ctx, handlertest.UserID, bookshelf.ListOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var ids []string
+			for _, book := range shelf {
+				ids = append(ids, book.ID)
+			}
+			slices.Sort(ids)
+			if !slices.Equal(ids, tt.wantShelf) {
+				t.Errorf("shelf = %v, want %v", ids, tt.wantShelf)
+			}
+			for _, want := range tt.wantBooks {
+				book, err := books.Get(ctx, handlertest.UserID, want.ID)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(book.ToAPI(), want) {
+					t.Errorf("stored %+v, want %+v", book.ToAPI(), want)
+				}
+			}
+			trash, err := books.ListTrash(ctx, handlertest.UserID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var trashed []string
+			for _, book := range trash {
+				trashed = append(trashed, book.ID)
+			}
+			if !slices.Equal(trashed, tt.wantTrash) {
+				t.Errorf("trash = %v, want %v", trashed, tt.wantTrash)
+			}
+			if tt.wantReport == nil {
+				return
+			}
+
+			var report importer.RestoreReport
+			if err := json.Unmarshal([]byte(resp.Body), &report); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(report, *tt.wantReport) {
+				t.Errorf("report = %+v, want %+v", report, *tt.wantReport)
+			}
+		})
+	}
+}
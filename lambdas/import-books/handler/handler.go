@@ -0,0 +1,112 @@
+// Package handler implements POST /import.
+package handler
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/bookshelf"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/importer"
+	"github.com/google/uuid"
+)
+
+// MaxRows is the most books one request may import, so the import finishes
+// well within the Lambda timeout.
+const MaxRows = 5000
+
+// Handler serves POST /import against an injected book repository.
+type Handler struct {
+	Books bookshelf.BookRepository
+}
+
+// New returns a Handler backed by books.
+func New(books bookshelf.BookRepository) *Handler {
+	return &Handler{Books: books}
+}
+
+// Handle is the Lambda function handler.
+func (h *Handler) Handle(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Extract user ID from JWT claims
+	userID, err := bookshelf.UserID(request)
+	if err != nil {
+		log.Printf("Error extracting user ID: %v", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusUnauthorized,
+			Body:       "Unauthorized: Could not extract user ID",
+		}, nil
+	}
+
+	// Record the request ID with the new books in their history
+	ctx = bookshelf.WithRequestID(ctx, request.RequestContext.RequestID)
+
+	// Pick the importer for the export's format
+	imp, ok := importer.Lookup(request.QueryStringParameters["format"])
+	if !ok {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Body:       importer.InvalidFormatMessage(),
+		}, nil
+	}
+
+	// The file is the raw request body, base64-encoded by API Gateway when
+	// it is sent as binary
+	body := request.Body
+	if request.IsBase64Encoded {
+		decoded, err := base64.StdEncoding.DecodeString(body)
+		if err != nil {
+			log.Printf("Error decoding request body: %v", err)
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusBadRequest,
+				Body:       "Invalid request body",
+			}, nil
+		}
+		body = string(decoded)
+	}
+
+	rows, err := imp.Parse(strings.NewReader(body))
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Body:       fmt.Sprintf("Invalid %s file: %v", imp.Format(), err),
+		}, nil
+	}
+	if len(rows) > MaxRows {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusRequestEntityTooLarge,
+			Body:       fmt.Sprintf("Too many rows. At most %d books can be imported at once", MaxRows),
+		}, nil
+	}
+
+	// Store the new books and report on every row
+	report, err := importer.Import(ctx, h.Books, userID, rows, uuid.NewString)
+	if err != nil {
+		log.Printf("Error importing books: %v", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Body:       "Internal Server Error",
+		}, nil
+	}
+
+	responseBody, err := json.Marshal(report)
+	if err != nil {
+		log.Printf("Error marshalling JSON: %v", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Body:       "Internal Server Error",
+		}, nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Headers: map[string]string{
+			"Content-Type": "application/json",
+		},
+		Body: string(responseBody),
+	}, nil
+}
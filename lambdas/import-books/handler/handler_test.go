@@ -0,0 +1,195 @@
+package handler
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/bookshelf"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/handlertest"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/importer"
+)
+
+func TestHandle(t *testing.T) {
+	upload := func(format, body string) events.APIGatewayProxyRequest {
+		return handlertest.Request(events.APIGatewayProxyRequest{
+			QueryStringParameters: map[string]string{"format": format},
+			Body:                  body,
+		})
+	}
+	rating := func(n int) *int { return &n }
+	// Dune is already on the shelf and the third row has no author
+	const goodreads = "Title,Author,My Rating,Exclusive Shelf,Bookshelves,Date Added\n" +
+		"Dune,Frank Herbert,5,read,,2024/01/01\n" +
+		"Emma,Jane Austen,0,to-read,classics,2024/02/03\n" +
+		"Beowulf,,4,read,,2024/01/01\n"
+	goodreadsRows := []importer.RowResult{
+		{Row: 2, Title: "Dune", Status: importer.RowSkipped, BookID: "book-1"},
+		{Row: 3, Title: "Emma", Status: importer.RowCreated},
+		{Row: 4, Title: "Beowulf", Status: importer.RowFailed, Error: "Author is required"},
+	}
+	emma := bookshelf.APIBook{
+		Title: "Emma", Author: "Jane Austen", Status: bookshelf.StatusWantToRead,
+		Tags: []string{"classics"}, CreatedAt: "2024-02-03T00:00:00Z",
+	}
+	encoded := upload("goodreads", base64.StdEncoding.EncodeToString([]byte(goodreads)))
+	encoded.IsBase64Encoded = true
+	tooMany := "Title,Author\n" + strings.Repeat("Dune,Frank Herbert\n", MaxRows+1)
+
+	tests := []struct {
+		name       string
+		request    events.APIGatewayProxyRequest
+		wantStatus int
+		wantBody   string               // contained in the body
+		wantRows   []importer.RowResult // BookID of created rows is checked against the stored book
+		wantBooks  []bookshelf.APIBook  // created, without IDs and versions
+	}{
+		{
+			name:       "no claims",
+			request:    events.APIGatewayProxyRequest{QueryStringParameters: map[string]string{"format": "goodreads"}, Body: goodreads},
+			wantStatus: 401,
+			wantBody:   "Unauthorized",
+		},
+		{
+			name:       "invalid format",
+			request:    upload("kindle", goodreads),
+			wantStatus: 400,
+			wantBody:   "Invalid format. Must be one of: goodreads, librarything, storygraph",
+		},
+		{
+			name: "invalid base64",
+			request: handlertest.Request(events.APIGatewayProxyRequest{
+				QueryStringParameters: map[string]string{"format": "goodreads"},
+				Body:                  "not base64!",
+				IsBase64Encoded:       true,
+			}),
+			wantStatus: 400,
+			wantBody:   "Invalid request body",
+		},
+		{
+			name:       "missing column",
+			request:    upload("goodreads", "Title,Exclusive Shelf\nDune,read\n"),
+			wantStatus: 400,
+			wantBody:   `Invalid goodreads file: missing column "Author"`,
+		},
+		{
+			// A Goodreads file uploaded as a StoryGraph export
+			name:       "wrong format",
+			request:    upload("storygraph", goodreads),
+			wantStatus: 400,
+			wantBody:   `Invalid storygraph file: missing column "Authors"`,
+		},
+		{
+			name:       "too many rows",
+			request:    upload("goodreads", tooMany),
+			wantStatus: 413,
+			wantBody:   "Too many rows. At most 5000 books can be imported at once",
+		},
+		{
+			name:       "goodreads",
+			request:    upload("goodreads", goodreads),
+			wantStatus: 200,
+			wantRows:   goodreadsRows,
+			wantBooks:  []bookshelf.APIBook{emma},
+		},
+		{
+			name:       "goodreads from binary",
+			request:    encoded,
+			wantStatus: 200,
+			wantRows:   goodreadsRows,
+			wantBooks:  []bookshelf.APIBook{emma},
+		},
+		{
+			name: "storygraph",
+			request: upload("storygraph", "Title,Authors,Read Status,Star Rating,Date Added\n"+
+				"Piranesi,Susanna Clarke,currently-reading,,2023/01/02\n"+
+				"dune,Frank Herbert,read,4.5,2023/01/02\n"+
+				"Emma,Jane Austen,lost,,2023/01/02\n"),
+			wantStatus: 200,
+			wantRows: []importer.RowResult{
+				{Row: 2, Title: "Piranesi", Status: importer.RowCreated},
+				{Row: 3, Title: "dune", Status: importer.RowSkipped, BookID: "book-1"},
+				{Row: 4, Title: "Emma", Status: importer.RowFailed, Error: `Invalid Read Status "lost"`},
+			},
+			wantBooks: []bookshelf.APIBook{
+				{Title: "Piranesi", Author: "Susanna Clarke", Status: bookshelf.StatusReading, CreatedAt: "2023-01-02T00:00:00Z"},
+			},
+		},
+		{
+			name: "librarything",
+			request: upload("librarything", "Title\tPrimary Author\tRating\tCollections\tDate Read\tEntry Date\n"+
+				"Hyperion\tSimmons, Dan\t3.5\tYour library\t2022-03-10\t2022-02-28\n"+
+				"Emma\t\t\tTo read\t\t2022-02-28\n"),
+			wantStatus: 200,
+			wantRows: []importer.RowResult{
+				{Row: 2, Title: "Hyperion", Status: importer.RowCreated},
+				{Row: 3, Title: "Emma", Status: importer.RowFailed, Error: "Primary Author is required"},
+			},
+			wantBooks: []bookshelf.APIBook{
+				{Title: "Hyperion", Author: "Dan Simmons", Status: bookshelf.StatusRead, Rating: rating(7),
+					FinishedAt: "2022-03-10", CreatedAt: "2022-02-28T00:00:00Z"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			books := handlertest.Books(t, bookshelf.APIBook{ID: "book-1", Title: "Dune", Author: "Frank Herbert", Status: bookshelf.StatusRead})
+			resp, err := New(books).Handle(ctx, tt.request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.wantStatus || !strings.Contains(resp.Body, tt.wantBody) {
+				t.Errorf("Handle = %d %s, want %d containing %q", resp.StatusCode, resp.Body, tt.wantStatus, tt.wantBody)
+			}
+
+			all, err := books.List(ctx, handlertest.UserID, bookshelf.ListOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			created := map[string]bookshelf.Book{}
+			var stored []bookshelf.APIBook
+			for _, book := range all {
+				if book.ID == "book-1" {
+					continue
+				}
+				created[book.ID] = book
+				api := book.ToAPI()
+				api.ID, api.Version = "", 0
+				stored = append(stored, api)
+			}
+			if !reflect.DeepEqual(stored, tt.wantBooks) {
+				t.Errorf("created books = %+v, want %+v", stored, tt.wantBooks)
+			}
+			if tt.wantRows == nil {
+				return
+			}
+
+			var report importer.Report
+			if err := json.Unmarshal([]byte(resp.Body), &report); err != nil {
+				t.Fatal(err)
+			}
+			for i, row := range report.Rows {
+				if row.Status != importer.RowCreated {
+					continue
+				}
+				// The report names the book that was stored for the row
+				if book, ok := created[row.BookID]; !ok || book.Title != row.Title {
+					t.Errorf("row %d created book %q, which is not stored", row.Row, row.BookID)
+				}
+				report.Rows[i].BookID = ""
+			}
+			if !reflect.DeepEqual(report.Rows, tt.wantRows) {
+				t.Errorf("rows = %+v, want %+v", report.Rows, tt.wantRows)
+			}
+			if report.Created != len(tt.wantBooks) || report.Created+report.Skipped+report.Failed != len(tt.wantRows) {
+				t.Errorf("report = %d created, %d skipped, %d failed, want %d created of %d rows",
+					report.Created, report.Skipped, report.Failed, len(tt.wantBooks), len(tt.wantRows))
+			}
+		})
+	}
+}
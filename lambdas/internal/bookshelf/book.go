@@ -0,0 +1,117 @@
+// Package bookshelf holds the domain model shared by the bookshelf Lambdas:
+// the DynamoDB item layout, the USER#/BOOK# key scheme, book statuses, JWT
+// claim extraction and the mapping between stored items and API responses.
+package bookshelf
+
+// Book represents a book record for DynamoDB.
+type Book struct {
+	PK         string   `dynamodbav:"PK"`
+	SK         string   `dynamodbav:"SK"`
+	ID         string   `dynamodbav:"id"`
+	Title      string   `dynamodbav:"Title"`
+	Author     string   `dynamodbav:"Author"`
+	Series     string   `dynamodbav:"Series,omitempty"`
+	Status     string   `dynamodbav:"status"`
+	Rating     *int     `dynamodbav:"rating,omitempty"`
+	Review     string   `dynamodbav:"review,omitempty"`
+	Tags       []string `dynamodbav:"tags,omitempty"`
+	StartedAt  string   `dynamodbav:"started_at,omitempty"`
+	FinishedAt string   `dynamodbav:"finished_at,omitempty"`
+	Thumbnail  string   `dynamodbav:"thumbnail,omitempty"`
+	Type       string   `dynamodbav:"type,omitempty"`
+	Comments   string   `dynamodbav:"comments,omitempty"`
+	CreatedAt  string   `dynamodbav:"created_at,omitempty"`
+	// VolumeID is the Google Books volume the book was added from, if any.
+	VolumeID string `dynamodbav:"google_volume_id,omitempty"`
+	ISBN     string `dynamodbav:"isbn,omitempty"`
+	// Version increases by one on every update. Books stored before
+	// versioning have no version attribute and read as 0.
+	Version int `dynamodbav:"version,omitempty"`
+
+	// Index keys, maintained by SetKeys.
+	GSI1PK string `dynamodbav:"GSI1PK,omitempty"`
+	GSI1SK string `dynamodbav:"GSI1SK,omitempty"`
+	GSI2PK string `dynamodbav:"GSI2PK,omitempty"`
+	GSI2SK string `dynamodbav:"GSI2SK,omitempty"`
+}
+
+// APIBook is the structure for the API response.
+type APIBook struct {
+	ID         string   `json:"id"`
+	Title      string   `json:"title"`
+	Author     string   `json:"author"`
+	Series     string   `json:"series,omitempty"`
+	Status     string   `json:"status"`
+	Rating     *int     `json:"rating,omitempty"`
+	Review     string   `json:"review,omitempty"`
+	Tags       []string `json:"tags,omitempty"`
+	StartedAt  string   `json:"started_at,omitempty"`
+	FinishedAt string   `json:"finished_at,omitempty"`
+	Thumbnail  string   `json:"thumbnail"`
+	Type       string   `json:"type,omitempty"`
+	Comments   string   `json:"comments,omitempty"`
+	CreatedAt  string   `json:"created_at,omitempty"`
+	VolumeID   string   `json:"google_volume_id,omitempty"`
+	ISBN       string   `json:"isbn,omitempty"`
+	Version    int      `json:"version"`
+}
+
+// NewBook builds the DynamoDB record for a book owned by userID, keyed by
+// bookID, from its API representation. The ID and version in api are
+// ignored; new books start at version 1.
+func NewBook(userID, bookID string, api APIBook) Book {
+	book := Book{
+		ID:         bookID,
+		Title:      api.Title,
+		Author:     api.Author,
+		Series:     api.Series,
+		Status:     api.Status,
+		Rating:     api.Rating,
+		Review:     api.Review,
+		Tags:       api.Tags,
+		StartedAt:  api.StartedAt,
+		FinishedAt: api.FinishedAt,
+		Thumbnail:  api.Thumbnail,
+		Type:       api.Type,
+		Comments:   api.Comments,
+		CreatedAt:  api.CreatedAt,
+		VolumeID:   api.VolumeID,
+		ISBN:       api.ISBN,
+		Version:    1,
+	}
+	book.SetKeys(userID)
+	return book
+}
+
+// ToAPI converts a stored book into its API representation.
+func (b Book) ToAPI() APIBook {
+	return APIBook{
+		ID:         b.ID,
+		Title:      b.Title,
+		Author:     b.Author,
+		Series:     b.Series,
+		Status:     b.Status,
+		Rating:     b.Rating,
+		Review:     b.Review,
+		Tags:       b.Tags,
+		StartedAt:  b.StartedAt,
+		FinishedAt: b.FinishedAt,
+		Thumbnail:  b.Thumbnail,
+		Type:       b.Type,
+		Comments:   b.Comments,
+		CreatedAt:  b.CreatedAt,
+		VolumeID:   b.VolumeID,
+		ISBN:       b.ISBN,
+		Version:    b.Version,
+	}
+}
+
+// ToAPIBooks converts a list of stored books into API representations.
+// The result is never nil so that it always marshals to a JSON array.
+func ToAPIBooks(books []Book) []APIBook {
+	apiBooks := make([]APIBook, len(books))
+	for i, book := range books {
+		apiBooks[i] = book.ToAPI()
+	}
+	return apiBooks
+}
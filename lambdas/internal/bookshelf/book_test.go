@@ -0,0 +1,195 @@
+package bookshelf
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+func intPtr(n int) *int { return &n }
+
+func TestNewBookToAPI(t *testing.T) {
+	tests := []struct {
+		name string
+		api  APIBook
+		// absent are attributes the stored item must not have.
+		absent []string
+	}{
+		{
+			name: "required fields only",
+			api:  APIBook{Title: "Dune", Author: "Frank Herbert", Status: StatusWantToRead},
+			absent: []string{
+				"Series", "rating", "review", "tags", "started_at", "finished_at",
+				"thumbnail", "type", "comments", "created_at", "google_volume_id", "isbn",
+			},
+		},
+		{
+			name: "every field",
+			api: APIBook{
+				Title:      "The Way of Kings",
+				Author:     "Brandon Sanderson",
+				Series:     "The Stormlight Archive",
+				Status:     StatusRead,
+				Rating:     intPtr(9),
+				Review:     "Long, and worth it.",
+				Tags:       []string{"fantasy", "epic"},
+				StartedAt:  "2024-01-03",
+				FinishedAt: "2024-02-11",
+				Thumbnail:  "https://books.example/cover.jpg",
+				Type:       "ebook",
+				Comments:   "Lent by a friend",
+				CreatedAt:  "2024-01-01T09:30:00Z",
+				VolumeID:   "QVn-CgAAQBAJ",
+				ISBN:       "0765326353",
+			},
+		},
+		{
+			name:   "reading with a start date",
+			api:    APIBook{Title: "Piranesi", Author: "Susanna Clarke", Status: StatusReading, StartedAt: "2025-03-01"},
+			absent: []string{"finished_at", "rating"},
+		},
+		{
+			name: "lowest rating and one tag",
+			api:  APIBook{Title: "Beowulf", Author: "Unknown", Status: StatusRead, Rating: intPtr(1), Tags: []string{"poetry"}},
+		},
+		{
+			name: "ID and version from the client are ignored",
+			api:  APIBook{ID: "client-id", Title: "Emma", Author: "Jane Austen", Status: StatusRead, Version: 7},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			book := NewBook("user-1", "book-1", tt.api)
+			if book.ID != "book-1" || book.Version != 1 {
+				t.Errorf("NewBook ID, version = %q, %d, want %q, 1", book.ID, book.Version, "book-1")
+			}
+			if book.PK != "USER#user-1" || book.SK != "BOOK#book-1" {
+				t.Errorf("NewBook keys = %q, %q", book.PK, book.SK)
+			}
+			if book.GSI1PK != book.PK || book.GSI1SK == "" || book.GSI2PK != book.PK || book.GSI2SK == "" {
+				t.Errorf("NewBook index keys not set: %+v", book)
+			}
+
+			want := tt.api
+			want.ID = "book-1"
+			want.Version = 1
+			if got := book.ToAPI(); !reflect.DeepEqual(got, want) {
+				t.Errorf("ToAPI =\n%+v\nwant\n%+v", got, want)
+			}
+
+			// Round trip through the DynamoDB item
+			item, err := attributevalue.MarshalMap(book)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, name := range tt.absent {
+				if _, ok := item[name]; ok {
+					t.Errorf("item has %s = %#v, want it omitted", name, item[name])
+				}
+			}
+			if v, ok := item["version"].(*types.AttributeValueMemberN); !ok || v.Value != "1" {
+				t.Errorf("item version = %#v, want N 1", item["version"])
+			}
+			var stored Book
+			if err := attributevalue.UnmarshalMap(item, &stored); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(stored, book) {
+				t.Errorf("round trip =\n%+v\nwant\n%+v", stored, book)
+			}
+			if got := stored.ToAPI(); !reflect.DeepEqual(got, want) {
+				t.Errorf("ToAPI after round trip =\n%+v\nwant\n%+v", got, want)
+			}
+		})
+	}
+}
+
+func TestBookItemAttributes(t *testing.T) {
+	book := NewBook("user-1", "book-1", APIBook{
+		Title:     "Leviathan Wakes",
+		Author:    "James S. A. Corey",
+		Series:    "The Expanse",
+		Status:    StatusRead,
+		Rating:    intPtr(8),
+		Tags:      []string{"sci-fi", "space"},
+		StartedAt: "2024-05-01",
+	})
+	item, err := attributevalue.MarshalMap(book)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Title, Author and Series keep the capitalised names of the original
+	// table layout
+	for name, want := range map[string]string{
+		"Title":      "Leviathan Wakes",
+		"Author":     "James S. A. Corey",
+		"Series":     "The Expanse",
+		"status":     StatusRead,
+		"started_at": "2024-05-01",
+	} {
+		if v, ok := item[name].(*types.AttributeValueMemberS); !ok || v.Value != want {
+			t.Errorf("item[%q] = %#v, want S %q", name, item[name], want)
+		}
+	}
+	if v, ok := item["rating"].(*types.AttributeValueMemberN); !ok || v.Value != "8" {
+		t.Errorf("item rating = %#v, want N 8", item["rating"])
+	}
+	tags, ok := item["tags"].(*types.AttributeValueMemberL)
+	if !ok || len(tags.Value) != 2 {
+		t.Fatalf("item tags = %#v, want a list of 2", item["tags"])
+	}
+	if v, ok := tags.Value[1].(*types.AttributeValueMemberS); !ok || v.Value != "space" {
+		t.Errorf("item tags[1] = %#v, want S %q", tags.Value[1], "space")
+	}
+}
+
+func TestToAPILegacyBook(t *testing.T) {
+	// Books stored before versioning have no version, and may have no tags
+	// or thumbnail
+	item := map[string]types.AttributeValue{
+		"PK":     &types.AttributeValueMemberS{Value: "USER#user-1"},
+		"SK":     &types.AttributeValueMemberS{Value: "BOOK#old"},
+		"id":     &types.AttributeValueMemberS{Value: "old"},
+		"Title":  &types.AttributeValueMemberS{Value: "Old Book"},
+		"Author": &types.AttributeValueMemberS{Value: "Old Author"},
+		"status": &types.AttributeValueMemberS{Value: StatusRead},
+	}
+	var book Book
+	if err := attributevalue.UnmarshalMap(item, &book); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := json.Marshal(book.ToAPI())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"id":        "old",
+		"title":     "Old Book",
+		"author":    "Old Author",
+		"status":    StatusRead,
+		"thumbnail": "",
+		"version":   float64(0),
+	}
+	if !reflect.DeepEqual(fields, want) {
+		t.Errorf("JSON = %s, want %v", data, want)
+	}
+}
+
+func TestToAPIBooksEmpty(t *testing.T) {
+	data, err := json.Marshal(ToAPIBooks(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "[]" {
+		t.Errorf("ToAPIBooks(nil) marshals to %s, want []", data)
+	}
+}
@@ -0,0 +1,39 @@
+package bookshelf
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// UserID extracts the user ID from the JWT claims in the request context
+func UserID(request events.APIGatewayProxyRequest) (string, error) {
+	// For HTTP API with JWT authorizer, the claims are nested under jwt.claims
+	jwt, ok := request.RequestContext.Authorizer["jwt"].(map[string]interface{})
+	if !ok {
+		log.Printf("Authorizer context: %+v", request.RequestContext.Authorizer)
+		return "", fmt.Errorf("no jwt found in authorizer context")
+	}
+
+	claims, ok := jwt["claims"].(map[string]interface{})
+	if !ok {
+		log.Printf("JWT context: %+v", jwt)
+		return "", fmt.Errorf("no claims found in jwt context")
+	}
+
+	// Try accessing the 'sub' claim first (standard JWT subject claim)
+	if sub, ok := claims["sub"].(string); ok {
+		return sub, nil
+	}
+
+	// Try cognito:username as fallback
+	if cognitoUsername, ok := claims["cognito:username"].(string); ok {
+		return cognitoUsername, nil
+	}
+
+	// Debug: log the actual claims structure if we can't find the user ID
+	log.Printf("Claims: %+v", claims)
+
+	return "", fmt.Errorf("no user ID found in JWT claims")
+}
@@ -0,0 +1,50 @@
+package bookshelf
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCursorCodec(t *testing.T) {
+	codec, err := NewCursorCodec(make([]byte, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := NewCursorCodec([]byte("0123456789abcdef"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cursor, err := codec.Encode("user-1", `{"status":"READ"}`, "token-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		codec   *CursorCodec
+		userID  string
+		query   string
+		cursor  string
+		want    string
+		wantErr error
+	}{
+		{"same user and query", codec, "user-1", `{"status":"READ"}`, cursor, "token-1", nil},
+		{"other user", codec, "user-2", `{"status":"READ"}`, cursor, "", ErrInvalidCursor},
+		{"other query", codec, "user-1", `{"status":"READING"}`, cursor, "", ErrCursorMismatch},
+		{"other key", other, "user-1", `{"status":"READ"}`, cursor, "", ErrInvalidCursor},
+		{"not base64", codec, "user-1", `{"status":"READ"}`, "not a cursor!", "", ErrInvalidCursor},
+		{"too short", codec, "user-1", `{"status":"READ"}`, "AAAA", "", ErrInvalidCursor},
+		{"tampered", codec, "user-1", `{"status":"READ"}`, cursor[:len(cursor)-2] + "AA", "", ErrInvalidCursor},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.codec.Decode(tt.userID, tt.query, tt.cursor)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Decode error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Decode = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package bookshelf
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// FindDuplicate returns the first of books that is likely the same book as
+// candidate: the same Google Books volume, the same ISBN, or the same title
+// and author once case, punctuation and a leading article are ignored, and
+// the author's initials however they are written.
+func FindDuplicate(books []Book, candidate Book) (Book, bool) {
+	isbn := NormalizeISBN(candidate.ISBN)
+	title := normalizeTitle(candidate.Title)
+	author := NormalizeAuthor(candidate.Author)
+
+	for _, book := range books {
+		switch {
+		case candidate.VolumeID != "" && book.VolumeID == candidate.VolumeID,
+			isbn != "" && NormalizeISBN(book.ISBN) == isbn,
+			title != "" && normalizeTitle(book.Title) == title && NormalizeAuthor(book.Author) == author:
+			return book, true
+		}
+	}
+	return Book{}, false
+}
+
+// NormalizeISBN returns isbn as a bare ISBN-13, converting ISBN-10s, or ""
+// if it is not a well-formed ISBN.
+func NormalizeISBN(isbn string) string {
+	var b strings.Builder
+	for _, r := range strings.ToUpper(isbn) {
+		if unicode.IsDigit(r) || r == 'X' {
+			b.WriteRune(r)
+		}
+	}
+	digits := b.String()
+
+	switch {
+	case len(digits) == 13 && !strings.Contains(digits, "X"):
+		return digits
+	case len(digits) == 10 && !strings.Contains(digits[:9], "X"):
+		// ISBN-13 is 978 + the first nine digits + a recomputed check digit.
+		isbn13 := "978" + digits[:9]
+		sum := 0
+		for i, r := range isbn13 {
+			weight := 1
+			if i%2 == 1 {
+				weight = 3
+			}
+			sum += int(r-'0') * weight
+		}
+		return isbn13 + string(rune('0'+(10-sum%10)%10))
+	}
+	return ""
+}
+
+// normalizeText lower-cases s and reduces it to words of letters and digits,
+// so "J.R.R. Tolkien" and "J. R. R. Tolkien" compare equal.
+func normalizeText(s string) string {
+	words := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(words, " ")
+}
+
+// NormalizeAuthor is normalizeText with runs of initials run together, so
+// "J.R.R. Tolkien", "J. R. R. Tolkien" and "JRR Tolkien" compare equal. It
+// keys AuthorIndex, so that the books FindDuplicate compares are the ones
+// ListByAuthor returns.
+func NormalizeAuthor(s string) string {
+	var words []string
+	initials := ""
+	for _, word := range strings.Fields(normalizeText(s)) {
+		if utf8.RuneCountInString(word) == 1 {
+			initials += word
+			continue
+		}
+		if initials != "" {
+			words, initials = append(words, initials), ""
+		}
+		words = append(words, word)
+	}
+	if initials != "" {
+		words = append(words, initials)
+	}
+	return strings.Join(words, " ")
+}
+
+// normalizeTitle is normalizeText without a leading English article.
+func normalizeTitle(s string) string {
+	title := normalizeText(s)
+	for _, article := range []string{"the ", "a ", "an "} {
+		if rest, ok := strings.CutPrefix(title, article); ok {
+			return rest
+		}
+	}
+	return title
+}
@@ -0,0 +1,55 @@
+package bookshelf
+
+import "testing"
+
+func TestNormalizeAuthor(t *testing.T) {
+	tests := []struct {
+		author string
+		want   string
+	}{
+		{"Frank Herbert", "frank herbert"},
+		{"  FRANK   herbert ", "frank herbert"},
+		{"J.R.R. Tolkien", "jrr tolkien"},
+		{"J. R. R. Tolkien", "jrr tolkien"},
+		{"JRR Tolkien", "jrr tolkien"},
+		{"Tolkien, J.R.R.", "tolkien jrr"},
+		{"Ursula K. Le Guin", "ursula k le guin"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := NormalizeAuthor(tt.author); got != tt.want {
+			t.Errorf("NormalizeAuthor(%q) = %q, want %q", tt.author, got, tt.want)
+		}
+	}
+}
+
+func TestFindDuplicate(t *testing.T) {
+	books := []Book{
+		{ID: "book-1", Title: "Dune", Author: "Frank Herbert", ISBN: "0441013597", VolumeID: "B1hSG45JCX4C"},
+		{ID: "book-2", Title: "The Hobbit", Author: "J.R.R. Tolkien"},
+	}
+	tests := []struct {
+		name      string
+		candidate Book
+		want      string // ID of the duplicate, if any
+	}{
+		{"title and author", Book{Title: "dune.", Author: "frank herbert"}, "book-1"},
+		{"ISBN-13 of the ISBN-10", Book{Title: "Dune Deluxe", Author: "Herbert, Frank", ISBN: "978-0-441-01359-3"}, "book-1"},
+		{"volume", Book{Title: "Dune Messiah", Author: "Anyone", VolumeID: "B1hSG45JCX4C"}, "book-1"},
+		{"initials written together", Book{Title: "Hobbit", Author: "JRR Tolkien"}, "book-2"},
+		{"other initials", Book{Title: "The Hobbit", Author: "J.R. Tolkien"}, ""},
+		{"same title by another author", Book{Title: "Dune", Author: "Brian Herbert"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := FindDuplicate(books, tt.candidate)
+			if ok != (tt.want != "") || got.ID != tt.want {
+				t.Errorf("FindDuplicate = %q, %v, want %q", got.ID, ok, tt.want)
+			}
+			// A title can only match among the author's books
+			if ok && tt.candidate.ISBN == "" && tt.candidate.VolumeID == "" && AuthorKeyPrefix(tt.candidate.Author) != AuthorKeyPrefix(got.Author) {
+				t.Errorf("AuthorKeyPrefix(%q) != AuthorKeyPrefix(%q), so ListByAuthor would miss the duplicate", tt.candidate.Author, got.Author)
+			}
+		})
+	}
+}
@@ -0,0 +1,1164 @@
+package bookshelf
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// DynamoDBAPI is the subset of the DynamoDB client used by DynamoRepository.
+type DynamoDBAPI interface {
+	GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error)
+	BatchWriteItem(ctx context.Context, params *dynamodb.BatchWriteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchWriteItemOutput, error)
+	TransactWriteItems(ctx context.Context, params *dynamodb.TransactWriteItemsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error)
+	DeleteItem(ctx context.Context, params *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error)
+	Query(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error)
+}
+
+// DynamoRepository is a BookRepository backed by a DynamoDB table using the
+// USER#/BOOK# key scheme.
+type DynamoRepository struct {
+	client DynamoDBAPI
+	table  string
+}
+
+// NewDynamoRepository returns a repository storing books in table.
+func NewDynamoRepository(client DynamoDBAPI, table string) *DynamoRepository {
+	return &DynamoRepository{client: client, table: table}
+}
+
+// Get returns the user's book with the given ID, or ErrNotFound.
+func (r *DynamoRepository) Get(ctx context.Context, userID, bookID string) (Book, error) {
+	return r.get(ctx, userID, bookID, false)
+}
+
+// get is Get with a choice of read consistency. Writes that record the
+// book's previous state read it consistently.
+func (r *DynamoRepository) get(ctx context.Context, userID, bookID string, consistent bool) (Book, error) {
+	result, err := r.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(r.table),
+		Key:            Key(userID, bookID),
+		ConsistentRead: aws.Bool(consistent),
+	})
+	if err != nil {
+		return Book{}, fmt.Errorf("failed to get book: %w", err)
+	}
+	if result.Item == nil {
+		return Book{}, ErrNotFound
+	}
+
+	var book Book
+	if err := attributevalue.UnmarshalMap(result.Item, &book); err != nil {
+		return Book{}, fmt.Errorf("failed to unmarshal book: %w", err)
+	}
+	return book, nil
+}
+
+// List returns all of the user's books in index order, following
+// LastEvaluatedKey until the query is exhausted.
+func (r *DynamoRepository) List(ctx context.Context, userID string, opts ListOptions) ([]Book, error) {
+	input := r.listInput(userID, opts)
+
+	var books []Book
+	for {
+		result, err := r.client.Query(ctx, input)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query books: %w", err)
+		}
+
+		var page []Book
+		if err := attributevalue.UnmarshalListOfMaps(result.Items, &page); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal books: %w", err)
+		}
+		books = append(books, filterTitle(page, opts)...)
+
+		if result.LastEvaluatedKey == nil {
+			return books, nil
+		}
+		input.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+}
+
+// ListPage returns up to opts.Limit of the user's books in index order.
+// Filters are applied after DynamoDB's Limit, so the query is repeated until
+// the page is full or the user's books are exhausted.
+func (r *DynamoRepository) ListPage(ctx context.Context, userID string, opts PageOptions) (Page, error) {
+	if opts.Limit <= 0 {
+		return Page{}, fmt.Errorf("invalid page limit %d", opts.Limit)
+	}
+
+	input := r.listInput(userID, opts.ListOptions)
+	if opts.StartToken != "" {
+		startKey, err := decodeStartKey(opts.StartToken)
+		if err != nil {
+			return Page{}, err
+		}
+		// Never let a token resume a query over another user's partition.
+		if pk, ok := startKey["PK"].(*types.AttributeValueMemberS); !ok || pk.Value != UserPK(userID) {
+			return Page{}, ErrInvalidToken
+		}
+		input.ExclusiveStartKey = startKey
+	}
+
+	var page Page
+	for {
+		input.Limit = aws.Int32(int32(opts.Limit - len(page.Books)))
+
+		result, err := r.client.Query(ctx, input)
+		if err != nil {
+			return Page{}, fmt.Errorf("failed to query books: %w", err)
+		}
+
+		var books []Book
+		if err := attributevalue.UnmarshalListOfMaps(result.Items, &books); err != nil {
+			return Page{}, fmt.Errorf("failed to unmarshal books: %w", err)
+		}
+		page.Books = append(page.Books, filterTitle(books, opts.ListOptions)...)
+
+		if result.LastEvaluatedKey == nil {
+			return page, nil
+		}
+		if len(page.Books) >= opts.Limit {
+			token, err := encodeStartKey(result.LastEvaluatedKey)
+			if err != nil {
+				return Page{}, err
+			}
+			page.NextToken = token
+			return page, nil
+		}
+		input.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+}
+
+// ListByAuthor returns the user's books by author, as NormalizeAuthor
+// matches them, by querying the author's partition of AuthorIndex.
+func (r *DynamoRepository) ListByAuthor(ctx context.Context, userID, author string) ([]Book, error) {
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(r.table),
+		IndexName:              aws.String(AuthorIndex),
+		KeyConditionExpression: aws.String("GSI2PK = :pk AND begins_with(GSI2SK, :sk)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk": &types.AttributeValueMemberS{Value: UserPK(userID)},
+			":sk": &types.AttributeValueMemberS{Value: AuthorKeyPrefix(author)},
+		},
+	}
+
+	var books []Book
+	for {
+		result, err := r.client.Query(ctx, input)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query books: %w", err)
+		}
+
+		var page []Book
+		if err := attributevalue.UnmarshalListOfMaps(result.Items, &page); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal books: %w", err)
+		}
+		books = append(books, page...)
+
+		if result.LastEvaluatedKey == nil {
+			return books, nil
+		}
+		input.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+}
+
+// filterTitle applies the case-insensitive title filter that the query's
+// FilterExpression cannot.
+func filterTitle(books []Book, opts ListOptions) []Book {
+	if opts.TitleContains == "" {
+		return books
+	}
+	matched := books[:0]
+	for _, book := range books {
+		if opts.matchesTitle(book) {
+			matched = append(matched, book)
+		}
+	}
+	return matched
+}
+
+// listInput builds the query for the user's books, filtered by opts. A
+// status filter is served by StatusIndex, narrowed to the status's date range
+// when one is given, and an author filter by AuthorIndex; anything else
+// queries the table. Remaining filters become the FilterExpression.
+func (r *DynamoRepository) listInput(userID string, opts ListOptions) *dynamodb.QueryInput {
+	str := func(v string) types.AttributeValue { return &types.AttributeValueMemberS{Value: v} }
+	num := func(v int) types.AttributeValue { return &types.AttributeValueMemberN{Value: strconv.Itoa(v)} }
+
+	input := &dynamodb.QueryInput{
+		TableName: aws.String(r.table),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk": str(UserPK(userID)),
+		},
+	}
+	values := input.ExpressionAttributeValues
+
+	switch {
+	case opts.Status != "":
+		prefix := StatusKeyPrefix(opts.Status)
+		from, to := opts.statusDateRange()
+		input.IndexName = aws.String(StatusIndex)
+		if from == "" && to == "" {
+			input.KeyConditionExpression = aws.String("GSI1PK = :pk AND begins_with(GSI1SK, :sk)")
+			values[":sk"] = str(prefix)
+		} else {
+			input.KeyConditionExpression = aws.String("GSI1PK = :pk AND GSI1SK BETWEEN :sk_from AND :sk_to")
+			values[":sk_from"] = str(prefix + from)
+			values[":sk_to"] = str(prefixEnd(prefix))
+			if to != "" {
+				// Keys on the day after "to" sort after this bare date.
+				values[":sk_to"] = str(prefix + dayAfter(to))
+			}
+		}
+	case opts.Author != "":
+		input.IndexName = aws.String(AuthorIndex)
+		input.KeyConditionExpression = aws.String("GSI2PK = :pk AND begins_with(GSI2SK, :sk)")
+		values[":sk"] = str(AuthorKeyPrefix(opts.Author))
+	default:
+		// The user's partition also holds items that are not books.
+		input.KeyConditionExpression = aws.String("PK = :pk AND begins_with(SK, :book)")
+		values[":book"] = str(bookPrefix)
+	}
+
+	var conditions []string
+	names := map[string]string{}
+
+	// equal adds "attr = value" when value is set.
+	equal := func(attr, value string) {
+		if value != "" {
+			names["#"+attr] = attr
+			values[":"+attr] = str(value)
+			conditions = append(conditions, fmt.Sprintf("#%s = :%s", attr, attr))
+		}
+	}
+	// The index key condition already selects the status; AuthorIndex
+	// matches authors as NormalizeAuthor writes them, so the exact match is
+	// still filtered.
+	equal("Author", opts.Author)
+	equal("Series", opts.Series)
+	equal("type", opts.Type)
+
+	if opts.Tag != "" {
+		names["#tags"] = "tags"
+		values[":tag"] = str(opts.Tag)
+		conditions = append(conditions, "contains(#tags, :tag)")
+	}
+	if opts.MinRating != 0 {
+		names["#rating"] = "rating"
+		values[":min_rating"] = num(opts.MinRating)
+		conditions = append(conditions, "#rating >= :min_rating")
+	}
+	if opts.MaxRating != 0 {
+		names["#rating"] = "rating"
+		values[":max_rating"] = num(opts.MaxRating)
+		conditions = append(conditions, "#rating <= :max_rating")
+	}
+
+	// dateRange bounds a date attribute; the upper bound is exclusive of the
+	// following day so timestamps on the last day still match.
+	dateRange := func(attr, from, to string) {
+		if from != "" {
+			names["#"+attr] = attr
+			values[":"+attr+"_from"] = str(from)
+			conditions = append(conditions, fmt.Sprintf("#%s >= :%s_from", attr, attr))
+		}
+		if to != "" {
+			names["#"+attr] = attr
+			values[":"+attr+"_to"] = str(dayAfter(to))
+			conditions = append(conditions, fmt.Sprintf("#%s < :%s_to", attr, attr))
+		}
+	}
+	dateRange("started_at", opts.StartedFrom, opts.StartedTo)
+	dateRange("finished_at", opts.FinishedFrom, opts.FinishedTo)
+
+	if len(conditions) > 0 {
+		input.FilterExpression = aws.String(strings.Join(conditions, " AND "))
+		input.ExpressionAttributeNames = names
+	}
+	return input
+}
+
+// prefixEnd returns the smallest string greater than every string starting
+// with prefix, which must end in '#'.
+func prefixEnd(prefix string) string {
+	return prefix[:len(prefix)-1] + "$"
+}
+
+// encodeStartKey serializes a LastEvaluatedKey into a page token. Every key
+// attribute in the table and its indexes is a string.
+func encodeStartKey(key map[string]types.AttributeValue) (string, error) {
+	var values map[string]string
+	if err := attributevalue.UnmarshalMap(key, &values); err != nil {
+		return "", fmt.Errorf("failed to encode page token: %w", err)
+	}
+	token, err := json.Marshal(values)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode page token: %w", err)
+	}
+	return string(token), nil
+}
+
+// decodeStartKey parses a page token back into an ExclusiveStartKey.
+func decodeStartKey(token string) (map[string]types.AttributeValue, error) {
+	var values map[string]string
+	if err := json.Unmarshal([]byte(token), &values); err != nil || len(values) == 0 {
+		return nil, ErrInvalidToken
+	}
+
+	key := make(map[string]types.AttributeValue, len(values))
+	for name, value := range values {
+		key[name] = &types.AttributeValueMemberS{Value: value}
+	}
+	return key, nil
+}
+
+// Put stores a new book for the user, overwriting any book with the same ID,
+// and records its creation in the book's history. A zero Version is stored
+// as 1.
+func (r *DynamoRepository) Put(ctx context.Context, userID string, book Book) error {
+	if book.Version == 0 {
+		book.Version = 1
+	}
+	book.SetKeys(userID)
+
+	item, err := attributevalue.MarshalMap(book)
+	if err != nil {
+		return fmt.Errorf("failed to marshal book: %w", err)
+	}
+	history, err := r.historyPut(newHistoryEntry(ctx, userID, HistoryCreate, Book{}, book, time.Now()))
+	if err != nil {
+		return err
+	}
+
+	_, err = r.client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: []types.TransactWriteItem{
+			{Put: &types.Put{
+				TableName: aws.String(r.table),
+				Item:      item,
+			}},
+			history,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to put book: %w", err)
+	}
+	return nil
+}
+
+// ErrUnprocessed is the reason PutBatch gives for a book DynamoDB left
+// unprocessed however often it was retried.
+var ErrUnprocessed = errors.New("write not processed by DynamoDB")
+
+const (
+	// batchWriteLimit is the most items one BatchWriteItem may write.
+	batchWriteLimit = 25
+	// maxBatchAttempts bounds the retries of unprocessed items, backing off
+	// from batchRetryDelay.
+	maxBatchAttempts = 5
+	batchRetryDelay  = 50 * time.Millisecond
+)
+
+// PutBatch stores new books for the user with BatchWriteItem, each book
+// alongside its history entry in the same batch. Items DynamoDB leaves
+// unprocessed are retried with backoff. A history entry that is still
+// unprocessed is lost; its book is reported as failed only if the book
+// itself was not written.
+func (r *DynamoRepository) PutBatch(ctx context.Context, userID string, books []Book) map[string]error {
+	failed := map[string]error{}
+	now := time.Now()
+	for start := 0; start < len(books); start += batchWriteLimit / 2 {
+		chunk := books[start:min(start+batchWriteLimit/2, len(books))]
+
+		var writes []types.WriteRequest
+		var written []string
+		for _, book := range chunk {
+			if book.Version == 0 {
+				book.Version = 1
+			}
+			book.SetKeys(userID)
+
+			item, err := attributevalue.MarshalMap(book)
+			if err != nil {
+				failed[book.ID] = fmt.Errorf("failed to marshal book: %w", err)
+				continue
+			}
+			history, err := attributevalue.MarshalMap(newHistoryEntry(ctx, userID, HistoryCreate, Book{}, book, now))
+			if err != nil {
+				failed[book.ID] = fmt.Errorf("failed to marshal history entry: %w", err)
+				continue
+			}
+			writes = append(writes,
+				types.WriteRequest{PutRequest: &types.PutRequest{Item: item}},
+				types.WriteRequest{PutRequest: &types.PutRequest{Item: history}},
+			)
+			written = append(written, book.ID)
+		}
+
+		unprocessed, err := r.batchWrite(ctx, writes)
+		if err != nil {
+			for _, id := range written {
+				failed[id] = err
+			}
+			continue
+		}
+		for _, write := range unprocessed {
+			var item struct {
+				SK string `dynamodbav:"SK"`
+			}
+			if err := attributevalue.UnmarshalMap(write.PutRequest.Item, &item); err != nil {
+				continue
+			}
+			if id, ok := BookIDFromSK(item.SK); ok {
+				failed[id] = ErrUnprocessed
+			}
+		}
+	}
+	return failed
+}
+
+// batchWrite writes up to batchWriteLimit items, retrying unprocessed ones,
+// and returns those still unprocessed after maxBatchAttempts.
+func (r *DynamoRepository) batchWrite(ctx context.Context, writes []types.WriteRequest) ([]types.WriteRequest, error) {
+	delay := batchRetryDelay
+	for attempt := 1; len(writes) > 0; attempt++ {
+		result, err := r.client.BatchWriteItem(ctx, &dynamodb.BatchWriteItemInput{
+			RequestItems: map[string][]types.WriteRequest{r.table: writes},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to batch write books: %w", err)
+		}
+		writes = result.UnprocessedItems[r.table]
+		if len(writes) == 0 || attempt == maxBatchAttempts {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+	return writes, nil
+}
+
+// PutIdempotent stores a new book like Put, together with record, in one
+// transaction conditioned on the user having no unexpired record for
+// record.Key. If they do, nothing is stored and that record is returned
+// instead.
+func (r *DynamoRepository) PutIdempotent(ctx context.Context, userID string, book Book, record IdempotencyRecord) (*IdempotencyRecord, error) {
+	if book.Version == 0 {
+		book.Version = 1
+	}
+	book.SetKeys(userID)
+
+	bookItem, err := attributevalue.MarshalMap(book)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal book: %w", err)
+	}
+	recordItem, err := attributevalue.MarshalMap(newIdempotencyItem(userID, record))
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal idempotency record: %w", err)
+	}
+	history, err := r.historyPut(newHistoryEntry(ctx, userID, HistoryCreate, Book{}, book, time.Now()))
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = r.client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: []types.TransactWriteItem{
+			{Put: &types.Put{
+				TableName: aws.String(r.table),
+				Item:      bookItem,
+			}},
+			{Put: &types.Put{
+				TableName: aws.String(r.table),
+				Item:      recordItem,
+				// Expired records linger until DynamoDB's TTL sweep deletes them.
+				ConditionExpression: aws.String("attribute_not_exists(PK) OR expires_at < :now"),
+				ExpressionAttributeValues: map[string]types.AttributeValue{
+					":now": &types.AttributeValueMemberN{Value: strconv.FormatInt(time.Now().Unix(), 10)},
+				},
+				ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
+			}},
+			history,
+		},
+	})
+	var canceled *types.TransactionCanceledException
+	if !errors.As(err, &canceled) {
+		if err != nil {
+			return nil, fmt.Errorf("failed to put book: %w", err)
+		}
+		return nil, nil
+	}
+
+	// The reasons are in the same order as TransactItems.
+	if len(canceled.CancellationReasons) > 1 {
+		reason := canceled.CancellationReasons[1]
+		if aws.ToString(reason.Code) == "ConditionalCheckFailed" && reason.Item != nil {
+			var existing idempotencyItem
+			if err := attributevalue.UnmarshalMap(reason.Item, &existing); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal idempotency record: %w", err)
+			}
+			found := existing.record(record.Key)
+			return &found, nil
+		}
+	}
+
+	// A concurrent request with the same key may have won the race.
+	existing, getErr := r.GetIdempotencyRecord(ctx, userID, record.Key)
+	if getErr != nil || existing == nil {
+		return nil, fmt.Errorf("failed to put book: %w", err)
+	}
+	return existing, nil
+}
+
+// GetIdempotencyRecord returns the user's unexpired record for key, or nil
+// if there is none.
+func (r *DynamoRepository) GetIdempotencyRecord(ctx context.Context, userID, key string) (*IdempotencyRecord, error) {
+	result, err := r.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(r.table),
+		Key: map[string]types.AttributeValue{
+			"PK": &types.AttributeValueMemberS{Value: UserPK(userID)},
+			"SK": &types.AttributeValueMemberS{Value: IdempotencySK(key)},
+		},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get idempotency record: %w", err)
+	}
+	if result.Item == nil {
+		return nil, nil
+	}
+
+	var item idempotencyItem
+	if err := attributevalue.UnmarshalMap(result.Item, &item); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal idempotency record: %w", err)
+	}
+	record := item.record(key)
+	if !time.Now().Before(record.ExpiresAt) {
+		return nil, nil
+	}
+	return &record, nil
+}
+
+// Update replaces an existing book if it is still at book.Version and returns
+// the stored book with its version incremented. The stored book is read
+// first so the history entry written with it records what changed. It
+// returns ErrNotFound if the book does not exist, or a ConflictError if it
+// has changed.
+func (r *DynamoRepository) Update(ctx context.Context, userID string, book Book) (Book, error) {
+	current, err := r.get(ctx, userID, book.ID, true)
+	if err != nil {
+		return Book{}, err
+	}
+	if current.Version != book.Version {
+		return Book{}, &ConflictError{Current: current}
+	}
+
+	condition, values := versionCondition(book.Version)
+	book.Version++
+	book.SetKeys(userID)
+
+	item, err := attributevalue.MarshalMap(book)
+	if err != nil {
+		return Book{}, fmt.Errorf("failed to marshal book: %w", err)
+	}
+	history, err := r.historyPut(newHistoryEntry(ctx, userID, HistoryUpdate, current, book, time.Now()))
+	if err != nil {
+		return Book{}, err
+	}
+
+	_, err = r.client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: []types.TransactWriteItem{
+			{Put: &types.Put{
+				TableName:                           aws.String(r.table),
+				Item:                                item,
+				ConditionExpression:                 aws.String(condition),
+				ExpressionAttributeValues:           values,
+				ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
+			}},
+			history,
+		},
+	})
+	if err != nil {
+		return Book{}, transactionError(err, "failed to update book")
+	}
+	return book, nil
+}
+
+// Patch applies patch to the user's book with an UpdateExpression, so fields
+// the patch does not mention are never overwritten, and returns the stored
+// book with its version incremented. The book is read first to check the
+// patch's tests, record the change in its history and set its index keys;
+// the update is conditioned on the version read. Without a version, a book
+// that changes in between is read again. It returns ErrNotFound, a
+// ConflictError if the book is not at version, or a TestFailedError if a
+// test does not hold.
+func (r *DynamoRepository) Patch(ctx context.Context, userID, bookID string, patch Patch, version *int) (Book, error) {
+	for attempt := 1; ; attempt++ {
+		book, err := r.patch(ctx, userID, bookID, patch, version)
+		var conflict *ConflictError
+		if version == nil && errors.As(err, &conflict) && attempt < maxPatchAttempts {
+			continue
+		}
+		return book, err
+	}
+}
+
+// maxPatchAttempts bounds the reads of a book patched without a version that
+// keeps changing under the patch.
+const maxPatchAttempts = 3
+
+func (r *DynamoRepository) patch(ctx context.Context, userID, bookID string, patch Patch, version *int) (Book, error) {
+	current, err := r.get(ctx, userID, bookID, true)
+	if err != nil {
+		return Book{}, err
+	}
+	if version != nil && current.Version != *version {
+		return Book{}, &ConflictError{Current: current}
+	}
+	if !patch.TestsPass(current) {
+		return Book{}, &TestFailedError{Current: current}
+	}
+
+	book := patch.Apply(current)
+	book.Version = current.Version + 1
+	book.SetKeys(userID)
+
+	expr := newExpression()
+	update, err := patch.updateExpression(expr, book)
+	if err != nil {
+		return Book{}, err
+	}
+	condition, err := patch.conditionExpression(expr, &current.Version)
+	if err != nil {
+		return Book{}, err
+	}
+	history, err := r.historyPut(newHistoryEntry(ctx, userID, HistoryUpdate, current, book, time.Now()))
+	if err != nil {
+		return Book{}, err
+	}
+
+	_, err = r.client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: []types.TransactWriteItem{
+			{Update: &types.Update{
+				TableName:                           aws.String(r.table),
+				Key:                                 Key(userID, bookID),
+				UpdateExpression:                    aws.String(update),
+				ConditionExpression:                 aws.String(condition),
+				ExpressionAttributeNames:            expr.names,
+				ExpressionAttributeValues:           expr.values,
+				ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
+			}},
+			history,
+		},
+	})
+	if err != nil {
+		return Book{}, patchConditionError(err, current.Version)
+	}
+	return book, nil
+}
+
+// patchConditionError is transactionError for Patch: a book still at the
+// version read that failed the condition failed one of the patch's tests.
+func patchConditionError(err error, version int) error {
+	err = transactionError(err, "failed to patch book")
+	var conflict *ConflictError
+	if errors.As(err, &conflict) && conflict.Current.Version == version {
+		return &TestFailedError{Current: conflict.Current}
+	}
+	return err
+}
+
+// Merge stores merged and deletes source in one transaction, each
+// conditioned on the book still being at the version that was read, and
+// records both in their books' histories.
+func (r *DynamoRepository) Merge(ctx context.Context, userID string, merged, source Book) (Book, error) {
+	current, err := r.get(ctx, userID, merged.ID, true)
+	if err != nil {
+		return Book{}, err
+	}
+	if current.Version != merged.Version {
+		return Book{}, &ConflictError{Current: current}
+	}
+
+	mergedCondition, mergedValues := versionCondition(merged.Version)
+	sourceCondition, sourceValues := versionCondition(source.Version)
+	merged.Version++
+	merged.SetKeys(userID)
+
+	item, err := attributevalue.MarshalMap(merged)
+	if err != nil {
+		return Book{}, fmt.Errorf("failed to marshal book: %w", err)
+	}
+	now := time.Now()
+	mergedEntry := newHistoryEntry(ctx, userID, HistoryMerge, current, merged, now)
+	mergedEntry.MergedFrom = source.ID
+	mergedHistory, err := r.historyPut(mergedEntry)
+	if err != nil {
+		return Book{}, err
+	}
+	sourceEntry := newHistoryEntry(ctx, userID, HistoryDelete, source, source, now)
+	sourceEntry.MergedInto = merged.ID
+	sourceHistory, err := r.historyPut(sourceEntry)
+	if err != nil {
+		return Book{}, err
+	}
+
+	_, err = r.client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: []types.TransactWriteItem{
+			{Put: &types.Put{
+				TableName:                           aws.String(r.table),
+				Item:                                item,
+				ConditionExpression:                 aws.String(mergedCondition),
+				ExpressionAttributeValues:           mergedValues,
+				ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
+			}},
+			{Delete: &types.Delete{
+				TableName:                           aws.String(r.table),
+				Key:                                 Key(userID, source.ID),
+				ConditionExpression:                 aws.String(sourceCondition),
+				ExpressionAttributeValues:           sourceValues,
+				ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
+			}},
+			mergedHistory,
+			sourceHistory,
+		},
+	})
+	if err != nil {
+		return Book{}, transactionError(err, "failed to merge books")
+	}
+	return merged, nil
+}
+
+// Trash deletes the book and puts a copy under its TRASH# key in one
+// transaction, conditioned on the book still being at book.Version, and
+// records the deletion in its history.
+func (r *DynamoRepository) Trash(ctx context.Context, userID string, book Book) (TrashedBook, error) {
+	condition, values := versionCondition(book.Version)
+	now := time.Now()
+	trashed := NewTrashedBook(userID, book, now)
+
+	item, err := attributevalue.MarshalMap(trashed)
+	if err != nil {
+		return TrashedBook{}, fmt.Errorf("failed to marshal trashed book: %w", err)
+	}
+	history, err := r.historyPut(newHistoryEntry(ctx, userID, HistoryDelete, book, book, now))
+	if err != nil {
+		return TrashedBook{}, err
+	}
+
+	_, err = r.client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: []types.TransactWriteItem{
+			{Delete: &types.Delete{
+				TableName:                           aws.String(r.table),
+				Key:                                 Key(userID, book.ID),
+				ConditionExpression:                 aws.String(condition),
+				ExpressionAttributeValues:           values,
+				ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
+			}},
+			{Put: &types.Put{
+				TableName: aws.String(r.table),
+				Item:      item,
+			}},
+			history,
+		},
+	})
+	if err != nil {
+		return TrashedBook{}, transactionError(err, "failed to trash book")
+	}
+	return trashed, nil
+}
+
+// ListTrash returns the books in the user's trash, most recently deleted
+// first. Books past their expiry that DynamoDB has not yet removed are
+// skipped.
+func (r *DynamoRepository) ListTrash(ctx context.Context, userID string) ([]TrashedBook, error) {
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(r.table),
+		KeyConditionExpression: aws.String("PK = :pk AND begins_with(SK, :trash)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk":    &types.AttributeValueMemberS{Value: UserPK(userID)},
+			":trash": &types.AttributeValueMemberS{Value: trashPrefix},
+		},
+	}
+
+	now := time.Now()
+	var books []TrashedBook
+	for {
+		result, err := r.client.Query(ctx, input)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query trash: %w", err)
+		}
+
+		var page []TrashedBook
+		if err := attributevalue.UnmarshalListOfMaps(result.Items, &page); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal trash: %w", err)
+		}
+		for _, book := range page {
+			if !book.Expired(now) {
+				books = append(books, book)
+			}
+		}
+
+		if result.LastEvaluatedKey == nil {
+			break
+		}
+		input.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+
+	sortTrash(books)
+	return books, nil
+}
+
+// Restore deletes the book's TRASH# copy and puts it back under its BOOK#
+// key in one transaction, conditioned on no book with that ID existing, and
+// records the restore in its history.
+func (r *DynamoRepository) Restore(ctx context.Context, userID, bookID string) (Book, error) {
+	result, err := r.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(r.table),
+		Key:            TrashKey(userID, bookID),
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return Book{}, fmt.Errorf("failed to get trashed book: %w", err)
+	}
+	if result.Item == nil {
+		return Book{}, ErrNotFound
+	}
+
+	var trashed TrashedBook
+	if err := attributevalue.UnmarshalMap(result.Item, &trashed); err != nil {
+		return Book{}, fmt.Errorf("failed to unmarshal trashed book: %w", err)
+	}
+	if trashed.Expired(time.Now()) {
+		return Book{}, ErrNotFound
+	}
+
+	book := trashed.Restored(userID)
+	item, err := attributevalue.MarshalMap(book)
+	if err != nil {
+		return Book{}, fmt.Errorf("failed to marshal book: %w", err)
+	}
+	history, err := r.historyPut(newHistoryEntry(ctx, userID, HistoryRestore, trashed.Book, book, time.Now()))
+	if err != nil {
+		return Book{}, err
+	}
+
+	_, err = r.client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: []types.TransactWriteItem{
+			{Delete: &types.Delete{
+				TableName:           aws.String(r.table),
+				Key:                 TrashKey(userID, bookID),
+				ConditionExpression: aws.String("attribute_exists(PK)"),
+			}},
+			{Put: &types.Put{
+				TableName:                           aws.String(r.table),
+				Item:                                item,
+				ConditionExpression:                 aws.String("attribute_not_exists(PK)"),
+				ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
+			}},
+			history,
+		},
+	})
+	if err != nil {
+		return Book{}, transactionError(err, "failed to restore book")
+	}
+	return book, nil
+}
+
+// Purge permanently deletes a book's TRASH# copy.
+func (r *DynamoRepository) Purge(ctx context.Context, userID, bookID string) error {
+	_, err := r.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName:           aws.String(r.table),
+		Key:                 TrashKey(userID, bookID),
+		ConditionExpression: aws.String("attribute_exists(PK)"),
+	})
+	if err != nil {
+		return conditionError(err, "failed to purge book")
+	}
+	return nil
+}
+
+// History returns every recorded write to the user's book, newest first.
+func (r *DynamoRepository) History(ctx context.Context, userID, bookID string) ([]HistoryEntry, error) {
+	return r.queryHistory(ctx, userID, HistoryKeyPrefix(bookID))
+}
+
+// AllHistory returns the history of every book the user has written to,
+// with one query over their history entries. Entries sort by book ID, then
+// time, so each book's come together, newest first.
+func (r *DynamoRepository) AllHistory(ctx context.Context, userID string) ([]HistoryEntry, error) {
+	return r.queryHistory(ctx, userID, historyPrefix)
+}
+
+// queryHistory returns the user's history entries whose sort keys start
+// with prefix, in descending sort key order.
+func (r *DynamoRepository) queryHistory(ctx context.Context, userID, prefix string) ([]HistoryEntry, error) {
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(r.table),
+		KeyConditionExpression: aws.String("PK = :pk AND begins_with(SK, :history)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk":      &types.AttributeValueMemberS{Value: UserPK(userID)},
+			":history": &types.AttributeValueMemberS{Value: prefix},
+		},
+		ScanIndexForward: aws.Bool(false),
+	}
+
+	var entries []HistoryEntry
+	for {
+		result, err := r.client.Query(ctx, input)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query history: %w", err)
+		}
+
+		var page []HistoryEntry
+		if err := attributevalue.UnmarshalListOfMaps(result.Items, &page); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal history: %w", err)
+		}
+		for _, entry := range page {
+			for i, change := range entry.Changes {
+				entry.Changes[i] = change.normalize()
+			}
+			entries = append(entries, entry)
+		}
+
+		if result.LastEvaluatedKey == nil {
+			return entries, nil
+		}
+		input.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+}
+
+// historyPut returns the transaction item that stores entry. The condition
+// keeps history immutable: an entry is never overwritten.
+func (r *DynamoRepository) historyPut(entry HistoryEntry) (types.TransactWriteItem, error) {
+	item, err := attributevalue.MarshalMap(entry)
+	if err != nil {
+		return types.TransactWriteItem{}, fmt.Errorf("failed to marshal history entry: %w", err)
+	}
+	return types.TransactWriteItem{Put: &types.Put{
+		TableName:           aws.String(r.table),
+		Item:                item,
+		ConditionExpression: aws.String("attribute_not_exists(PK)"),
+	}}, nil
+}
+
+// versionCondition returns a condition that the book exists at version.
+// Books written before versioning have no version attribute and count as
+// version 0.
+func versionCondition(version int) (string, map[string]types.AttributeValue) {
+	if version == 0 {
+		return "attribute_exists(PK) AND attribute_not_exists(version)", nil
+	}
+	return "attribute_exists(PK) AND version = :version", map[string]types.AttributeValue{
+		":version": &types.AttributeValueMemberN{Value: strconv.Itoa(version)},
+	}
+}
+
+// conditionError maps a failed condition to ErrNotFound when the book is
+// missing, or to a ConflictError carrying the stored book when it is at
+// another version, and wraps any other error with msg.
+func conditionError(err error, msg string) error {
+	var ccf *types.ConditionalCheckFailedException
+	if !errors.As(err, &ccf) {
+		return fmt.Errorf("%s: %w", msg, err)
+	}
+	if ccf.Item == nil {
+		return ErrNotFound
+	}
+
+	var current Book
+	if err := attributevalue.UnmarshalMap(ccf.Item, &current); err != nil {
+		return fmt.Errorf("failed to unmarshal book: %w", err)
+	}
+	return &ConflictError{Current: current}
+}
+
+// transactionError is conditionError for a transaction of book writes: the
+// first item whose condition failed becomes ErrNotFound or a ConflictError.
+func transactionError(err error, msg string) error {
+	var canceled *types.TransactionCanceledException
+	if !errors.As(err, &canceled) {
+		return fmt.Errorf("%s: %w", msg, err)
+	}
+	for _, reason := range canceled.CancellationReasons {
+		if aws.ToString(reason.Code) != "ConditionalCheckFailed" {
+			continue
+		}
+		if reason.Item == nil {
+			return ErrNotFound
+		}
+		var current Book
+		if err := attributevalue.UnmarshalMap(reason.Item, &current); err != nil {
+			return fmt.Errorf("failed to unmarshal book: %w", err)
+		}
+		return &ConflictError{Current: current}
+	}
+	return fmt.Errorf("%s: %w", msg, err)
+}
+
+// expression collects the attribute name and value placeholders of an
+// update and its condition.
+type expression struct {
+	names  map[string]string
+	values map[string]types.AttributeValue
+}
+
+func newExpression() *expression {
+	return &expression{names: map[string]string{}, values: map[string]types.AttributeValue{}}
+}
+
+// name returns the placeholder for a top-level attribute.
+func (e *expression) name(attr string) string {
+	placeholder := "#" + attr
+	e.names[placeholder] = attr
+	return placeholder
+}
+
+// value returns a new placeholder for v.
+func (e *expression) value(v interface{}) (string, error) {
+	av, err := attributevalue.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal patch value: %w", err)
+	}
+	placeholder := fmt.Sprintf(":v%d", len(e.values))
+	e.values[placeholder] = av
+	return placeholder, nil
+}
+
+// updateExpression renders the patch as SET and REMOVE clauses, always
+// incrementing the version and setting the index keys of patched, the book
+// as it will be stored.
+func (p Patch) updateExpression(e *expression, patched Book) (string, error) {
+	var set, remove []string
+	tags := e.name(patchFields["tags"].attr)
+
+	for _, name := range sortedKeys(p.set) {
+		v, err := e.value(p.set[name])
+		if err != nil {
+			return "", err
+		}
+		set = append(set, fmt.Sprintf("%s = %s", e.name(patchFields[name].attr), v))
+	}
+	for _, i := range sortedKeys(p.setTags) {
+		v, err := e.value(p.setTags[i])
+		if err != nil {
+			return "", err
+		}
+		set = append(set, fmt.Sprintf("%s[%d] = %s", tags, i, v))
+	}
+	if len(p.appendTags) > 0 {
+		empty, err := e.value([]string{})
+		if err != nil {
+			return "", err
+		}
+		appended, err := e.value(p.appendTags)
+		if err != nil {
+			return "", err
+		}
+		set = append(set, fmt.Sprintf("%s = list_append(if_not_exists(%s, %s), %s)", tags, tags, empty, appended))
+	}
+
+	zero, err := e.value(0)
+	if err != nil {
+		return "", err
+	}
+	one, err := e.value(1)
+	if err != nil {
+		return "", err
+	}
+	version := e.name("version")
+	set = append(set, fmt.Sprintf("%s = if_not_exists(%s, %s) + %s", version, version, zero, one))
+
+	for _, key := range []struct{ attr, value string }{
+		{"GSI1PK", patched.GSI1PK},
+		{"GSI1SK", patched.GSI1SK},
+		{"GSI2PK", patched.GSI2PK},
+		{"GSI2SK", patched.GSI2SK},
+	} {
+		v, err := e.value(key.value)
+		if err != nil {
+			return "", err
+		}
+		set = append(set, fmt.Sprintf("%s = %s", e.name(key.attr), v))
+	}
+
+	for _, name := range sortedKeys(p.remove) {
+		remove = append(remove, e.name(patchFields[name].attr))
+	}
+	for _, i := range sortedKeys(p.removeTags) {
+		remove = append(remove, fmt.Sprintf("%s[%d]", tags, i))
+	}
+
+	update := "SET " + strings.Join(set, ", ")
+	if len(remove) > 0 {
+		update += " REMOVE " + strings.Join(remove, ", ")
+	}
+	return update, nil
+}
+
+// conditionExpression requires the book to exist, to be at version when it
+// is not nil, and to pass every test in the patch.
+func (p Patch) conditionExpression(e *expression, version *int) (string, error) {
+	conditions := []string{"attribute_exists(PK)"}
+	if version != nil {
+		if *version == 0 {
+			conditions = append(conditions, fmt.Sprintf("attribute_not_exists(%s)", e.name("version")))
+		} else {
+			v, err := e.value(*version)
+			if err != nil {
+				return "", err
+			}
+			conditions = append(conditions, fmt.Sprintf("%s = %s", e.name("version"), v))
+		}
+	}
+
+	for _, t := range p.tests {
+		path := e.name(patchFields[t.field].attr)
+		if t.index >= 0 {
+			path = fmt.Sprintf("%s[%d]", path, t.index)
+		}
+		switch {
+		case t.exists:
+			conditions = append(conditions, fmt.Sprintf("attribute_exists(%s)", path))
+		case t.value == nil && t.field == "tags":
+			zero, err := e.value(0)
+			if err != nil {
+				return "", err
+			}
+			conditions = append(conditions, fmt.Sprintf("(attribute_not_exists(%s) OR size(%s) = %s)", path, path, zero))
+		case t.value == nil:
+			conditions = append(conditions, fmt.Sprintf("attribute_not_exists(%s)", path))
+		default:
+			v, err := e.value(t.value)
+			if err != nil {
+				return "", err
+			}
+			conditions = append(conditions, fmt.Sprintf("%s = %s", path, v))
+		}
+	}
+	return strings.Join(conditions, " AND "), nil
+}
+
+func sortedKeys[K cmp.Ordered, V any](m map[K]V) []K {
+	keys := make([]K, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
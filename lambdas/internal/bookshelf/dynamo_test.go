@@ -0,0 +1,421 @@
+package bookshelf
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// fakeDynamo records the queries and transactions it is handed. GetItem
+// returns stored, or no item if it is nil; Query returns no items; and
+// TransactWriteItems fails with transactErr.
+type fakeDynamo struct {
+	stored       *Book
+	transactErr  error
+	queries      []*dynamodb.QueryInput
+	transactions []*dynamodb.TransactWriteItemsInput
+}
+
+func (f *fakeDynamo) GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	if f.stored == nil {
+		return &dynamodb.GetItemOutput{}, nil
+	}
+	item, err := attributevalue.MarshalMap(f.stored)
+	return &dynamodb.GetItemOutput{Item: item}, err
+}
+
+func (f *fakeDynamo) Query(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+	f.queries = append(f.queries, params)
+	return &dynamodb.QueryOutput{}, nil
+}
+
+func (f *fakeDynamo) TransactWriteItems(ctx context.Context, params *dynamodb.TransactWriteItemsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+	f.transactions = append(f.transactions, params)
+	return &dynamodb.TransactWriteItemsOutput{}, f.transactErr
+}
+
+func (f *fakeDynamo) BatchWriteItem(ctx context.Context, params *dynamodb.BatchWriteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchWriteItemOutput, error) {
+	return nil, errors.New("BatchWriteItem not implemented")
+}
+
+func (f *fakeDynamo) DeleteItem(ctx context.Context, params *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+	return nil, errors.New("DeleteItem not implemented")
+}
+
+func TestDynamoListInput(t *testing.T) {
+	s := func(v string) types.AttributeValue { return &types.AttributeValueMemberS{Value: v} }
+	n := func(v string) types.AttributeValue { return &types.AttributeValueMemberN{Value: v} }
+	pk := s("USER#user-1")
+
+	tests := []struct {
+		name       string
+		opts       ListOptions
+		wantIndex  string
+		wantKey    string
+		wantFilter string
+		wantNames  map[string]string
+		wantValues map[string]types.AttributeValue
+	}{
+		{
+			name:       "every book",
+			wantKey:    "PK = :pk AND begins_with(SK, :book)",
+			wantValues: map[string]types.AttributeValue{":pk": pk, ":book": s("BOOK#")},
+		},
+		{
+			name:       "status",
+			opts:       ListOptions{Status: StatusRead},
+			wantIndex:  StatusIndex,
+			wantKey:    "GSI1PK = :pk AND begins_with(GSI1SK, :sk)",
+			wantValues: map[string]types.AttributeValue{":pk": pk, ":sk": s("STATUS#READ#")},
+		},
+		{
+			name:      "read from a date",
+			opts:      ListOptions{Status: StatusRead, FinishedFrom: "2024-01-01"},
+			wantIndex: StatusIndex,
+			// prefixEnd bounds every key under the status prefix
+			wantKey:    "GSI1PK = :pk AND GSI1SK BETWEEN :sk_from AND :sk_to",
+			wantFilter: "#finished_at >= :finished_at_from",
+			wantNames:  map[string]string{"#finished_at": "finished_at"},
+			wantValues: map[string]types.AttributeValue{
+				":pk": pk, ":sk_from": s("STATUS#READ#2024-01-01"), ":sk_to": s("STATUS#READ$"),
+				":finished_at_from": s("2024-01-01"),
+			},
+		},
+		{
+			name:       "read up to a date",
+			opts:       ListOptions{Status: StatusRead, FinishedTo: "2024-12-31"},
+			wantIndex:  StatusIndex,
+			wantKey:    "GSI1PK = :pk AND GSI1SK BETWEEN :sk_from AND :sk_to",
+			wantFilter: "#finished_at < :finished_at_to",
+			wantNames:  map[string]string{"#finished_at": "finished_at"},
+			wantValues: map[string]types.AttributeValue{
+				":pk": pk, ":sk_from": s("STATUS#READ#"), ":sk_to": s("STATUS#READ#2025-01-01"),
+				":finished_at_to": s("2025-01-01"),
+			},
+		},
+		{
+			name:       "reading in a date range",
+			opts:       ListOptions{Status: StatusReading, StartedFrom: "2025-02-01", StartedTo: "2025-02-28", FinishedFrom: "2025-03-01"},
+			wantIndex:  StatusIndex,
+			wantKey:    "GSI1PK = :pk AND GSI1SK BETWEEN :sk_from AND :sk_to",
+			wantFilter: "#started_at >= :started_at_from AND #started_at < :started_at_to AND #finished_at >= :finished_at_from",
+			wantNames:  map[string]string{"#started_at": "started_at", "#finished_at": "finished_at"},
+			wantValues: map[string]types.AttributeValue{
+				":pk": pk, ":sk_from": s("STATUS#READING#2025-02-01"), ":sk_to": s("STATUS#READING#2025-03-01"),
+				":started_at_from": s("2025-02-01"), ":started_at_to": s("2025-03-01"), ":finished_at_from": s("2025-03-01"),
+			},
+		},
+		{
+			// Want to read books are ordered by created_at, which has no filter
+			name:       "want to read with finished dates",
+			opts:       ListOptions{Status: StatusWantToRead, FinishedFrom: "2024-01-01"},
+			wantIndex:  StatusIndex,
+			wantKey:    "GSI1PK = :pk AND begins_with(GSI1SK, :sk)",
+			wantFilter: "#finished_at >= :finished_at_from",
+			wantNames:  map[string]string{"#finished_at": "finished_at"},
+			wantValues: map[string]types.AttributeValue{
+				":pk": pk, ":sk": s("STATUS#WANT_TO_READ#"), ":finished_at_from": s("2024-01-01"),
+			},
+		},
+		{
+			name:       "author",
+			opts:       ListOptions{Author: " Frank Herbert"},
+			wantIndex:  AuthorIndex,
+			wantKey:    "GSI2PK = :pk AND begins_with(GSI2SK, :sk)",
+			wantFilter: "#Author = :Author",
+			wantNames:  map[string]string{"#Author": "Author"},
+			wantValues: map[string]types.AttributeValue{
+				":pk": pk, ":sk": s("AUTHOR#frank herbert#"), ":Author": s(" Frank Herbert"),
+			},
+		},
+		{
+			name:       "status and author",
+			opts:       ListOptions{Status: StatusRead, Author: "Frank Herbert"},
+			wantIndex:  StatusIndex,
+			wantKey:    "GSI1PK = :pk AND begins_with(GSI1SK, :sk)",
+			wantFilter: "#Author = :Author",
+			wantNames:  map[string]string{"#Author": "Author"},
+			wantValues: map[string]types.AttributeValue{":pk": pk, ":sk": s("STATUS#READ#"), ":Author": s("Frank Herbert")},
+		},
+		{
+			name:       "filters",
+			opts:       ListOptions{Series: "Dune", Type: "kindle", Tag: "sci-fi", MinRating: 6, MaxRating: 9, TitleContains: "dune"},
+			wantKey:    "PK = :pk AND begins_with(SK, :book)",
+			wantFilter: "#Series = :Series AND #type = :type AND contains(#tags, :tag) AND #rating >= :min_rating AND #rating <= :max_rating",
+			wantNames:  map[string]string{"#Series": "Series", "#type": "type", "#tags": "tags", "#rating": "rating"},
+			wantValues: map[string]types.AttributeValue{
+				":pk": pk, ":book": s("BOOK#"), ":Series": s("Dune"), ":type": s("kindle"), ":tag": s("sci-fi"),
+				":min_rating": n("6"), ":max_rating": n("9"),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &fakeDynamo{}
+			repo := NewDynamoRepository(client, "books")
+			if _, err := repo.ListPage(context.Background(), "user-1", PageOptions{ListOptions: tt.opts, Limit: 25}); err != nil {
+				t.Fatal(err)
+			}
+			if len(client.queries) != 1 {
+				t.Fatalf("ran %d queries, want 1", len(client.queries))
+			}
+			input := client.queries[0]
+
+			if got := aws.ToString(input.TableName); got != "books" {
+				t.Errorf("TableName = %q, want books", got)
+			}
+			if got := aws.ToString(input.IndexName); got != tt.wantIndex {
+				t.Errorf("IndexName = %q, want %q", got, tt.wantIndex)
+			}
+			if got := aws.ToString(input.KeyConditionExpression); got != tt.wantKey {
+				t.Errorf("KeyConditionExpression = %q, want %q", got, tt.wantKey)
+			}
+			if got := aws.ToString(input.FilterExpression); got != tt.wantFilter {
+				t.Errorf("FilterExpression = %q, want %q", got, tt.wantFilter)
+			}
+			if !reflect.DeepEqual(input.ExpressionAttributeNames, tt.wantNames) {
+				t.Errorf("ExpressionAttributeNames = %v, want %v", input.ExpressionAttributeNames, tt.wantNames)
+			}
+			if !reflect.DeepEqual(input.ExpressionAttributeValues, tt.wantValues) {
+				t.Errorf("ExpressionAttributeValues = %#v, want %#v", input.ExpressionAttributeValues, tt.wantValues)
+			}
+			if got := aws.ToInt32(input.Limit); got != 25 {
+				t.Errorf("Limit = %d, want 25", got)
+			}
+		})
+	}
+}
+
+func TestDynamoListByAuthor(t *testing.T) {
+	client := &fakeDynamo{}
+	repo := NewDynamoRepository(client, "books")
+	if _, err := repo.ListByAuthor(context.Background(), "user-1", " Frank HERBERT"); err != nil {
+		t.Fatal(err)
+	}
+	if len(client.queries) != 1 {
+		t.Fatalf("ran %d queries, want 1", len(client.queries))
+	}
+	input := client.queries[0]
+
+	// The author's whole index partition, with no exact-match filter
+	if got := aws.ToString(input.IndexName); got != AuthorIndex {
+		t.Errorf("IndexName = %q, want %q", got, AuthorIndex)
+	}
+	if got, want := aws.ToString(input.KeyConditionExpression), "GSI2PK = :pk AND begins_with(GSI2SK, :sk)"; got != want {
+		t.Errorf("KeyConditionExpression = %q, want %q", got, want)
+	}
+	if input.FilterExpression != nil {
+		t.Errorf("FilterExpression = %q, want none", aws.ToString(input.FilterExpression))
+	}
+	want := map[string]types.AttributeValue{
+		":pk": &types.AttributeValueMemberS{Value: "USER#user-1"},
+		":sk": &types.AttributeValueMemberS{Value: "AUTHOR#frank herbert#"},
+	}
+	if !reflect.DeepEqual(input.ExpressionAttributeValues, want) {
+		t.Errorf("ExpressionAttributeValues = %#v, want %#v", input.ExpressionAttributeValues, want)
+	}
+}
+
+// canceled returns a canceled transaction with a cancellation reason for
+// each of its items: "None", or a failed condition with the book as stored,
+// if any.
+func canceled(t *testing.T, reasons ...any) error {
+	t.Helper()
+	err := &types.TransactionCanceledException{Message: aws.String("Transaction cancelled")}
+	for _, reason := range reasons {
+		switch r := reason.(type) {
+		case string:
+			err.CancellationReasons = append(err.CancellationReasons, types.CancellationReason{Code: aws.String(r)})
+		case *Book:
+			cancellation := types.CancellationReason{Code: aws.String("ConditionalCheckFailed")}
+			if r != nil {
+				item, marshalErr := attributevalue.MarshalMap(r)
+				if marshalErr != nil {
+					t.Fatal(marshalErr)
+				}
+				cancellation.Item = item
+			}
+			err.CancellationReasons = append(err.CancellationReasons, cancellation)
+		}
+	}
+	return err
+}
+
+func TestDynamoUpdate(t *testing.T) {
+	stored := func(version int) *Book {
+		book := NewBook("user-1", "book-1", APIBook{Title: "Dune", Author: "Frank Herbert", Status: StatusRead})
+		book.Version = version
+		book.SetKeys("user-1")
+		return &book
+	}
+	noBook := (*Book)(nil)
+
+	tests := []struct {
+		name          string
+		stored        *Book
+		version       int
+		transactErr   error
+		wantCondition string
+		wantValues    map[string]types.AttributeValue
+		wantErr       error // ErrNotFound, or ErrVersionConflict with the conflicting version
+		wantConflict  int
+	}{
+		{
+			name:          "updated",
+			stored:        stored(2),
+			version:       2,
+			wantCondition: "attribute_exists(PK) AND version = :version",
+			wantValues:    map[string]types.AttributeValue{":version": &types.AttributeValueMemberN{Value: "2"}},
+		},
+		{
+			name:          "legacy book without a version",
+			stored:        stored(0),
+			wantCondition: "attribute_exists(PK) AND attribute_not_exists(version)",
+		},
+		{
+			name:         "stale version read",
+			stored:       stored(3),
+			version:      2,
+			wantErr:      ErrVersionConflict,
+			wantConflict: 3,
+		},
+		{
+			name:    "missing book",
+			version: 2,
+			wantErr: ErrNotFound,
+		},
+		{
+			name:         "changed after the read",
+			stored:       stored(2),
+			version:      2,
+			transactErr:  canceled(t, stored(4), "None"),
+			wantErr:      ErrVersionConflict,
+			wantConflict: 4,
+		},
+		{
+			name:        "deleted after the read",
+			stored:      stored(2),
+			version:     2,
+			transactErr: canceled(t, noBook, "None"),
+			wantErr:     ErrNotFound,
+		},
+		{
+			// Only a failed condition names the book that changed
+			name:         "later item failed",
+			stored:       stored(2),
+			version:      2,
+			transactErr:  canceled(t, "None", stored(5)),
+			wantErr:      ErrVersionConflict,
+			wantConflict: 5,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &fakeDynamo{stored: tt.stored, transactErr: tt.transactErr}
+			repo := NewDynamoRepository(client, "books")
+			book := NewBook("user-1", "book-1", APIBook{Title: "Dune", Author: "Frank Herbert", Status: StatusRead, Rating: intPtr(9)})
+			book.Version = tt.version
+
+			updated, err := repo.Update(context.Background(), "user-1", book)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Update = %v, want %v", err, tt.wantErr)
+			}
+			var conflict *ConflictError
+			if errors.As(err, &conflict) && conflict.Current.Version != tt.wantConflict {
+				t.Errorf("conflict with version %d, want %d", conflict.Current.Version, tt.wantConflict)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+
+			if updated.Version != tt.version+1 {
+				t.Errorf("Version = %d, want %d", updated.Version, tt.version+1)
+			}
+			if len(client.transactions) != 1 {
+				t.Fatalf("ran %d transactions, want 1", len(client.transactions))
+			}
+			items := client.transactions[0].TransactItems
+			if len(items) != 2 || items[0].Put == nil || items[1].Put == nil {
+				t.Fatalf("transaction = %+v, want the book and its history entry", items)
+			}
+			put := items[0].Put
+			if got := aws.ToString(put.ConditionExpression); got != tt.wantCondition {
+				t.Errorf("ConditionExpression = %q, want %q", got, tt.wantCondition)
+			}
+			if !reflect.DeepEqual(put.ExpressionAttributeValues, tt.wantValues) {
+				t.Errorf("ExpressionAttributeValues = %#v, want %#v", put.ExpressionAttributeValues, tt.wantValues)
+			}
+			if put.ReturnValuesOnConditionCheckFailure != types.ReturnValuesOnConditionCheckFailureAllOld {
+				t.Errorf("ReturnValuesOnConditionCheckFailure = %q, want ALL_OLD", put.ReturnValuesOnConditionCheckFailure)
+			}
+			var item Book
+			if err := attributevalue.UnmarshalMap(put.Item, &item); err != nil {
+				t.Fatal(err)
+			}
+			if item.Version != tt.version+1 || item.GSI1SK != StatusKey(item) || item.GSI2SK != AuthorKey(item) {
+				t.Errorf("stored version %d with keys %s, %s", item.Version, item.GSI1SK, item.GSI2SK)
+			}
+
+			history := items[1].Put
+			if sk := history.Item["SK"].(*types.AttributeValueMemberS).Value; !strings.HasPrefix(sk, HistoryKeyPrefix("book-1")) {
+				t.Errorf("history SK = %q, want it under %q", sk, HistoryKeyPrefix("book-1"))
+			}
+			if got := aws.ToString(history.ConditionExpression); got != "attribute_not_exists(PK)" {
+				t.Errorf("history ConditionExpression = %q, want attribute_not_exists(PK)", got)
+			}
+		})
+	}
+}
+
+func TestDynamoTransactionErrors(t *testing.T) {
+	throttled := errors.New("throttled")
+	tests := []struct {
+		name        string
+		transactErr error
+		// wantErr is what the error wraps: ErrNotFound, ErrVersionConflict
+		// (412), ErrPatchTestFailed (409) or throttled. Nil is the canceled
+		// transaction itself.
+		wantErr error
+	}{
+		{"condition failed at another version", canceled(t, &Book{ID: "book-1", Version: 4}), ErrVersionConflict},
+		{"condition failed at the version read", canceled(t, &Book{ID: "book-1", Version: 2}), ErrPatchTestFailed},
+		{"book deleted", canceled(t, (*Book)(nil)), ErrNotFound},
+		{"transaction conflict", canceled(t, "TransactionConflict", "None"), nil},
+		{"other error", throttled, throttled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stored := NewBook("user-1", "book-1", APIBook{Title: "Dune", Author: "Frank Herbert", Status: StatusRead})
+			stored.Version = 2
+			client := &fakeDynamo{stored: &stored, transactErr: tt.transactErr}
+			patch, err := ParseJSONPatch([]byte(`[{"op":"test","path":"/status","value":"READ"},{"op":"replace","path":"/rating","value":7}]`))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = NewDynamoRepository(client, "books").Patch(context.Background(), "user-1", "book-1", patch, intPtr(2))
+			if err == nil {
+				t.Fatal("Patch succeeded, want an error")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("Patch = %v, want %v", err, tt.wantErr)
+			}
+			var cancellation *types.TransactionCanceledException
+			if tt.wantErr == nil && (errors.Is(err, ErrVersionConflict) || !errors.As(err, &cancellation)) {
+				t.Errorf("Patch = %v, want the canceled transaction", err)
+			}
+
+			update := client.transactions[0].TransactItems[0].Update
+			if got, want := aws.ToString(update.ConditionExpression), "attribute_exists(PK) AND #version = :v7 AND #status = :v8"; got != want {
+				t.Errorf("ConditionExpression = %q, want %q", got, want)
+			}
+		})
+	}
+}
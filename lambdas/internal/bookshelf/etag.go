@@ -0,0 +1,112 @@
+package bookshelf
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// ErrVersionConflict is returned, wrapped in a ConflictError, when a
+// conditional write finds the book at a different version than expected.
+var ErrVersionConflict = errors.New("book version conflict")
+
+// ConflictError reports a failed optimistic concurrency check and carries
+// the book as currently stored.
+type ConflictError struct {
+	Current Book
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("%v: current version is %d", ErrVersionConflict, e.Current.Version)
+}
+
+func (e *ConflictError) Unwrap() error {
+	return ErrVersionConflict
+}
+
+// ETag returns the strong entity tag for a book version.
+func ETag(version int) string {
+	return strconv.Quote(strconv.Itoa(version))
+}
+
+// Header returns the value of the named request header, ignoring case since
+// HTTP APIs lower-case header names and REST APIs do not.
+func Header(request events.APIGatewayProxyRequest, name string) (string, bool) {
+	for k, v := range request.Headers {
+		if strings.EqualFold(k, name) {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// IfMatch is a parsed If-Match request header.
+type IfMatch struct {
+	// Present is false when the request had no If-Match header.
+	Present bool
+	any     bool
+	tags    []string
+}
+
+// ParseIfMatch reads the request's If-Match header.
+func ParseIfMatch(request events.APIGatewayProxyRequest) IfMatch {
+	header, ok := Header(request, "If-Match")
+	if !ok {
+		return IfMatch{}
+	}
+
+	m := IfMatch{Present: true}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			m.any = true
+		}
+		m.tags = append(m.tags, tag)
+	}
+	return m
+}
+
+// Matches reports whether a book at version satisfies the header. If-Match
+// uses strong comparison, so weak tags never match; "*" matches any book
+// that exists.
+func (m IfMatch) Matches(version int) bool {
+	if !m.Present || m.any {
+		return true
+	}
+	etag := ETag(version)
+	for _, tag := range m.tags {
+		if tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// ConflictResponse returns a response with the given status carrying the
+// current server copy of a book and its ETag, so the client can merge its
+// changes and retry.
+func ConflictResponse(status int, current Book) events.APIGatewayProxyResponse {
+	body, err := json.Marshal(current.ToAPI())
+	if err != nil {
+		log.Printf("Error marshalling JSON: %v", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Body:       "Internal Server Error",
+		}
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: status,
+		Headers: map[string]string{
+			"Content-Type": "application/json",
+			"ETag":         ETag(current.Version),
+		},
+		Body: string(body),
+	}
+}
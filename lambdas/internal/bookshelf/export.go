@@ -0,0 +1,19 @@
+package bookshelf
+
+// ExportSchemaVersion is the version of the JSON export format written by
+// export-books. It increases whenever a change to the format would stop an
+// older reader from restoring a newer file.
+const ExportSchemaVersion = 1
+
+// Export is a JSON export of a user's books: metadata and every exported
+// book, IDs and versions included, so that the file can be restored as a
+// backup.
+type Export struct {
+	SchemaVersion int    `json:"schema_version"`
+	ExportedAt    string `json:"exported_at"`
+	// Filters are the filters the books were exported with. An export with
+	// filters holds only some of the user's books.
+	Filters   map[string]string `json:"filters,omitempty"`
+	BookCount int               `json:"book_count"`
+	Books     []APIBook         `json:"books"`
+}
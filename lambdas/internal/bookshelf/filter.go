@@ -0,0 +1,144 @@
+package bookshelf
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// MinRating and MaxRating bound the star rating a book can have.
+	MinRating = 1
+	MaxRating = 10
+
+	dateLayout = "2006-01-02"
+)
+
+// ParseListOptions builds ListOptions from GET /books style query parameters:
+// status, tag, author, series, type, min_rating, max_rating, started_from,
+// started_to, finished_from, finished_to and title. The returned error's
+// message is safe to show to clients.
+func ParseListOptions(params map[string]string) (ListOptions, error) {
+	opts := ListOptions{
+		Status:        params["status"],
+		Tag:           params["tag"],
+		Author:        params["author"],
+		Series:        params["series"],
+		Type:          params["type"],
+		TitleContains: params["title"],
+	}
+	if opts.Status != "" && !ValidStatus(opts.Status) {
+		return ListOptions{}, errors.New(InvalidStatusMessage)
+	}
+
+	var err error
+	if opts.MinRating, err = parseRating(params, "min_rating"); err != nil {
+		return ListOptions{}, err
+	}
+	if opts.MaxRating, err = parseRating(params, "max_rating"); err != nil {
+		return ListOptions{}, err
+	}
+	if opts.MinRating != 0 && opts.MaxRating != 0 && opts.MinRating > opts.MaxRating {
+		return ListOptions{}, fmt.Errorf("Invalid rating range. min_rating must not be greater than max_rating")
+	}
+
+	dates := []struct {
+		from, to         *string
+		fromName, toName string
+	}{
+		{&opts.StartedFrom, &opts.StartedTo, "started_from", "started_to"},
+		{&opts.FinishedFrom, &opts.FinishedTo, "finished_from", "finished_to"},
+	}
+	for _, d := range dates {
+		if *d.from, err = parseDate(params, d.fromName); err != nil {
+			return ListOptions{}, err
+		}
+		if *d.to, err = parseDate(params, d.toName); err != nil {
+			return ListOptions{}, err
+		}
+		if *d.from != "" && *d.to != "" && *d.from > *d.to {
+			return ListOptions{}, fmt.Errorf("Invalid date range. %s must not be after %s", d.fromName, d.toName)
+		}
+	}
+
+	return opts, nil
+}
+
+func parseRating(params map[string]string, name string) (int, error) {
+	value, ok := params[name]
+	if !ok {
+		return 0, nil
+	}
+	rating, err := strconv.Atoi(value)
+	if err != nil || rating < MinRating || rating > MaxRating {
+		return 0, fmt.Errorf("Invalid %s. Must be an integer between %d and %d", name, MinRating, MaxRating)
+	}
+	return rating, nil
+}
+
+func parseDate(params map[string]string, name string) (string, error) {
+	value, ok := params[name]
+	if !ok {
+		return "", nil
+	}
+	if _, err := time.Parse(dateLayout, value); err != nil {
+		return "", fmt.Errorf("Invalid %s. Must be a date in YYYY-MM-DD format", name)
+	}
+	return value, nil
+}
+
+// dayAfter returns the date following a YYYY-MM-DD date. Upper date bounds
+// compare against it exclusively so stored timestamps on the last day match.
+func dayAfter(date string) string {
+	t, _ := time.Parse(dateLayout, date)
+	return t.AddDate(0, 0, 1).Format(dateLayout)
+}
+
+// statusDateRange returns the date bounds on the date StatusIndex orders
+// opts.Status by, if any.
+func (o ListOptions) statusDateRange() (from, to string) {
+	switch o.Status {
+	case StatusRead:
+		return o.FinishedFrom, o.FinishedTo
+	case StatusReading:
+		return o.StartedFrom, o.StartedTo
+	}
+	return "", ""
+}
+
+// Matches reports whether book passes every filter in opts.
+func (o ListOptions) Matches(book Book) bool {
+	switch {
+	case o.Status != "" && book.Status != o.Status,
+		o.Tag != "" && !slices.Contains(book.Tags, o.Tag),
+		o.Author != "" && book.Author != o.Author,
+		o.Series != "" && book.Series != o.Series,
+		o.Type != "" && book.Type != o.Type,
+		(o.MinRating != 0 || o.MaxRating != 0) && book.Rating == nil,
+		o.MinRating != 0 && *book.Rating < o.MinRating,
+		o.MaxRating != 0 && *book.Rating > o.MaxRating,
+		!inDateRange(book.StartedAt, o.StartedFrom, o.StartedTo),
+		!inDateRange(book.FinishedAt, o.FinishedFrom, o.FinishedTo):
+		return false
+	}
+	return o.matchesTitle(book)
+}
+
+// matchesTitle applies the TitleContains filter, which DynamoDB cannot
+// evaluate because its contains() is case-sensitive.
+func (o ListOptions) matchesTitle(book Book) bool {
+	return o.TitleContains == "" ||
+		strings.Contains(strings.ToLower(book.Title), strings.ToLower(o.TitleContains))
+}
+
+func inDateRange(date, from, to string) bool {
+	if from == "" && to == "" {
+		return true
+	}
+	return date != "" &&
+		(from == "" || date >= from) &&
+		(to == "" || date < dayAfter(to))
+}
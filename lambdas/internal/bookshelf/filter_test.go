@@ -0,0 +1,160 @@
+package bookshelf
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseListOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  map[string]string
+		want    ListOptions
+		wantErr string // contained in the error; empty if valid
+	}{
+		{name: "none", params: nil},
+		{
+			name: "every filter",
+			params: map[string]string{
+				"status": "READ", "tag": "sci-fi", "author": "Frank Herbert", "series": "Dune", "type": "kindle",
+				"min_rating": "1", "max_rating": "10", "title": "dune",
+				"started_from": "2024-01-01", "started_to": "2024-01-31",
+				"finished_from": "2024-02-01", "finished_to": "2024-02-01",
+			},
+			want: ListOptions{
+				Status: StatusRead, Tag: "sci-fi", Author: "Frank Herbert", Series: "Dune", Type: "kindle",
+				MinRating: 1, MaxRating: 10, TitleContains: "dune",
+				StartedFrom: "2024-01-01", StartedTo: "2024-01-31",
+				FinishedFrom: "2024-02-01", FinishedTo: "2024-02-01",
+			},
+		},
+		{name: "open date range", params: map[string]string{"finished_to": "2024-12-31"}, want: ListOptions{FinishedTo: "2024-12-31"}},
+		{name: "invalid status", params: map[string]string{"status": "bogus"}, wantErr: InvalidStatusMessage},
+		{name: "lower-case status", params: map[string]string{"status": "read"}, wantErr: InvalidStatusMessage},
+		{name: "rating not a number", params: map[string]string{"min_rating": "high"}, wantErr: "Invalid min_rating"},
+		{name: "rating out of range", params: map[string]string{"max_rating": "11"}, wantErr: "Invalid max_rating"},
+		{name: "empty rating", params: map[string]string{"min_rating": ""}, wantErr: "Invalid min_rating"},
+		{name: "inverted rating range", params: map[string]string{"min_rating": "8", "max_rating": "3"}, wantErr: "Invalid rating range"},
+		{name: "timestamp for a date", params: map[string]string{"started_from": "2024-01-01T00:00:00Z"}, wantErr: "Invalid started_from. Must be a date in YYYY-MM-DD format"},
+		{name: "impossible date", params: map[string]string{"finished_to": "2024-02-30"}, wantErr: "Invalid finished_to"},
+		{name: "inverted date range", params: map[string]string{"finished_from": "2024-03-01", "finished_to": "2024-02-01"}, wantErr: "Invalid date range. finished_from must not be after finished_to"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseListOptions(tt.params)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ParseListOptions = %v, want an error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseListOptions = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSortOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  map[string]string
+		want    SortOptions
+		wantErr string
+	}{
+		{name: "none"},
+		{name: "ascending", params: map[string]string{"sort": "title"}, want: SortOptions{Field: "title"}},
+		{name: "explicit ascending", params: map[string]string{"sort": "rating", "order": "asc"}, want: SortOptions{Field: "rating"}},
+		{name: "descending", params: map[string]string{"sort": "finished_at", "order": "desc"}, want: SortOptions{Field: "finished_at", Descending: true}},
+		{name: "invalid sort", params: map[string]string{"sort": "colour"}, wantErr: "Invalid sort. Must be one of: title, author, rating"},
+		{name: "invalid order", params: map[string]string{"sort": "title", "order": "DESC"}, wantErr: "Invalid order. Must be one of: asc, desc"},
+		{name: "order without sort", params: map[string]string{"order": "desc"}, wantErr: "Invalid order. order requires sort"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSortOptions(tt.params)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ParseSortOptions = %v, want an error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseSortOptions = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestListOptionsMatches(t *testing.T) {
+	book := Book{
+		Title: "Dune", Author: "Frank Herbert", Status: StatusRead, Rating: intPtr(8), Tags: []string{"sci-fi"},
+		StartedAt: "2024-01-10", FinishedAt: "2024-02-01T21:00:00Z",
+	}
+	tests := []struct {
+		name string
+		opts ListOptions
+		want bool
+	}{
+		{"no filters", ListOptions{}, true},
+		{"status", ListOptions{Status: StatusReading}, false},
+		{"tag", ListOptions{Tag: "sci-fi"}, true},
+		{"title ignoring case", ListOptions{TitleContains: "DUN"}, true},
+		{"rating in range", ListOptions{MinRating: 8, MaxRating: 8}, true},
+		{"rating below range", ListOptions{MinRating: 9}, false},
+		// A timestamp on the last day is inside an inclusive range
+		{"finished on the last day", ListOptions{FinishedFrom: "2024-01-01", FinishedTo: "2024-02-01"}, true},
+		{"finished after the range", ListOptions{FinishedTo: "2024-01-31"}, false},
+		{"started before the range", ListOptions{StartedFrom: "2024-01-11"}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.opts.Matches(book); got != tt.want {
+			t.Errorf("%s: Matches = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+	if (ListOptions{MinRating: 1}).Matches(Book{Status: StatusRead}) {
+		t.Error("an unrated book matches a rating range")
+	}
+}
+
+func TestSortBooks(t *testing.T) {
+	books := []Book{
+		{ID: "c", Title: "emma", Rating: intPtr(6)},
+		{ID: "a", Title: "Dune"},
+		{ID: "d", Title: "Beowulf", Rating: intPtr(10)},
+		{ID: "b", Title: "Emma", Rating: intPtr(6)},
+	}
+	ids := func(books []Book) string {
+		var ids []string
+		for _, book := range books {
+			ids = append(ids, book.ID)
+		}
+		return strings.Join(ids, "")
+	}
+	tests := []struct {
+		opts SortOptions
+		want string
+	}{
+		{SortOptions{}, "cadb"},
+		// Titles ignore case and ties keep ID order
+		{SortOptions{Field: "title"}, "dabc"},
+		{SortOptions{Field: "title", Descending: true}, "bcad"},
+		// Unrated books come last either way
+		{SortOptions{Field: "rating"}, "bcda"},
+		{SortOptions{Field: "rating", Descending: true}, "dbca"},
+	}
+	for _, tt := range tests {
+		sorted := append([]Book(nil), books...)
+		SortBooks(sorted, tt.opts)
+		if got := ids(sorted); got != tt.want {
+			t.Errorf("SortBooks(%+v) = %s, want %s", tt.opts, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,240 @@
+package bookshelf
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sort"
+	"time"
+)
+
+// Actions recorded in a book's history.
+const (
+	HistoryCreate  = "create"
+	HistoryUpdate  = "update"
+	HistoryMerge   = "merge"
+	HistoryDelete  = "delete"
+	HistoryRestore = "restore"
+	HistoryRevert  = "revert"
+)
+
+// historyTimeFormat is fixed-width so HIST# sort keys order by time.
+const historyTimeFormat = "2006-01-02T15:04:05.000000000Z"
+
+// ErrVersionNotFound is returned by RevertBook when the book's history does
+// not reach back to the requested version.
+var ErrVersionNotFound = errors.New("version not found in history")
+
+// FieldChange is one field of a book changed by a write. From and To are
+// the API values, or nil when the field was absent.
+type FieldChange struct {
+	Field string      `dynamodbav:"field" json:"field"`
+	From  interface{} `dynamodbav:"from" json:"from"`
+	To    interface{} `dynamodbav:"to" json:"to"`
+}
+
+// HistoryEntry records one write to a book. Entries are stored under
+// HIST#<book id>#<timestamp> and never modified, so they outlive the book.
+type HistoryEntry struct {
+	PK        string `dynamodbav:"PK"`
+	SK        string `dynamodbav:"SK"`
+	BookID    string `dynamodbav:"book_id"`
+	Timestamp string `dynamodbav:"timestamp"`
+	Action    string `dynamodbav:"action"`
+	// Version is the book's version after the write. A delete leaves the
+	// version of the book it deleted.
+	Version   int           `dynamodbav:"version"`
+	RequestID string        `dynamodbav:"request_id,omitempty"`
+	Changes   []FieldChange `dynamodbav:"changes"`
+	// MergedFrom is the duplicate folded into the book by a merge, and
+	// MergedInto the book a deleted duplicate was merged into.
+	MergedFrom string `dynamodbav:"merged_from,omitempty"`
+	MergedInto string `dynamodbav:"merged_into,omitempty"`
+}
+
+// APIHistoryEntry is the API representation of a HistoryEntry.
+type APIHistoryEntry struct {
+	BookID     string        `json:"book_id"`
+	Timestamp  string        `json:"timestamp"`
+	Action     string        `json:"action"`
+	Version    int           `json:"version"`
+	RequestID  string        `json:"request_id,omitempty"`
+	Changes    []FieldChange `json:"changes"`
+	MergedFrom string        `json:"merged_from,omitempty"`
+	MergedInto string        `json:"merged_into,omitempty"`
+}
+
+type historyContextKey int
+
+const (
+	requestIDKey historyContextKey = iota
+	historyActionKey
+)
+
+// WithRequestID returns a copy of ctx carrying the ID of the API request
+// making a change, which repositories record in the book's history.
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey, requestID)
+}
+
+// WithHistoryAction returns a copy of ctx that records writes as action
+// rather than the repository method's own, such as HistoryRevert for an
+// Update that reverts a book.
+func WithHistoryAction(ctx context.Context, action string) context.Context {
+	return context.WithValue(ctx, historyActionKey, action)
+}
+
+// newHistoryEntry records a write of action that took a user's book from
+// before to after. The request ID and any action override come from ctx.
+func newHistoryEntry(ctx context.Context, userID, action string, before, after Book, now time.Time) HistoryEntry {
+	if override, ok := ctx.Value(historyActionKey).(string); ok && override != "" {
+		action = override
+	}
+	requestID, _ := ctx.Value(requestIDKey).(string)
+	timestamp := now.UTC().Format(historyTimeFormat)
+	return HistoryEntry{
+		PK:        UserPK(userID),
+		SK:        HistorySK(after.ID, timestamp),
+		BookID:    after.ID,
+		Timestamp: timestamp,
+		Action:    action,
+		Version:   after.Version,
+		RequestID: requestID,
+		Changes:   DiffBooks(before, after),
+	}
+}
+
+// DiffBooks returns the API fields that differ between before and after,
+// in field name order.
+func DiffBooks(before, after Book) []FieldChange {
+	from, to := before.ToAPI(), after.ToAPI()
+	changes := []FieldChange{}
+	for _, name := range sortedKeys(patchFields) {
+		a, b := fieldValue(from, name), fieldValue(to, name)
+		if !equalFieldValue(a, b) {
+			changes = append(changes, FieldChange{Field: name, From: a, To: b})
+		}
+	}
+	return changes
+}
+
+// normalize converts values read back from DynamoDB, where numbers are
+// float64 and lists []interface{}, to the types fieldValue returns.
+func (c FieldChange) normalize() FieldChange {
+	convert := func(v interface{}) interface{} {
+		switch v := v.(type) {
+		case float64:
+			return int(v)
+		case []interface{}:
+			tags := make([]string, 0, len(v))
+			for _, tag := range v {
+				if s, ok := tag.(string); ok {
+					tags = append(tags, s)
+				}
+			}
+			return tags
+		}
+		return v
+	}
+	c.From, c.To = convert(c.From), convert(c.To)
+	return c
+}
+
+// undo returns a patch reversing the entry's changes.
+func (e HistoryEntry) undo() Patch {
+	p := newPatch()
+	for _, change := range e.Changes {
+		if _, ok := patchFields[change.Field]; !ok {
+			continue
+		}
+		if change.From == nil {
+			p.remove[change.Field] = true
+		} else {
+			p.set[change.Field] = change.From
+		}
+	}
+	return p
+}
+
+// RevertBook returns current with its fields as they were at version,
+// undoing the changes recorded in history, newest first, one version at a
+// time. The result keeps current's version so it can be stored with Update.
+// A book's history starts at its most recent create, whose version need not
+// be 1: a book restored from a backup is created at its backed-up version.
+// It returns ErrVersionNotFound unless history has every version from the
+// requested one on.
+func RevertBook(current Book, history []HistoryEntry, version int) (Book, error) {
+	if version < 0 || version >= current.Version {
+		return Book{}, ErrVersionNotFound
+	}
+
+	// Deleting does not change a book's version or fields, and entries
+	// before the create belong to an earlier book with the same ID. Order
+	// by version rather than timestamp, which comes from the writer's clock.
+	var writes []HistoryEntry
+	first := 0
+	for _, entry := range history {
+		if entry.Action == HistoryDelete {
+			continue
+		}
+		if entry.Version > version {
+			writes = append(writes, entry)
+		}
+		if entry.Action == HistoryCreate {
+			first = entry.Version
+			break
+		}
+	}
+	if version < first {
+		return Book{}, ErrVersionNotFound
+	}
+	sort.Slice(writes, func(i, j int) bool { return writes[i].Version > writes[j].Version })
+
+	reverted := cloneBook(current)
+	expected := current.Version
+	for _, entry := range writes {
+		if entry.Version != expected {
+			return Book{}, ErrVersionNotFound
+		}
+		reverted = entry.undo().Apply(reverted)
+		expected--
+	}
+	if expected != version {
+		return Book{}, ErrVersionNotFound
+	}
+	return reverted, nil
+}
+
+// ToAPI converts a history entry into its API representation.
+func (e HistoryEntry) ToAPI() APIHistoryEntry {
+	changes := e.Changes
+	if changes == nil {
+		changes = []FieldChange{}
+	}
+	return APIHistoryEntry{
+		BookID:     e.BookID,
+		Timestamp:  e.Timestamp,
+		Action:     e.Action,
+		Version:    e.Version,
+		RequestID:  e.RequestID,
+		Changes:    changes,
+		MergedFrom: e.MergedFrom,
+		MergedInto: e.MergedInto,
+	}
+}
+
+// ToAPIHistory converts history entries into API representations. The
+// result is never nil so that it always marshals to a JSON array.
+func ToAPIHistory(entries []HistoryEntry) []APIHistoryEntry {
+	apiEntries := make([]APIHistoryEntry, len(entries))
+	for i, entry := range entries {
+		apiEntries[i] = entry.ToAPI()
+	}
+	return apiEntries
+}
+
+// cloneHistoryEntry copies entry so callers cannot mutate stored state.
+func cloneHistoryEntry(entry HistoryEntry) HistoryEntry {
+	entry.Changes = slices.Clone(entry.Changes)
+	return entry
+}
@@ -0,0 +1,146 @@
+package bookshelf
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestRevertBook(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryRepository()
+
+	// put stores book, as a create, and returns it as stored
+	put := func(book Book) Book {
+		t.Helper()
+		if err := repo.Put(ctx, "user-1", book); err != nil {
+			t.Fatal(err)
+		}
+		stored, err := repo.Get(ctx, "user-1", book.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return stored
+	}
+	// update stores book changed by change and returns it as stored
+	update := func(book Book, change func(*Book)) Book {
+		t.Helper()
+		change(&book)
+		stored, err := repo.Update(ctx, "user-1", book)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return stored
+	}
+	history := func(bookID string) []HistoryEntry {
+		t.Helper()
+		entries, err := repo.History(ctx, "user-1", bookID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return entries
+	}
+
+	// dune is rated at version 2 and finished at version 3
+	dune1 := put(NewBook("user-1", "dune", APIBook{Title: "Dune", Author: "Frank Herbert", Status: StatusReading}))
+	dune2 := update(dune1, func(b *Book) { b.Rating = intPtr(8) })
+	dune3 := update(dune2, func(b *Book) { b.Status, b.FinishedAt = StatusRead, "2024-05-01" })
+
+	// emma is restored from a backup at version 5, then reviewed
+	restored := NewBook("user-1", "emma", APIBook{Title: "Emma", Author: "Jane Austen", Status: StatusRead})
+	restored.Version = 5
+	emma5 := put(restored)
+	emma6 := update(emma5, func(b *Book) { b.Review = "Delightful" })
+
+	// hyperion is replaced at version 2 by a book with the same ID, whose
+	// history must not be mixed with the one it replaced
+	hyperion1 := put(NewBook("user-1", "hyperion", APIBook{Title: "Hyperion", Author: "Dan Simmons", Status: StatusRead}))
+	update(update(hyperion1, func(b *Book) { b.Rating = intPtr(4) }), func(b *Book) { b.Rating = intPtr(6) })
+	replaced := NewBook("user-1", "hyperion", APIBook{Title: "Hyperion", Author: "Dan Simmons", Status: StatusWantToRead})
+	replaced.Version = 2
+	hyperion2 := put(replaced)
+	hyperion3 := update(hyperion2, func(b *Book) { b.Status = StatusReading })
+
+	tests := []struct {
+		name    string
+		current Book
+		history []HistoryEntry
+		version int
+		want    Book // fields of the wanted version; zero for ErrVersionNotFound
+	}{
+		{"previous version", dune3, history("dune"), 2, dune2},
+		{"first version", dune3, history("dune"), 1, dune1},
+		{"before the book was created", dune3, history("dune"), 0, Book{}},
+		{"current version", dune3, history("dune"), 3, Book{}},
+		{"later version", dune3, history("dune"), 4, Book{}},
+		{"negative version", dune3, history("dune"), -1, Book{}},
+		{"gap in history", dune3, history("dune")[:1], 1, Book{}},
+		{"restored version", emma6, history("emma"), 5, emma5},
+		{"before the restored version", emma6, history("emma"), 4, Book{}},
+		{"replaced book", hyperion3, history("hyperion"), 2, hyperion2},
+		{"version of the book it replaced", hyperion3, history("hyperion"), 1, Book{}},
+		{"deleted and restored", dune3, append([]HistoryEntry{{Action: HistoryDelete, Version: 3}}, history("dune")...), 2, dune2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RevertBook(tt.current, tt.history, tt.version)
+			if tt.want.ID == "" {
+				if !errors.Is(err, ErrVersionNotFound) {
+					t.Errorf("RevertBook = %v, %v, want ErrVersionNotFound", got, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			// The reverted book keeps the current version to be stored with
+			// Update.
+			want := tt.want
+			want.Version = tt.current.Version
+			if !reflect.DeepEqual(got.ToAPI(), want.ToAPI()) {
+				t.Errorf("RevertBook = %+v, want %+v", got.ToAPI(), want.ToAPI())
+			}
+		})
+	}
+}
+
+func TestDiffBooks(t *testing.T) {
+	before := NewBook("user-1", "book-1", APIBook{Title: "Dune", Author: "Frank Herbert", Status: StatusReading, Tags: []string{"sci-fi"}})
+	tests := []struct {
+		name   string
+		change func(*Book)
+		want   []FieldChange
+	}{
+		{"nothing", func(b *Book) {}, []FieldChange{}},
+		{"version and keys only", func(b *Book) { b.Version = 7; b.SetKeys("user-1") }, []FieldChange{}},
+		{
+			name:   "fields in name order",
+			change: func(b *Book) { b.Status, b.Rating, b.Series = StatusRead, intPtr(8), "Dune" },
+			want: []FieldChange{
+				{Field: "rating", From: nil, To: 8},
+				{Field: "series", From: nil, To: "Dune"},
+				{Field: "status", From: StatusReading, To: StatusRead},
+			},
+		},
+		{
+			name:   "tags",
+			change: func(b *Book) { b.Tags = []string{"sci-fi", "classics"} },
+			want:   []FieldChange{{Field: "tags", From: []string{"sci-fi"}, To: []string{"sci-fi", "classics"}}},
+		},
+		{
+			name:   "field removed",
+			change: func(b *Book) { b.Tags = nil },
+			want:   []FieldChange{{Field: "tags", From: []string{"sci-fi"}, To: nil}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			after := cloneBook(before)
+			tt.change(&after)
+			if got := DiffBooks(before, after); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DiffBooks = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package bookshelf
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+const (
+	// IdempotencyTTL is how long the response to a request made with an
+	// Idempotency-Key is remembered.
+	IdempotencyTTL = 24 * time.Hour
+
+	// MaxIdempotencyKeyLength bounds the length of an Idempotency-Key.
+	MaxIdempotencyKeyLength = 255
+)
+
+// ErrInvalidIdempotencyKey is returned by ParseIdempotencyKey for a header
+// that is empty or too long.
+var ErrInvalidIdempotencyKey = errors.New("Invalid Idempotency-Key. Must be between 1 and 255 characters")
+
+// IdempotencyRecord is the response to a request made with an
+// Idempotency-Key, kept so that retries of the request get the same answer.
+type IdempotencyRecord struct {
+	Key string
+	// RequestHash identifies the request body the key was first used with.
+	RequestHash string
+	StatusCode  int
+	Headers     map[string]string
+	Body        string
+	// ExpiresAt is when the key may be reused for a new request.
+	ExpiresAt time.Time
+}
+
+// NewIdempotencyRecord records response as the answer to a request with the
+// given key and body, remembered for IdempotencyTTL from now.
+func NewIdempotencyRecord(key, body string, response events.APIGatewayProxyResponse) IdempotencyRecord {
+	return IdempotencyRecord{
+		Key:         key,
+		RequestHash: RequestHash(body),
+		StatusCode:  response.StatusCode,
+		Headers:     response.Headers,
+		Body:        response.Body,
+		ExpiresAt:   time.Now().Add(IdempotencyTTL),
+	}
+}
+
+// RequestHash returns the hash of a request body stored with its
+// Idempotency-Key.
+func RequestHash(body string) string {
+	sum := sha256.Sum256([]byte(body))
+	return hex.EncodeToString(sum[:])
+}
+
+// Matches reports whether a request with body is a retry of the request the
+// record was made for.
+func (r IdempotencyRecord) Matches(body string) bool {
+	return r.RequestHash == RequestHash(body)
+}
+
+// Response replays the recorded response, marked with an
+// Idempotent-Replayed header.
+func (r IdempotencyRecord) Response() events.APIGatewayProxyResponse {
+	headers := map[string]string{"Idempotent-Replayed": "true"}
+	for k, v := range r.Headers {
+		headers[k] = v
+	}
+	return events.APIGatewayProxyResponse{
+		StatusCode: r.StatusCode,
+		Headers:    headers,
+		Body:       r.Body,
+	}
+}
+
+// ParseIdempotencyKey reads the request's Idempotency-Key header. It returns
+// "" when there is none, or ErrInvalidIdempotencyKey.
+func ParseIdempotencyKey(request events.APIGatewayProxyRequest) (string, error) {
+	key, ok := Header(request, "Idempotency-Key")
+	if !ok {
+		return "", nil
+	}
+	if key == "" || len(key) > MaxIdempotencyKeyLength {
+		return "", ErrInvalidIdempotencyKey
+	}
+	return key, nil
+}
+
+// idempotencyItem is the DynamoDB item for an IdempotencyRecord, stored in
+// the user's partition under IDEMPOTENCY#<key>. DynamoDB deletes it some
+// time after expires_at, so reads must still check the expiry.
+type idempotencyItem struct {
+	PK          string            `dynamodbav:"PK"`
+	SK          string            `dynamodbav:"SK"`
+	RequestHash string            `dynamodbav:"request_hash"`
+	StatusCode  int               `dynamodbav:"status_code"`
+	Headers     map[string]string `dynamodbav:"headers,omitempty"`
+	Body        string            `dynamodbav:"body"`
+	ExpiresAt   int64             `dynamodbav:"expires_at"`
+}
+
+func newIdempotencyItem(userID string, record IdempotencyRecord) idempotencyItem {
+	return idempotencyItem{
+		PK:          UserPK(userID),
+		SK:          IdempotencySK(record.Key),
+		RequestHash: record.RequestHash,
+		StatusCode:  record.StatusCode,
+		Headers:     record.Headers,
+		Body:        record.Body,
+		ExpiresAt:   record.ExpiresAt.Unix(),
+	}
+}
+
+func (i idempotencyItem) record(key string) IdempotencyRecord {
+	return IdempotencyRecord{
+		Key:         key,
+		RequestHash: i.RequestHash,
+		StatusCode:  i.StatusCode,
+		Headers:     i.Headers,
+		Body:        i.Body,
+		ExpiresAt:   time.Unix(i.ExpiresAt, 0),
+	}
+}
@@ -0,0 +1,166 @@
+package bookshelf
+
+import (
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// TableName is the DynamoDB table holding every user's books.
+const TableName = "books"
+
+// Per-user global secondary indexes. Both are partitioned by USER#<id> so a
+// query only ever reads one user's books.
+const (
+	// StatusIndex is keyed GSI1PK = USER#<id>, GSI1SK =
+	// STATUS#<status>#<date>#<book id>, ordering each status by the date
+	// that matters for it (see StatusDate).
+	StatusIndex = "status-index"
+	// AuthorIndex is keyed GSI2PK = USER#<id>, GSI2SK =
+	// AUTHOR#<normalized author>#<book id>, the author as NormalizeAuthor
+	// writes it.
+	AuthorIndex = "author-index"
+)
+
+const (
+	userPrefix   = "USER#"
+	bookPrefix   = "BOOK#"
+	statusPrefix = "STATUS#"
+	authorPrefix = "AUTHOR#"
+
+	idempotencyPrefix = "IDEMPOTENCY#"
+	trashPrefix       = "TRASH#"
+	historyPrefix     = "HIST#"
+	importPrefix      = "IMPORT#"
+	exportPrefix      = "EXPORT#"
+)
+
+// UserPK returns the partition key for all items owned by userID.
+func UserPK(userID string) string {
+	return userPrefix + userID
+}
+
+// BookSK returns the sort key for the book with the given ID.
+func BookSK(bookID string) string {
+	return bookPrefix + bookID
+}
+
+// BookIDFromSK extracts the book ID from a BOOK# sort key.
+func BookIDFromSK(sk string) (string, bool) {
+	return strings.CutPrefix(sk, bookPrefix)
+}
+
+// TrashSK returns the sort key of a book in the user's trash.
+func TrashSK(bookID string) string {
+	return trashPrefix + bookID
+}
+
+// TrashKey returns the DynamoDB primary key of a book in the user's trash.
+func TrashKey(userID, bookID string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"PK": &types.AttributeValueMemberS{Value: UserPK(userID)},
+		"SK": &types.AttributeValueMemberS{Value: TrashSK(bookID)},
+	}
+}
+
+// HistoryKeyPrefix returns the sort key prefix shared by every history
+// entry of a book.
+func HistoryKeyPrefix(bookID string) string {
+	return historyPrefix + bookID + "#"
+}
+
+// HistorySK returns the sort key of the history entry for a write to a book
+// at timestamp.
+func HistorySK(bookID, timestamp string) string {
+	return HistoryKeyPrefix(bookID) + timestamp
+}
+
+// IdempotencySK returns the sort key remembering a request made with the
+// given Idempotency-Key.
+func IdempotencySK(key string) string {
+	return idempotencyPrefix + key
+}
+
+// ImportSK returns the sort key of an import job.
+func ImportSK(jobID string) string {
+	return importPrefix + jobID
+}
+
+// ExportSK returns the sort key of an export job. ExportSK("") is the prefix
+// shared by every export job of a user.
+func ExportSK(exportID string) string {
+	return exportPrefix + exportID
+}
+
+// Key returns the DynamoDB primary key of a user's book.
+func Key(userID, bookID string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"PK": &types.AttributeValueMemberS{Value: UserPK(userID)},
+		"SK": &types.AttributeValueMemberS{Value: BookSK(bookID)},
+	}
+}
+
+// StatusDate returns the date a book is ordered by within its status:
+// finished_at for READ, started_at for READING and created_at otherwise.
+func StatusDate(book Book) string {
+	switch book.Status {
+	case StatusRead:
+		return book.FinishedAt
+	case StatusReading:
+		return book.StartedAt
+	default:
+		return book.CreatedAt
+	}
+}
+
+// StatusKeyPrefix returns the StatusIndex sort key prefix shared by every
+// book in status.
+func StatusKeyPrefix(status string) string {
+	return statusPrefix + status + "#"
+}
+
+// StatusKey returns the StatusIndex sort key for a book.
+func StatusKey(book Book) string {
+	return StatusKeyPrefix(book.Status) + StatusDate(book) + "#" + book.ID
+}
+
+// AuthorKeyPrefix returns the AuthorIndex sort key prefix shared by every
+// book by author. Authors are matched as FindDuplicate matches them,
+// ignoring case, punctuation and how initials are written.
+func AuthorKeyPrefix(author string) string {
+	return authorPrefix + NormalizeAuthor(author) + "#"
+}
+
+// AuthorKey returns the AuthorIndex sort key for a book.
+func AuthorKey(book Book) string {
+	return AuthorKeyPrefix(book.Author) + book.ID
+}
+
+// SetKeys sets the table and index keys of a book owned by userID from its
+// other attributes. Every write must call it so the indexes stay current.
+func (b *Book) SetKeys(userID string) {
+	b.PK = UserPK(userID)
+	b.SK = BookSK(b.ID)
+	b.GSI1PK = b.PK
+	b.GSI1SK = StatusKey(*b)
+	b.GSI2PK = b.PK
+	b.GSI2SK = AuthorKey(*b)
+}
+
+// orderKey returns the sort key DynamoRepository's query for opts orders
+// book by.
+func (o ListOptions) orderKey(book Book) string {
+	switch {
+	case o.Status != "":
+		return book.GSI1SK
+	case o.Author != "":
+		return book.GSI2SK
+	default:
+		return book.SK
+	}
+}
+
+// UserIDFromPK extracts the user ID from a USER# partition key.
+func UserIDFromPK(pk string) (string, bool) {
+	return strings.CutPrefix(pk, userPrefix)
+}
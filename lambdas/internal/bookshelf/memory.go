@@ -0,0 +1,367 @@
+package bookshelf
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryRepository is an in-memory BookRepository for local development and
+// tests. It is safe for concurrent use.
+type MemoryRepository struct {
+	mu          sync.RWMutex
+	books       map[string]map[string]Book              // user ID -> book ID -> book
+	idempotency map[string]map[string]IdempotencyRecord // user ID -> key -> record
+	trash       map[string]map[string]TrashedBook       // user ID -> book ID -> book
+	history     map[string][]HistoryEntry               // user ID -> entries, oldest first
+}
+
+// NewMemoryRepository returns an empty in-memory repository.
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{
+		books:       make(map[string]map[string]Book),
+		idempotency: make(map[string]map[string]IdempotencyRecord),
+		trash:       make(map[string]map[string]TrashedBook),
+		history:     make(map[string][]HistoryEntry),
+	}
+}
+
+// Get returns the user's book with the given ID, or ErrNotFound.
+func (r *MemoryRepository) Get(ctx context.Context, userID, bookID string) (Book, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	book, ok := r.books[userID][bookID]
+	if !ok {
+		return Book{}, ErrNotFound
+	}
+	return cloneBook(book), nil
+}
+
+// List returns all of the user's books in the order DynamoRepository would.
+func (r *MemoryRepository) List(ctx context.Context, userID string, opts ListOptions) ([]Book, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var books []Book
+	for _, book := range r.books[userID] {
+		if !opts.Matches(book) {
+			continue
+		}
+		books = append(books, cloneBook(book))
+	}
+
+	// Match DynamoDB, which returns items in the sort key order of the
+	// table or index being queried.
+	sort.Slice(books, func(i, j int) bool { return opts.orderKey(books[i]) < opts.orderKey(books[j]) })
+	return books, nil
+}
+
+// ListPage returns up to opts.Limit of the user's books in the same order as
+// List. Page tokens are the sort key of the last book in the previous page.
+func (r *MemoryRepository) ListPage(ctx context.Context, userID string, opts PageOptions) (Page, error) {
+	if opts.Limit <= 0 {
+		return Page{}, fmt.Errorf("invalid page limit %d", opts.Limit)
+	}
+
+	books, err := r.List(ctx, userID, opts.ListOptions)
+	if err != nil {
+		return Page{}, err
+	}
+
+	start := 0
+	if opts.StartToken != "" {
+		start = sort.Search(len(books), func(i int) bool { return opts.orderKey(books[i]) > opts.StartToken })
+	}
+	books = books[start:]
+
+	if len(books) <= opts.Limit {
+		return Page{Books: books}, nil
+	}
+	books = books[:opts.Limit]
+	return Page{Books: books, NextToken: opts.orderKey(books[len(books)-1])}, nil
+}
+
+// ListByAuthor returns the user's books by author, as NormalizeAuthor
+// matches them, in the order DynamoRepository would.
+func (r *MemoryRepository) ListByAuthor(ctx context.Context, userID, author string) ([]Book, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	prefix := AuthorKeyPrefix(author)
+	var books []Book
+	for _, book := range r.books[userID] {
+		if AuthorKeyPrefix(book.Author) == prefix {
+			books = append(books, cloneBook(book))
+		}
+	}
+	sort.Slice(books, func(i, j int) bool { return AuthorKey(books[i]) < AuthorKey(books[j]) })
+	return books, nil
+}
+
+// Put stores a new book for the user, overwriting any book with the same ID.
+// A zero Version is stored as 1.
+func (r *MemoryRepository) Put(ctx context.Context, userID string, book Book) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if book.Version == 0 {
+		book.Version = 1
+	}
+	r.record(userID, newHistoryEntry(ctx, userID, HistoryCreate, Book{}, book, time.Now()))
+	r.store(userID, book)
+	return nil
+}
+
+// PutBatch stores new books for the user like Put. Every book is stored.
+func (r *MemoryRepository) PutBatch(ctx context.Context, userID string, books []Book) map[string]error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for _, book := range books {
+		if book.Version == 0 {
+			book.Version = 1
+		}
+		r.record(userID, newHistoryEntry(ctx, userID, HistoryCreate, Book{}, book, now))
+		r.store(userID, book)
+	}
+	return map[string]error{}
+}
+
+// PutIdempotent stores a new book like Put, together with record. If the
+// user already has an unexpired record for record.Key, nothing is stored and
+// that record is returned instead.
+func (r *MemoryRepository) PutIdempotent(ctx context.Context, userID string, book Book, record IdempotencyRecord) (*IdempotencyRecord, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if existing, ok := r.idempotency[userID][record.Key]; ok && time.Now().Before(existing.ExpiresAt) {
+		return &existing, nil
+	}
+
+	if book.Version == 0 {
+		book.Version = 1
+	}
+	r.record(userID, newHistoryEntry(ctx, userID, HistoryCreate, Book{}, book, time.Now()))
+	r.store(userID, book)
+	if r.idempotency[userID] == nil {
+		r.idempotency[userID] = make(map[string]IdempotencyRecord)
+	}
+	r.idempotency[userID][record.Key] = record
+	return nil, nil
+}
+
+// GetIdempotencyRecord returns the user's unexpired record for key, or nil
+// if there is none.
+func (r *MemoryRepository) GetIdempotencyRecord(ctx context.Context, userID, key string) (*IdempotencyRecord, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	record, ok := r.idempotency[userID][key]
+	if !ok || !time.Now().Before(record.ExpiresAt) {
+		return nil, nil
+	}
+	return &record, nil
+}
+
+// Update replaces an existing book if it is still at book.Version and returns
+// the stored book with its version incremented. It returns ErrNotFound if the
+// book does not exist, or a ConflictError if it has changed.
+func (r *MemoryRepository) Update(ctx context.Context, userID string, book Book) (Book, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.checkVersion(userID, book.ID, &book.Version); err != nil {
+		return Book{}, err
+	}
+	book.Version++
+	r.record(userID, newHistoryEntry(ctx, userID, HistoryUpdate, r.books[userID][book.ID], book, time.Now()))
+	return r.store(userID, book), nil
+}
+
+// Patch applies patch to the user's book atomically and returns the stored
+// book with its version incremented. It returns ErrNotFound, a ConflictError
+// if version is not nil and the book has changed, or a TestFailedError.
+func (r *MemoryRepository) Patch(ctx context.Context, userID, bookID string, patch Patch, version *int) (Book, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.checkVersion(userID, bookID, version); err != nil {
+		return Book{}, err
+	}
+	current := cloneBook(r.books[userID][bookID])
+	if !patch.TestsPass(current) {
+		return Book{}, &TestFailedError{Current: current}
+	}
+
+	book := patch.Apply(current)
+	book.Version++
+	r.record(userID, newHistoryEntry(ctx, userID, HistoryUpdate, current, book, time.Now()))
+	return r.store(userID, book), nil
+}
+
+// Merge atomically stores merged with its version incremented and deletes
+// source, provided both are still at the versions given. It returns
+// ErrNotFound or a ConflictError carrying the book that changed.
+func (r *MemoryRepository) Merge(ctx context.Context, userID string, merged, source Book) (Book, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.checkVersion(userID, merged.ID, &merged.Version); err != nil {
+		return Book{}, err
+	}
+	if err := r.checkVersion(userID, source.ID, &source.Version); err != nil {
+		return Book{}, err
+	}
+	merged.Version++
+	now := time.Now()
+	mergedEntry := newHistoryEntry(ctx, userID, HistoryMerge, r.books[userID][merged.ID], merged, now)
+	mergedEntry.MergedFrom = source.ID
+	r.record(userID, mergedEntry)
+	sourceEntry := newHistoryEntry(ctx, userID, HistoryDelete, source, source, now)
+	sourceEntry.MergedInto = merged.ID
+	r.record(userID, sourceEntry)
+
+	delete(r.books[userID], source.ID)
+	return r.store(userID, merged), nil
+}
+
+// Trash moves the user's book, which must still be at book.Version, to their
+// trash. It returns ErrNotFound, or a ConflictError if the book has changed.
+func (r *MemoryRepository) Trash(ctx context.Context, userID string, book Book) (TrashedBook, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.checkVersion(userID, book.ID, &book.Version); err != nil {
+		return TrashedBook{}, err
+	}
+	now := time.Now()
+	current := cloneBook(r.books[userID][book.ID])
+	r.record(userID, newHistoryEntry(ctx, userID, HistoryDelete, current, current, now))
+	trashed := NewTrashedBook(userID, current, now)
+	delete(r.books[userID], book.ID)
+	if r.trash[userID] == nil {
+		r.trash[userID] = make(map[string]TrashedBook)
+	}
+	r.trash[userID][book.ID] = trashed
+	return trashed, nil
+}
+
+// ListTrash returns the books in the user's trash, most recently deleted
+// first.
+func (r *MemoryRepository) ListTrash(ctx context.Context, userID string) ([]TrashedBook, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	now := time.Now()
+	var books []TrashedBook
+	for _, book := range r.trash[userID] {
+		if !book.Expired(now) {
+			book.Book = cloneBook(book.Book)
+			books = append(books, book)
+		}
+	}
+	sortTrash(books)
+	return books, nil
+}
+
+// Restore moves a book out of the user's trash with its version incremented.
+// It returns ErrNotFound if the book is not in the trash, or a ConflictError
+// carrying the book if one with the same ID exists.
+func (r *MemoryRepository) Restore(ctx context.Context, userID, bookID string) (Book, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	trashed, ok := r.trash[userID][bookID]
+	if !ok || trashed.Expired(time.Now()) {
+		return Book{}, ErrNotFound
+	}
+	if current, ok := r.books[userID][bookID]; ok {
+		return Book{}, &ConflictError{Current: cloneBook(current)}
+	}
+	delete(r.trash[userID], bookID)
+	book := trashed.Restored(userID)
+	r.record(userID, newHistoryEntry(ctx, userID, HistoryRestore, trashed.Book, book, time.Now()))
+	return r.store(userID, book), nil
+}
+
+// Purge permanently removes a book from the user's trash, or returns
+// ErrNotFound.
+func (r *MemoryRepository) Purge(ctx context.Context, userID, bookID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.trash[userID][bookID]; !ok {
+		return ErrNotFound
+	}
+	delete(r.trash[userID], bookID)
+	return nil
+}
+
+// History returns every recorded write to the user's book, newest first.
+func (r *MemoryRepository) History(ctx context.Context, userID, bookID string) ([]HistoryEntry, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var entries []HistoryEntry
+	for _, entry := range r.history[userID] {
+		if entry.BookID == bookID {
+			entries = append(entries, cloneHistoryEntry(entry))
+		}
+	}
+	slices.Reverse(entries)
+	return entries, nil
+}
+
+// AllHistory returns the history of every book the user has written to,
+// each book's entries newest first.
+func (r *MemoryRepository) AllHistory(ctx context.Context, userID string) ([]HistoryEntry, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	entries := make([]HistoryEntry, 0, len(r.history[userID]))
+	for _, entry := range r.history[userID] {
+		entries = append(entries, cloneHistoryEntry(entry))
+	}
+	slices.Reverse(entries)
+	return entries, nil
+}
+
+// checkVersion returns ErrNotFound if the book does not exist, or a
+// ConflictError if version is not nil and differs from the stored version.
+func (r *MemoryRepository) checkVersion(userID, bookID string, version *int) error {
+	current, ok := r.books[userID][bookID]
+	if !ok {
+		return ErrNotFound
+	}
+	if version != nil && current.Version != *version {
+		return &ConflictError{Current: cloneBook(current)}
+	}
+	return nil
+}
+
+// record appends entry to the user's history.
+func (r *MemoryRepository) record(userID string, entry HistoryEntry) {
+	r.history[userID] = append(r.history[userID], cloneHistoryEntry(entry))
+}
+
+func (r *MemoryRepository) store(userID string, book Book) Book {
+	book.SetKeys(userID)
+
+	if r.books[userID] == nil {
+		r.books[userID] = make(map[string]Book)
+	}
+	r.books[userID][book.ID] = cloneBook(book)
+	return book
+}
+
+// cloneBook copies book so callers cannot mutate stored state through the
+// shared Tags slice.
+func cloneBook(book Book) Book {
+	book.Tags = slices.Clone(book.Tags)
+	return book
+}
@@ -0,0 +1,82 @@
+package bookshelf
+
+import (
+	"slices"
+	"strings"
+)
+
+// statusProgress orders statuses by how far through a book they are.
+var statusProgress = map[string]int{
+	StatusWantToRead: 0,
+	StatusReading:    1,
+	StatusRead:       2,
+}
+
+// MergeBooks returns target with the details of source, a duplicate of it,
+// folded in. Tags are combined, reviews and comments are joined, the
+// furthest status and widest reading dates win, and target's other fields
+// are kept unless it has no value for them. The result keeps target's ID
+// and version.
+func MergeBooks(target, source Book) Book {
+	merged := target
+	merged.Tags = slices.Clone(target.Tags)
+	for _, tag := range source.Tags {
+		if !slices.Contains(merged.Tags, tag) {
+			merged.Tags = append(merged.Tags, tag)
+		}
+	}
+
+	merged.Review = joinText(target.Review, source.Review)
+	merged.Comments = joinText(target.Comments, source.Comments)
+
+	if statusProgress[source.Status] > statusProgress[target.Status] {
+		merged.Status = source.Status
+	}
+	merged.StartedAt = earliest(target.StartedAt, source.StartedAt)
+	merged.FinishedAt = latest(target.FinishedAt, source.FinishedAt)
+	merged.CreatedAt = earliest(target.CreatedAt, source.CreatedAt)
+
+	if merged.Rating == nil && source.Rating != nil {
+		rating := *source.Rating
+		merged.Rating = &rating
+	}
+	for _, field := range []struct{ into, from *string }{
+		{&merged.Series, &source.Series},
+		{&merged.Thumbnail, &source.Thumbnail},
+		{&merged.Type, &source.Type},
+		{&merged.VolumeID, &source.VolumeID},
+		{&merged.ISBN, &source.ISBN},
+	} {
+		if *field.into == "" {
+			*field.into = *field.from
+		}
+	}
+	return merged
+}
+
+// joinText combines two free-text fields, keeping both unless one is empty
+// or they are the same.
+func joinText(a, b string) string {
+	switch {
+	case strings.TrimSpace(b) == "" || a == b:
+		return a
+	case strings.TrimSpace(a) == "":
+		return b
+	}
+	return a + "\n\n" + b
+}
+
+// earliest and latest compare ISO 8601 dates, ignoring missing ones.
+func earliest(a, b string) string {
+	if a == "" || (b != "" && b < a) {
+		return b
+	}
+	return a
+}
+
+func latest(a, b string) string {
+	if b > a {
+		return b
+	}
+	return a
+}
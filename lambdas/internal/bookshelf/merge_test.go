@@ -0,0 +1,69 @@
+package bookshelf
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMergeBooks(t *testing.T) {
+	target := Book{
+		ID: "book-1", Version: 3, Title: "Dune", Author: "Frank Herbert", Status: StatusReading,
+		Tags: []string{"sci-fi", "desert"}, Review: "Slow start.", StartedAt: "2024-03-01",
+		CreatedAt: "2024-02-01T10:00:00Z", ISBN: "0441013597",
+	}
+
+	tests := []struct {
+		name   string
+		source Book
+		want   func(*Book) // changes target into the merged book
+	}{
+		{
+			name:   "nothing to add",
+			source: Book{ID: "book-2", Version: 1, Title: "Dune", Author: "Frank Herbert", Status: StatusWantToRead},
+			want:   func(b *Book) {},
+		},
+		{
+			name: "everything to add",
+			source: Book{
+				ID: "book-2", Version: 7, Title: "Dune (Deluxe)", Author: "F. Herbert", Status: StatusRead,
+				Rating: intPtr(9), Tags: []string{"desert", "classics"}, Review: "Worth it.", Comments: "Lent to Sam",
+				StartedAt: "2024-01-15", FinishedAt: "2024-04-02", CreatedAt: "2024-01-10T08:00:00Z",
+				Series: "Dune", Thumbnail: "https://books.example/dune.jpg", Type: "paperback",
+				VolumeID: "B1hSG45JCX4C", ISBN: "9780441172719",
+			},
+			want: func(b *Book) {
+				b.Status = StatusRead
+				b.Rating = intPtr(9)
+				b.Tags = []string{"sci-fi", "desert", "classics"}
+				b.Review = "Slow start.\n\nWorth it."
+				b.Comments = "Lent to Sam"
+				b.StartedAt, b.FinishedAt = "2024-01-15", "2024-04-02"
+				b.CreatedAt = "2024-01-10T08:00:00Z"
+				b.Series, b.Thumbnail, b.Type, b.VolumeID = "Dune", "https://books.example/dune.jpg", "paperback", "B1hSG45JCX4C"
+			},
+		},
+		{
+			name:   "same review",
+			source: Book{ID: "book-2", Review: "Slow start.", Comments: "  "},
+			want:   func(b *Book) {},
+		},
+		{
+			name:   "later start and a finish date",
+			source: Book{ID: "book-2", StartedAt: "2024-05-01", FinishedAt: "2023-01-01"},
+			want:   func(b *Book) { b.FinishedAt = "2023-01-01" },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := cloneBook(target)
+			tt.want(&want)
+			got := MergeBooks(target, tt.source)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("MergeBooks = %+v, want %+v", got, want)
+			}
+			if len(target.Tags) != 2 {
+				t.Errorf("MergeBooks changed the target's tags to %v", target.Tags)
+			}
+		})
+	}
+}
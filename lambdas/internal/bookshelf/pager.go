@@ -0,0 +1,106 @@
+package bookshelf
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+
+	"github.com/aws/smithy-go"
+)
+
+// DefaultPageSize is the number of books a BookPager requests per page.
+const DefaultPageSize = 100
+
+// throttlingCodes are the DynamoDB error codes that mean the request should
+// be retried after backing off.
+var throttlingCodes = map[string]bool{
+	"ProvisionedThroughputExceededException": true,
+	"RequestLimitExceeded":                   true,
+	"ThrottlingException":                    true,
+}
+
+// IsThrottlingError reports whether err is a DynamoDB throttling error that
+// is safe to retry.
+func IsThrottlingError(err error) bool {
+	var apiErr smithy.APIError
+	return errors.As(err, &apiErr) && throttlingCodes[apiErr.ErrorCode()]
+}
+
+// BookPager walks every page of a user's books. Pages that fail because
+// DynamoDB is throttling are retried with capped exponential backoff and
+// jitter, on top of the SDK's own retries, so callers that need the whole
+// library get all of it or an error, never a silently short list.
+type BookPager struct {
+	// MaxAttempts is the number of tries per page before giving up.
+	MaxAttempts int
+	// BaseDelay is the backoff before the first retry; it doubles on each
+	// following retry up to MaxDelay.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+
+	books  BookRepository
+	userID string
+	opts   PageOptions
+	done   bool
+}
+
+// NewBookPager returns a pager over the user's books matching opts.
+func NewBookPager(books BookRepository, userID string, opts ListOptions) *BookPager {
+	return &BookPager{
+		MaxAttempts: 5,
+		BaseDelay:   100 * time.Millisecond,
+		MaxDelay:    5 * time.Second,
+		books:       books,
+		userID:      userID,
+		opts:        PageOptions{ListOptions: opts, Limit: DefaultPageSize},
+	}
+}
+
+// HasMorePages reports whether NextPage has more books to return.
+func (p *BookPager) HasMorePages() bool {
+	return !p.done
+}
+
+// NextPage returns the next page of books.
+func (p *BookPager) NextPage(ctx context.Context) ([]Book, error) {
+	if p.done {
+		return nil, errors.New("no more pages")
+	}
+
+	delay := p.BaseDelay
+	for attempt := 1; ; attempt++ {
+		page, err := p.books.ListPage(ctx, p.userID, p.opts)
+		if err == nil {
+			p.opts.StartToken = page.NextToken
+			p.done = page.NextToken == ""
+			return page.Books, nil
+		}
+		if !IsThrottlingError(err) || attempt >= p.MaxAttempts {
+			return nil, err
+		}
+
+		// Full jitter keeps concurrent exports from retrying in lockstep.
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(rand.N(delay) + 1):
+		}
+		delay = min(delay*2, p.MaxDelay)
+	}
+}
+
+// ListAll returns every one of the user's books matching opts using a
+// BookPager.
+func ListAll(ctx context.Context, books BookRepository, userID string, opts ListOptions) ([]Book, error) {
+	var all []Book
+	pager := NewBookPager(books, userID, opts)
+	for pager.HasMorePages() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, page...)
+	}
+	return all, nil
+}
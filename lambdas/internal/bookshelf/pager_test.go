@@ -0,0 +1,110 @@
+package bookshelf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/smithy-go"
+)
+
+// flakyPages is a repository whose ListPage fails with errs, in order,
+// before listing each page.
+type flakyPages struct {
+	*MemoryRepository
+	errs  []error
+	calls int
+}
+
+func (r *flakyPages) ListPage(ctx context.Context, userID string, opts PageOptions) (Page, error) {
+	r.calls++
+	if len(r.errs) > 0 {
+		err := r.errs[0]
+		r.errs = r.errs[1:]
+		return Page{}, err
+	}
+	return r.MemoryRepository.ListPage(ctx, userID, opts)
+}
+
+func TestBookPager(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryRepository()
+	// More than two pages of books
+	for i := range 2*DefaultPageSize + 1 {
+		book := NewBook("user-1", fmt.Sprintf("book-%03d", i), APIBook{Title: "Dune", Author: "Frank Herbert", Status: StatusRead})
+		if err := repo.Put(ctx, "user-1", book); err != nil {
+			t.Fatal(err)
+		}
+	}
+	throttled := &smithy.GenericAPIError{Code: "ThrottlingException"}
+	denied := &smithy.GenericAPIError{Code: "AccessDeniedException"}
+
+	tests := []struct {
+		name      string
+		errs      []error
+		wantBooks int
+		wantCalls int
+		wantErr   error
+	}{
+		{
+			name:      "every page",
+			wantBooks: 2*DefaultPageSize + 1,
+			wantCalls: 3,
+		},
+		{
+			name:      "throttled",
+			errs:      []error{throttled, throttled},
+			wantBooks: 2*DefaultPageSize + 1,
+			wantCalls: 5,
+		},
+		{
+			name:      "throttled for every attempt",
+			errs:      []error{throttled, throttled, throttled},
+			wantCalls: 3,
+			wantErr:   throttled,
+		},
+		{
+			name:      "not retried",
+			errs:      []error{denied},
+			wantCalls: 1,
+			wantErr:   denied,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			books := &flakyPages{MemoryRepository: repo, errs: tt.errs}
+			pager := NewBookPager(books, "user-1", ListOptions{})
+			pager.MaxAttempts, pager.BaseDelay = 3, time.Millisecond
+			var all []Book
+			var err error
+			for pager.HasMorePages() {
+				var page []Book
+				if page, err = pager.NextPage(ctx); err != nil {
+					break
+				}
+				all = append(all, page...)
+			}
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("NextPage error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && len(all) != tt.wantBooks {
+				t.Errorf("%d books, want %d", len(all), tt.wantBooks)
+			}
+			if books.calls != tt.wantCalls {
+				t.Errorf("ListPage called %d times, want %d", books.calls, tt.wantCalls)
+			}
+		})
+	}
+
+	// A cancelled caller stops waiting to retry
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	pager := NewBookPager(&flakyPages{MemoryRepository: repo, errs: []error{throttled}}, "user-1", ListOptions{})
+	pager.BaseDelay = time.Hour
+	if _, err := pager.NextPage(cancelled); !errors.Is(err, context.Canceled) {
+		t.Errorf("NextPage error = %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,565 @@
+package bookshelf
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Media types accepted by PATCH /books/{id}.
+const (
+	MergePatchContentType = "application/merge-patch+json"
+	JSONPatchContentType  = "application/json-patch+json"
+)
+
+// PatchError reports a patch that is valid JSON but cannot be applied to a
+// book. Its message is safe to show to clients.
+type PatchError struct {
+	msg string
+}
+
+func (e *PatchError) Error() string {
+	return e.msg
+}
+
+func patchErrorf(format string, args ...interface{}) error {
+	return &PatchError{msg: fmt.Sprintf(format, args...)}
+}
+
+// ErrPatchTestFailed is returned, wrapped in a TestFailedError, when a JSON
+// Patch "test" operation does not hold against the stored book.
+var ErrPatchTestFailed = errors.New("patch test failed")
+
+// TestFailedError reports a failed JSON Patch test and carries the book as
+// currently stored.
+type TestFailedError struct {
+	Current Book
+}
+
+func (e *TestFailedError) Error() string {
+	return ErrPatchTestFailed.Error()
+}
+
+func (e *TestFailedError) Unwrap() error {
+	return ErrPatchTestFailed
+}
+
+type fieldKind int
+
+const (
+	stringField fieldKind = iota
+	ratingField
+	tagsField
+)
+
+type patchField struct {
+	attr     string // DynamoDB attribute name
+	kind     fieldKind
+	required bool
+}
+
+// patchFields are the API fields a patch may change, keyed by JSON name.
+var patchFields = map[string]patchField{
+	"title":       {attr: "Title", kind: stringField, required: true},
+	"author":      {attr: "Author", kind: stringField, required: true},
+	"series":      {attr: "Series", kind: stringField},
+	"status":      {attr: "status", kind: stringField, required: true},
+	"rating":      {attr: "rating", kind: ratingField},
+	"review":      {attr: "review", kind: stringField},
+	"tags":        {attr: "tags", kind: tagsField},
+	"started_at":  {attr: "started_at", kind: stringField},
+	"finished_at": {attr: "finished_at", kind: stringField},
+	"thumbnail":   {attr: "thumbnail", kind: stringField},
+	"type":        {attr: "type", kind: stringField},
+	"comments":    {attr: "comments", kind: stringField},
+	"isbn":        {attr: "isbn", kind: stringField},
+
+	"google_volume_id": {attr: "google_volume_id", kind: stringField},
+}
+
+// readOnlyFields are API fields that only the server sets.
+var readOnlyFields = map[string]bool{"id": true, "version": true, "created_at": true}
+
+// Patch is a validated set of changes to one book, built by ParseMergePatch
+// or ParseJSONPatch. Every operation is evaluated against the stored book and
+// applied atomically, so a patch touches each field at most once, changes at
+// most one tag by index, and tests the stored book before changing it.
+type Patch struct {
+	set        map[string]interface{} // field -> string, int or []string
+	remove     map[string]bool
+	appendTags []string
+	setTags    map[int]string
+	removeTags map[int]bool
+	tests      []patchTest
+}
+
+// patchTest is a condition the stored book must meet for the patch to apply.
+type patchTest struct {
+	field string
+	index int         // tag index, or -1 for the whole field
+	value interface{} // expected value; nil means absent
+	// exists only requires the tag at index to exist.
+	exists bool
+}
+
+func newPatch() Patch {
+	return Patch{
+		set:        map[string]interface{}{},
+		remove:     map[string]bool{},
+		setTags:    map[int]string{},
+		removeTags: map[int]bool{},
+	}
+}
+
+// Empty reports whether the patch changes nothing. It may still have tests,
+// which TestsPass evaluates.
+func (p Patch) Empty() bool {
+	return len(p.set) == 0 && len(p.remove) == 0 && len(p.appendTags) == 0 &&
+		len(p.setTags) == 0 && len(p.removeTags) == 0
+}
+
+// ParseMergePatch parses a JSON Merge Patch (RFC 7386). A null member
+// removes the field; any other value replaces it.
+func ParseMergePatch(body []byte) (Patch, error) {
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(body, &members); err != nil {
+		return Patch{}, err
+	}
+	if members == nil {
+		return Patch{}, patchErrorf("Merge patch must be a JSON object")
+	}
+
+	p := newPatch()
+	for name, raw := range members {
+		field, err := lookupField(name)
+		if err != nil {
+			return Patch{}, err
+		}
+		if isNull(raw) {
+			if err := p.removeField(name, field); err != nil {
+				return Patch{}, err
+			}
+			continue
+		}
+		if err := p.setField(name, field, raw); err != nil {
+			return Patch{}, err
+		}
+	}
+	return p, nil
+}
+
+// jsonPatchOp is one operation of a JSON Patch document.
+type jsonPatchOp struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value"`
+}
+
+// ParseJSONPatch parses a JSON Patch (RFC 6902). It supports add, remove,
+// replace and test on top-level fields, "add" to /tags/- to append a tag, and
+// remove, replace and test on /tags/<index>.
+func ParseJSONPatch(body []byte) (Patch, error) {
+	var ops []jsonPatchOp
+	if err := json.Unmarshal(body, &ops); err != nil {
+		return Patch{}, err
+	}
+
+	p := newPatch()
+	touched := map[string]bool{}
+	changed := false
+	for i, op := range ops {
+		name, index, err := parsePath(op.Path)
+		if err != nil {
+			return Patch{}, err
+		}
+		field, err := lookupField(name)
+		if err != nil {
+			return Patch{}, err
+		}
+		if index != "" && field.kind != tagsField {
+			return Patch{}, patchErrorf("Invalid path %q: only tags has elements", op.Path)
+		}
+		needsValue := op.Op == "add" || op.Op == "replace" || op.Op == "test"
+		if needsValue && op.Value == nil {
+			return Patch{}, patchErrorf("Operation %d (%s %s) requires a value", i, op.Op, op.Path)
+		}
+
+		// Operations apply together against the stored book, so a sequence
+		// whose operations depend on the ones before it is rejected rather
+		// than applied differently from RFC 6902.
+		if op.Op == "test" && changed {
+			return Patch{}, patchErrorf("Operation %d (test %s) must come before the operations that change the book", i, op.Path)
+		}
+		if op.Op != "test" {
+			changed = true
+			key := name
+			if index != "" && index != "-" {
+				key = name + "/" + index
+			}
+			if touched[key] || (index == "" && touched[name+"/*"]) || (index != "" && touched[name]) {
+				return Patch{}, patchErrorf("Path %s is changed more than once", op.Path)
+			}
+			// Removing a tag shifts the ones after it
+			if index != "" && index != "-" && touched[name+"/#"] {
+				return Patch{}, patchErrorf("Only one tag can be changed by index in a patch")
+			}
+			if index != "-" {
+				touched[key] = true
+			}
+			if index != "" {
+				touched[name+"/*"] = true
+			}
+			if index != "" && index != "-" {
+				touched[name+"/#"] = true
+			}
+		}
+
+		switch {
+		case op.Op == "test" && index == "":
+			value, err := decodeTestValue(field, op.Value)
+			if err != nil {
+				return Patch{}, err
+			}
+			p.tests = append(p.tests, patchTest{field: name, index: -1, value: value})
+
+		case op.Op == "test":
+			n, err := tagIndex(op.Path, index)
+			if err != nil {
+				return Patch{}, err
+			}
+			var tag string
+			if err := json.Unmarshal(op.Value, &tag); err != nil {
+				return Patch{}, patchErrorf("Tags must be strings")
+			}
+			p.tests = append(p.tests, patchTest{field: name, index: n, value: tag})
+
+		case (op.Op == "add" || op.Op == "replace") && index == "":
+			if isNull(op.Value) {
+				if err := p.removeField(name, field); err != nil {
+					return Patch{}, err
+				}
+				continue
+			}
+			if err := p.setField(name, field, op.Value); err != nil {
+				return Patch{}, err
+			}
+
+		case op.Op == "add" && index == "-":
+			tag, err := decodeTag(op.Value)
+			if err != nil {
+				return Patch{}, err
+			}
+			p.appendTags = append(p.appendTags, tag)
+
+		case op.Op == "add":
+			return Patch{}, patchErrorf("Inserting into tags is not supported; add to /tags/- to append")
+
+		case op.Op == "replace" && index != "-":
+			n, err := tagIndex(op.Path, index)
+			if err != nil {
+				return Patch{}, err
+			}
+			tag, err := decodeTag(op.Value)
+			if err != nil {
+				return Patch{}, err
+			}
+			p.setTags[n] = tag
+			p.tests = append(p.tests, patchTest{field: name, index: n, exists: true})
+
+		case op.Op == "remove" && index == "":
+			if err := p.removeField(name, field); err != nil {
+				return Patch{}, err
+			}
+
+		case op.Op == "remove" && index != "-":
+			n, err := tagIndex(op.Path, index)
+			if err != nil {
+				return Patch{}, err
+			}
+			p.removeTags[n] = true
+			p.tests = append(p.tests, patchTest{field: name, index: n, exists: true})
+
+		case op.Op == "move" || op.Op == "copy":
+			return Patch{}, patchErrorf("Unsupported operation %q. Supported operations: add, remove, replace, test", op.Op)
+
+		default:
+			return Patch{}, patchErrorf("Invalid operation %q at %s", op.Op, op.Path)
+		}
+	}
+
+	// DynamoDB cannot append to a list and change its elements in one update.
+	if len(p.appendTags) > 0 && (len(p.setTags) > 0 || len(p.removeTags) > 0) {
+		return Patch{}, patchErrorf("Tags cannot be appended to and changed by index in the same patch")
+	}
+	return p, nil
+}
+
+// parsePath splits a JSON Pointer into a field name and an optional element.
+func parsePath(path string) (name, index string, err error) {
+	parts := strings.Split(path, "/")
+	if len(parts) < 2 || len(parts) > 3 || parts[0] != "" {
+		return "", "", patchErrorf("Invalid path %q", path)
+	}
+	if len(parts) == 3 {
+		return parts[1], parts[2], nil
+	}
+	return parts[1], "", nil
+}
+
+func tagIndex(path, index string) (int, error) {
+	n, err := strconv.Atoi(index)
+	if err != nil || n < 0 || (len(index) > 1 && index[0] == '0') {
+		return 0, patchErrorf("Invalid path %q: expected a tag index", path)
+	}
+	return n, nil
+}
+
+func lookupField(name string) (patchField, error) {
+	if readOnlyFields[name] {
+		return patchField{}, patchErrorf("Field %s cannot be changed", name)
+	}
+	field, ok := patchFields[name]
+	if !ok {
+		return patchField{}, patchErrorf("Unknown field %s", name)
+	}
+	return field, nil
+}
+
+func (p *Patch) removeField(name string, field patchField) error {
+	if field.required {
+		return patchErrorf("Field %s cannot be removed", name)
+	}
+	p.remove[name] = true
+	return nil
+}
+
+// setField validates and records a new value for a field. Empty strings and
+// empty tag lists remove the field, matching how books are stored.
+func (p *Patch) setField(name string, field patchField, raw json.RawMessage) error {
+	switch field.kind {
+	case stringField:
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return patchErrorf("Field %s must be a string", name)
+		}
+		if s == "" {
+			return p.removeField(name, field)
+		}
+		if name == "status" && !ValidStatus(s) {
+			return &PatchError{msg: InvalidStatusMessage}
+		}
+		p.set[name] = s
+
+	case ratingField:
+		var n int
+		if err := json.Unmarshal(raw, &n); err != nil || n < MinRating || n > MaxRating {
+			return patchErrorf("Field rating must be an integer between %d and %d", MinRating, MaxRating)
+		}
+		p.set[name] = n
+
+	case tagsField:
+		var tags []string
+		if err := json.Unmarshal(raw, &tags); err != nil {
+			return patchErrorf("Field tags must be an array of strings")
+		}
+		if len(tags) == 0 {
+			return p.removeField(name, field)
+		}
+		p.set[name] = tags
+	}
+	return nil
+}
+
+// decodeTestValue decodes the expected value of a whole-field test. Null,
+// empty strings and empty lists all mean the field is absent.
+func decodeTestValue(field patchField, raw json.RawMessage) (interface{}, error) {
+	if isNull(raw) {
+		return nil, nil
+	}
+	switch field.kind {
+	case ratingField:
+		var n int
+		if err := json.Unmarshal(raw, &n); err != nil {
+			return nil, patchErrorf("Field rating must be an integer")
+		}
+		return n, nil
+	case tagsField:
+		var tags []string
+		if err := json.Unmarshal(raw, &tags); err != nil {
+			return nil, patchErrorf("Field tags must be an array of strings")
+		}
+		if len(tags) == 0 {
+			return nil, nil
+		}
+		return tags, nil
+	default:
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return nil, patchErrorf("Expected a string")
+		}
+		if s == "" {
+			return nil, nil
+		}
+		return s, nil
+	}
+}
+
+func decodeTag(raw json.RawMessage) (string, error) {
+	var tag string
+	if err := json.Unmarshal(raw, &tag); err != nil || tag == "" {
+		return "", patchErrorf("Tags must be non-empty strings")
+	}
+	return tag, nil
+}
+
+func isNull(raw json.RawMessage) bool {
+	return bytes.Equal(bytes.TrimSpace(raw), []byte("null"))
+}
+
+// TestsPass reports whether every test in the patch holds for book.
+func (p Patch) TestsPass(book Book) bool {
+	api := book.ToAPI()
+	for _, t := range p.tests {
+		if t.index >= 0 {
+			if t.index >= len(book.Tags) || (!t.exists && book.Tags[t.index] != t.value) {
+				return false
+			}
+			continue
+		}
+		if !equalFieldValue(fieldValue(api, t.field), t.value) {
+			return false
+		}
+	}
+	return true
+}
+
+// Apply returns book with the patch applied. It does not check the tests.
+func (p Patch) Apply(book Book) Book {
+	api := book.ToAPI()
+	for name := range p.remove {
+		setFieldValue(&api, name, nil)
+	}
+	for name, value := range p.set {
+		setFieldValue(&api, name, value)
+	}
+
+	tags := slices.Clone(api.Tags)
+	for i, tag := range p.setTags {
+		if i < len(tags) {
+			tags[i] = tag
+		} else {
+			tags = append(tags, tag)
+		}
+	}
+	if len(p.removeTags) > 0 {
+		kept := tags[:0]
+		for i, tag := range tags {
+			if !p.removeTags[i] {
+				kept = append(kept, tag)
+			}
+		}
+		tags = kept
+	}
+	tags = append(tags, p.appendTags...)
+	if len(tags) == 0 {
+		tags = nil
+	}
+	api.Tags = tags
+
+	patched := book
+	patched.Title = api.Title
+	patched.Author = api.Author
+	patched.Series = api.Series
+	patched.Status = api.Status
+	patched.Rating = api.Rating
+	patched.Review = api.Review
+	patched.Tags = api.Tags
+	patched.StartedAt = api.StartedAt
+	patched.FinishedAt = api.FinishedAt
+	patched.Thumbnail = api.Thumbnail
+	patched.Type = api.Type
+	patched.Comments = api.Comments
+	patched.ISBN = api.ISBN
+	patched.VolumeID = api.VolumeID
+	return patched
+}
+
+// fieldValue returns a patchable field of book as the patch represents it,
+// or nil when the field is absent.
+func fieldValue(book APIBook, name string) interface{} {
+	switch name {
+	case "rating":
+		if book.Rating == nil {
+			return nil
+		}
+		return *book.Rating
+	case "tags":
+		if len(book.Tags) == 0 {
+			return nil
+		}
+		return book.Tags
+	}
+	if s := *stringFieldPtr(&book, name); s != "" {
+		return s
+	}
+	return nil
+}
+
+// setFieldValue sets a patchable field of book; nil clears it.
+func setFieldValue(book *APIBook, name string, value interface{}) {
+	switch name {
+	case "rating":
+		book.Rating = nil
+		if n, ok := value.(int); ok {
+			book.Rating = &n
+		}
+	case "tags":
+		book.Tags, _ = value.([]string)
+	default:
+		s, _ := value.(string)
+		*stringFieldPtr(book, name) = s
+	}
+}
+
+func stringFieldPtr(book *APIBook, name string) *string {
+	switch name {
+	case "title":
+		return &book.Title
+	case "author":
+		return &book.Author
+	case "series":
+		return &book.Series
+	case "status":
+		return &book.Status
+	case "review":
+		return &book.Review
+	case "started_at":
+		return &book.StartedAt
+	case "finished_at":
+		return &book.FinishedAt
+	case "thumbnail":
+		return &book.Thumbnail
+	case "type":
+		return &book.Type
+	case "comments":
+		return &book.Comments
+	case "isbn":
+		return &book.ISBN
+	case "google_volume_id":
+		return &book.VolumeID
+	}
+	panic("bookshelf: unknown patch field " + name)
+}
+
+func equalFieldValue(a, b interface{}) bool {
+	as, aok := a.([]string)
+	bs, bok := b.([]string)
+	if aok || bok {
+		return aok && bok && slices.Equal(as, bs)
+	}
+	return a == b
+}
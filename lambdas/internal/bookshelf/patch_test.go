@@ -0,0 +1,420 @@
+package bookshelf
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// patchedBook is the book the patch tests apply patches to.
+func patchedBook() Book {
+	return NewBook("user-1", "book-1", APIBook{
+		Title:  "Dune",
+		Author: "Frank Herbert",
+		Series: "Dune Chronicles",
+		Status: StatusRead,
+		Rating: intPtr(8),
+		Review: "Spice.",
+		Tags:   []string{"a", "b", "c"},
+	})
+}
+
+func TestPatch(t *testing.T) {
+	const merge, jsonPatch = MergePatchContentType, JSONPatchContentType
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		// want changes the stored book into the patched one.
+		want       func(b *APIBook)
+		wantErr    string // contained in the PatchError
+		testFailed bool
+	}{
+		{
+			name:        "merge sets fields",
+			contentType: merge,
+			body:        `{"rating":10,"status":"READING","tags":["x"],"started_at":"2025-01-02"}`,
+			want: func(b *APIBook) {
+				b.Rating, b.Status, b.Tags, b.StartedAt = intPtr(10), StatusReading, []string{"x"}, "2025-01-02"
+			},
+		},
+		{
+			name:        "merge null removes a field",
+			contentType: merge,
+			body:        `{"series":null,"rating":null,"tags":null}`,
+			want:        func(b *APIBook) { b.Series, b.Rating, b.Tags = "", nil, nil },
+		},
+		{
+			name:        "merge empty values remove a field",
+			contentType: merge,
+			body:        `{"review":"","tags":[]}`,
+			want:        func(b *APIBook) { b.Review, b.Tags = "", nil },
+		},
+		{
+			name:        "merge empty object",
+			contentType: merge,
+			body:        `{}`,
+			want:        func(b *APIBook) {},
+		},
+		{
+			name:        "merge null document",
+			contentType: merge,
+			body:        `null`,
+			wantErr:     "Merge patch must be a JSON object",
+		},
+		{
+			name:        "merge removes a required field",
+			contentType: merge,
+			body:        `{"title":null}`,
+			wantErr:     "Field title cannot be removed",
+		},
+		{
+			name:        "merge empties a required field",
+			contentType: merge,
+			body:        `{"author":""}`,
+			wantErr:     "Field author cannot be removed",
+		},
+		{
+			name:        "merge unknown field",
+			contentType: merge,
+			body:        `{"colour":"red"}`,
+			wantErr:     "Unknown field colour",
+		},
+		{
+			name:        "merge read-only field",
+			contentType: merge,
+			body:        `{"version":9}`,
+			wantErr:     "Field version cannot be changed",
+		},
+		{
+			name:        "merge rating out of range",
+			contentType: merge,
+			body:        `{"rating":11}`,
+			wantErr:     "between 1 and 10",
+		},
+		{
+			name:        "merge invalid status",
+			contentType: merge,
+			body:        `{"status":"DONE"}`,
+			wantErr:     InvalidStatusMessage,
+		},
+		{
+			name:        "merge tags of the wrong type",
+			contentType: merge,
+			body:        `{"tags":"a"}`,
+			wantErr:     "Field tags must be an array of strings",
+		},
+		{
+			name:        "replace and remove fields",
+			contentType: jsonPatch,
+			body:        `[{"op":"replace","path":"/rating","value":3},{"op":"add","path":"/type","value":"kindle"},{"op":"remove","path":"/review"}]`,
+			want:        func(b *APIBook) { b.Rating, b.Type, b.Review = intPtr(3), "kindle", "" },
+		},
+		{
+			name:        "replace with null removes a field",
+			contentType: jsonPatch,
+			body:        `[{"op":"replace","path":"/series","value":null}]`,
+			want:        func(b *APIBook) { b.Series = "" },
+		},
+		{
+			name:        "append tags",
+			contentType: jsonPatch,
+			body:        `[{"op":"add","path":"/tags/-","value":"d"},{"op":"add","path":"/tags/-","value":"e"}]`,
+			want:        func(b *APIBook) { b.Tags = []string{"a", "b", "c", "d", "e"} },
+		},
+		{
+			name:        "replace a tag",
+			contentType: jsonPatch,
+			body:        `[{"op":"replace","path":"/tags/1","value":"B"}]`,
+			want:        func(b *APIBook) { b.Tags = []string{"a", "B", "c"} },
+		},
+		{
+			name:        "remove a tag",
+			contentType: jsonPatch,
+			body:        `[{"op":"remove","path":"/tags/0"}]`,
+			want:        func(b *APIBook) { b.Tags = []string{"b", "c"} },
+		},
+		{
+			name:        "replace a tag out of range",
+			contentType: jsonPatch,
+			body:        `[{"op":"replace","path":"/tags/3","value":"d"}]`,
+			testFailed:  true,
+		},
+		{
+			name:        "remove a tag out of range",
+			contentType: jsonPatch,
+			body:        `[{"op":"remove","path":"/tags/7"}]`,
+			testFailed:  true,
+		},
+		{
+			name:        "tests pass",
+			contentType: jsonPatch,
+			body: `[{"op":"test","path":"/status","value":"READ"},{"op":"test","path":"/rating","value":8},` +
+				`{"op":"test","path":"/tags","value":["a","b","c"]},{"op":"test","path":"/tags/1","value":"b"},` +
+				`{"op":"test","path":"/comments","value":null},{"op":"replace","path":"/status","value":"READING"}]`,
+			want: func(b *APIBook) { b.Status = StatusReading },
+		},
+		{
+			name:        "field test fails",
+			contentType: jsonPatch,
+			body:        `[{"op":"test","path":"/status","value":"READING"},{"op":"replace","path":"/rating","value":2}]`,
+			testFailed:  true,
+		},
+		{
+			name:        "absent field test fails",
+			contentType: jsonPatch,
+			body:        `[{"op":"test","path":"/series","value":null}]`,
+			testFailed:  true,
+		},
+		{
+			name:        "tag test fails",
+			contentType: jsonPatch,
+			body:        `[{"op":"test","path":"/tags/0","value":"b"}]`,
+			testFailed:  true,
+		},
+		{
+			name:        "tag test out of range fails",
+			contentType: jsonPatch,
+			body:        `[{"op":"test","path":"/tags/3","value":"d"}]`,
+			testFailed:  true,
+		},
+		{
+			name:        "same field changed twice",
+			contentType: jsonPatch,
+			body:        `[{"op":"replace","path":"/rating","value":2},{"op":"remove","path":"/rating"}]`,
+			wantErr:     "Path /rating is changed more than once",
+		},
+		{
+			name:        "same tag changed twice",
+			contentType: jsonPatch,
+			body:        `[{"op":"replace","path":"/tags/0","value":"x"},{"op":"remove","path":"/tags/0"}]`,
+			wantErr:     "Path /tags/0 is changed more than once",
+		},
+		{
+			name:        "tags and a tag changed",
+			contentType: jsonPatch,
+			body:        `[{"op":"remove","path":"/tags/0"},{"op":"replace","path":"/tags","value":["x"]}]`,
+			wantErr:     "Path /tags is changed more than once",
+		},
+		{
+			name:        "tags appended to and changed by index",
+			contentType: jsonPatch,
+			body:        `[{"op":"add","path":"/tags/-","value":"d"},{"op":"remove","path":"/tags/0"}]`,
+			wantErr:     "Tags cannot be appended to and changed by index in the same patch",
+		},
+		{
+			// RFC 6902 removes the stored tags 0 and 2, as the first removal
+			// shifts the list
+			name:        "tags removed by index twice",
+			contentType: jsonPatch,
+			body:        `[{"op":"remove","path":"/tags/0"},{"op":"remove","path":"/tags/1"}]`,
+			wantErr:     "Only one tag can be changed by index in a patch",
+		},
+		{
+			name:        "tags replaced and removed by index",
+			contentType: jsonPatch,
+			body:        `[{"op":"replace","path":"/tags/2","value":"C"},{"op":"remove","path":"/tags/0"}]`,
+			wantErr:     "Only one tag can be changed by index in a patch",
+		},
+		{
+			// RFC 6902 tests the replaced title, which would pass
+			name:        "test after a change",
+			contentType: jsonPatch,
+			body:        `[{"op":"replace","path":"/title","value":"X"},{"op":"test","path":"/title","value":"X"}]`,
+			wantErr:     "Operation 1 (test /title) must come before the operations that change the book",
+		},
+		{
+			name:        "insert a tag",
+			contentType: jsonPatch,
+			body:        `[{"op":"add","path":"/tags/0","value":"z"}]`,
+			wantErr:     "Inserting into tags is not supported",
+		},
+		{
+			name:        "invalid tag index",
+			contentType: jsonPatch,
+			body:        `[{"op":"remove","path":"/tags/01"}]`,
+			wantErr:     `Invalid path "/tags/01": expected a tag index`,
+		},
+		{
+			name:        "negative tag index",
+			contentType: jsonPatch,
+			body:        `[{"op":"replace","path":"/tags/-1","value":"x"}]`,
+			wantErr:     `Invalid path "/tags/-1": expected a tag index`,
+		},
+		{
+			name:        "unknown path",
+			contentType: jsonPatch,
+			body:        `[{"op":"test","path":"/colour","value":"red"}]`,
+			wantErr:     "Unknown field colour",
+		},
+		{
+			name:        "element of a string field",
+			contentType: jsonPatch,
+			body:        `[{"op":"replace","path":"/title/0","value":"X"}]`,
+			wantErr:     "only tags has elements",
+		},
+		{
+			name:        "path without a slash",
+			contentType: jsonPatch,
+			body:        `[{"op":"replace","path":"title","value":"X"}]`,
+			wantErr:     `Invalid path "title"`,
+		},
+		{
+			name:        "path too deep",
+			contentType: jsonPatch,
+			body:        `[{"op":"remove","path":"/tags/0/x"}]`,
+			wantErr:     `Invalid path "/tags/0/x"`,
+		},
+		{
+			name:        "read-only path",
+			contentType: jsonPatch,
+			body:        `[{"op":"replace","path":"/id","value":"x"}]`,
+			wantErr:     "Field id cannot be changed",
+		},
+		{
+			name:        "missing value",
+			contentType: jsonPatch,
+			body:        `[{"op":"replace","path":"/rating"}]`,
+			wantErr:     "Operation 0 (replace /rating) requires a value",
+		},
+		{
+			name:        "move",
+			contentType: jsonPatch,
+			body:        `[{"op":"move","from":"/series","path":"/comments"}]`,
+			wantErr:     `Unsupported operation "move"`,
+		},
+		{
+			name:        "unknown operation",
+			contentType: jsonPatch,
+			body:        `[{"op":"upsert","path":"/rating","value":1}]`,
+			wantErr:     `Invalid operation "upsert" at /rating`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parse := ParseJSONPatch
+			if tt.contentType == MergePatchContentType {
+				parse = ParseMergePatch
+			}
+			p, err := parse([]byte(tt.body))
+			if tt.wantErr != "" {
+				var patchErr *PatchError
+				if !errors.As(err, &patchErr) || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("parse = %v, want a PatchError containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			book := patchedBook()
+			if passed := p.TestsPass(book); passed == tt.testFailed {
+				t.Fatalf("TestsPass = %v, want %v", passed, !tt.testFailed)
+			}
+			if tt.testFailed {
+				return
+			}
+			want := book.ToAPI()
+			tt.want(&want)
+			if got := p.Apply(book).ToAPI(); !reflect.DeepEqual(got, want) {
+				t.Errorf("Apply =\n%+v\nwant\n%+v", got, want)
+			}
+			if got, want := p.Empty(), reflect.DeepEqual(want, book.ToAPI()); got != want {
+				t.Errorf("Empty = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestPatchExpressions(t *testing.T) {
+	p, err := ParseJSONPatch([]byte(`[
+		{"op":"test","path":"/status","value":"READ"},
+		{"op":"test","path":"/comments","value":null},
+		{"op":"replace","path":"/status","value":"READING"},
+		{"op":"replace","path":"/rating","value":9},
+		{"op":"remove","path":"/series"},
+		{"op":"replace","path":"/tags/1","value":"B"}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	patched := p.Apply(patchedBook())
+	patched.SetKeys("user-1")
+
+	e := newExpression()
+	update, err := p.updateExpression(e, patched)
+	if err != nil {
+		t.Fatal(err)
+	}
+	condition, err := p.conditionExpression(e, intPtr(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantUpdate := "SET #rating = :v0, #status = :v1, #tags[1] = :v2, #version = if_not_exists(#version, :v3) + :v4, " +
+		"#GSI1PK = :v5, #GSI1SK = :v6, #GSI2PK = :v7, #GSI2SK = :v8 REMOVE #Series"
+	if update != wantUpdate {
+		t.Errorf("update =\n%s\nwant\n%s", update, wantUpdate)
+	}
+	wantCondition := "attribute_exists(PK) AND #version = :v9 AND #status = :v10 AND attribute_not_exists(#comments) AND " +
+		"attribute_exists(#tags[1])"
+	if condition != wantCondition {
+		t.Errorf("condition =\n%s\nwant\n%s", condition, wantCondition)
+	}
+
+	wantNames := map[string]string{
+		"#rating": "rating", "#status": "status", "#tags": "tags", "#version": "version", "#Series": "Series",
+		"#comments": "comments", "#GSI1PK": "GSI1PK", "#GSI1SK": "GSI1SK", "#GSI2PK": "GSI2PK", "#GSI2SK": "GSI2SK",
+	}
+	if !reflect.DeepEqual(e.names, wantNames) {
+		t.Errorf("names = %v, want %v", e.names, wantNames)
+	}
+	s := func(v string) types.AttributeValue { return &types.AttributeValueMemberS{Value: v} }
+	n := func(v string) types.AttributeValue { return &types.AttributeValueMemberN{Value: v} }
+	wantValues := map[string]types.AttributeValue{
+		":v0":  n("9"),
+		":v1":  s(StatusReading),
+		":v2":  s("B"),
+		":v3":  n("0"),
+		":v4":  n("1"),
+		":v5":  s(patched.GSI1PK),
+		":v6":  s(patched.GSI1SK),
+		":v7":  s(patched.GSI2PK),
+		":v8":  s(patched.GSI2SK),
+		":v9":  n("3"),
+		":v10": s(StatusRead),
+	}
+	if !reflect.DeepEqual(e.values, wantValues) {
+		t.Errorf("values = %#v, want %#v", e.values, wantValues)
+	}
+	// The index keys follow the patched status
+	if !strings.HasPrefix(patched.GSI1SK, StatusKeyPrefix(StatusReading)) {
+		t.Errorf("GSI1SK = %q, want it under %q", patched.GSI1SK, StatusKeyPrefix(StatusReading))
+	}
+}
+
+func TestPatchAppendExpression(t *testing.T) {
+	p, err := ParseJSONPatch([]byte(`[{"op":"add","path":"/tags/-","value":"d"},{"op":"remove","path":"/review"}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := newExpression()
+	update, err := p.updateExpression(e, patchedBook())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "SET #tags = list_append(if_not_exists(#tags, :v0), :v1), #version = if_not_exists(#version, :v2) + :v3, " +
+		"#GSI1PK = :v4, #GSI1SK = :v5, #GSI2PK = :v6, #GSI2SK = :v7 REMOVE #review"
+	if update != want {
+		t.Errorf("update =\n%s\nwant\n%s", update, want)
+	}
+	wantList := &types.AttributeValueMemberL{Value: []types.AttributeValue{&types.AttributeValueMemberS{Value: "d"}}}
+	if !reflect.DeepEqual(e.values[":v1"], wantList) {
+		t.Errorf(":v1 = %#v, want %#v", e.values[":v1"], wantList)
+	}
+}
@@ -0,0 +1,135 @@
+package bookshelf
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrNotFound is returned when a book does not exist for the given user.
+var ErrNotFound = errors.New("book not found")
+
+// ErrInvalidToken is returned by ListPage for a malformed start token.
+var ErrInvalidToken = errors.New("invalid page token")
+
+// ListOptions narrows the books returned by BookRepository.List. Zero
+// fields do not filter. See ParseListOptions for the matching query
+// parameters.
+type ListOptions struct {
+	// Status, when set, only returns books with this reading status.
+	Status string
+	// Tag only returns books carrying this tag.
+	Tag string
+	// Author, Series and Type only return books with exactly this value.
+	Author string
+	Series string
+	Type   string
+	// MinRating and MaxRating bound the rating, inclusive. Unrated books
+	// are excluded when either is set.
+	MinRating int
+	MaxRating int
+	// StartedFrom, StartedTo, FinishedFrom and FinishedTo bound the
+	// started_at and finished_at dates, inclusive, as YYYY-MM-DD.
+	StartedFrom  string
+	StartedTo    string
+	FinishedFrom string
+	FinishedTo   string
+	// TitleContains only returns books whose title contains this text,
+	// ignoring case.
+	TitleContains string
+}
+
+// PageOptions selects one page of books for BookRepository.ListPage.
+type PageOptions struct {
+	ListOptions
+	// Limit is the maximum number of books in the page. It must be positive.
+	Limit int
+	// StartToken is the NextToken of the previous page, or empty for the
+	// first page.
+	StartToken string
+}
+
+// Page is one page of a user's books.
+type Page struct {
+	Books []Book
+	// NextToken resumes the listing after this page. It is empty on the last
+	// page. Tokens are repository-specific and not safe to hand to clients
+	// as-is; see CursorCodec.
+	NextToken string
+}
+
+// BookRepository stores books. Every operation is scoped to a single user so
+// one user can never read or modify another user's books. Every write to a
+// book also records a HistoryEntry, atomically with the write, carrying the
+// request ID and action from its context (see WithRequestID).
+type BookRepository interface {
+	// Get returns the user's book with the given ID, or ErrNotFound.
+	Get(ctx context.Context, userID, bookID string) (Book, error)
+	// List returns all of the user's books in index order: by status date
+	// when filtering by status, by author when filtering by author, and by
+	// ID otherwise.
+	List(ctx context.Context, userID string, opts ListOptions) ([]Book, error)
+	// ListPage returns up to opts.Limit of the user's books in the same
+	// order as List, starting after opts.StartToken. It returns ErrInvalidToken if the token
+	// was not produced by this repository.
+	ListPage(ctx context.Context, userID string, opts PageOptions) (Page, error)
+	// ListByAuthor returns the user's books by author in AuthorIndex order.
+	// Unlike List with an Author filter, authors are matched as
+	// NormalizeAuthor writes them, and only that author's books are read.
+	ListByAuthor(ctx context.Context, userID, author string) ([]Book, error)
+	// Put stores a new book for the user, overwriting any book with the same
+	// ID. A zero Version is stored as 1.
+	Put(ctx context.Context, userID string, book Book) error
+	// PutBatch stores new books for the user like Put, many to a write
+	// rather than one at a time. Writes are not atomic across books: it
+	// returns, by book ID, the reason each book that was not stored failed.
+	PutBatch(ctx context.Context, userID string, books []Book) map[string]error
+	// PutIdempotent stores a new book like Put, together with record, in one
+	// atomic write. If the user already has an unexpired record for
+	// record.Key, nothing is stored and that record is returned instead.
+	PutIdempotent(ctx context.Context, userID string, book Book, record IdempotencyRecord) (*IdempotencyRecord, error)
+	// GetIdempotencyRecord returns the user's unexpired record for key, or
+	// nil if there is none.
+	GetIdempotencyRecord(ctx context.Context, userID, key string) (*IdempotencyRecord, error)
+	// Update replaces an existing book if it is still at book.Version and
+	// returns the stored book with its version incremented. It returns
+	// ErrNotFound if the book does not exist, or a ConflictError if it has
+	// changed.
+	Update(ctx context.Context, userID string, book Book) (Book, error)
+	// Patch applies patch to the user's book atomically and returns the
+	// stored book with its version incremented. When version is not nil the
+	// book is only patched if it is still at that version. It returns
+	// ErrNotFound, a ConflictError if the version has changed, or a
+	// TestFailedError if one of the patch's tests does not hold.
+	Patch(ctx context.Context, userID, bookID string, patch Patch, version *int) (Book, error)
+	// Merge atomically stores merged, which must be an update of the book
+	// at merged.Version, with its version incremented, and deletes source,
+	// which must still be at source.Version. Nothing is written if either
+	// book is missing (ErrNotFound) or has changed (a ConflictError carrying
+	// that book).
+	Merge(ctx context.Context, userID string, merged, source Book) (Book, error)
+	// Trash moves the user's book, which must still be at book.Version, to
+	// their trash for TrashTTL. It returns ErrNotFound, or a ConflictError
+	// if the book has changed.
+	Trash(ctx context.Context, userID string, book Book) (TrashedBook, error)
+	// ListTrash returns the books in the user's trash, most recently deleted
+	// first.
+	ListTrash(ctx context.Context, userID string) ([]TrashedBook, error)
+	// Restore moves a book out of the user's trash with its version
+	// incremented. It returns ErrNotFound if the book is not in the trash,
+	// or a ConflictError carrying the book if one with the same ID exists.
+	Restore(ctx context.Context, userID, bookID string) (Book, error)
+	// Purge permanently removes a book from the user's trash, or returns
+	// ErrNotFound.
+	Purge(ctx context.Context, userID, bookID string) error
+	// History returns every recorded write to the user's book, newest
+	// first, including writes before it was deleted.
+	History(ctx context.Context, userID, bookID string) ([]HistoryEntry, error)
+	// AllHistory returns the history of every book the user has written
+	// to, each book's entries newest first.
+	AllHistory(ctx context.Context, userID string) ([]HistoryEntry, error)
+}
+
+var (
+	_ BookRepository = (*DynamoRepository)(nil)
+	_ BookRepository = (*MemoryRepository)(nil)
+)
@@ -0,0 +1,110 @@
+package bookshelf
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// SortFields are the fields books can be sorted by.
+var SortFields = []string{"title", "author", "rating", "finished_at", "started_at", "created_at"}
+
+// SortOptions orders a list of books. The zero value keeps the order the
+// repository returned them in.
+type SortOptions struct {
+	Field      string
+	Descending bool
+}
+
+// ParseSortOptions reads the sort and order query parameters. The returned
+// error's message is safe to show to clients.
+func ParseSortOptions(params map[string]string) (SortOptions, error) {
+	opts := SortOptions{Field: params["sort"]}
+	if opts.Field != "" && !slices.Contains(SortFields, opts.Field) {
+		return SortOptions{}, fmt.Errorf("Invalid sort. Must be one of: %s", strings.Join(SortFields, ", "))
+	}
+
+	switch order := params["order"]; order {
+	case "", "asc":
+	case "desc":
+		opts.Descending = true
+	default:
+		return SortOptions{}, fmt.Errorf("Invalid order. Must be one of: asc, desc")
+	}
+	if opts.Field == "" && opts.Descending {
+		return SortOptions{}, fmt.Errorf("Invalid order. order requires sort")
+	}
+	return opts, nil
+}
+
+// SortBooks orders books in place. Books missing the sort field always come
+// last, and ties keep ID order so pages over a sorted list are stable.
+func SortBooks(books []Book, opts SortOptions) {
+	if opts.Field == "" {
+		return
+	}
+
+	slices.SortStableFunc(books, func(a, b Book) int {
+		return opts.Compare(opts.Position(a), opts.Position(b))
+	})
+}
+
+// SortPosition is a book's place in a list sorted by SortOptions: its sort
+// key, empty when it has none, and its ID, which breaks ties. A page of a
+// sorted list resumes after the position of its last book, so books added
+// or removed in the meantime do not shift the pages that follow.
+type SortPosition struct {
+	Key string `json:"k"`
+	ID  string `json:"id"`
+}
+
+// Position returns book's position in a list sorted by o.
+func (o SortOptions) Position(book Book) SortPosition {
+	key, _ := sortKey(book, o.Field)
+	return SortPosition{Key: key, ID: book.ID}
+}
+
+// Compare orders positions as SortBooks orders the books they came from.
+func (o SortOptions) Compare(a, b SortPosition) int {
+	switch {
+	case a.Key == "" && b.Key == "":
+		return strings.Compare(a.ID, b.ID)
+	case a.Key == "":
+		return 1
+	case b.Key == "":
+		return -1
+	}
+
+	c := strings.Compare(a.Key, b.Key)
+	if o.Descending {
+		c = -c
+	}
+	if c == 0 {
+		c = strings.Compare(a.ID, b.ID)
+	}
+	return c
+}
+
+// sortKey returns a string that orders book by field, and whether the book
+// has a value for it.
+func sortKey(book Book, field string) (string, bool) {
+	var v string
+	switch field {
+	case "title":
+		v = strings.ToLower(book.Title)
+	case "author":
+		v = strings.ToLower(book.Author)
+	case "rating":
+		if book.Rating == nil {
+			return "", false
+		}
+		v = fmt.Sprintf("%03d", *book.Rating)
+	case "finished_at":
+		v = book.FinishedAt
+	case "started_at":
+		v = book.StartedAt
+	case "created_at":
+		v = book.CreatedAt
+	}
+	return v, v != ""
+}
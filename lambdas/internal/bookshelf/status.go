@@ -0,0 +1,25 @@
+package bookshelf
+
+// Reading statuses a book can be in.
+const (
+	StatusWantToRead = "WANT_TO_READ"
+	StatusReading    = "READING"
+	StatusRead       = "READ"
+)
+
+// Statuses lists every valid status in display order.
+var Statuses = []string{StatusWantToRead, StatusReading, StatusRead}
+
+// InvalidStatusMessage is the error body returned when a request carries an
+// unknown status.
+const InvalidStatusMessage = "Invalid status. Must be one of: WANT_TO_READ, READING, READ"
+
+// ValidStatus reports whether status is one of the known reading statuses.
+func ValidStatus(status string) bool {
+	for _, s := range Statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
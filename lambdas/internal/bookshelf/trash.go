@@ -0,0 +1,83 @@
+package bookshelf
+
+import (
+	"sort"
+	"time"
+)
+
+// TrashTTL is how long a deleted book stays in the trash before DynamoDB
+// removes it for good.
+const TrashTTL = 30 * 24 * time.Hour
+
+// TrashedBook is a deleted book waiting in the user's trash. It is stored
+// under TRASH#<book id> without index keys, so book listings, exports and
+// recommendations never see it.
+type TrashedBook struct {
+	Book
+	DeletedAt string `dynamodbav:"deleted_at"`
+	// ExpiresAt is when the book is purged, in Unix seconds. DynamoDB deletes
+	// it some time after, so reads must still check it.
+	ExpiresAt int64 `dynamodbav:"expires_at"`
+}
+
+// APITrashedBook is the API representation of a TrashedBook.
+type APITrashedBook struct {
+	APIBook
+	DeletedAt string `json:"deleted_at"`
+	ExpiresAt string `json:"expires_at"`
+}
+
+// NewTrashedBook moves book, owned by userID, to the trash as of now.
+func NewTrashedBook(userID string, book Book, now time.Time) TrashedBook {
+	book.PK = UserPK(userID)
+	book.SK = TrashSK(book.ID)
+	book.GSI1PK, book.GSI1SK, book.GSI2PK, book.GSI2SK = "", "", "", ""
+	return TrashedBook{
+		Book:      book,
+		DeletedAt: now.UTC().Format(time.RFC3339),
+		ExpiresAt: now.Add(TrashTTL).Unix(),
+	}
+}
+
+// Expired reports whether the book is past its time in the trash.
+func (t TrashedBook) Expired(now time.Time) bool {
+	return now.Unix() >= t.ExpiresAt
+}
+
+// Restored returns the book as it is stored again once restored from the
+// trash, one version on from when it was deleted.
+func (t TrashedBook) Restored(userID string) Book {
+	book := t.Book
+	book.Version++
+	book.SetKeys(userID)
+	return book
+}
+
+// ToAPI converts a trashed book into its API representation.
+func (t TrashedBook) ToAPI() APITrashedBook {
+	return APITrashedBook{
+		APIBook:   t.Book.ToAPI(),
+		DeletedAt: t.DeletedAt,
+		ExpiresAt: time.Unix(t.ExpiresAt, 0).UTC().Format(time.RFC3339),
+	}
+}
+
+// ToAPITrashedBooks converts trashed books into API representations. The
+// result is never nil so that it always marshals to a JSON array.
+func ToAPITrashedBooks(books []TrashedBook) []APITrashedBook {
+	apiBooks := make([]APITrashedBook, len(books))
+	for i, book := range books {
+		apiBooks[i] = book.ToAPI()
+	}
+	return apiBooks
+}
+
+// sortTrash orders the trash most recently deleted first.
+func sortTrash(books []TrashedBook) {
+	sort.Slice(books, func(i, j int) bool {
+		if books[i].DeletedAt != books[j].DeletedAt {
+			return books[i].DeletedAt > books[j].DeletedAt
+		}
+		return books[i].ID < books[j].ID
+	})
+}
@@ -0,0 +1,93 @@
+package bookshelf
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTrashedBook(t *testing.T) {
+	deleted := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	book := NewBook("user-1", "book-1", APIBook{Title: "Dune", Author: "Frank Herbert", Status: StatusRead})
+	book.Version = 4
+	book.SetKeys("user-1")
+
+	trashed := NewTrashedBook("user-1", book, deleted)
+	// Out of the indexes, so listings never see it
+	if trashed.SK != "TRASH#book-1" || trashed.GSI1PK != "" || trashed.GSI1SK != "" || trashed.GSI2PK != "" || trashed.GSI2SK != "" {
+		t.Errorf("trashed keys = %q %q %q %q %q, want TRASH#book-1 and no index keys",
+			trashed.SK, trashed.GSI1PK, trashed.GSI1SK, trashed.GSI2PK, trashed.GSI2SK)
+	}
+	api := trashed.ToAPI()
+	if api.DeletedAt != "2025-03-01T12:00:00Z" || api.ExpiresAt != "2025-03-31T12:00:00Z" {
+		t.Errorf("deleted_at %s, expires_at %s, want 2025-03-01T12:00:00Z and 30 days later", api.DeletedAt, api.ExpiresAt)
+	}
+
+	for _, tt := range []struct {
+		now  time.Time
+		want bool
+	}{
+		{deleted.Add(TrashTTL - time.Second), false},
+		{deleted.Add(TrashTTL), true},
+	} {
+		if got := trashed.Expired(tt.now); got != tt.want {
+			t.Errorf("Expired(%v) = %v, want %v", tt.now, got, tt.want)
+		}
+	}
+
+	restored := trashed.Restored("user-1")
+	if restored.Version != 5 || restored.SK != book.SK || restored.GSI1SK != book.GSI1SK || restored.GSI2SK != book.GSI2SK {
+		t.Errorf("restored = version %d keys %q %q %q, want version 5 and the keys it was deleted with",
+			restored.Version, restored.SK, restored.GSI1SK, restored.GSI2SK)
+	}
+}
+
+func TestSortTrash(t *testing.T) {
+	books := []TrashedBook{
+		{Book: Book{ID: "c"}, DeletedAt: "2025-03-01T12:00:00Z"},
+		{Book: Book{ID: "b"}, DeletedAt: "2025-03-02T09:00:00Z"},
+		{Book: Book{ID: "a"}, DeletedAt: "2025-03-01T12:00:00Z"},
+	}
+	sortTrash(books)
+	var got string
+	for _, book := range books {
+		got += book.ID
+	}
+	if got != "bac" {
+		t.Errorf("sortTrash = %s, want the most recently deleted first, then by ID: bac", got)
+	}
+}
+
+func TestMemoryTrashExpiry(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryRepository()
+	for _, id := range []string{"book-1", "book-2"} {
+		book := NewBook("user-1", id, APIBook{Title: "Dune", Author: "Frank Herbert", Status: StatusRead})
+		if err := repo.Put(ctx, "user-1", book); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := repo.Trash(ctx, "user-1", Book{ID: id, Version: 1}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// book-2 has been in the trash for longer than TrashTTL, but DynamoDB
+	// has not deleted it yet
+	expired := repo.trash["user-1"]["book-2"]
+	expired.ExpiresAt = time.Now().Add(-time.Minute).Unix()
+	repo.trash["user-1"]["book-2"] = expired
+
+	trash, err := repo.ListTrash(ctx, "user-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trash) != 1 || trash[0].ID != "book-1" {
+		t.Errorf("ListTrash = %v, want only book-1", trash)
+	}
+	if _, err := repo.Restore(ctx, "user-1", "book-2"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Restore of an expired book = %v, want ErrNotFound", err)
+	}
+	if trash, _ := repo.ListTrash(ctx, "user-2"); len(trash) != 0 {
+		t.Errorf("another user's trash = %v, want it empty", trash)
+	}
+}
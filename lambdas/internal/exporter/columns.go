@@ -0,0 +1,231 @@
+package exporter
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	// Lambda runtimes do not ship a time zone database
+	_ "time/tzdata"
+
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/bookshelf"
+)
+
+// CSVOptions customise the layout of CSV exports. The zero value is the
+// default layout: the columns of defaultFields, separated by commas, with
+// ISO dates and UTC timestamps and no byte order mark.
+type CSVOptions struct {
+	// Fields are the columns to write, in order, named by their API field
+	// names, as in "title" or "finished_at".
+	Fields []string `json:"fields,omitempty" dynamodbav:"fields,omitempty"`
+	// Delimiter separates columns: comma, tab, or semicolon, which Excel
+	// expects in locales that use a decimal comma.
+	Delimiter string `json:"delimiter,omitempty" dynamodbav:"delimiter,omitempty"`
+	// DateFormat is how dates are written, as one of dateFormats.
+	DateFormat string `json:"date_format,omitempty" dynamodbav:"date_format,omitempty"`
+	// Timezone is the IANA time zone timestamps are written in.
+	Timezone string `json:"timezone,omitempty" dynamodbav:"timezone,omitempty"`
+	// BOM starts the file with a UTF-8 byte order mark, without which Excel
+	// misreads non-ASCII text.
+	BOM bool `json:"bom,omitempty" dynamodbav:"bom,omitempty"`
+}
+
+// IsZero reports whether o is the default layout.
+func (o CSVOptions) IsZero() bool {
+	return len(o.Fields) == 0 && o.Delimiter == "" && o.DateFormat == "" && o.Timezone == "" && !o.BOM
+}
+
+// Validate checks o for an export in format. Its errors are safe to show to
+// clients.
+func (o CSVOptions) Validate(format string) error {
+	if format != "csv" && !o.IsZero() {
+		return errors.New("fields, delimiter, date_format, timezone and bom only apply to the csv format")
+	}
+	_, err := o.layout()
+	return err
+}
+
+// csvColumn is a column CSV exports can have.
+type csvColumn struct {
+	header string
+	value  func(book bookshelf.Book, layout csvLayout) string
+}
+
+// csvColumns are the columns CSV exports can have, by API field name.
+var csvColumns = map[string]csvColumn{
+	"id":               {"ID", func(b bookshelf.Book, _ csvLayout) string { return b.ID }},
+	"title":            {"Title", func(b bookshelf.Book, _ csvLayout) string { return b.Title }},
+	"author":           {"Author", func(b bookshelf.Book, _ csvLayout) string { return b.Author }},
+	"series":           {"Series", func(b bookshelf.Book, _ csvLayout) string { return b.Series }},
+	"status":           {"Status", func(b bookshelf.Book, _ csvLayout) string { return b.Status }},
+	"rating":           {"Rating", func(b bookshelf.Book, _ csvLayout) string { return optionalInt(b.Rating) }},
+	"review":           {"Review", func(b bookshelf.Book, _ csvLayout) string { return b.Review }},
+	"tags":             {"Tags", func(b bookshelf.Book, _ csvLayout) string { return strings.Join(b.Tags, "; ") }},
+	"started_at":       {"Started Date", func(b bookshelf.Book, l csvLayout) string { return l.date(b.StartedAt) }},
+	"finished_at":      {"Finished Date", func(b bookshelf.Book, l csvLayout) string { return l.date(b.FinishedAt) }},
+	"thumbnail":        {"Thumbnail", func(b bookshelf.Book, _ csvLayout) string { return b.Thumbnail }},
+	"type":             {"Type", func(b bookshelf.Book, _ csvLayout) string { return b.Type }},
+	"comments":         {"Comments", func(b bookshelf.Book, _ csvLayout) string { return b.Comments }},
+	"created_at":       {"Created At", func(b bookshelf.Book, l csvLayout) string { return l.timestamp(b.CreatedAt) }},
+	"google_volume_id": {"Google Volume ID", func(b bookshelf.Book, _ csvLayout) string { return b.VolumeID }},
+	"isbn":             {"ISBN", func(b bookshelf.Book, _ csvLayout) string { return b.ISBN }},
+	"version":          {"Version", func(b bookshelf.Book, _ csvLayout) string { return strconv.Itoa(b.Version) }},
+}
+
+// fieldNames are the names of csvColumns in the order of APIBook, as they
+// are listed to clients.
+var fieldNames = []string{
+	"id", "title", "author", "series", "status", "rating", "review", "tags",
+	"started_at", "finished_at", "thumbnail", "type", "comments",
+	"created_at", "google_volume_id", "isbn", "version",
+}
+
+// defaultFields are the columns of CSV exports that do not choose their
+// own.
+var defaultFields = []string{
+	"title", "author", "series", "status", "rating", "started_at",
+	"finished_at", "tags", "type", "review", "comments", "thumbnail",
+}
+
+// delimiters are the supported column delimiters, by name.
+var delimiters = map[string]rune{
+	"comma":     ',',
+	"tab":       '\t',
+	"semicolon": ';',
+}
+
+// dateFormats are the supported date formats, in the order they are listed
+// to clients, with their time layouts.
+var dateFormats = []struct {
+	name   string
+	layout string
+}{
+	{"YYYY-MM-DD", "2006-01-02"},
+	{"MM/DD/YYYY", "01/02/2006"},
+	{"DD/MM/YYYY", "02/01/2006"},
+	{"DD.MM.YYYY", "02.01.2006"},
+}
+
+// csvLayout is a validated CSVOptions, ready to write rows with.
+type csvLayout struct {
+	columns  []csvColumn
+	comma    rune
+	dates    string // time layout of dates; empty for ISO
+	location *time.Location
+	bom      bool
+}
+
+// layout validates o and resolves it into a csvLayout. Its errors are safe
+// to show to clients.
+func (o CSVOptions) layout() (csvLayout, error) {
+	layout := csvLayout{comma: ',', location: time.UTC, bom: o.BOM}
+
+	fields := o.Fields
+	if len(fields) == 0 {
+		fields = defaultFields
+	}
+	var unknown []string
+	for i, field := range fields {
+		column, ok := csvColumns[field]
+		if !ok {
+			unknown = append(unknown, strconv.Quote(field))
+			continue
+		}
+		if slices.Contains(fields[:i], field) {
+			return csvLayout{}, fmt.Errorf("Duplicate field %q", field)
+		}
+		layout.columns = append(layout.columns, column)
+	}
+	if len(unknown) > 0 {
+		return csvLayout{}, fmt.Errorf("Unknown fields: %s. Supported fields: %s", strings.Join(unknown, ", "), strings.Join(fieldNames, ", "))
+	}
+
+	if o.Delimiter != "" {
+		comma, ok := delimiters[o.Delimiter]
+		if !ok {
+			return csvLayout{}, errors.New("Invalid delimiter. Supported delimiters: comma, tab, semicolon")
+		}
+		layout.comma = comma
+	}
+
+	if o.DateFormat != "" {
+		i := slices.IndexFunc(dateFormats, func(f struct{ name, layout string }) bool { return f.name == o.DateFormat })
+		if i < 0 {
+			names := make([]string, len(dateFormats))
+			for i, f := range dateFormats {
+				names[i] = f.name
+			}
+			return csvLayout{}, errors.New("Invalid date_format. Supported date formats: " + strings.Join(names, ", "))
+		}
+		if i > 0 {
+			layout.dates = dateFormats[i].layout
+		}
+	}
+
+	if o.Timezone != "" {
+		location, err := time.LoadLocation(o.Timezone)
+		// LoadLocation also accepts "Local", the Lambda's own zone
+		if err != nil || o.Timezone == "Local" {
+			return csvLayout{}, fmt.Errorf("Invalid timezone %q. Use an IANA time zone name, as in Europe/Berlin", o.Timezone)
+		}
+		layout.location = location
+	}
+	return layout, nil
+}
+
+// header is the header row of the layout.
+func (l csvLayout) header() []string {
+	header := make([]string, len(l.columns))
+	for i, column := range l.columns {
+		header[i] = column.header
+	}
+	return header
+}
+
+// record is the row of the layout for book.
+func (l csvLayout) record(book bookshelf.Book) []string {
+	record := make([]string, len(l.columns))
+	for i, column := range l.columns {
+		record[i] = column.value(book, l)
+	}
+	return record
+}
+
+// date writes a YYYY-MM-DD date in the layout's date format. Dates are
+// calendar days, so they are not moved into the time zone. Anything else is
+// written as it is.
+func (l csvLayout) date(s string) string {
+	if l.dates == "" {
+		return s
+	}
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		return s
+	}
+	return t.Format(l.dates)
+}
+
+// timestamp writes an RFC 3339 timestamp in the layout's time zone: as RFC
+// 3339 with ISO dates, or as a date in the date format and a 24-hour time.
+func (l csvLayout) timestamp(s string) string {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return s
+	}
+	t = t.In(l.location)
+	if l.dates == "" {
+		return t.Format(time.RFC3339)
+	}
+	return t.Format(l.dates + " 15:04:05")
+}
+
+// optionalInt writes n, or nothing if it is nil.
+func optionalInt(n *int) string {
+	if n == nil {
+		return ""
+	}
+	return strconv.Itoa(*n)
+}
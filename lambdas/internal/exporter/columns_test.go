@@ -0,0 +1,118 @@
+package exporter
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/bookshelf"
+)
+
+func TestWriteCSVOptions(t *testing.T) {
+	rating := 8
+	books := []bookshelf.Book{{
+		ID:         "book-1",
+		Title:      "Der Zauberberg",
+		Author:     "Thomas Mann",
+		Status:     bookshelf.StatusRead,
+		Rating:     &rating,
+		FinishedAt: "2024-03-09",
+		CreatedAt:  "2024-01-31T23:30:00Z",
+		Tags:       []string{"klassiker", "roman"},
+	}}
+	fields := []string{"title", "rating", "finished_at", "created_at"}
+
+	tests := []struct {
+		name string
+		opts CSVOptions
+		want string
+	}{
+		{
+			name: "default",
+			opts: CSVOptions{Fields: fields},
+			want: "Title,Rating,Finished Date,Created At\nDer Zauberberg,8,2024-03-09,2024-01-31T23:30:00Z\n",
+		},
+		{
+			name: "fields",
+			opts: CSVOptions{Fields: []string{"tags", "id", "author"}},
+			want: "Tags,ID,Author\nklassiker; roman,book-1,Thomas Mann\n",
+		},
+		{
+			name: "tab",
+			opts: CSVOptions{Fields: fields, Delimiter: "tab"},
+			want: "Title\tRating\tFinished Date\tCreated At\nDer Zauberberg\t8\t2024-03-09\t2024-01-31T23:30:00Z\n",
+		},
+		{
+			name: "semicolon",
+			opts: CSVOptions{Fields: fields, Delimiter: "semicolon"},
+			want: "Title;Rating;Finished Date;Created At\nDer Zauberberg;8;2024-03-09;2024-01-31T23:30:00Z\n",
+		},
+		{
+			name: "US dates",
+			opts: CSVOptions{Fields: fields, DateFormat: "MM/DD/YYYY"},
+			want: "Title,Rating,Finished Date,Created At\nDer Zauberberg,8,03/09/2024,01/31/2024 23:30:00\n",
+		},
+		{
+			// Dates are calendar days and stay put; timestamps move into the zone
+			name: "timezone",
+			opts: CSVOptions{Fields: fields, Timezone: "Europe/Berlin"},
+			want: "Title,Rating,Finished Date,Created At\nDer Zauberberg,8,2024-03-09,2024-02-01T00:30:00+01:00\n",
+		},
+		{
+			name: "German locale",
+			opts: CSVOptions{Fields: fields, Delimiter: "semicolon", DateFormat: "DD.MM.YYYY", Timezone: "Europe/Berlin", BOM: true},
+			want: "\ufeffTitle;Rating;Finished Date;Created At\nDer Zauberberg;8;09.03.2024;01.02.2024 00:30:00\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			n, err := writeCSV(context.Background(), &buf, bookPages(books), tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n != 1 {
+				t.Errorf("wrote %d books, want 1", n)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("wrote %q, want %q", got, tt.want)
+			}
+			if got := bytes.HasPrefix(buf.Bytes(), []byte{0xEF, 0xBB, 0xBF}); got != tt.opts.BOM {
+				t.Errorf("starts with a byte order mark: %v, want %v", got, tt.opts.BOM)
+			}
+		})
+	}
+}
+
+func TestCSVOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		opts    CSVOptions
+		wantErr string // contained in the error; empty if valid
+	}{
+		{"default", "csv", CSVOptions{}, ""},
+		{"default for json", "json", CSVOptions{}, ""},
+		{"every option", "csv", CSVOptions{Fields: []string{"isbn"}, Delimiter: "tab", DateFormat: "DD/MM/YYYY", Timezone: "America/New_York", BOM: true}, ""},
+		{"options for goodreads", "goodreads", CSVOptions{BOM: true}, "only apply to the csv format"},
+		{"unknown fields", "csv", CSVOptions{Fields: []string{"title", "colour", "pages"}}, `Unknown fields: "colour", "pages"`},
+		{"duplicate field", "csv", CSVOptions{Fields: []string{"title", "title"}}, `Duplicate field "title"`},
+		{"delimiter", "csv", CSVOptions{Delimiter: "pipe"}, "Invalid delimiter"},
+		{"delimiter character", "csv", CSVOptions{Delimiter: ";"}, "Invalid delimiter"},
+		{"date format", "csv", CSVOptions{DateFormat: "YYYY/MM/DD"}, "Invalid date_format. Supported date formats: YYYY-MM-DD, MM/DD/YYYY"},
+		{"timezone", "csv", CSVOptions{Timezone: "Mars/Olympus_Mons"}, `Invalid timezone "Mars/Olympus_Mons"`},
+		{"local timezone", "csv", CSVOptions{Timezone: "Local"}, `Invalid timezone "Local"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.opts.Validate(tt.format)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("Validate = %v, want nil", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("Validate = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+// Package exportertest provides the export jobs and files the export
+// handlers are tested with.
+package exportertest
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/exporter"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/handlertest"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/objectstore"
+)
+
+// Exports are the export jobs and files of handlertest.UserID, one export in
+// each state the export endpoints tell apart, named by their IDs.
+type Exports struct {
+	Jobs  *exporter.MemoryJobRepository
+	Store *objectstore.FileStore
+
+	// Pending is a job that has not started, created a minute ago.
+	Pending string
+	// Failed is a job that failed, created two minutes ago.
+	Failed string
+	// Completed is a job that wrote its file of 2 books, created three
+	// minutes ago.
+	Completed string
+	// File was written by POST /export without a job, just now.
+	File string
+	// Expired is a completed job whose file has been deleted.
+	Expired string
+	// Stalled is a job that was never delivered to the export worker.
+	Stalled string
+}
+
+// New returns Exports kept in memory and in a temporary directory, whose
+// files are linked to under https://exports.example.com.
+func New(t testing.TB) Exports {
+	t.Helper()
+	ctx := context.Background()
+	now := time.Now()
+	exports := Exports{
+		Jobs:  exporter.NewMemoryJobRepository(),
+		Store: objectstore.NewFileStore(t.TempDir(), "https://exports.example.com"),
+	}
+
+	put := func(format string, created time.Duration, finish func(*exporter.Job), file bool) string {
+		job := exporter.NewJob(handlertest.UserID, exporter.NewID(format, now.Add(-created)), format, nil, exporter.CSVOptions{}, now.Add(-created))
+		if finish != nil {
+			finish(&job)
+		}
+		if err := exports.Jobs.Put(ctx, handlertest.UserID, job); err != nil {
+			t.Fatal(err)
+		}
+		if file {
+			exports.putFile(t, job.ID)
+		}
+		return job.ID
+	}
+	exports.Pending = put("csv", time.Minute, nil, false)
+	exports.Failed = put("json", 2*time.Minute, func(job *exporter.Job) { job.Fail("The books could not be read") }, false)
+	exports.Completed = put("xlsx", 3*time.Minute, func(job *exporter.Job) { job.Complete(2, 7) }, true)
+	exports.Expired = put("pdf", 4*time.Minute, func(job *exporter.Job) { job.Complete(2, 7) }, false)
+	exports.Stalled = put("zip", 2*exporter.JobMaxEventAge, nil, false)
+	exports.File = exporter.NewID("markdown", now)
+	exports.putFile(t, exports.File)
+	return exports
+}
+
+// putFile writes the export file with the given ID.
+func (e Exports) putFile(t testing.TB, exportID string) {
+	t.Helper()
+	if err := e.Store.Put(context.Background(), exporter.Key(handlertest.UserID, exportID), strings.NewReader("exports"), nil); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,208 @@
+package exporter
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/objectstore"
+)
+
+// keyPrefix is the folder of the object store export files are written to.
+// The exports bucket deletes files in it after Retention.
+const keyPrefix = "exports/"
+
+// Retention is how long export files are kept before the bucket lifecycle
+// deletes them.
+const Retention = 7 * 24 * time.Hour
+
+// DownloadURLExpiry is how long a download URL handed out for an export
+// file works.
+const DownloadURLExpiry = 15 * time.Minute
+
+// ErrExportNotFound is returned for an export that has no job and no file,
+// or whose file has been deleted.
+var ErrExportNotFound = errors.New("export not found")
+
+// APIExport is the API representation of one of a user's exports, whether
+// its file has been written or its job is still running.
+type APIExport struct {
+	// ID is the name of the export's file, as in
+	// books-20261017-125030-3f9a1c.csv.
+	ID      string            `json:"id"`
+	Format  string            `json:"format"`
+	Status  string            `json:"status"`
+	Filters map[string]string `json:"filters,omitempty"`
+	Options *CSVOptions       `json:"options,omitempty"`
+	// BookCount is known for exports run as jobs, and Size once the file is
+	// written.
+	BookCount *int   `json:"book_count,omitempty"`
+	Size      int64  `json:"size"`
+	Error     string `json:"error,omitempty"`
+	CreatedAt string `json:"created_at"`
+	// ExpiresAt is when the bucket lifecycle deletes the file.
+	ExpiresAt string `json:"expires_at,omitempty"`
+}
+
+// Prefix returns the folder of the object store holding the user's export
+// files.
+func Prefix(userID string) string {
+	return keyPrefix + userID + "/"
+}
+
+// Key returns the object store key of the user's export with the given ID.
+func Key(userID, exportID string) string {
+	return Prefix(userID) + exportID
+}
+
+// NewID returns the ID of a new export in format taken at now. It is the
+// name of the export's file, unique even for exports taken in the same
+// second.
+func NewID(format string, now time.Time) string {
+	suffix := make([]byte, 3)
+	rand.Read(suffix)
+	return fmt.Sprintf("books-%s-%s.%s", now.UTC().Format("20060102-150405"), hex.EncodeToString(suffix), fileExtensions[format])
+}
+
+// ValidID reports whether id can name an export: a file name within the
+// user's folder.
+func ValidID(id string) bool {
+	return id != "" && id != "." && id != ".." && !strings.ContainsAny(id, `/\`)
+}
+
+// FormatOf returns the format of an export file from its extension.
+func FormatOf(id string) (string, bool) {
+	format, longest := "", 0
+	for name, ext := range fileExtensions {
+		if strings.HasSuffix(id, "."+ext) && len(ext) > longest {
+			format, longest = name, len(ext)
+		}
+	}
+	return format, format != ""
+}
+
+// History returns the user's exports, newest first: every export file still
+// in the store, and the jobs that have not written theirs yet or failed.
+// Files written by POST /export without a job are listed with what the
+// store knows of them.
+func History(ctx context.Context, jobs JobRepository, store objectstore.Store, userID string) ([]APIExport, error) {
+	objects, err := store.List(ctx, Prefix(userID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list export files: %w", err)
+	}
+	userJobs, err := jobs.List(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]Job, len(userJobs))
+	for _, job := range userJobs {
+		byID[job.ID] = job
+	}
+
+	exports := []APIExport{}
+	for _, object := range objects {
+		id := strings.TrimPrefix(object.Key, Prefix(userID))
+		format, ok := FormatOf(id)
+		if !ok || !ValidID(id) {
+			continue
+		}
+		job, ok := byID[id]
+		if ok && job.Status != JobCompleted {
+			// Still being written; the job is listed below
+			continue
+		}
+		delete(byID, id)
+		if ok {
+			exports = append(exports, job.ToAPI(object))
+		} else {
+			exports = append(exports, fileToAPI(id, format, object))
+		}
+	}
+	for _, job := range byID {
+		// A completed job whose file is gone has expired
+		if job.Status != JobCompleted {
+			exports = append(exports, job.ToAPI(objectstore.Object{}))
+		}
+	}
+
+	slices.SortFunc(exports, func(a, b APIExport) int {
+		return strings.Compare(b.CreatedAt+b.ID, a.CreatedAt+a.ID)
+	})
+	return exports, nil
+}
+
+// Lookup returns the user's export with the given ID, or ErrExportNotFound.
+func Lookup(ctx context.Context, jobs JobRepository, store objectstore.Store, userID, exportID string) (APIExport, error) {
+	if !ValidID(exportID) {
+		return APIExport{}, ErrExportNotFound
+	}
+	job, err := jobs.Get(ctx, userID, exportID)
+	if err != nil && !errors.Is(err, ErrJobNotFound) {
+		return APIExport{}, err
+	}
+	if err == nil && job.Status != JobCompleted {
+		return job.ToAPI(objectstore.Object{}), nil
+	}
+
+	object, err := store.Stat(ctx, Key(userID, exportID))
+	if errors.Is(err, objectstore.ErrNotFound) {
+		return APIExport{}, ErrExportNotFound
+	}
+	if err != nil {
+		return APIExport{}, fmt.Errorf("failed to find export file: %w", err)
+	}
+	if job.ID != "" {
+		return job.ToAPI(object), nil
+	}
+	format, ok := FormatOf(exportID)
+	if !ok {
+		return APIExport{}, ErrExportNotFound
+	}
+	return fileToAPI(exportID, format, object), nil
+}
+
+// fileToAPI describes an export file that has no job.
+func fileToAPI(id, format string, object objectstore.Object) APIExport {
+	return APIExport{
+		ID:        id,
+		Format:    format,
+		Status:    JobCompleted,
+		Size:      object.Size,
+		CreatedAt: object.LastModified.UTC().Format(time.RFC3339),
+		ExpiresAt: object.LastModified.Add(Retention).UTC().Format(time.RFC3339),
+	}
+}
+
+// Upload streams the export file write writes to the store under key,
+// recording when it was written in its metadata, and returns its size in
+// bytes.
+func Upload(ctx context.Context, store objectstore.Store, key string, write func(io.Writer) error) (int64, error) {
+	var size int64
+	err := objectstore.PutStream(ctx, store, key, map[string]string{
+		"created-at": time.Now().UTC().Format(time.RFC3339),
+	}, func(w io.Writer) error {
+		counter := &countingWriter{w: w}
+		err := write(counter)
+		size = counter.n
+		return err
+	})
+	return size, err
+}
+
+// countingWriter counts the bytes written through it.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
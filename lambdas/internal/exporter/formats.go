@@ -0,0 +1,186 @@
+// Package exporter writes a user's books to export files, and tracks the
+// export jobs that write them in the background and the files they leave in
+// the object store.
+package exporter
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/bookshelf"
+)
+
+// formats are the supported export formats, in the order they are listed
+// to clients.
+var formats = []string{"csv", "json", "goodreads", "markdown", "html", "pdf", "xlsx", "zip"}
+
+// fileExtensions maps each export format to the extension of its files.
+// Goodreads exports are CSV files too, so their extension says which
+// layout they are in.
+var fileExtensions = map[string]string{
+	"csv":       "csv",
+	"json":      "json",
+	"goodreads": "goodreads.csv",
+	"markdown":  "md",
+	"html":      "html",
+	"pdf":       "pdf",
+	"xlsx":      "xlsx",
+	"zip":       "zip",
+}
+
+// ValidFormat reports whether format is a supported export format.
+func ValidFormat(format string) bool {
+	_, ok := fileExtensions[format]
+	return ok
+}
+
+// RequiresJob reports whether exports in format are only written by export
+// jobs. Zip backups download every cover, which can take longer than an API
+// request may.
+func RequiresJob(format string) bool {
+	return format == "zip"
+}
+
+// InvalidFormatMessage is the error shown to clients for an unknown format.
+func InvalidFormatMessage() string {
+	return "Invalid format. Supported formats: " + strings.Join(formats, ", ")
+}
+
+// Exporter writes export files. Most formats are written from the books
+// alone; zip backups also read each book's history from Books and download
+// its cover image with Covers.
+type Exporter struct {
+	Books bookshelf.BookRepository
+	// Covers fetches cover images; CoverClient() when nil.
+	Covers *http.Client
+}
+
+// New returns an Exporter reading history from books and fetching covers
+// with covers.
+func New(books bookshelf.BookRepository, covers *http.Client) *Exporter {
+	return &Exporter{Books: books, Covers: covers}
+}
+
+// Export writes the user's books that filters select to w as an export
+// file in format, taken at now, and returns how many books it wrote. CSV
+// exports are laid out as opts directs.
+//
+// CSV, Goodreads CSV, JSON and xlsx exports are written page by page as the
+// books are read from Books, so they take the same memory however large the
+// library is. Reports and zip backups, which group and cross-reference
+// books, read them all first.
+func (e *Exporter) Export(ctx context.Context, w io.Writer, userID, format string, filters map[string]string, opts CSVOptions, now time.Time) (int, error) {
+	pager := bookshelf.NewBookPager(e.Books, userID, listOptions(filters))
+	switch format {
+	case "csv":
+		return writeCSV(ctx, w, pager, opts)
+	case "goodreads":
+		return writeRows(ctx, w, pager, ',', goodreadsHeader, goodreadsRecord)
+	case "json":
+		return writeJSON(ctx, w, pager, filters, now)
+	case "xlsx":
+		return writeXLSX(ctx, w, func(status string) pages {
+			return bookshelf.NewBookPager(e.Books, userID, bookshelf.ListOptions{Status: status})
+		}, filters, now)
+	}
+
+	books, err := listBooks(ctx, e.Books, userID, filters)
+	if err != nil {
+		return 0, err
+	}
+	if format == "zip" {
+		return len(books), e.writeZip(ctx, w, userID, books, filters, now)
+	}
+	data, err := Generate(format, books, filters, now)
+	if err != nil {
+		return 0, err
+	}
+	_, err = w.Write(data)
+	return len(books), err
+}
+
+// Generate writes books as an export file in format, taken at now with
+// filters, in the default layout. Zip backups, which need more than the
+// books, are written by Exporter.Export.
+func Generate(format string, books []bookshelf.Book, filters map[string]string, now time.Time) ([]byte, error) {
+	switch format {
+	case "csv":
+		return generate(func(w io.Writer) error {
+			_, err := writeCSV(context.Background(), w, bookPages(books), CSVOptions{})
+			return err
+		})
+	case "json":
+		return generateJSON(books, filters, now)
+	case "goodreads":
+		return generate(func(w io.Writer) error {
+			_, err := writeRows(context.Background(), w, bookPages(books), ',', goodreadsHeader, goodreadsRecord)
+			return err
+		})
+	case "markdown":
+		return generateMarkdown(books, now)
+	case "html":
+		return generateHTML(books, now)
+	case "pdf":
+		return generatePDF(books, now)
+	case "xlsx":
+		return generate(func(w io.Writer) error {
+			_, err := writeXLSX(context.Background(), w, func(status string) pages {
+				return bookPages(slices.DeleteFunc(slices.Clone(books), func(book bookshelf.Book) bool {
+					return book.Status != status
+				}))
+			}, filters, now)
+			return err
+		})
+	}
+	return nil, fmt.Errorf("unsupported export format %q", format)
+}
+
+// generateJSON writes books as a versioned export that can be restored with
+// POST /import/backup.
+func generateJSON(books []bookshelf.Book, filters map[string]string, now time.Time) ([]byte, error) {
+	return generate(func(w io.Writer) error {
+		_, err := writeJSON(context.Background(), w, bookPages(books), filters, now)
+		return err
+	})
+}
+
+// generate returns what write writes.
+func generate(write func(io.Writer) error) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := write(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// AppliedFilters returns the filters an export is taken with: only the
+// status filter is applied, so any others are dropped.
+func AppliedFilters(filters map[string]string) map[string]string {
+	if status := filters["status"]; status != "" {
+		return map[string]string{"status": status}
+	}
+	return nil
+}
+
+// listBooks returns every one of the user's books that filters select,
+// walking every page so the export is complete however large the library
+// is.
+func listBooks(ctx context.Context, books bookshelf.BookRepository, userID string, filters map[string]string) ([]bookshelf.Book, error) {
+	all, err := bookshelf.ListAll(ctx, books, userID, listOptions(filters))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrListBooks, err)
+	}
+	return all, nil
+}
+
+// listOptions returns the list options that select the books filters
+// export. Only the status filter is applied.
+func listOptions(filters map[string]string) bookshelf.ListOptions {
+	return bookshelf.ListOptions{Status: filters["status"]}
+}
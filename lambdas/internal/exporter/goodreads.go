@@ -0,0 +1,140 @@
+package exporter
+
+import (
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/bookshelf"
+)
+
+// goodreadsHeader is the column layout of a Goodreads library export, which
+// Goodreads and the trackers that import from it expect.
+var goodreadsHeader = []string{
+	"Book Id", "Title", "Author", "Author l-f", "Additional Authors",
+	"ISBN", "ISBN13", "My Rating", "Average Rating", "Publisher", "Binding",
+	"Number of Pages", "Year Published", "Original Publication Year",
+	"Date Read", "Date Added", "Bookshelves", "Bookshelves with positions",
+	"Exclusive Shelf", "My Review", "Spoiler", "Private Notes", "Read Count",
+	"Owned Copies",
+}
+
+// goodreadsShelves maps statuses to Goodreads' exclusive shelves.
+var goodreadsShelves = map[string]string{
+	bookshelf.StatusWantToRead: "to-read",
+	bookshelf.StatusReading:    "currently-reading",
+	bookshelf.StatusRead:       "read",
+}
+
+// goodreadsBindings maps book types to Goodreads bindings. Other types are
+// left blank.
+var goodreadsBindings = map[string]string{
+	"audiobook": "Audiobook",
+	"kindle":    "Kindle Edition",
+}
+
+// numberedSeries matches a series with its number, as in "The Expanse #1"
+// or "The Expanse, #1".
+var numberedSeries = regexp.MustCompile(`^(.+?),?\s+#([\d.]+(?:-[\d.]+)?)$`)
+
+// goodreadsRecord is the row of a Goodreads export for book. Goodreads
+// identifies books by its own IDs, which books added here do not have, so
+// Book Id is left blank and trackers match books by ISBN, title and author
+// instead.
+func goodreadsRecord(book bookshelf.Book) []string {
+	isbn10, isbn13 := "", ""
+	switch len(book.ISBN) {
+	case 10:
+		isbn10 = book.ISBN
+	case 13:
+		isbn13 = book.ISBN
+	}
+
+	readCount := "0"
+	if book.Status == bookshelf.StatusRead {
+		readCount = "1"
+	}
+
+	return []string{
+		"",
+		goodreadsTitle(book),
+		book.Author,
+		authorLastFirst(book.Author),
+		"",
+		goodreadsISBN(isbn10),
+		goodreadsISBN(isbn13),
+		goodreadsStars(book.Rating),
+		"",
+		"",
+		goodreadsBindings[book.Type],
+		"",
+		"",
+		"",
+		goodreadsDate(book.FinishedAt),
+		goodreadsDate(book.CreatedAt),
+		strings.Join(book.Tags, ", "),
+		"",
+		goodreadsShelves[book.Status],
+		goodreadsReview(book.Review),
+		"",
+		book.Comments,
+		readCount,
+		"0",
+	}
+}
+
+// goodreadsTitle appends a numbered series to the title the way Goodreads
+// does, as in "Leviathan Wakes (The Expanse, #1)". Goodreads only shows
+// numbered series, so a series without a number is left out.
+func goodreadsTitle(book bookshelf.Book) string {
+	m := numberedSeries.FindStringSubmatch(book.Series)
+	if m == nil {
+		return book.Title
+	}
+	return book.Title + " (" + m[1] + ", #" + m[2] + ")"
+}
+
+// authorLastFirst turns "First Middle Last" into "Last, First Middle".
+func authorLastFirst(author string) string {
+	i := strings.LastIndex(author, " ")
+	if i < 0 {
+		return author
+	}
+	return author[i+1:] + ", " + author[:i]
+}
+
+// goodreadsISBN writes an ISBN as ="9780765326355", as Goodreads does, so
+// that spreadsheets keep it as text.
+func goodreadsISBN(isbn string) string {
+	return `="` + isbn + `"`
+}
+
+// goodreadsStars converts a 1-10 rating to Goodreads' whole stars, rounding
+// half stars up. Unrated books are 0.
+func goodreadsStars(rating *int) string {
+	if rating == nil {
+		return "0"
+	}
+	return strconv.Itoa((*rating + 1) / 2)
+}
+
+// goodreadsDate writes a date, or the date of a timestamp, as YYYY/MM/DD.
+// Dates that cannot be read are left blank.
+func goodreadsDate(s string) string {
+	if len(s) < len(time.DateOnly) {
+		return ""
+	}
+	t, err := time.Parse(time.DateOnly, s[:len(time.DateOnly)])
+	if err != nil {
+		return ""
+	}
+	return t.Format("2006/01/02")
+}
+
+// goodreadsReview writes a review as the HTML Goodreads stores, with line
+// breaks as <br/>.
+func goodreadsReview(review string) string {
+	return strings.ReplaceAll(html.EscapeString(review), "\n", "<br/>")
+}
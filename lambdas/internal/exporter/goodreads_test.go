@@ -0,0 +1,122 @@
+package exporter
+
+import (
+	"bytes"
+	"encoding/csv"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/bookshelf"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/importer"
+)
+
+func TestGoodreadsHeader(t *testing.T) {
+	out, err := Generate("goodreads", nil, nil, reportNow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The header of a library export downloaded from Goodreads
+	want := "Book Id,Title,Author,Author l-f,Additional Authors,ISBN,ISBN13,My Rating,Average Rating,Publisher,Binding," +
+		"Number of Pages,Year Published,Original Publication Year,Date Read,Date Added,Bookshelves," +
+		"Bookshelves with positions,Exclusive Shelf,My Review,Spoiler,Private Notes,Read Count,Owned Copies\n"
+	if string(out) != want {
+		t.Errorf("header = %q, want %q", out, want)
+	}
+}
+
+func TestGoodreadsRecord(t *testing.T) {
+	rating := func(n int) *int { return &n }
+	tests := []struct {
+		name string
+		book bookshelf.Book
+		want map[string]string
+	}{
+		{
+			name: "read",
+			book: bookshelf.Book{Title: "Leviathan Wakes", Author: "James S. A. Corey", Series: "The Expanse #1", Status: bookshelf.StatusRead,
+				Rating: rating(9), ISBN: "9780316129084", FinishedAt: "2024-05-06", CreatedAt: "2024-01-02T03:04:05Z", Type: "kindle"},
+			want: map[string]string{
+				"Title": "Leviathan Wakes (The Expanse, #1)", "Author l-f": "Corey, James S. A.", "ISBN": `=""`, "ISBN13": `="9780316129084"`,
+				"My Rating": "5", "Binding": "Kindle Edition", "Date Read": "2024/05/06", "Date Added": "2024/01/02",
+				"Exclusive Shelf": "read", "Read Count": "1",
+			},
+		},
+		{
+			name: "reading",
+			book: bookshelf.Book{Title: "Piranesi", Author: "Susanna Clarke", Status: bookshelf.StatusReading, Rating: rating(1), ISBN: "0316129089"},
+			want: map[string]string{"ISBN": `="0316129089"`, "ISBN13": `=""`, "My Rating": "1", "Exclusive Shelf": "currently-reading", "Read Count": "0"},
+		},
+		{
+			name: "want to read",
+			book: bookshelf.Book{Title: "Middlemarch", Author: "George Eliot", Status: bookshelf.StatusWantToRead, Rating: rating(4), Series: "Unnumbered"},
+			want: map[string]string{"Title": "Middlemarch", "My Rating": "2", "Exclusive Shelf": "to-read", "Read Count": "0"},
+		},
+		{
+			name: "unrated",
+			book: bookshelf.Book{Title: "Dune", Author: "Frank Herbert", Status: bookshelf.StatusRead, Review: "Spice & <sand>\nmore"},
+			want: map[string]string{"My Rating": "0", "My Review": "Spice &amp; &lt;sand&gt;<br/>more"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record := goodreadsRecord(tt.book)
+			if len(record) != len(goodreadsHeader) {
+				t.Fatalf("record has %d columns, want %d", len(record), len(goodreadsHeader))
+			}
+			for i, column := range goodreadsHeader {
+				if want, ok := tt.want[column]; ok && record[i] != want {
+					t.Errorf("%s = %q, want %q", column, record[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestGoodreadsRoundTrip(t *testing.T) {
+	rating := func(n int) *int { return &n }
+	books := []bookshelf.Book{
+		{Title: "Leviathan Wakes", Author: "James S. A. Corey", Series: "The Expanse #1", Status: bookshelf.StatusRead, Rating: rating(9),
+			ISBN: "9780316129084", FinishedAt: "2024-05-06", CreatedAt: "2024-01-02T03:04:05Z", Tags: []string{"sci-fi", "space opera"},
+			Review: "Great start.\nHolden & Miller, \"the odd couple\"."},
+		{Title: "Piranesi", Author: "Susanna Clarke", Status: bookshelf.StatusReading, Rating: rating(2), ISBN: "0316129089", CreatedAt: "2025-02-20T00:00:00Z"},
+		{Title: "Middlemarch", Author: "George Eliot", Status: bookshelf.StatusWantToRead},
+	}
+
+	out, err := Generate("goodreads", books, nil, reportNow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := csv.NewReader(bytes.NewReader(out)).ReadAll(); err != nil {
+		t.Fatalf("export is not valid CSV: %v", err)
+	}
+	rows, err := importer.Goodreads{}.Parse(bytes.NewReader(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []bookshelf.APIBook{
+		// The series number is lost in the title, and 9 is four and a half
+		// stars, exported as 5 and imported as 10
+		{Title: "Leviathan Wakes", Author: "James S. A. Corey", Series: "The Expanse", Status: bookshelf.StatusRead,
+			Rating: rating(10), ISBN: "9780316129084", FinishedAt: "2024-05-06", CreatedAt: "2024-01-02T00:00:00Z",
+			Tags: []string{"sci-fi", "space opera"}, Review: "Great start.\nHolden & Miller, \"the odd couple\"."},
+		{Title: "Piranesi", Author: "Susanna Clarke", Status: bookshelf.StatusReading, Rating: rating(2), ISBN: "0316129089", CreatedAt: "2025-02-20T00:00:00Z"},
+		{Title: "Middlemarch", Author: "George Eliot", Status: bookshelf.StatusWantToRead},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("imported %d rows, want %d", len(rows), len(want))
+	}
+	for i, row := range rows {
+		if row.Err != nil {
+			t.Errorf("row %d: %v", row.Number, row.Err)
+			continue
+		}
+		if !reflect.DeepEqual(row.Book, want[i]) {
+			t.Errorf("row %d imported as\n%+v\nwant\n%+v", row.Number, row.Book, want[i])
+		}
+	}
+	if t.Failed() {
+		t.Logf("export:\n%s", strings.TrimSpace(string(out)))
+	}
+}
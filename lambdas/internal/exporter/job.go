@@ -0,0 +1,222 @@
+package exporter
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/bookshelf"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/objectstore"
+)
+
+// Statuses of an export job.
+const (
+	// JobPending is waiting for the background export to start.
+	JobPending    = "pending"
+	JobProcessing = "processing"
+	JobCompleted  = "completed"
+	JobFailed     = "failed"
+)
+
+// JobTTL is how long an export job can be looked up after it was created.
+// It matches Retention, so a job is kept as long as its file.
+const JobTTL = Retention
+
+const (
+	// JobLease is how long a started job may run before it is presumed
+	// dead: the worker's 900 second timeout and a minute to spare. Start
+	// takes over a job that has been processing for longer.
+	JobLease = 16 * time.Minute
+
+	// JobMaxEventAge is how long Lambda keeps retrying to deliver a job to
+	// the worker, as maximum_event_age_in_seconds in
+	// lambda_export_process.tf. A job still pending after that never
+	// starts.
+	JobMaxEventAge = time.Hour
+)
+
+// JobTimedOut is the error of a job that did not finish in time.
+const JobTimedOut = "The export did not finish in time"
+
+var (
+	// ErrJobNotFound is returned when an export job does not exist for the
+	// given user.
+	ErrJobNotFound = errors.New("export job not found")
+
+	// ErrJobStarted is returned by JobRepository.Start for a job that is no
+	// longer pending, or is still within its lease.
+	ErrJobStarted = errors.New("export job already started")
+)
+
+// Job tracks an export written in the background. Jobs are stored in the
+// user's partition under EXPORT#<export id> until expires_at.
+type Job struct {
+	PK      string            `dynamodbav:"PK"`
+	SK      string            `dynamodbav:"SK"`
+	ID      string            `dynamodbav:"id"`
+	Format  string            `dynamodbav:"format"`
+	Filters map[string]string `dynamodbav:"filters,omitempty"`
+	// Options lay out CSV exports, when they are not the default.
+	Options *CSVOptions `dynamodbav:"options,omitempty"`
+	Status  string      `dynamodbav:"status"`
+	// BookCount is the number of books written to the file and Size its
+	// length in bytes, known once the job completes.
+	BookCount int   `dynamodbav:"book_count"`
+	Size      int64 `dynamodbav:"size"`
+	// Error is why a failed job could not write the file. It is safe to
+	// show to clients.
+	Error     string `dynamodbav:"error,omitempty"`
+	CreatedAt string `dynamodbav:"created_at"`
+	// StartedAt is when the worker last started the job.
+	StartedAt string `dynamodbav:"started_at,omitempty"`
+	UpdatedAt string `dynamodbav:"updated_at"`
+	ExpiresAt int64  `dynamodbav:"expires_at"`
+}
+
+// JobRepository stores export jobs. Like BookRepository, every operation is
+// scoped to a single user.
+type JobRepository interface {
+	// Put stores the user's job, replacing any job with the same ID.
+	Put(ctx context.Context, userID string, job Job) error
+	// Get returns the user's unexpired job with the given ID, or
+	// ErrJobNotFound. A job no worker will finish is returned failed.
+	Get(ctx context.Context, userID, exportID string) (Job, error)
+	// Start moves the user's pending job to JobProcessing and returns it,
+	// or takes over a job processing for longer than JobLease, whose worker
+	// died. It returns ErrJobNotFound, or ErrJobStarted otherwise, so a job
+	// delivered twice is only run once.
+	Start(ctx context.Context, userID, exportID string) (Job, error)
+	// List returns the user's unexpired jobs, like Get.
+	List(ctx context.Context, userID string) ([]Job, error)
+}
+
+var (
+	_ JobRepository = (*DynamoJobRepository)(nil)
+	_ JobRepository = (*MemoryJobRepository)(nil)
+)
+
+// NewJob returns a pending job for the user, created at now, exporting the
+// books filters select in format, laid out as opts directs.
+func NewJob(userID, exportID, format string, filters map[string]string, opts CSVOptions, now time.Time) Job {
+	timestamp := now.UTC().Format(time.RFC3339)
+	job := Job{
+		PK:        bookshelf.UserPK(userID),
+		SK:        bookshelf.ExportSK(exportID),
+		ID:        exportID,
+		Format:    format,
+		Filters:   AppliedFilters(filters),
+		Status:    JobPending,
+		CreatedAt: timestamp,
+		UpdatedAt: timestamp,
+		ExpiresAt: now.Add(JobTTL).Unix(),
+	}
+	if !opts.IsZero() {
+		job.Options = &opts
+	}
+	return job
+}
+
+// Complete marks the job completed, having written bookCount books in size
+// bytes.
+func (j *Job) Complete(bookCount int, size int64) {
+	j.touch()
+	j.Status = JobCompleted
+	j.BookCount = bookCount
+	j.Size = size
+}
+
+// Fail marks the job failed with a reason that is safe to show to clients.
+func (j *Job) Fail(reason string) {
+	j.touch()
+	j.Status = JobFailed
+	j.Error = reason
+}
+
+func (j *Job) touch() {
+	j.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
+}
+
+// start moves the job to JobProcessing at now.
+func (j *Job) start(now time.Time) {
+	timestamp := now.UTC().Format(time.RFC3339)
+	j.Status = JobProcessing
+	j.StartedAt = timestamp
+	j.UpdatedAt = timestamp
+}
+
+// leaseExpired reports whether the job has been processing for longer than
+// JobLease at now, so its worker is no longer running.
+func (j Job) leaseExpired(now time.Time) bool {
+	if j.Status != JobProcessing {
+		return false
+	}
+	started, err := time.Parse(time.RFC3339, j.StartedAt)
+	if err != nil {
+		// Jobs started before leases were recorded only have updated_at
+		started, err = time.Parse(time.RFC3339, j.UpdatedAt)
+	}
+	return err == nil && now.Sub(started) > JobLease
+}
+
+// stalled reports whether no worker will finish the job at now: it has
+// outlived its lease, or Lambda has stopped trying to deliver it.
+func (j Job) stalled(now time.Time) bool {
+	if j.Status == JobPending {
+		created, err := time.Parse(time.RFC3339, j.CreatedAt)
+		return err == nil && now.Sub(created) > JobMaxEventAge
+	}
+	return j.leaseExpired(now)
+}
+
+// current returns the job as it stands at now: a stalled job has failed, so
+// that clients stop polling it.
+func (j Job) current(now time.Time) Job {
+	if j.stalled(now) {
+		j.Status = JobFailed
+		j.Error = JobTimedOut
+	}
+	return j
+}
+
+// expired reports whether the job is past its TTL at now. DynamoDB deletes
+// expired jobs some time after expires_at, so reads must check it.
+func (j Job) expired(now time.Time) bool {
+	return now.Unix() >= j.ExpiresAt
+}
+
+// ToAPI converts a job into its API representation, with the size and
+// expiry of its file if it has one.
+func (j Job) ToAPI(file objectstore.Object) APIExport {
+	export := APIExport{
+		ID:        j.ID,
+		Format:    j.Format,
+		Status:    j.Status,
+		Filters:   j.Filters,
+		Options:   j.Options,
+		Error:     j.Error,
+		CreatedAt: j.CreatedAt,
+	}
+	if j.Status == JobCompleted {
+		bookCount := j.BookCount
+		export.BookCount = &bookCount
+		export.Size = j.Size
+	}
+	if file.Key != "" {
+		export.Size = file.Size
+		export.ExpiresAt = file.LastModified.Add(Retention).UTC().Format(time.RFC3339)
+	}
+	return export
+}
+
+// cloneJob copies job so callers cannot mutate stored state.
+func cloneJob(job Job) Job {
+	job.Filters = maps.Clone(job.Filters)
+	if job.Options != nil {
+		opts := *job.Options
+		opts.Fields = slices.Clone(opts.Fields)
+		job.Options = &opts
+	}
+	return job
+}
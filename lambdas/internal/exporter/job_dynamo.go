@@ -0,0 +1,176 @@
+package exporter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/bookshelf"
+)
+
+// JobDynamoDBAPI is the subset of the DynamoDB client used by
+// DynamoJobRepository.
+type JobDynamoDBAPI interface {
+	GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error)
+	PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error)
+	UpdateItem(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error)
+	Query(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error)
+}
+
+// DynamoJobRepository is a JobRepository keeping jobs in the books table,
+// next to the books of the user who started them.
+type DynamoJobRepository struct {
+	client JobDynamoDBAPI
+	table  string
+}
+
+// NewDynamoJobRepository returns a repository storing jobs in table.
+func NewDynamoJobRepository(client JobDynamoDBAPI, table string) *DynamoJobRepository {
+	return &DynamoJobRepository{client: client, table: table}
+}
+
+// Put stores the user's job, replacing any job with the same ID.
+func (r *DynamoJobRepository) Put(ctx context.Context, userID string, job Job) error {
+	job.PK = bookshelf.UserPK(userID)
+	job.SK = bookshelf.ExportSK(job.ID)
+	item, err := attributevalue.MarshalMap(job)
+	if err != nil {
+		return fmt.Errorf("failed to marshal export job: %w", err)
+	}
+
+	_, err = r.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(r.table),
+		Item:      item,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to put export job: %w", err)
+	}
+	return nil
+}
+
+// Get returns the user's unexpired job with the given ID, or ErrJobNotFound.
+func (r *DynamoJobRepository) Get(ctx context.Context, userID, exportID string) (Job, error) {
+	result, err := r.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(r.table),
+		Key:            jobKey(userID, exportID),
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return Job{}, fmt.Errorf("failed to get export job: %w", err)
+	}
+	if result.Item == nil {
+		return Job{}, ErrJobNotFound
+	}
+
+	var job Job
+	if err := attributevalue.UnmarshalMap(result.Item, &job); err != nil {
+		return Job{}, fmt.Errorf("failed to unmarshal export job: %w", err)
+	}
+	now := time.Now()
+	if job.expired(now) {
+		return Job{}, ErrJobNotFound
+	}
+	return job.current(now), nil
+}
+
+// Start moves the user's pending job to JobProcessing with one conditional
+// update and returns it, or takes over a job whose lease has expired.
+// RFC 3339 UTC timestamps sort as strings, so the lease is compared in the
+// condition.
+func (r *DynamoJobRepository) Start(ctx context.Context, userID, exportID string) (Job, error) {
+	now := time.Now()
+	result, err := r.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:        aws.String(r.table),
+		Key:              jobKey(userID, exportID),
+		UpdateExpression: aws.String("SET #status = :processing, started_at = :now, updated_at = :now"),
+		ConditionExpression: aws.String("attribute_exists(PK) AND expires_at > :unix AND " +
+			"(#status = :pending OR (#status = :processing AND started_at < :stale))"),
+		ExpressionAttributeNames: map[string]string{
+			"#status": "status",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":processing": &types.AttributeValueMemberS{Value: JobProcessing},
+			":pending":    &types.AttributeValueMemberS{Value: JobPending},
+			":now":        &types.AttributeValueMemberS{Value: now.UTC().Format(time.RFC3339)},
+			":stale":      &types.AttributeValueMemberS{Value: now.Add(-JobLease).UTC().Format(time.RFC3339)},
+			":unix":       &types.AttributeValueMemberN{Value: strconv.FormatInt(now.Unix(), 10)},
+		},
+		ReturnValues:                        types.ReturnValueAllNew,
+		ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
+	})
+	var failed *types.ConditionalCheckFailedException
+	if errors.As(err, &failed) {
+		var job Job
+		if failed.Item == nil {
+			return Job{}, ErrJobNotFound
+		}
+		if err := attributevalue.UnmarshalMap(failed.Item, &job); err != nil {
+			return Job{}, fmt.Errorf("failed to unmarshal export job: %w", err)
+		}
+		if job.expired(now) {
+			return Job{}, ErrJobNotFound
+		}
+		return Job{}, ErrJobStarted
+	}
+	if err != nil {
+		return Job{}, fmt.Errorf("failed to start export job: %w", err)
+	}
+
+	var job Job
+	if err := attributevalue.UnmarshalMap(result.Attributes, &job); err != nil {
+		return Job{}, fmt.Errorf("failed to unmarshal export job: %w", err)
+	}
+	return job, nil
+}
+
+// List returns the user's unexpired jobs, querying every EXPORT# item in
+// their partition.
+func (r *DynamoJobRepository) List(ctx context.Context, userID string) ([]Job, error) {
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(r.table),
+		KeyConditionExpression: aws.String("PK = :pk AND begins_with(SK, :export)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk":     &types.AttributeValueMemberS{Value: bookshelf.UserPK(userID)},
+			":export": &types.AttributeValueMemberS{Value: bookshelf.ExportSK("")},
+		},
+	}
+
+	now := time.Now()
+	var jobs []Job
+	for {
+		result, err := r.client.Query(ctx, input)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query export jobs: %w", err)
+		}
+
+		var page []Job
+		if err := attributevalue.UnmarshalListOfMaps(result.Items, &page); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal export jobs: %w", err)
+		}
+		for _, job := range page {
+			if !job.expired(now) {
+				jobs = append(jobs, job.current(now))
+			}
+		}
+
+		if result.LastEvaluatedKey == nil {
+			break
+		}
+		input.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+	return jobs, nil
+}
+
+// jobKey returns the DynamoDB primary key of a user's export job.
+func jobKey(userID, exportID string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"PK": &types.AttributeValueMemberS{Value: bookshelf.UserPK(userID)},
+		"SK": &types.AttributeValueMemberS{Value: bookshelf.ExportSK(exportID)},
+	}
+}
@@ -0,0 +1,78 @@
+package exporter
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryJobRepository is an in-memory JobRepository for local development
+// and tests. It is safe for concurrent use.
+type MemoryJobRepository struct {
+	mu   sync.Mutex
+	jobs map[string]map[string]Job // user ID -> export ID -> job
+}
+
+// NewMemoryJobRepository returns an empty in-memory job repository.
+func NewMemoryJobRepository() *MemoryJobRepository {
+	return &MemoryJobRepository{jobs: make(map[string]map[string]Job)}
+}
+
+// Put stores the user's job, replacing any job with the same ID.
+func (r *MemoryJobRepository) Put(ctx context.Context, userID string, job Job) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.jobs[userID] == nil {
+		r.jobs[userID] = make(map[string]Job)
+	}
+	r.jobs[userID][job.ID] = cloneJob(job)
+	return nil
+}
+
+// Get returns the user's unexpired job with the given ID, or ErrJobNotFound.
+func (r *MemoryJobRepository) Get(ctx context.Context, userID, exportID string) (Job, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	job, ok := r.jobs[userID][exportID]
+	if !ok || job.expired(now) {
+		return Job{}, ErrJobNotFound
+	}
+	return cloneJob(job).current(now), nil
+}
+
+// Start moves the user's pending job to JobProcessing and returns it, or
+// takes over a job whose lease has expired.
+func (r *MemoryJobRepository) Start(ctx context.Context, userID, exportID string) (Job, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	job, ok := r.jobs[userID][exportID]
+	if !ok || job.expired(now) {
+		return Job{}, ErrJobNotFound
+	}
+	if job.Status != JobPending && !job.leaseExpired(now) {
+		return Job{}, ErrJobStarted
+	}
+	job.start(now)
+	r.jobs[userID][exportID] = job
+	return cloneJob(job), nil
+}
+
+// List returns the user's unexpired jobs.
+func (r *MemoryJobRepository) List(ctx context.Context, userID string) ([]Job, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	var jobs []Job
+	for _, job := range r.jobs[userID] {
+		if !job.expired(now) {
+			jobs = append(jobs, cloneJob(job).current(now))
+		}
+	}
+	return jobs, nil
+}
@@ -0,0 +1,115 @@
+package exporter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMemoryJobRepositoryLease(t *testing.T) {
+	now := time.Now()
+	ago := func(d time.Duration) string { return now.Add(-d).UTC().Format(time.RFC3339) }
+
+	tests := []struct {
+		name       string
+		job        Job
+		wantStatus string // as Get reports it before Start
+		wantStart  error
+	}{
+		{
+			name:       "pending",
+			job:        Job{Status: JobPending, CreatedAt: ago(time.Minute)},
+			wantStatus: JobPending,
+		},
+		{
+			name:       "processing within its lease",
+			job:        Job{Status: JobProcessing, CreatedAt: ago(5 * time.Minute), StartedAt: ago(5 * time.Minute)},
+			wantStatus: JobProcessing,
+			wantStart:  ErrJobStarted,
+		},
+		{
+			name:       "processing past its lease",
+			job:        Job{Status: JobProcessing, CreatedAt: ago(JobLease + time.Minute), StartedAt: ago(JobLease + time.Minute)},
+			wantStatus: JobFailed,
+		},
+		{
+			name:       "processing past its lease without started_at",
+			job:        Job{Status: JobProcessing, CreatedAt: ago(JobLease + time.Minute), UpdatedAt: ago(JobLease + time.Minute)},
+			wantStatus: JobFailed,
+		},
+		{
+			name:       "pending past the event age",
+			job:        Job{Status: JobPending, CreatedAt: ago(JobMaxEventAge + time.Minute)},
+			wantStatus: JobFailed,
+		},
+		{
+			name:       "completed",
+			job:        Job{Status: JobCompleted, CreatedAt: ago(time.Hour), StartedAt: ago(time.Hour)},
+			wantStatus: JobCompleted,
+			wantStart:  ErrJobStarted,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			jobs := NewMemoryJobRepository()
+			tt.job.ID = "export-1"
+			tt.job.ExpiresAt = now.Add(JobTTL).Unix()
+			if err := jobs.Put(ctx, "user-1", tt.job); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := jobs.Get(ctx, "user-1", "export-1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Status != tt.wantStatus {
+				t.Errorf("Get status = %q, want %q", got.Status, tt.wantStatus)
+			}
+			if tt.wantStatus == JobFailed && got.Error != JobTimedOut {
+				t.Errorf("Get error = %q, want %q", got.Error, JobTimedOut)
+			}
+			listed, err := jobs.List(ctx, "user-1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(listed) != 1 || listed[0].Status != tt.wantStatus {
+				t.Errorf("List = %+v, want one job %q", listed, tt.wantStatus)
+			}
+
+			started, err := jobs.Start(ctx, "user-1", "export-1")
+			if !errors.Is(err, tt.wantStart) {
+				t.Fatalf("Start error = %v, want %v", err, tt.wantStart)
+			}
+			if err != nil {
+				return
+			}
+			if started.Status != JobProcessing {
+				t.Errorf("Start status = %q, want %q", started.Status, JobProcessing)
+			}
+			if started.StartedAt < ago(time.Minute) {
+				t.Errorf("Start started_at = %q, want about %q", started.StartedAt, ago(0))
+			}
+			if _, err := jobs.Start(ctx, "user-1", "export-1"); !errors.Is(err, ErrJobStarted) {
+				t.Errorf("second Start error = %v, want %v", err, ErrJobStarted)
+			}
+		})
+	}
+}
+
+func TestMemoryJobRepositoryStartNotFound(t *testing.T) {
+	jobs := NewMemoryJobRepository()
+	ctx := context.Background()
+	if _, err := jobs.Start(ctx, "user-1", "missing"); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("Start error = %v, want %v", err, ErrJobNotFound)
+	}
+
+	expired := Job{ID: "expired", Status: JobPending, ExpiresAt: time.Now().Add(-time.Minute).Unix()}
+	if err := jobs.Put(ctx, "user-1", expired); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := jobs.Start(ctx, "user-1", "expired"); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("Start error = %v, want %v", err, ErrJobNotFound)
+	}
+}
@@ -0,0 +1,280 @@
+package exporter
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/bookshelf"
+	"github.com/go-pdf/fpdf"
+)
+
+// PDF layout, in millimetres on A4 paper.
+const (
+	pdfMargin     = 18.0
+	pdfLineHeight = 5.0
+	pdfStarSize   = 3.6
+	pdfStarsWidth = 5 * (pdfStarSize + 0.6)
+)
+
+// PDF colours, as RGB.
+var (
+	pdfTextColor  = [3]int{44, 42, 38}
+	pdfMutedColor = [3]int{122, 115, 104}
+	pdfRuleColor  = [3]int{217, 210, 197}
+	pdfStarColor  = [3]int{201, 138, 20}
+)
+
+// pdfReport draws a reading report with fpdf, which is pure Go and so runs
+// in the Lambda without a browser. Text is set in the PDF core fonts, so
+// characters outside Windows-1252 cannot be shown.
+type pdfReport struct {
+	pdf *fpdf.Fpdf
+	// tr converts UTF-8 text to the core fonts' encoding.
+	tr func(string) string
+}
+
+// generatePDF renders books as a printable PDF reading report: summary
+// statistics followed by the books grouped like the Markdown and HTML
+// reports. Page content is left uncompressed so that the report's text can
+// be searched and checked without a PDF library.
+func generatePDF(books []bookshelf.Book, now time.Time) ([]byte, error) {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetCompression(false)
+	pdf.SetMargins(pdfMargin, pdfMargin, pdfMargin)
+	pdf.SetAutoPageBreak(true, pdfMargin)
+	pdf.SetTitle("Reading report", true)
+	pdf.SetCreator("bookshelf", true)
+	pdf.SetCreationDate(now)
+	pdf.SetModificationDate(now)
+	pdf.AliasNbPages("")
+
+	r := &pdfReport{pdf: pdf, tr: pdf.UnicodeTranslatorFromDescriptor("")}
+	pdf.SetFooterFunc(r.footer)
+	r.draw(newReport(books, now))
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (r *pdfReport) draw(report report) {
+	r.pdf.AddPage()
+	r.text("B", 22, pdfTextColor)
+	r.pdf.CellFormat(0, 10, r.tr("Reading report"), "", 1, "L", false, 0, "")
+	r.text("", 10, pdfMutedColor)
+	r.pdf.CellFormat(0, 6, r.tr(fmt.Sprintf("Generated %s", report.GeneratedAt)), "", 1, "L", false, 0, "")
+	r.pdf.Ln(4)
+	r.summary(report)
+
+	if len(report.Sections) == 0 {
+		r.text("", 11, pdfTextColor)
+		r.pdf.CellFormat(0, 8, "No books to report.", "", 1, "L", false, 0, "")
+	}
+	for _, section := range report.Sections {
+		r.keep(30)
+		r.pdf.Ln(4)
+		r.text("B", 16, pdfTextColor)
+		r.pdf.CellFormat(0, 9, r.tr(fmt.Sprintf("%s (%d)", section.Label, section.Count)), "B", 1, "L", false, 0, "")
+		for _, year := range section.Years {
+			r.year(year)
+		}
+	}
+}
+
+// summary draws the report's totals as a row of figures, followed by the
+// number of books read in each year.
+func (r *pdfReport) summary(report report) {
+	counts := map[string]int{}
+	rated, sum, reviewed := 0, 0, 0
+	var readByYear []string
+	for _, section := range report.Sections {
+		counts[section.Status] = section.Count
+		for _, year := range section.Years {
+			if section.Status == bookshelf.StatusRead && year.Year != "" {
+				readByYear = append(readByYear, fmt.Sprintf("%s: %d", year.Year, year.Count))
+			}
+			for _, book := range year.Books {
+				if book.Rating != 0 {
+					rated++
+					sum += book.Rating
+				}
+				if book.Review != "" {
+					reviewed++
+				}
+			}
+		}
+	}
+	average := "-"
+	if rated > 0 {
+		average = strings.TrimSuffix(strconv.FormatFloat(float64(sum)/float64(rated)/2, 'f', 1, 64), ".0") + " / 5"
+	}
+
+	figures := []struct{ value, label string }{
+		{strconv.Itoa(report.BookCount), "Books"},
+		{strconv.Itoa(counts[bookshelf.StatusRead]), "Read"},
+		{strconv.Itoa(counts[bookshelf.StatusReading]), "Reading"},
+		{strconv.Itoa(counts[bookshelf.StatusWantToRead]), "Want to read"},
+		{average, "Average rating"},
+		{strconv.Itoa(reviewed), "Reviews"},
+	}
+	pageWidth, _ := r.pdf.GetPageSize()
+	width := (pageWidth - 2*pdfMargin) / float64(len(figures))
+	x, y := r.pdf.GetX(), r.pdf.GetY()
+	r.pdf.SetDrawColor(pdfRuleColor[0], pdfRuleColor[1], pdfRuleColor[2])
+	for i, figure := range figures {
+		r.pdf.SetXY(x+float64(i)*width, y)
+		r.text("B", 16, pdfTextColor)
+		r.pdf.CellFormat(width, 9, figure.value, "LTR", 2, "C", false, 0, "")
+		r.text("", 8, pdfMutedColor)
+		r.pdf.CellFormat(width, 6, figure.label, "LBR", 0, "C", false, 0, "")
+	}
+	r.pdf.SetXY(x, y+17)
+
+	if len(readByYear) > 0 {
+		r.text("", 9, pdfMutedColor)
+		r.pdf.MultiCell(0, pdfLineHeight, r.tr("Books read by year: "+strings.Join(readByYear, " · ")), "", "L", false)
+	}
+}
+
+// year draws a year heading with its totals, then its books.
+func (r *pdfReport) year(year reportYear) {
+	r.keep(24)
+	r.pdf.Ln(3)
+	label := year.Year
+	if label == "" {
+		label = "Undated"
+	}
+	totals := fmt.Sprintf("%d %s", year.Count, plural(year.Count, "book", "books"))
+	if year.Rated > 0 {
+		totals += fmt.Sprintf(" · average %s / 5 from %d rated", year.AverageStars, year.Rated)
+	}
+	r.text("B", 12, pdfTextColor)
+	r.pdf.CellFormat(18, 7, label, "", 0, "L", false, 0, "")
+	r.text("", 9, pdfMutedColor)
+	r.pdf.CellFormat(0, 7, r.tr(totals), "", 1, "L", false, 0, "")
+
+	for _, book := range year.Books {
+		r.book(book)
+	}
+}
+
+// book draws one book: its title with the rating as stars beside it, a line
+// of details, and its review.
+func (r *pdfReport) book(book reportBook) {
+	pageWidth, _ := r.pdf.GetPageSize()
+	contentWidth := pageWidth - 2*pdfMargin
+	titleWidth := contentWidth - pdfStarsWidth - 4
+
+	var details []string
+	details = append(details, book.Author)
+	if book.Series != "" {
+		details = append(details, book.Series)
+	}
+	if book.Date != "" {
+		details = append(details, book.Date)
+	}
+	if len(book.Tags) > 0 {
+		details = append(details, strings.Join(book.Tags, ", "))
+	}
+	detailLine := r.tr(strings.Join(details, " · "))
+
+	// Keep short entries on one page
+	r.text("B", 11, pdfTextColor)
+	height := float64(len(r.pdf.SplitLines([]byte(r.tr(book.Title)), titleWidth)))*pdfLineHeight + pdfLineHeight + 4
+	if book.Review != "" {
+		r.text("I", 9.5, pdfTextColor)
+		height += float64(len(r.pdf.SplitLines([]byte(r.tr(book.Review)), contentWidth-6)))*4.5 + 2
+	}
+	r.keep(height)
+
+	y := r.pdf.GetY()
+	if book.Stars != "" {
+		r.stars(pdfMargin+contentWidth-pdfStarsWidth, y+0.7, book.Rating)
+	}
+	r.text("B", 11, pdfTextColor)
+	r.pdf.MultiCell(titleWidth, pdfLineHeight, r.tr(book.Title), "", "L", false)
+	r.text("", 9, pdfMutedColor)
+	r.pdf.MultiCell(contentWidth, pdfLineHeight, detailLine, "", "L", false)
+	if book.Review != "" {
+		r.pdf.Ln(1)
+		r.text("I", 9.5, pdfTextColor)
+		r.pdf.SetX(pdfMargin + 6)
+		r.pdf.MultiCell(contentWidth-6, 4.5, r.tr(book.Review), "", "L", false)
+	}
+
+	r.pdf.Ln(1.5)
+	r.pdf.SetDrawColor(pdfRuleColor[0], pdfRuleColor[1], pdfRuleColor[2])
+	r.pdf.Line(pdfMargin, r.pdf.GetY(), pdfMargin+contentWidth, r.pdf.GetY())
+	r.pdf.Ln(2)
+}
+
+// stars draws a 1-10 rating as five stars from (x, y), a point being half a
+// star.
+func (r *pdfReport) stars(x, y float64, rating int) {
+	r.pdf.SetDrawColor(pdfStarColor[0], pdfStarColor[1], pdfStarColor[2])
+	r.pdf.SetFillColor(pdfStarColor[0], pdfStarColor[1], pdfStarColor[2])
+	r.pdf.SetLineWidth(0.2)
+	for i := 0; i < 5; i++ {
+		left := x + float64(i)*(pdfStarSize+0.6)
+		points := starPoints(left+pdfStarSize/2, y+pdfStarSize/2, pdfStarSize/2)
+		switch {
+		case rating >= 2*(i+1):
+			r.pdf.Polygon(points, "FD")
+		case rating == 2*i+1:
+			r.pdf.ClipRect(left, y, pdfStarSize/2, pdfStarSize, false)
+			r.pdf.Polygon(points, "F")
+			r.pdf.ClipEnd()
+			r.pdf.Polygon(points, "D")
+		default:
+			r.pdf.Polygon(points, "D")
+		}
+	}
+}
+
+// starPoints returns the corners of a five-pointed star centred on (cx, cy)
+// with points radius from the centre.
+func starPoints(cx, cy, radius float64) []fpdf.PointType {
+	points := make([]fpdf.PointType, 10)
+	for i := range points {
+		distance := radius
+		if i%2 == 1 {
+			distance = radius * 0.45
+		}
+		angle := -math.Pi/2 + float64(i)*math.Pi/5
+		points[i] = fpdf.PointType{X: cx + distance*math.Cos(angle), Y: cy + distance*math.Sin(angle)}
+	}
+	return points
+}
+
+// keep starts a new page unless height millimetres fit on this one.
+func (r *pdfReport) keep(height float64) {
+	_, pageHeight := r.pdf.GetPageSize()
+	if r.pdf.GetY()+height > pageHeight-pdfMargin {
+		r.pdf.AddPage()
+	}
+}
+
+func (r *pdfReport) footer() {
+	r.pdf.SetY(-12)
+	r.text("", 8, pdfMutedColor)
+	r.pdf.CellFormat(0, 5, r.tr(fmt.Sprintf("Reading report · Page %d of {nb}", r.pdf.PageNo())), "", 0, "C", false, 0, "")
+}
+
+// text sets the font style and size and the text colour.
+func (r *pdfReport) text(style string, size float64, color [3]int) {
+	r.pdf.SetFont("Helvetica", style, size)
+	r.pdf.SetTextColor(color[0], color[1], color[2])
+}
+
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return one
+	}
+	return many
+}
@@ -0,0 +1,108 @@
+package exporter
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/bookshelf"
+)
+
+// pdfPageCount matches the page count of the page tree.
+var pdfPageCount = regexp.MustCompile(`/Type /Pages\s*/Kids \[[^\]]*\]\s*/Count (\d+)`)
+
+// pdfShows reports whether the uncompressed pdf shows text as one string,
+// in the Windows-1252 encoding of the core fonts.
+func pdfShows(pdf []byte, text string) bool {
+	text = strings.NewReplacer(`\`, `\\`, "(", `\(`, ")", `\)`, "·", "\xb7").Replace(text)
+	return bytes.Contains(pdf, []byte("("+text+")Tj"))
+}
+
+func TestGeneratePDF(t *testing.T) {
+	// Enough reviewed books to fill several pages
+	books := reportBooks()
+	for i := range 40 {
+		rating := i%10 + 1
+		books = append(books, bookshelf.Book{
+			ID:         fmt.Sprintf("extra-%d", i),
+			Title:      fmt.Sprintf("Extra Book %d", i),
+			Author:     "Prolific Author",
+			Status:     bookshelf.StatusRead,
+			Rating:     &rating,
+			FinishedAt: "2022-06-01",
+			Review:     strings.Repeat("A review that runs over more than one line of the page. ", 4),
+		})
+	}
+
+	tests := []struct {
+		name  string
+		books []bookshelf.Book
+		pages int
+		shows []string
+	}{
+		{
+			name:  "library",
+			books: books,
+			pages: 5,
+			shows: []string{
+				"Reading report",
+				"Generated March 1, 2025",
+				"46", "Books",
+				"Average rating", "2.8 / 5",
+				"Books read by year: 2024: 2 · 2023: 1 · 2022: 40",
+				"Currently reading (1)",
+				"Read (44)",
+				"Want to read (1)",
+				"2 books · average 4 / 5 from 2 rated",
+				"40 books · average 2.8 / 5 from 40 rated",
+				"Undated",
+				"The <b>Bold</b> & the *Starred*",
+				"Loved it <script>alert(1)</script>",
+				"Frank Herbert · Dune #1 · 2024-11-02 · sci-fi, classic",
+				"Reading report · Page 1 of 5",
+				"Reading report · Page 5 of 5",
+			},
+		},
+		{
+			name:  "empty",
+			pages: 1,
+			shows: []string{
+				"Reading report",
+				"0", "Books",
+				"-", "Average rating",
+				"No books to report.",
+				"Reading report · Page 1 of 1",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pdf, err := generatePDF(tt.books, reportNow)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.HasPrefix(pdf, []byte("%PDF-")) {
+				t.Fatalf("output starts %q, want a PDF header", pdf[:min(len(pdf), 8)])
+			}
+
+			match := pdfPageCount.FindSubmatch(pdf)
+			if match == nil {
+				t.Fatal("PDF has no page tree count")
+			}
+			if pages, _ := strconv.Atoi(string(match[1])); pages != tt.pages {
+				t.Errorf("PDF has %d pages, want %d", pages, tt.pages)
+			}
+			for _, text := range tt.shows {
+				if !pdfShows(pdf, text) {
+					t.Errorf("PDF does not show %q", text)
+				}
+			}
+			if got := pdfShows(pdf, "No books to report."); got != (len(tt.books) == 0) {
+				t.Errorf("PDF shows the empty library note: %v, want %v", got, len(tt.books) == 0)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package exporter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+// Task is the event a background export is started with. The job it names
+// holds everything else the export needs.
+type Task struct {
+	UserID   string `json:"user_id"`
+	ExportID string `json:"export_id"`
+}
+
+// Queue starts background exports.
+type Queue interface {
+	// Enqueue hands task to the export worker without waiting for it to
+	// run.
+	Enqueue(ctx context.Context, task Task) error
+}
+
+var _ Queue = (*LambdaQueue)(nil)
+
+// LambdaAPI is the subset of the Lambda client used by LambdaQueue.
+type LambdaAPI interface {
+	Invoke(ctx context.Context, params *lambda.InvokeInput, optFns ...func(*lambda.Options)) (*lambda.InvokeOutput, error)
+}
+
+// LambdaQueue is a Queue that invokes the export worker function
+// asynchronously. Lambda queues the event and retries the worker if it
+// returns an error.
+type LambdaQueue struct {
+	client   LambdaAPI
+	function string
+}
+
+// NewLambdaQueue returns a queue invoking function.
+func NewLambdaQueue(client LambdaAPI, function string) *LambdaQueue {
+	return &LambdaQueue{client: client, function: function}
+}
+
+// Enqueue invokes the worker with task as an Event invocation.
+func (q *LambdaQueue) Enqueue(ctx context.Context, task Task) error {
+	payload, err := json.Marshal(task)
+	if err != nil {
+		return fmt.Errorf("failed to marshal export task: %w", err)
+	}
+
+	_, err = q.client.Invoke(ctx, &lambda.InvokeInput{
+		FunctionName:   aws.String(q.function),
+		InvocationType: types.InvocationTypeEvent,
+		Payload:        payload,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to invoke export worker: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,217 @@
+package exporter
+
+import (
+	"bytes"
+	"embed"
+	"html"
+	htmltemplate "html/template"
+	"sort"
+	"strconv"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/bookshelf"
+)
+
+// templates holds the reading report templates. Edit the files under
+// templates/ to change how reports look; the report data they are given is
+// described by report.
+//
+//go:embed templates/*.tmpl
+var templates embed.FS
+
+var (
+	markdownTemplate = texttemplate.Must(texttemplate.New("report.md.tmpl").Funcs(texttemplate.FuncMap{
+		"markdown": escapeMarkdown,
+		"attr":     html.EscapeString,
+		"quote":    quoteMarkdown,
+	}).ParseFS(templates, "templates/report.md.tmpl"))
+	htmlTemplate = htmltemplate.Must(htmltemplate.New("report.html.tmpl").ParseFS(templates, "templates/report.html.tmpl"))
+)
+
+// reportStatuses is the order report sections appear in.
+var reportStatuses = []string{bookshelf.StatusReading, bookshelf.StatusRead, bookshelf.StatusWantToRead}
+
+// statusLabels are the section headings of each status.
+var statusLabels = map[string]string{
+	bookshelf.StatusReading:    "Currently reading",
+	bookshelf.StatusRead:       "Read",
+	bookshelf.StatusWantToRead: "Want to read",
+}
+
+// report is the data a reading report template is executed with.
+type report struct {
+	GeneratedAt string
+	BookCount   int
+	Sections    []reportSection
+}
+
+// reportSection is the books in one status, by year.
+type reportSection struct {
+	Status string
+	Label  string
+	Count  int
+	Years  []reportYear
+}
+
+// reportYear is the books in a section dated in one year: the year they
+// were finished, for read books, started, for books being read, or added.
+// Books without a date are grouped last, with an empty Year.
+type reportYear struct {
+	Year  string
+	Count int
+	// Rated is how many of the books have a rating, and AverageStars their
+	// average rating in stars, as in "3.5".
+	Rated        int
+	AverageStars string
+	Books        []reportBook
+}
+
+// reportBook is one book in a report.
+type reportBook struct {
+	Title     string
+	Author    string
+	Series    string
+	Thumbnail string
+	Date      string
+	// Rating is the book's 1-10 rating, or 0 if it is unrated, and Stars
+	// the rating as five stars, as in "★★★½☆".
+	Rating int
+	Stars  string
+	Tags   []string
+	Review string
+}
+
+// newReport groups books by status and year, newest year first, keeping
+// the export's order within each year.
+func newReport(books []bookshelf.Book, now time.Time) report {
+	r := report{GeneratedAt: now.Format("January 2, 2006"), BookCount: len(books)}
+	for _, status := range reportStatuses {
+		section := reportSection{Status: status, Label: statusLabels[status]}
+		years := map[string]*reportYear{}
+		for _, book := range books {
+			if book.Status != status {
+				continue
+			}
+			item := newReportBook(book)
+			year := years[yearOf(item.Date)]
+			if year == nil {
+				year = &reportYear{Year: yearOf(item.Date)}
+				years[year.Year] = year
+			}
+			year.Books = append(year.Books, item)
+			section.Count++
+		}
+		if section.Count == 0 {
+			continue
+		}
+
+		for _, year := range years {
+			year.total()
+			section.Years = append(section.Years, *year)
+		}
+		sort.Slice(section.Years, func(i, j int) bool {
+			a, b := section.Years[i].Year, section.Years[j].Year
+			return b == "" || (a != "" && a > b)
+		})
+		r.Sections = append(r.Sections, section)
+	}
+	return r
+}
+
+// total counts the year's books and averages their ratings.
+func (y *reportYear) total() {
+	sum := 0
+	for _, book := range y.Books {
+		y.Count++
+		if book.Rating != 0 {
+			y.Rated++
+			sum += book.Rating
+		}
+	}
+	if y.Rated > 0 {
+		average := strconv.FormatFloat(float64(sum)/float64(y.Rated)/2, 'f', 1, 64)
+		y.AverageStars = strings.TrimSuffix(average, ".0")
+	}
+}
+
+func newReportBook(book bookshelf.Book) reportBook {
+	item := reportBook{
+		Title:     book.Title,
+		Author:    book.Author,
+		Series:    book.Series,
+		Thumbnail: book.Thumbnail,
+		Tags:      book.Tags,
+		Review:    book.Review,
+	}
+	switch book.Status {
+	case bookshelf.StatusRead:
+		item.Date = book.FinishedAt
+	case bookshelf.StatusReading:
+		item.Date = book.StartedAt
+	default:
+		item.Date = book.CreatedAt
+	}
+	if len(item.Date) >= len(time.DateOnly) {
+		item.Date = item.Date[:len(time.DateOnly)]
+	}
+	if book.Rating != nil {
+		item.Rating = *book.Rating
+		item.Stars = stars(*book.Rating)
+	}
+	return item
+}
+
+// yearOf returns the year of a YYYY-MM-DD date, or "" if there is none.
+func yearOf(date string) string {
+	if len(date) < 4 {
+		return ""
+	}
+	return date[:4]
+}
+
+// stars draws a 1-10 rating as five stars, a point being half a star.
+func stars(rating int) string {
+	rating = min(max(rating, 0), 10)
+	return strings.Repeat("★", rating/2) + strings.Repeat("½", rating%2) + strings.Repeat("☆", (10-rating)/2)
+}
+
+// generateMarkdown renders books as a Markdown reading report.
+func generateMarkdown(books []bookshelf.Book, now time.Time) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := markdownTemplate.Execute(&buf, newReport(books, now)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// generateHTML renders books as a standalone HTML reading report.
+func generateHTML(books []bookshelf.Book, now time.Time) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := htmlTemplate.Execute(&buf, newReport(books, now)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// markdownSpecial are the characters escaped in Markdown text.
+var markdownSpecial = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`,
+	"<", `\<`, ">", `\>`, "#", `\#`, "|", `\|`,
+)
+
+// escapeMarkdown escapes s so that it renders as plain text in Markdown.
+func escapeMarkdown(s string) string {
+	return markdownSpecial.Replace(s)
+}
+
+// quoteMarkdown escapes s and writes it as a Markdown block quote indented
+// by indent, keeping its line breaks.
+func quoteMarkdown(indent, s string) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	for i, line := range lines {
+		lines[i] = indent + "> " + escapeMarkdown(strings.TrimRight(line, " \r"))
+	}
+	return strings.Join(lines, "\n")
+}
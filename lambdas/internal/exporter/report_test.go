@@ -0,0 +1,177 @@
+package exporter
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/bookshelf"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// reportNow is when the test reports are generated.
+var reportNow = time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+// reportBooks is a library with books in every status, read books over two
+// years and undated, ratings from half a star to four and a half, and titles,
+// tags and reviews that must be escaped.
+func reportBooks() []bookshelf.Book {
+	rating := func(n int) *int { return &n }
+	return []bookshelf.Book{
+		{ID: "1", Title: "Dune", Author: "Frank Herbert", Series: "Dune #1", Status: bookshelf.StatusRead, Rating: rating(9), FinishedAt: "2024-11-02", Tags: []string{"sci-fi", "classic"}},
+		{ID: "2", Title: "The <b>Bold</b> & the *Starred*", Author: "A_Writer", Status: bookshelf.StatusRead, Rating: rating(7), FinishedAt: "2024-03-15T10:00:00Z",
+			Review: "Loved it <script>alert(1)</script>\nSecond line with [a link](http://example.com)."},
+		{ID: "3", Title: "Hyperion", Author: "Dan Simmons", Status: bookshelf.StatusRead, Rating: rating(1), FinishedAt: "2023-07-04"},
+		{ID: "4", Title: "Undated Read", Author: "Nobody", Status: bookshelf.StatusRead},
+		{ID: "5", Title: "Piranesi", Author: "Susanna Clarke", Status: bookshelf.StatusReading, StartedAt: "2025-02-20", Thumbnail: `https://covers.example.com/p.jpg?a=1&b="2"`},
+		{ID: "6", Title: "Middlemarch", Author: "George Eliot", Status: bookshelf.StatusWantToRead, CreatedAt: "2025-01-05T08:00:00Z", Tags: []string{"<classic>"}},
+	}
+}
+
+// checkGolden compares got with testdata/name, rewriting it with -update.
+func checkGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	golden := filepath.Join("testdata", name)
+	if *update {
+		if err := os.MkdirAll("testdata", 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(golden, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("%v (run go test -update to create it)", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("output differs from %s (run go test -update to accept):\n%s", golden, got)
+	}
+}
+
+func TestReportGolden(t *testing.T) {
+	tests := []struct {
+		golden   string
+		generate func([]bookshelf.Book, time.Time) ([]byte, error)
+		books    []bookshelf.Book
+	}{
+		{"report.golden.md", generateMarkdown, reportBooks()},
+		{"report.golden.html", generateHTML, reportBooks()},
+		{"report-empty.golden.md", generateMarkdown, nil},
+		{"report-empty.golden.html", generateHTML, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.golden, func(t *testing.T) {
+			got, err := tt.generate(tt.books, reportNow)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkGolden(t, tt.golden, got)
+		})
+	}
+}
+
+func TestNewReport(t *testing.T) {
+	r := newReport(reportBooks(), reportNow)
+	if r.BookCount != 6 {
+		t.Errorf("BookCount = %d, want 6", r.BookCount)
+	}
+	var labels []string
+	for _, section := range r.Sections {
+		labels = append(labels, section.Label)
+	}
+	if got, want := strings.Join(labels, ", "), "Currently reading, Read, Want to read"; got != want {
+		t.Errorf("sections = %s, want %s", got, want)
+	}
+
+	read := r.Sections[1]
+	if read.Count != 4 {
+		t.Errorf("read count = %d, want 4", read.Count)
+	}
+	want := []struct {
+		year    string
+		count   int
+		rated   int
+		average string
+	}{
+		{"2024", 2, 2, "4"},
+		{"2023", 1, 1, "0.5"},
+		{"", 1, 0, ""},
+	}
+	if len(read.Years) != len(want) {
+		t.Fatalf("read has %d years, want %d", len(read.Years), len(want))
+	}
+	for i, w := range want {
+		y := read.Years[i]
+		if y.Year != w.year || y.Count != w.count || y.Rated != w.rated || y.AverageStars != w.average {
+			t.Errorf("year %d = %q %d books, %d rated, average %q; want %q %d, %d, %q",
+				i, y.Year, y.Count, y.Rated, y.AverageStars, w.year, w.count, w.rated, w.average)
+		}
+	}
+	if got := read.Years[0].Books[1].Date; got != "2024-03-15" {
+		t.Errorf("date = %q, want it cut to 2024-03-15", got)
+	}
+}
+
+func TestStars(t *testing.T) {
+	tests := []struct {
+		rating int
+		want   string
+	}{
+		{0, "☆☆☆☆☆"},
+		{1, "½☆☆☆☆"},
+		{2, "★☆☆☆☆"},
+		{7, "★★★½☆"},
+		{10, "★★★★★"},
+		{12, "★★★★★"},
+		{-1, "☆☆☆☆☆"},
+	}
+	for _, tt := range tests {
+		if got := stars(tt.rating); got != tt.want {
+			t.Errorf("stars(%d) = %q, want %q", tt.rating, got, tt.want)
+		}
+	}
+}
+
+func TestReportEscaping(t *testing.T) {
+	md, err := generateMarkdown(reportBooks(), reportNow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`**The \<b\>Bold\</b\> & the \*Starred\***`,
+		`by A\_Writer`,
+		`  > Loved it \<script\>alert(1)\</script\>`,
+		`  > Second line with \[a link\](http://example.com).`,
+		`src="https://covers.example.com/p.jpg?a=1&amp;b=&#34;2&#34;"`,
+	} {
+		if !strings.Contains(string(md), want) {
+			t.Errorf("markdown report does not contain %s", want)
+		}
+	}
+
+	page, err := generateHTML(reportBooks(), reportNow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	html := string(page)
+	for _, unwanted := range []string{"<script>", "<b>Bold</b>", "<classic>"} {
+		if strings.Contains(html, unwanted) {
+			t.Errorf("HTML report contains unescaped %s", unwanted)
+		}
+	}
+	for _, want := range []string{
+		`The &lt;b&gt;Bold&lt;/b&gt; &amp; the *Starred*`,
+		`Loved it &lt;script&gt;alert(1)&lt;/script&gt;`,
+		`<li>&lt;classic&gt;</li>`,
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("HTML report does not contain %s", want)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package exporter
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/bookshelf"
+)
+
+// ErrListBooks wraps the errors of reading the books to export, as opposed
+// to writing them.
+var ErrListBooks = errors.New("failed to list books")
+
+// pages is a source of books, one page at a time, as a
+// bookshelf.BookPager reads them.
+type pages interface {
+	HasMorePages() bool
+	NextPage(ctx context.Context) ([]bookshelf.Book, error)
+}
+
+// slicePages is books already read, as a single page.
+type slicePages struct {
+	books []bookshelf.Book
+	done  bool
+}
+
+// bookPages returns books as a source of pages.
+func bookPages(books []bookshelf.Book) *slicePages {
+	return &slicePages{books: books}
+}
+
+func (p *slicePages) HasMorePages() bool {
+	return !p.done
+}
+
+func (p *slicePages) NextPage(ctx context.Context) ([]bookshelf.Book, error) {
+	p.done = true
+	return p.books, nil
+}
+
+// eachBook calls fn with every book in src, in order, and returns how many
+// there were. Only one page is held at a time.
+func eachBook(ctx context.Context, src pages, fn func(bookshelf.Book) error) (int, error) {
+	count := 0
+	for src.HasMorePages() {
+		page, err := src.NextPage(ctx)
+		if err != nil {
+			return count, fmt.Errorf("%w: %w", ErrListBooks, err)
+		}
+		for _, book := range page {
+			if err := fn(book); err != nil {
+				return count, err
+			}
+			count++
+		}
+	}
+	return count, nil
+}
+
+// writeCSV writes the books in src to w as a CSV export laid out as opts
+// directs, and returns how many it wrote.
+func writeCSV(ctx context.Context, w io.Writer, src pages, opts CSVOptions) (int, error) {
+	layout, err := opts.layout()
+	if err != nil {
+		return 0, err
+	}
+	if layout.bom {
+		if _, err := io.WriteString(w, "\ufeff"); err != nil {
+			return 0, err
+		}
+	}
+	return writeRows(ctx, w, src, layout.comma, layout.header(), layout.record)
+}
+
+// writeRows writes the books in src to w as CSV separated by comma, with
+// header and then the record of each book, and returns how many it wrote.
+func writeRows(ctx context.Context, w io.Writer, src pages, comma rune, header []string, record func(bookshelf.Book) []string) (int, error) {
+	writer := csv.NewWriter(w)
+	writer.Comma = comma
+	if err := writer.Write(header); err != nil {
+		return 0, err
+	}
+
+	count, err := eachBook(ctx, src, func(book bookshelf.Book) error {
+		return writer.Write(record(book))
+	})
+	if err != nil {
+		return count, err
+	}
+
+	writer.Flush()
+	return count, writer.Error()
+}
+
+// writeJSON writes the books in src to w as a versioned export that can be
+// restored with POST /import/backup, and returns how many it wrote. Only the
+// filters that were applied are recorded.
+//
+// The books are written as they are read, one array element at a time, in
+// the layout json.MarshalIndent gives a bookshelf.Export, except that
+// book_count follows the books, once it is known.
+func writeJSON(ctx context.Context, w io.Writer, src pages, filters map[string]string, now time.Time) (int, error) {
+	out := bufio.NewWriter(w)
+	header, err := json.MarshalIndent(struct {
+		SchemaVersion int               `json:"schema_version"`
+		ExportedAt    string            `json:"exported_at"`
+		Filters       map[string]string `json:"filters,omitempty"`
+	}{bookshelf.ExportSchemaVersion, now.UTC().Format(time.RFC3339), AppliedFilters(filters)}, "", "  ")
+	if err != nil {
+		return 0, err
+	}
+	// Reopen the header object to add the books to it
+	out.Write(bytes.TrimSuffix(header, []byte("\n}")))
+	out.WriteString(",\n  \"books\": [")
+
+	separator := "\n    "
+	count, err := eachBook(ctx, src, func(book bookshelf.Book) error {
+		data, err := json.MarshalIndent(book.ToAPI(), "    ", "  ")
+		if err != nil {
+			return err
+		}
+		out.WriteString(separator)
+		_, err = out.Write(data)
+		separator = ",\n    "
+		return err
+	})
+	if err != nil {
+		return count, err
+	}
+	if count > 0 {
+		out.WriteString("\n  ")
+	}
+	fmt.Fprintf(out, "],\n  \"book_count\": %d\n}", count)
+	return count, out.Flush()
+}
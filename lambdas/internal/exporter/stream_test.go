@@ -0,0 +1,111 @@
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"runtime"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/bookshelf"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/objectstore"
+)
+
+// syntheticBooks is a library of n generated books, listed a page at a time
+// like DynamoDB lists them, so that the benchmark's own data takes no
+// memory. Every book is read. It samples the heap as the export reads it.
+// Only ListPage is implemented.
+type syntheticBooks struct {
+	bookshelf.BookRepository
+	n    int
+	peak uint64
+}
+
+func (r *syntheticBooks) ListPage(ctx context.Context, userID string, opts bookshelf.PageOptions) (bookshelf.Page, error) {
+	if opts.Status != "" && opts.Status != bookshelf.StatusRead {
+		return bookshelf.Page{}, nil
+	}
+	start, _ := strconv.Atoi(opts.StartToken)
+	end := min(start+opts.Limit, r.n)
+
+	var page bookshelf.Page
+	for i := start; i < end; i++ {
+		page.Books = append(page.Books, syntheticBook(i))
+	}
+	if end < r.n {
+		page.NextToken = strconv.Itoa(end)
+	}
+
+	if start/opts.Limit%10 == 0 {
+		var stats runtime.MemStats
+		runtime.ReadMemStats(&stats)
+		r.peak = max(r.peak, stats.HeapInuse)
+	}
+	return page, nil
+}
+
+// syntheticBook is the i-th generated book.
+func syntheticBook(i int) bookshelf.Book {
+	rating := i%10 + 1
+	return bookshelf.Book{
+		ID:         fmt.Sprintf("book-%06d", i),
+		Title:      fmt.Sprintf("Synthetic Book %d", i),
+		Author:     fmt.Sprintf("Author %d", i%1000),
+		Series:     "The Benchmark Cycle",
+		Status:     bookshelf.StatusRead,
+		Rating:     &rating,
+		StartedAt:  "2024-01-01",
+		FinishedAt: "2024-02-01",
+		Tags:       []string{"fiction", "benchmark"},
+		Review:     "A review long enough to look like one a reader would write about a book they finished.",
+		Version:    1,
+		CreatedAt:  "2024-01-01T00:00:00Z",
+	}
+}
+
+// discardStore stores objects nowhere, reading them as S3Store.Put would.
+type discardStore struct {
+	objectstore.Store
+}
+
+func (discardStore) Put(ctx context.Context, key string, body io.Reader, metadata map[string]string) error {
+	_, err := io.Copy(io.Discard, body)
+	return err
+}
+
+// BenchmarkExport streams exports of growing libraries through Upload and
+// reports the peak heap in use while writing them: it stays flat as the
+// library grows. Xlsx sheets level off higher, as excelize buffers up to
+// 16 MB of each sheet before moving it to a temporary file. Run with
+//
+//	go test -run '^$' -bench Export -benchtime 1x ./exporter
+func BenchmarkExport(b *testing.B) {
+	for _, format := range []string{"csv", "goodreads", "json", "xlsx"} {
+		for _, n := range []int{1_000, 10_000, 100_000} {
+			b.Run(fmt.Sprintf("%s/%d", format, n), func(b *testing.B) {
+				books := &syntheticBooks{n: n}
+				e := New(books, nil)
+				now := time.Now()
+				runtime.GC()
+
+				for b.Loop() {
+					var count int
+					_, err := Upload(context.Background(), discardStore{}, "exports/bench", func(w io.Writer) error {
+						var err error
+						count, err = e.Export(context.Background(), w, "bench-user", format, nil, CSVOptions{}, now)
+						return err
+					})
+					if err != nil {
+						b.Fatal(err)
+					}
+					if count != n {
+						b.Fatalf("exported %d books, want %d", count, n)
+					}
+				}
+				b.ReportMetric(float64(books.peak)/(1<<20), "peak-heap-MB")
+			})
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package exporter
+
+import (
+	"context"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/bookshelf"
+	"github.com/xuri/excelize/v2"
+)
+
+// xlsxSheets are the sheets of xlsx exports, one per status, in the order
+// books move through them.
+var xlsxSheets = []struct {
+	status string
+	name   string
+}{
+	{bookshelf.StatusWantToRead, "Want to Read"},
+	{bookshelf.StatusReading, "Reading"},
+	{bookshelf.StatusRead, "Read"},
+}
+
+// xlsxSummarySheet is the first sheet of xlsx exports, counting the books
+// on the others.
+const xlsxSummarySheet = "Summary"
+
+// xlsxShortDate is Excel's built-in short date format, which follows the
+// reader's locale.
+const xlsxShortDate = 14
+
+// xlsxStyles are the cell styles of an xlsx export.
+type xlsxStyles struct {
+	header int
+	date   int
+	stars  int
+}
+
+// xlsxColumn is a column of the status sheets.
+type xlsxColumn struct {
+	header string
+	width  float64
+	value  func(book bookshelf.Book, styles xlsxStyles) any
+}
+
+// xlsxColumns are the columns of the status sheets. Dates and ratings are
+// typed cells, so that spreadsheets sort and format them, and ISBNs are
+// text, so that they keep their leading zeros.
+var xlsxColumns = []xlsxColumn{
+	{"Title", 40, func(b bookshelf.Book, _ xlsxStyles) any { return b.Title }},
+	{"Author", 25, func(b bookshelf.Book, _ xlsxStyles) any { return b.Author }},
+	{"Series", 25, func(b bookshelf.Book, _ xlsxStyles) any { return b.Series }},
+	{"Rating (stars)", 14, func(b bookshelf.Book, s xlsxStyles) any {
+		if b.Rating == nil {
+			return nil
+		}
+		return excelize.Cell{StyleID: s.stars, Value: float64(*b.Rating) / 2}
+	}},
+	{"Started", 12, func(b bookshelf.Book, s xlsxStyles) any { return xlsxDate(b.StartedAt, "2006-01-02", s) }},
+	{"Finished", 12, func(b bookshelf.Book, s xlsxStyles) any { return xlsxDate(b.FinishedAt, "2006-01-02", s) }},
+	{"Added", 12, func(b bookshelf.Book, s xlsxStyles) any { return xlsxDate(b.CreatedAt, time.RFC3339, s) }},
+	{"Tags", 25, func(b bookshelf.Book, _ xlsxStyles) any { return strings.Join(b.Tags, ", ") }},
+	{"Type", 12, func(b bookshelf.Book, _ xlsxStyles) any { return b.Type }},
+	{"ISBN", 16, func(b bookshelf.Book, _ xlsxStyles) any { return b.ISBN }},
+	{"Review", 50, func(b bookshelf.Book, _ xlsxStyles) any { return b.Review }},
+	{"Comments", 50, func(b bookshelf.Book, _ xlsxStyles) any { return b.Comments }},
+}
+
+// xlsxDate is a date cell for s in layout, or s as text if it is not one.
+func xlsxDate(s, layout string, styles xlsxStyles) any {
+	if s == "" {
+		return nil
+	}
+	t, err := time.Parse(layout, s)
+	if err != nil {
+		return s
+	}
+	// Spreadsheet dates have no time zone; keep the UTC day
+	return excelize.Cell{StyleID: styles.date, Value: t.UTC()}
+}
+
+// xlsxTotal counts the books on a sheet and sums their ratings.
+type xlsxTotal struct {
+	name  string
+	books int
+	rated int
+	sum   int
+}
+
+// writeXLSX writes the books that filters select to w as an Excel workbook,
+// and returns how many it wrote. The workbook has a sheet for each status,
+// read from the pages statusPages returns, and a summary sheet before them
+// with the number of books and their average rating on each. Every sheet
+// has a frozen header row.
+func writeXLSX(ctx context.Context, w io.Writer, statusPages func(status string) pages, filters map[string]string, now time.Time) (int, error) {
+	f := excelize.NewFile()
+	defer f.Close()
+	if err := f.SetDocProps(&excelize.DocProperties{
+		Title:   "Bookshelf export",
+		Created: now.UTC().Format(time.RFC3339),
+	}); err != nil {
+		return 0, err
+	}
+	if err := f.SetSheetName("Sheet1", xlsxSummarySheet); err != nil {
+		return 0, err
+	}
+
+	var styles xlsxStyles
+	var err error
+	if styles.header, err = f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}}); err != nil {
+		return 0, err
+	}
+	if styles.date, err = f.NewStyle(&excelize.Style{NumFmt: xlsxShortDate}); err != nil {
+		return 0, err
+	}
+	stars := "0.0"
+	if styles.stars, err = f.NewStyle(&excelize.Style{CustomNumFmt: &stars}); err != nil {
+		return 0, err
+	}
+
+	header := make([]any, len(xlsxColumns))
+	widths := make([]float64, len(xlsxColumns))
+	for i, column := range xlsxColumns {
+		header[i], widths[i] = column.header, column.width
+	}
+
+	var totals []xlsxTotal
+	count := 0
+	for _, sheet := range xlsxSheets {
+		if status := filters["status"]; status != "" && status != sheet.status {
+			continue
+		}
+		if _, err := f.NewSheet(sheet.name); err != nil {
+			return count, err
+		}
+		sw, err := newXLSXSheet(f, sheet.name, header, widths, styles)
+		if err != nil {
+			return count, err
+		}
+
+		total := xlsxTotal{name: sheet.name}
+		row := make([]any, len(xlsxColumns))
+		_, err = eachBook(ctx, statusPages(sheet.status), func(book bookshelf.Book) error {
+			total.books++
+			if book.Rating != nil {
+				total.rated++
+				total.sum += *book.Rating
+			}
+			for i, column := range xlsxColumns {
+				row[i] = column.value(book, styles)
+			}
+			cell, _ := excelize.CoordinatesToCellName(1, total.books+1)
+			return sw.SetRow(cell, row)
+		})
+		if err != nil {
+			return count, err
+		}
+		if err := sw.Flush(); err != nil {
+			return count, err
+		}
+		count += total.books
+		totals = append(totals, total)
+	}
+
+	if err := writeXLSXSummary(f, totals, styles); err != nil {
+		return count, err
+	}
+	f.SetActiveSheet(0)
+	_, err = f.WriteTo(w)
+	return count, err
+}
+
+// newXLSXSheet starts streaming rows to the named sheet, below a frozen
+// header row, in columns of the given widths.
+func newXLSXSheet(f *excelize.File, name string, header []any, widths []float64, styles xlsxStyles) (*excelize.StreamWriter, error) {
+	sw, err := f.NewStreamWriter(name)
+	if err != nil {
+		return nil, err
+	}
+	// Widths must be set before any row is written
+	for i, width := range widths {
+		if err := sw.SetColWidth(i+1, i+1, width); err != nil {
+			return nil, err
+		}
+	}
+	if err := sw.SetPanes(&excelize.Panes{
+		Freeze:      true,
+		YSplit:      1,
+		TopLeftCell: "A2",
+		ActivePane:  "bottomLeft",
+	}); err != nil {
+		return nil, err
+	}
+	if err := sw.SetRow("A1", header, excelize.RowOpts{StyleID: styles.header}); err != nil {
+		return nil, err
+	}
+	return sw, nil
+}
+
+// writeXLSXSummary writes the number of books on each status sheet, how
+// many are rated and their average rating in stars, and the totals.
+func writeXLSXSummary(f *excelize.File, totals []xlsxTotal, styles xlsxStyles) error {
+	sw, err := newXLSXSheet(f, xlsxSummarySheet, []any{"Shelf", "Books", "Rated", "Average rating (stars)"}, []float64{16, 10, 10, 22}, styles)
+	if err != nil {
+		return err
+	}
+
+	all := xlsxTotal{name: "Total"}
+	for _, total := range totals {
+		all.books += total.books
+		all.rated += total.rated
+		all.sum += total.sum
+	}
+	for i, total := range append(totals, all) {
+		var average any
+		if total.rated > 0 {
+			average = excelize.Cell{StyleID: styles.stars, Value: float64(total.sum) / float64(total.rated) / 2}
+		}
+		var opts []excelize.RowOpts
+		if total.name == all.name {
+			opts = append(opts, excelize.RowOpts{StyleID: styles.header})
+		}
+		cell, _ := excelize.CoordinatesToCellName(1, i+2)
+		if err := sw.SetRow(cell, []any{total.name, total.books, total.rated, average}, opts...); err != nil {
+			return err
+		}
+	}
+	return sw.Flush()
+}
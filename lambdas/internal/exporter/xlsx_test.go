@@ -0,0 +1,125 @@
+package exporter
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/bookshelf"
+	"github.com/xuri/excelize/v2"
+)
+
+func TestWriteXLSX(t *testing.T) {
+	rating := func(n int) *int { return &n }
+	books := []bookshelf.Book{
+		{Title: "Dune", Author: "Frank Herbert", Status: bookshelf.StatusRead, Rating: rating(9), ISBN: "0441013597",
+			StartedAt: "2024-10-01", FinishedAt: "2024-11-02", CreatedAt: "2024-09-30T23:30:00Z"},
+		{Title: "Hyperion", Author: "Dan Simmons", Status: bookshelf.StatusRead, Rating: rating(6), ISBN: "9780553283686"},
+		{Title: "Piranesi", Author: "Susanna Clarke", Status: bookshelf.StatusReading, StartedAt: "someday"},
+	}
+	out, err := Generate("xlsx", books, nil, reportNow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := excelize.OpenReader(bytes.NewReader(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if got, want := f.GetSheetList(), []string{"Summary", "Want to Read", "Reading", "Read"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("sheets = %v, want %v", got, want)
+	}
+	if active := f.GetSheetName(f.GetActiveSheetIndex()); active != "Summary" {
+		t.Errorf("active sheet = %q, want Summary", active)
+	}
+	for _, sheet := range f.GetSheetList() {
+		panes, err := f.GetPanes(sheet)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !panes.Freeze || panes.YSplit != 1 || panes.XSplit != 0 || panes.TopLeftCell != "A2" {
+			t.Errorf("%s panes = %+v, want the header row frozen", sheet, panes)
+		}
+	}
+
+	// cell returns the value of a cell and whether it is a number
+	cell := func(sheet, name string) (string, bool) {
+		t.Helper()
+		value, err := f.GetCellValue(sheet, name, excelize.Options{RawCellValue: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		typ, err := f.GetCellType(sheet, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return value, typ == excelize.CellTypeUnset || typ == excelize.CellTypeNumber
+	}
+	// numFmt returns the number format of a cell
+	numFmt := func(sheet, name string) int {
+		t.Helper()
+		id, err := f.GetCellStyle(sheet, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		style, err := f.GetStyle(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if style.CustomNumFmt != nil {
+			return -1
+		}
+		return style.NumFmt
+	}
+
+	header, err := f.GetRows("Read")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"Title", "Author", "Series", "Rating (stars)", "Started", "Finished", "Added", "Tags", "Type", "ISBN", "Review", "Comments"}
+	if !reflect.DeepEqual(header[0], want) {
+		t.Errorf("Read header = %v, want %v", header[0], want)
+	}
+
+	tests := []struct {
+		sheet, cell string
+		value       string
+		number      bool
+		numFmt      int // -1 for the custom stars format
+	}{
+		// Ratings are stars, as numbers
+		{"Read", "D2", "4.5", true, -1},
+		{"Read", "D3", "3", true, -1},
+		// Dates are serial day numbers in the short date format, and
+		// timestamps are in UTC
+		{"Read", "E2", "45566", true, xlsxShortDate},
+		{"Read", "F2", "45598", true, xlsxShortDate},
+		{"Read", "G2", "45565.979166666664", true, xlsxShortDate},
+		// ISBNs are text, with their leading zeros
+		{"Read", "J2", "0441013597", false, 0},
+		{"Read", "J3", "9780553283686", false, 0},
+		// Dates that cannot be read are kept as text
+		{"Reading", "E2", "someday", false, 0},
+		// Summary counts and averages
+		{"Summary", "B4", "2", true, 0},
+		{"Summary", "C4", "2", true, 0},
+		{"Summary", "D4", "3.75", true, -1},
+		{"Summary", "B5", "3", true, 0},
+	}
+	for _, tt := range tests {
+		value, number := cell(tt.sheet, tt.cell)
+		if value != tt.value || number != tt.number {
+			t.Errorf("%s!%s = %q (number %v), want %q (number %v)", tt.sheet, tt.cell, value, number, tt.value, tt.number)
+		}
+		if got := numFmt(tt.sheet, tt.cell); got != tt.numFmt {
+			t.Errorf("%s!%s number format = %d, want %d", tt.sheet, tt.cell, got, tt.numFmt)
+		}
+	}
+	if name, _ := cell("Summary", "A4"); name != "Read" {
+		t.Errorf("Summary!A4 = %q, want Read", name)
+	}
+	if name, _ := cell("Summary", "A5"); name != "Total" {
+		t.Errorf("Summary!A5 = %q, want Total", name)
+	}
+}
@@ -0,0 +1,266 @@
+package exporter
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/bookshelf"
+)
+
+const (
+	// MaxCoverSize is the largest cover image included in a backup, in
+	// bytes. Larger covers are left out and linked by URL instead.
+	MaxCoverSize = 5 << 20
+
+	// CoverTimeout bounds downloading one cover image.
+	CoverTimeout = 10 * time.Second
+
+	// CoversTimeout bounds downloading all the covers of a backup. Covers
+	// not downloaded by then are left out.
+	CoversTimeout = 2 * time.Minute
+
+	// coverDownloads is how many covers are downloaded at once.
+	coverDownloads = 8
+)
+
+// coverExtensions are the image types kept as cover images, by content
+// type.
+var coverExtensions = map[string]string{
+	"image/jpeg": "jpg",
+	"image/png":  "png",
+	"image/gif":  "gif",
+	"image/webp": "webp",
+}
+
+// safeFileName matches book IDs that can be used as file names as they
+// are.
+var safeFileName = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// writeZip writes a full backup of books as a ZIP archive:
+//
+//   - books.json, the JSON export, which POST /import/backup restores
+//   - books.csv, the CSV export
+//   - report.md, the Markdown reading report, showing the covers below
+//   - history.json, every book's history, by book ID
+//   - covers/<book id>.<ext>, each book's cover image
+//
+// The report links covers by relative path, so the archive reads the same
+// unpacked and offline. A cover that cannot be downloaded is left out and
+// the report links its URL instead.
+func (e *Exporter) writeZip(ctx context.Context, w io.Writer, userID string, books []bookshelf.Book, filters map[string]string, now time.Time) error {
+	archive := zip.NewWriter(w)
+
+	// Covers come first, so the report knows which were downloaded
+	covers := e.addCovers(ctx, archive, books, now)
+	local := make([]bookshelf.Book, len(books))
+	for i, book := range books {
+		local[i] = book
+		if path, ok := covers[book.Thumbnail]; ok {
+			local[i].Thumbnail = path
+		}
+	}
+
+	entries, err := e.Books.AllHistory(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to read history: %w", err)
+	}
+	byBook := map[string][]bookshelf.HistoryEntry{}
+	for _, entry := range entries {
+		byBook[entry.BookID] = append(byBook[entry.BookID], entry)
+	}
+	history := make(map[string][]bookshelf.APIHistoryEntry, len(books))
+	for _, book := range books {
+		history[book.ID] = bookshelf.ToAPIHistory(byBook[book.ID])
+	}
+
+	files := []struct {
+		name  string
+		write func(io.Writer) error
+	}{
+		{"books.json", func(w io.Writer) error {
+			_, err := writeJSON(ctx, w, bookPages(books), filters, now)
+			return err
+		}},
+		{"books.csv", func(w io.Writer) error {
+			_, err := writeCSV(ctx, w, bookPages(books), CSVOptions{})
+			return err
+		}},
+		{"report.md", func(w io.Writer) error {
+			data, err := generateMarkdown(local, now)
+			if err != nil {
+				return err
+			}
+			_, err = w.Write(data)
+			return err
+		}},
+		{"history.json", func(w io.Writer) error {
+			encoder := json.NewEncoder(w)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(history)
+		}},
+	}
+	for _, file := range files {
+		entry, err := archive.CreateHeader(&zip.FileHeader{Name: file.name, Method: zip.Deflate, Modified: now})
+		if err != nil {
+			return err
+		}
+		if err := file.write(entry); err != nil {
+			return fmt.Errorf("failed to write %s: %w", file.name, err)
+		}
+	}
+	return archive.Close()
+}
+
+// addCovers downloads the covers of books, coverDownloads at a time and
+// within CoversTimeout, and stores them in the archive as
+// covers/<name>.<ext>. It returns the path of each stored cover by URL; a
+// URL shared by several books is downloaded once.
+func (e *Exporter) addCovers(ctx context.Context, archive *zip.Writer, books []bookshelf.Book, now time.Time) map[string]string {
+	ctx, cancel := context.WithTimeout(ctx, CoversTimeout)
+	defer cancel()
+
+	type cover struct {
+		book bookshelf.Book
+		name string
+	}
+	queue := make(chan cover)
+	go func() {
+		defer close(queue)
+		seen := map[string]bool{}
+		for i, book := range books {
+			if book.Thumbnail == "" || seen[book.Thumbnail] {
+				continue
+			}
+			seen[book.Thumbnail] = true
+			select {
+			case queue <- cover{book, coverName(book, i)}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	// Covers are downloaded in parallel and written to the archive, which
+	// takes one file at a time, as they arrive
+	var mu sync.Mutex
+	paths := map[string]string{}
+	var wg sync.WaitGroup
+	for range coverDownloads {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for c := range queue {
+				data, ext, err := e.fetchCover(ctx, c.book.Thumbnail)
+				if err != nil {
+					log.Printf("Leaving out cover of book %s: %v", c.book.ID, err)
+					continue
+				}
+				path := "covers/" + c.name + "." + ext
+
+				mu.Lock()
+				// Images are already compressed
+				entry, err := archive.CreateHeader(&zip.FileHeader{Name: path, Method: zip.Store, Modified: now})
+				if err == nil {
+					_, err = entry.Write(data)
+				}
+				if err == nil {
+					paths[c.book.Thumbnail] = path
+				}
+				mu.Unlock()
+				if err != nil {
+					log.Printf("Leaving out cover of book %s: %v", c.book.ID, err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return paths
+}
+
+// fetchCover downloads the image at url, returning it and its file
+// extension. The whole image is read, so that an oversized one leaves
+// nothing in the archive.
+func (e *Exporter) fetchCover(ctx context.Context, url string) ([]byte, string, error) {
+	client := e.Covers
+	if client == nil {
+		client = CoverClient()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+		return nil, "", fmt.Errorf("unsupported URL scheme %q", req.URL.Scheme)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	ext, ok := coverExtensions[mediaType]
+	if !ok {
+		return nil, "", fmt.Errorf("unsupported content type %q", mediaType)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, MaxCoverSize+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if len(data) > MaxCoverSize {
+		return nil, "", errors.New("image too large")
+	}
+	return data, ext, nil
+}
+
+// coverName returns the file name of the cover of the i-th book: its ID,
+// unless the ID is not a safe file name.
+func coverName(book bookshelf.Book, i int) string {
+	if safeFileName.MatchString(book.ID) {
+		return book.ID
+	}
+	return "book-" + strconv.Itoa(i+1)
+}
+
+// CoverClient returns the HTTP client cover images are downloaded with. Cover
+// URLs are chosen by users, so it only connects to public addresses.
+func CoverClient() *http.Client {
+	dialer := &net.Dialer{Timeout: 5 * time.Second, Control: publicOnly}
+	return &http.Client{
+		Timeout: CoverTimeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: 5 * time.Second,
+		},
+	}
+}
+
+// publicOnly refuses connections to loopback, private, link-local and
+// other non-public addresses.
+func publicOnly(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !ip.IsGlobalUnicast() || ip.IsPrivate() {
+		return fmt.Errorf("refusing to connect to non-public address %s", host)
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package exporter
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/bookshelf"
+)
+
+// coverDelay is how long the test server takes to serve each cover.
+const coverDelay = 100 * time.Millisecond
+
+func TestWriteZip(t *testing.T) {
+	covers := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(coverDelay)
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/missing"):
+			http.NotFound(w, r)
+		case strings.HasPrefix(r.URL.Path, "/text"):
+			w.Header().Set("Content-Type", "text/plain")
+			io.WriteString(w, "not an image")
+		default:
+			w.Header().Set("Content-Type", "image/png")
+			io.WriteString(w, "png "+r.URL.Path)
+		}
+	}))
+	defer covers.Close()
+
+	ctx := context.Background()
+	repo := bookshelf.NewMemoryRepository()
+	const n = 24
+	var books []bookshelf.Book
+	for i := range n {
+		book := bookshelf.Book{
+			ID:        fmt.Sprintf("book-%02d", i),
+			Title:     fmt.Sprintf("Book %d", i),
+			Author:    "Author",
+			Status:    bookshelf.StatusRead,
+			Thumbnail: fmt.Sprintf("%s/cover/%d.png", covers.URL, i),
+			Version:   1,
+		}
+		switch i {
+		case 0:
+			book.Thumbnail = covers.URL + "/missing.png"
+		case 1:
+			book.Thumbnail = covers.URL + "/text"
+		case 2:
+			book.Thumbnail = ""
+		case 3:
+			// Shares its cover with book 4, which is downloaded once
+			book.Thumbnail = fmt.Sprintf("%s/cover/%d.png", covers.URL, 4)
+		}
+		if err := repo.Put(ctx, "user-1", book); err != nil {
+			t.Fatal(err)
+		}
+		books = append(books, book)
+	}
+	updated := books[5]
+	updated.Rating = new(int)
+	*updated.Rating = 8
+	if _, err := repo.Update(ctx, "user-1", updated); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	start := time.Now()
+	err := New(repo, http.DefaultClient).writeZip(ctx, &buf, "user-1", books, nil, time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// One at a time, the covers alone would take n times coverDelay
+	if elapsed := time.Since(start); elapsed > n*coverDelay/2 {
+		t.Errorf("writeZip took %v; covers are not downloaded in parallel", elapsed)
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]*zip.File{}
+	for _, f := range archive.File {
+		files[f.Name] = f
+	}
+	for _, name := range []string{"books.json", "books.csv", "report.md", "history.json"} {
+		if files[name] == nil {
+			t.Errorf("archive has no %s", name)
+		}
+	}
+
+	var covered []string
+	for name := range files {
+		if strings.HasPrefix(name, "covers/") {
+			covered = append(covered, name)
+		}
+	}
+	// Every book but the missing, the text and the one without a cover
+	// has one, and books 3 and 4 share theirs
+	if want := n - 4; len(covered) != want {
+		t.Errorf("archive has %d covers, want %d: %v", len(covered), want, covered)
+	}
+	if files["covers/book-00.png"] != nil || files["covers/book-04.png"] != nil {
+		t.Errorf("archive has covers that failed or are shared: %v", covered)
+	}
+
+	report := readZipFile(t, files["report.md"])
+	for _, want := range []string{"covers/book-03.png", covers.URL + "/missing.png"} {
+		if !strings.Contains(report, want) {
+			t.Errorf("report.md does not link %s", want)
+		}
+	}
+	if got := strings.Count(report, "covers/book-03.png"); got != 2 {
+		t.Errorf("report.md links the shared cover %d times, want 2", got)
+	}
+
+	var history map[string][]bookshelf.APIHistoryEntry
+	if err := json.Unmarshal([]byte(readZipFile(t, files["history.json"])), &history); err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != n {
+		t.Errorf("history.json has %d books, want %d", len(history), n)
+	}
+	// Each book has its own entries, newest first
+	if got := history["book-05"]; len(got) != 2 || got[0].Action != bookshelf.HistoryUpdate || got[1].Action != bookshelf.HistoryCreate {
+		t.Errorf("history of book-05 = %+v, want its update and creation", got)
+	}
+	if got := history["book-06"]; len(got) != 1 || got[0].BookID != "book-06" {
+		t.Errorf("history of book-06 = %+v, want its creation", got)
+	}
+}
+
+// readZipFile returns the contents of f.
+func readZipFile(t *testing.T, f *zip.File) string {
+	t.Helper()
+	if f == nil {
+		t.Fatal("no such file in the archive")
+	}
+	r, err := f.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
@@ -0,0 +1,104 @@
+// Package handlertest provides the requests and repositories the Lambda
+// handlers are tested with.
+package handlertest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/bookshelf"
+)
+
+// UserID is the user Request signs requests in as.
+const UserID = "user-1"
+
+// Request returns request as API Gateway passes it to a handler behind the
+// JWT authorizer, signed in as UserID. Requests not passed through Request
+// have no claims, as if the authorizer were missing.
+func Request(request events.APIGatewayProxyRequest) events.APIGatewayProxyRequest {
+	request.RequestContext.Authorizer = map[string]interface{}{
+		"jwt": map[string]interface{}{
+			"claims": map[string]interface{}{"sub": UserID},
+		},
+	}
+	return request
+}
+
+// Books returns a memory repository holding books for UserID, each with the
+// ID it is given at version 1.
+func Books(t testing.TB, books ...bookshelf.APIBook) *bookshelf.MemoryRepository {
+	t.Helper()
+	repo := bookshelf.NewMemoryRepository()
+	for _, book := range books {
+		if err := repo.Put(context.Background(), UserID, bookshelf.NewBook(UserID, book.ID, book)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return repo
+}
+
+// Racing is a repository in which another writer changes a book just
+// before each update, trash or merge of it, as if every write lost a race.
+// Those writes fail with a ConflictError however often they are retried.
+type Racing struct {
+	*bookshelf.MemoryRepository
+}
+
+// Update races the update of book.
+func (r Racing) Update(ctx context.Context, userID string, book bookshelf.Book) (bookshelf.Book, error) {
+	r.race(ctx, userID, book.ID)
+	return r.MemoryRepository.Update(ctx, userID, book)
+}
+
+// Trash races the trashing of book.
+func (r Racing) Trash(ctx context.Context, userID string, book bookshelf.Book) (bookshelf.TrashedBook, error) {
+	r.race(ctx, userID, book.ID)
+	return r.MemoryRepository.Trash(ctx, userID, book)
+}
+
+// Merge races the write of merged.
+func (r Racing) Merge(ctx context.Context, userID string, merged, source bookshelf.Book) (bookshelf.Book, error) {
+	r.race(ctx, userID, merged.ID)
+	return r.MemoryRepository.Merge(ctx, userID, merged, source)
+}
+
+// race stores a new version of the user's book, if it exists.
+func (r Racing) race(ctx context.Context, userID, bookID string) {
+	book, err := r.MemoryRepository.Get(ctx, userID, bookID)
+	if err == nil {
+		r.MemoryRepository.Update(ctx, userID, book)
+	}
+}
+
+// RacingOnce is a repository in which another writer changes a book just
+// before the first update, trash or merge, so only a retry succeeds.
+type RacingOnce struct {
+	*bookshelf.MemoryRepository
+	raced bool
+}
+
+// Update races the update of book if nothing has raced yet.
+func (r *RacingOnce) Update(ctx context.Context, userID string, book bookshelf.Book) (bookshelf.Book, error) {
+	r.race(ctx, userID, book.ID)
+	return r.MemoryRepository.Update(ctx, userID, book)
+}
+
+// Trash races the trashing of book if nothing has raced yet.
+func (r *RacingOnce) Trash(ctx context.Context, userID string, book bookshelf.Book) (bookshelf.TrashedBook, error) {
+	r.race(ctx, userID, book.ID)
+	return r.MemoryRepository.Trash(ctx, userID, book)
+}
+
+// Merge races the write of merged if nothing has raced yet.
+func (r *RacingOnce) Merge(ctx context.Context, userID string, merged, source bookshelf.Book) (bookshelf.Book, error) {
+	r.race(ctx, userID, merged.ID)
+	return r.MemoryRepository.Merge(ctx, userID, merged, source)
+}
+
+func (r *RacingOnce) race(ctx context.Context, userID, bookID string) {
+	if !r.raced {
+		r.raced = true
+		Racing{r.MemoryRepository}.race(ctx, userID, bookID)
+	}
+}
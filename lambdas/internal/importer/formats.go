@@ -0,0 +1,49 @@
+package importer
+
+import (
+	"io"
+	"slices"
+	"strings"
+)
+
+// Importer reads the library export of another book tracking service.
+type Importer interface {
+	// Format is the name clients select the importer by, as in
+	// ?format=goodreads.
+	Format() string
+	// Parse reads the books in an export. A row that cannot be read is
+	// returned with Err set; an error is returned only if the file is not
+	// an export in this format.
+	Parse(r io.Reader) ([]Row, error)
+}
+
+// importers are the supported formats, by name.
+var importers = map[string]Importer{}
+
+func init() {
+	for _, imp := range []Importer{Goodreads{}, StoryGraph{}, LibraryThing{}} {
+		importers[imp.Format()] = imp
+	}
+}
+
+// Lookup returns the importer for format.
+func Lookup(format string) (Importer, bool) {
+	imp, ok := importers[format]
+	return imp, ok
+}
+
+// Formats returns the names of the supported formats in order.
+func Formats() []string {
+	formats := make([]string, 0, len(importers))
+	for name := range importers {
+		formats = append(formats, name)
+	}
+	slices.Sort(formats)
+	return formats
+}
+
+// InvalidFormatMessage is the error shown to clients for a missing or
+// unknown format.
+func InvalidFormatMessage() string {
+	return "Invalid format. Must be one of: " + strings.Join(Formats(), ", ")
+}
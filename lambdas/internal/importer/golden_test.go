@@ -0,0 +1,79 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/bookshelf"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// goldenRow is a Row as the golden files record it.
+type goldenRow struct {
+	Number int               `json:"number"`
+	Book   bookshelf.APIBook `json:"book"`
+	Err    string            `json:"error,omitempty"`
+}
+
+func TestParseGolden(t *testing.T) {
+	tests := []struct {
+		format string
+		input  string
+	}{
+		{"goodreads", "goodreads.csv"},
+		{"storygraph", "storygraph.csv"},
+		{"librarything", "librarything.tsv"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			imp, ok := Lookup(tt.format)
+			if !ok {
+				t.Fatalf("Lookup(%q) found no importer", tt.format)
+			}
+			f, err := os.Open(filepath.Join("testdata", tt.input))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			rows, err := imp.Parse(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			parsed := make([]goldenRow, len(rows))
+			for i, row := range rows {
+				parsed[i] = goldenRow{Number: row.Number, Book: row.Book}
+				if row.Err != nil {
+					parsed[i].Err = row.Err.Error()
+				}
+			}
+			var buf bytes.Buffer
+			enc := json.NewEncoder(&buf)
+			enc.SetEscapeHTML(false)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(parsed); err != nil {
+				t.Fatal(err)
+			}
+			got := buf.Bytes()
+
+			golden := filepath.Join("testdata", tt.format+".golden.json")
+			if *update {
+				if err := os.WriteFile(golden, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("%v (run go test -update to create it)", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("%s parsed differently from %s (run go test -update to accept):\n%s", tt.input, golden, got)
+			}
+		})
+	}
+}
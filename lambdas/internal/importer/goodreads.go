@@ -0,0 +1,105 @@
+package importer
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/bookshelf"
+)
+
+// goodreadsShelves maps Goodreads' built-in exclusive shelves to statuses.
+// Books on a custom exclusive shelf are imported as WANT_TO_READ and tagged
+// with the shelf.
+var goodreadsShelves = map[string]string{
+	"to-read":           bookshelf.StatusWantToRead,
+	"currently-reading": bookshelf.StatusReading,
+	"read":              bookshelf.StatusRead,
+}
+
+// goodreadsRequired are the columns a Goodreads export must have.
+var goodreadsRequired = []string{"Title", "Author"}
+
+// goodreadsSeries matches the series Goodreads appends to titles, as in
+// "The Way of Kings (The Stormlight Archive, #1)".
+var goodreadsSeries = regexp.MustCompile(`^(.+?)\s+\(([^()]+?),?\s+#[\d.]+(?:-[\d.]+)?\)$`)
+
+// Goodreads imports a Goodreads library export ("My Books" > "Import and
+// export"). Titles, authors, ISBNs, the exclusive shelf as the status, other
+// shelves as tags, the 5-star rating doubled to the 1-10 scale, the dates
+// read and added, and the review are imported.
+type Goodreads struct{}
+
+// Format returns "goodreads".
+func (Goodreads) Format() string { return "goodreads" }
+
+// Parse reads a Goodreads CSV export.
+func (Goodreads) Parse(r io.Reader) ([]Row, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	return readTable(reader, goodreadsRequired, goodreadsBook)
+}
+
+// goodreadsBook converts one row of a Goodreads export, read through field.
+func goodreadsBook(field func(name string) string) (bookshelf.APIBook, error) {
+	book := bookshelf.APIBook{
+		Title:  field("Title"),
+		Author: field("Author"),
+		Review: plainText(field("My Review")),
+	}
+	if m := goodreadsSeries.FindStringSubmatch(book.Title); m != nil {
+		book.Title, book.Series = m[1], m[2]
+	}
+	if book.Title == "" {
+		return book, errors.New("Title is required")
+	}
+	if book.Author == "" {
+		return book, errors.New("Author is required")
+	}
+
+	// ISBNs are written as ="9780765326355" so spreadsheets keep them as text.
+	for _, name := range []string{"ISBN13", "ISBN"} {
+		if isbn := cleanISBN(field(name)); isbn != "" {
+			book.ISBN = isbn
+			break
+		}
+	}
+
+	rating, err := parseStars(field("My Rating"))
+	if err != nil || strings.Contains(field("My Rating"), ".") {
+		return book, fmt.Errorf("Invalid My Rating %q. Must be between 0 and 5", field("My Rating"))
+	}
+	book.Rating = rating
+
+	shelf := field("Exclusive Shelf")
+	book.Status = goodreadsShelves[shelf]
+	if book.Status == "" {
+		book.Status = bookshelf.StatusWantToRead
+		book.Tags = appendTags(book.Tags, shelf)
+	}
+	for _, tag := range splitList(field("Bookshelves"), ",") {
+		if _, ok := goodreadsShelves[tag]; !ok {
+			book.Tags = appendTags(book.Tags, tag)
+		}
+	}
+
+	read, err := parseDate(field("Date Read"), goodreadsDateLayouts...)
+	if err != nil {
+		return book, fmt.Errorf("Invalid Date Read %q", field("Date Read"))
+	}
+	book.FinishedAt = formatDate(read)
+	added, err := parseDate(field("Date Added"), goodreadsDateLayouts...)
+	if err != nil {
+		return book, fmt.Errorf("Invalid Date Added %q", field("Date Added"))
+	}
+	book.CreatedAt = formatTimestamp(added)
+	return book, nil
+}
+
+// goodreadsDateLayouts are Goodreads' YYYY/MM/DD dates, also accepting
+// YYYY-MM-DD.
+var goodreadsDateLayouts = []string{"2006/01/02", time.DateOnly}
@@ -0,0 +1,156 @@
+// Package importer reads library exports from other book tracking services
+// and adds their books to a user's shelf, reporting what happened to every
+// row. It also restores the shelf's own JSON exports as backups.
+package importer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/bookshelf"
+)
+
+// Outcomes of an imported row.
+const (
+	RowCreated = "created"
+	RowSkipped = "skipped"
+	RowFailed  = "failed"
+)
+
+// Row is one book read from an import file.
+type Row struct {
+	// Number is the row's position in the file: its line, counting the
+	// header as 1, in CSV and TSV files, and its entry, counting from 1, in
+	// JSON files.
+	Number int
+	Book   bookshelf.APIBook
+	// Err is why the row could not be read as a book, if it could not. Its
+	// message is safe to show to clients.
+	Err error
+}
+
+// RowResult reports what happened to one row.
+type RowResult struct {
+	Row    int    `dynamodbav:"row" json:"row"`
+	Title  string `dynamodbav:"title,omitempty" json:"title,omitempty"`
+	Status string `dynamodbav:"status" json:"status"`
+	// BookID is the created book, or for a skipped row the book it
+	// duplicates.
+	BookID string `dynamodbav:"book_id,omitempty" json:"book_id,omitempty"`
+	Error  string `dynamodbav:"error,omitempty" json:"error,omitempty"`
+}
+
+// Report summarizes an import.
+type Report struct {
+	Created int         `json:"created"`
+	Skipped int         `json:"skipped"`
+	Failed  int         `json:"failed"`
+	Rows    []RowResult `json:"rows"`
+}
+
+func (r *Report) add(result RowResult) {
+	switch result.Status {
+	case RowCreated:
+		r.Created++
+	case RowSkipped:
+		r.Skipped++
+	case RowFailed:
+		r.Failed++
+	}
+	r.Rows = append(r.Rows, result)
+}
+
+// Import stores the books in rows for userID with one batched write. Rows
+// that duplicate one of the user's books, or an earlier row, are skipped
+// (see bookshelf.FindDuplicate). newID assigns the IDs of new books. The
+// error is only for failing to read the user's existing books; everything
+// else is reported per row.
+func Import(ctx context.Context, books bookshelf.BookRepository, userID string, rows []Row, newID func() string) (Report, error) {
+	return ImportBatches(ctx, books, userID, rows, newID, len(rows), nil)
+}
+
+// ImportBatches is Import writing batchSize rows at a time. After each batch
+// it calls progress, if not nil, with the report so far; an error from
+// progress stops the import and is returned. A batchSize below 1 writes
+// every row in one batch.
+func ImportBatches(ctx context.Context, books bookshelf.BookRepository, userID string, rows []Row, newID func() string, batchSize int, progress func(Report) error) (Report, error) {
+	if batchSize < 1 {
+		batchSize = max(len(rows), 1)
+	}
+	existing, err := bookshelf.ListAll(ctx, books, userID, bookshelf.ListOptions{})
+	if err != nil {
+		return Report{}, fmt.Errorf("failed to list books: %w", err)
+	}
+
+	report := Report{Rows: []RowResult{}}
+	now := time.Now().UTC().Format(time.RFC3339)
+	for start := 0; start < len(rows); start += batchSize {
+		batch := rows[start:min(start+batchSize, len(rows))]
+		for _, result := range importBatch(ctx, books, userID, batch, newID, now, &existing) {
+			report.add(result)
+		}
+		if progress != nil {
+			if err := progress(report); err != nil {
+				return report, err
+			}
+		}
+	}
+	return report, nil
+}
+
+// importBatch stores the new books in rows with one batched write, checking
+// them for duplicates against existing and adding the stored ones to it.
+func importBatch(ctx context.Context, books bookshelf.BookRepository, userID string, rows []Row, newID func() string, now string, existing *[]bookshelf.Book) []RowResult {
+	results := make([]RowResult, len(rows))
+	var created []bookshelf.Book
+	for i, row := range rows {
+		results[i] = RowResult{Row: row.Number, Title: row.Book.Title}
+		if row.Err != nil {
+			results[i].Status = RowFailed
+			results[i].Error = row.Err.Error()
+			continue
+		}
+
+		book := bookshelf.NewBook(userID, newID(), row.Book)
+		if book.CreatedAt == "" {
+			book.CreatedAt = now
+		}
+		if duplicate, ok := bookshelf.FindDuplicate(*existing, book); ok {
+			results[i].Status = RowSkipped
+			results[i].BookID = duplicate.ID
+			continue
+		}
+
+		results[i].Status = RowCreated
+		results[i].BookID = book.ID
+		created = append(created, book)
+		*existing = append(*existing, book)
+	}
+
+	failed := books.PutBatch(ctx, userID, created)
+	if len(failed) == 0 {
+		return results
+	}
+	// Later batches must not skip rows as duplicates of unsaved books.
+	*existing = slices.DeleteFunc(*existing, func(book bookshelf.Book) bool {
+		_, ok := failed[book.ID]
+		return ok
+	})
+	for i := range results {
+		err, ok := failed[results[i].BookID]
+		if !ok {
+			continue
+		}
+		if results[i].Status == RowCreated {
+			log.Printf("Error importing row %d: %v", results[i].Row, err)
+		}
+		// A row skipped as a duplicate of a failed row fails with it.
+		results[i].Status = RowFailed
+		results[i].BookID = ""
+		results[i].Error = "Could not be saved"
+	}
+	return results
+}
@@ -0,0 +1,179 @@
+package importer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/bookshelf"
+)
+
+// failingBatches is a repository that fails to store the books with the
+// given IDs.
+type failingBatches struct {
+	*bookshelf.MemoryRepository
+	fail map[string]bool
+}
+
+func (r failingBatches) PutBatch(ctx context.Context, userID string, books []bookshelf.Book) map[string]error {
+	failed := map[string]error{}
+	var stored []bookshelf.Book
+	for _, book := range books {
+		if r.fail[book.ID] {
+			failed[book.ID] = errors.New("throttled")
+		} else {
+			stored = append(stored, book)
+		}
+	}
+	for id, err := range r.MemoryRepository.PutBatch(ctx, userID, stored) {
+		failed[id] = err
+	}
+	return failed
+}
+
+func TestImportBatches(t *testing.T) {
+	book := func(title, author string) bookshelf.APIBook {
+		return bookshelf.APIBook{Title: title, Author: author, Status: bookshelf.StatusRead}
+	}
+	// Dune is already on the shelf, row 4 repeats row 3 and row 5 could not
+	// be read
+	rows := []Row{
+		{Number: 2, Book: book("Dune", "Frank Herbert")},
+		{Number: 3, Book: book("Emma", "Jane Austen")},
+		{Number: 4, Book: book("emma", "Jane Austen")},
+		{Number: 5, Book: book("Beowulf", ""), Err: errors.New("Author is required")},
+		{Number: 6, Book: book("Persuasion", "Jane Austen")},
+	}
+
+	tests := []struct {
+		name         string
+		batchSize    int
+		fail         []string // IDs of the new books that are not stored
+		wantRows     []RowResult
+		wantProgress []int // books created after each batch
+	}{
+		{
+			name: "one batch",
+			wantRows: []RowResult{
+				{Row: 2, Title: "Dune", Status: RowSkipped, BookID: "book-1"},
+				{Row: 3, Title: "Emma", Status: RowCreated, BookID: "new-2"},
+				{Row: 4, Title: "emma", Status: RowSkipped, BookID: "new-2"},
+				{Row: 5, Title: "Beowulf", Status: RowFailed, Error: "Author is required"},
+				{Row: 6, Title: "Persuasion", Status: RowCreated, BookID: "new-4"},
+			},
+			wantProgress: []int{2},
+		},
+		{
+			name:      "batches of two",
+			batchSize: 2,
+			wantRows: []RowResult{
+				{Row: 2, Title: "Dune", Status: RowSkipped, BookID: "book-1"},
+				{Row: 3, Title: "Emma", Status: RowCreated, BookID: "new-2"},
+				{Row: 4, Title: "emma", Status: RowSkipped, BookID: "new-2"},
+				{Row: 5, Title: "Beowulf", Status: RowFailed, Error: "Author is required"},
+				{Row: 6, Title: "Persuasion", Status: RowCreated, BookID: "new-4"},
+			},
+			wantProgress: []int{1, 1, 2},
+		},
+		{
+			// The duplicate of a row that was not stored fails with it
+			name: "failed write",
+			fail: []string{"new-2"},
+			wantRows: []RowResult{
+				{Row: 2, Title: "Dune", Status: RowSkipped, BookID: "book-1"},
+				{Row: 3, Title: "Emma", Status: RowFailed, Error: "Could not be saved"},
+				{Row: 4, Title: "emma", Status: RowFailed, Error: "Could not be saved"},
+				{Row: 5, Title: "Beowulf", Status: RowFailed, Error: "Author is required"},
+				{Row: 6, Title: "Persuasion", Status: RowCreated, BookID: "new-4"},
+			},
+			wantProgress: []int{1},
+		},
+		{
+			// A later batch is not skipped as a duplicate of the unsaved row
+			name:      "failed write in an earlier batch",
+			batchSize: 2,
+			fail:      []string{"new-2"},
+			wantRows: []RowResult{
+				{Row: 2, Title: "Dune", Status: RowSkipped, BookID: "book-1"},
+				{Row: 3, Title: "Emma", Status: RowFailed, Error: "Could not be saved"},
+				{Row: 4, Title: "emma", Status: RowCreated, BookID: "new-3"},
+				{Row: 5, Title: "Beowulf", Status: RowFailed, Error: "Author is required"},
+				{Row: 6, Title: "Persuasion", Status: RowCreated, BookID: "new-4"},
+			},
+			wantProgress: []int{0, 1, 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			memory := bookshelf.NewMemoryRepository()
+			if err := memory.Put(ctx, "user-1", bookshelf.NewBook("user-1", "book-1", book("Dune", "Frank Herbert"))); err != nil {
+				t.Fatal(err)
+			}
+			books := failingBatches{MemoryRepository: memory, fail: map[string]bool{}}
+			for _, id := range tt.fail {
+				books.fail[id] = true
+			}
+			// Every row read is given an ID, even if it is then skipped
+			ids := 0
+			newID := func() string {
+				ids++
+				return fmt.Sprintf("new-%d", ids)
+			}
+			var progress []int
+			report, err := ImportBatches(ctx, books, "user-1", rows, newID, tt.batchSize, func(report Report) error {
+				progress = append(progress, report.Created)
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(report.Rows, tt.wantRows) {
+				t.Errorf("rows = %+v, want %+v", report.Rows, tt.wantRows)
+			}
+			if !reflect.DeepEqual(progress, tt.wantProgress) {
+				t.Errorf("created after each batch = %v, want %v", progress, tt.wantProgress)
+			}
+			var created int
+			for _, row := range tt.wantRows {
+				if row.Status == RowCreated {
+					created++
+					if _, err := memory.Get(ctx, "user-1", row.BookID); err != nil {
+						t.Errorf("row %d book: %v", row.Row, err)
+					}
+				}
+			}
+			if report.Created != created || report.Created+report.Skipped+report.Failed != len(rows) {
+				t.Errorf("report = %d created, %d skipped, %d failed, want %d created of %d rows",
+					report.Created, report.Skipped, report.Failed, created, len(rows))
+			}
+		})
+	}
+}
+
+func TestImportBatchesStopped(t *testing.T) {
+	ctx := context.Background()
+	books := bookshelf.NewMemoryRepository()
+	rows := []Row{
+		{Number: 2, Book: bookshelf.APIBook{Title: "Dune", Author: "Frank Herbert"}},
+		{Number: 3, Book: bookshelf.APIBook{Title: "Emma", Author: "Jane Austen"}},
+	}
+	stop := errors.New("out of time")
+	report, err := ImportBatches(ctx, books, "user-1", rows, func() string { return "book-" + fmt.Sprint(len(rows)) }, 1, func(Report) error { return stop })
+	if !errors.Is(err, stop) {
+		t.Fatalf("ImportBatches error = %v, want %v", err, stop)
+	}
+	if len(report.Rows) != 1 || report.Created != 1 {
+		t.Errorf("report = %+v, want the first batch only", report)
+	}
+	stored, err := books.List(ctx, "user-1", bookshelf.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stored) != 1 {
+		t.Errorf("stored %d books, want the first batch's 1", len(stored))
+	}
+}
@@ -0,0 +1,248 @@
+package importer
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/bookshelf"
+)
+
+// Statuses of an import job.
+const (
+	// JobPending is waiting for its file to be uploaded.
+	JobPending    = "pending"
+	JobProcessing = "processing"
+	JobCompleted  = "completed"
+	JobFailed     = "failed"
+)
+
+const (
+	// JobTTL is how long an import job can be looked up after it was
+	// created.
+	JobTTL = 7 * 24 * time.Hour
+
+	// MaxJobErrors bounds the failed rows kept with a job, so that it fits in
+	// one DynamoDB item however bad the file is.
+	MaxJobErrors = 100
+
+	// JobLease is how long a started job may run before it is presumed
+	// dead: process-import's 900 second timeout and a minute to spare.
+	// Start takes over a job that has been processing for longer.
+	JobLease = 16 * time.Minute
+)
+
+// JobTimedOut is the error of a job whose worker stopped before it
+// finished.
+const JobTimedOut = "The import did not finish in time"
+
+// jobKeyPrefix is the folder of the object store import files are
+// uploaded to.
+const jobKeyPrefix = "imports/"
+
+var (
+	// ErrJobNotFound is returned when an import job does not exist for the
+	// given user.
+	ErrJobNotFound = errors.New("import job not found")
+
+	// ErrJobStarted is returned by JobRepository.Start for a job that is no
+	// longer pending, or is still within its lease.
+	ErrJobStarted = errors.New("import job already started")
+)
+
+// Job tracks an asynchronous import of a file uploaded to the object store.
+// Jobs are stored in the user's partition under IMPORT#<job id> until
+// expires_at.
+type Job struct {
+	PK     string `dynamodbav:"PK"`
+	SK     string `dynamodbav:"SK"`
+	ID     string `dynamodbav:"id"`
+	Format string `dynamodbav:"format"`
+	Status string `dynamodbav:"status"`
+	// Total is the number of rows in the file, known once it is parsed, and
+	// Processed how many of them have been imported so far.
+	Total     int `dynamodbav:"total"`
+	Processed int `dynamodbav:"processed"`
+	Created   int `dynamodbav:"created"`
+	Skipped   int `dynamodbav:"skipped"`
+	Failed    int `dynamodbav:"failed"`
+	// Errors are the first MaxJobErrors failed rows.
+	Errors []RowResult `dynamodbav:"errors"`
+	// Error is why a failed job could not import the file at all. It is
+	// safe to show to clients.
+	Error     string `dynamodbav:"error,omitempty"`
+	CreatedAt string `dynamodbav:"created_at"`
+	UpdatedAt string `dynamodbav:"updated_at"`
+	// StartedAt is when the worker last started the job.
+	StartedAt string `dynamodbav:"started_at,omitempty"`
+	ExpiresAt int64  `dynamodbav:"expires_at"`
+}
+
+// APIJob is the API representation of a Job.
+type APIJob struct {
+	ID        string      `json:"id"`
+	Format    string      `json:"format"`
+	Status    string      `json:"status"`
+	Total     int         `json:"total"`
+	Processed int         `json:"processed"`
+	Created   int         `json:"created"`
+	Skipped   int         `json:"skipped"`
+	Failed    int         `json:"failed"`
+	Errors    []RowResult `json:"errors"`
+	// ErrorsTruncated is set when more rows failed than are listed.
+	ErrorsTruncated bool   `json:"errors_truncated"`
+	Error           string `json:"error,omitempty"`
+	CreatedAt       string `json:"created_at"`
+	UpdatedAt       string `json:"updated_at"`
+}
+
+// JobRepository stores import jobs. Like BookRepository, every operation is
+// scoped to a single user.
+type JobRepository interface {
+	// Put stores the user's job, replacing any job with the same ID.
+	Put(ctx context.Context, userID string, job Job) error
+	// Get returns the user's unexpired job with the given ID, or
+	// ErrJobNotFound. A job whose worker died is returned failed.
+	Get(ctx context.Context, userID, jobID string) (Job, error)
+	// Start moves the user's pending job to JobProcessing and returns it,
+	// or takes over a job processing for longer than JobLease, whose worker
+	// died. It returns ErrJobNotFound, or ErrJobStarted otherwise, so a
+	// file delivered twice is only imported once.
+	Start(ctx context.Context, userID, jobID string) (Job, error)
+}
+
+var (
+	_ JobRepository = (*DynamoJobRepository)(nil)
+	_ JobRepository = (*MemoryJobRepository)(nil)
+)
+
+// NewJob returns a pending job for the user, created at now, importing a
+// file in format.
+func NewJob(userID, jobID, format string, now time.Time) Job {
+	timestamp := now.UTC().Format(time.RFC3339)
+	return Job{
+		PK:        bookshelf.UserPK(userID),
+		SK:        bookshelf.ImportSK(jobID),
+		ID:        jobID,
+		Format:    format,
+		Status:    JobPending,
+		Errors:    []RowResult{},
+		CreatedAt: timestamp,
+		UpdatedAt: timestamp,
+		ExpiresAt: now.Add(JobTTL).Unix(),
+	}
+}
+
+// Progress records the rows reported so far.
+func (j *Job) Progress(report Report) {
+	j.touch()
+	j.Processed = len(report.Rows)
+	j.Created = report.Created
+	j.Skipped = report.Skipped
+	j.Failed = report.Failed
+	j.Errors = []RowResult{}
+	for _, row := range report.Rows {
+		if row.Status == RowFailed && len(j.Errors) < MaxJobErrors {
+			j.Errors = append(j.Errors, row)
+		}
+	}
+}
+
+// Complete marks the job completed.
+func (j *Job) Complete() {
+	j.touch()
+	j.Status = JobCompleted
+}
+
+// Fail marks the job failed with a reason that is safe to show to clients.
+func (j *Job) Fail(reason string) {
+	j.touch()
+	j.Status = JobFailed
+	j.Error = reason
+}
+
+func (j *Job) touch() {
+	j.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
+}
+
+// start moves the job to JobProcessing at now.
+func (j *Job) start(now time.Time) {
+	timestamp := now.UTC().Format(time.RFC3339)
+	j.Status = JobProcessing
+	j.StartedAt = timestamp
+	j.UpdatedAt = timestamp
+}
+
+// leaseExpired reports whether the job has been processing for longer than
+// JobLease at now, so its worker is no longer running.
+func (j Job) leaseExpired(now time.Time) bool {
+	if j.Status != JobProcessing {
+		return false
+	}
+	started, err := time.Parse(time.RFC3339, j.StartedAt)
+	if err != nil {
+		// Jobs started before leases were recorded only have updated_at,
+		// which progress moves on
+		started, err = time.Parse(time.RFC3339, j.UpdatedAt)
+	}
+	return err == nil && now.Sub(started) > JobLease
+}
+
+// current returns the job as it stands at now: one whose lease expired has
+// failed, so that clients stop polling it. Pending jobs wait for their file
+// for as long as they are kept.
+func (j Job) current(now time.Time) Job {
+	if j.leaseExpired(now) {
+		j.Status = JobFailed
+		j.Error = JobTimedOut
+	}
+	return j
+}
+
+// expired reports whether the job is past its TTL at now. DynamoDB deletes
+// expired jobs some time after expires_at, so reads must check it.
+func (j Job) expired(now time.Time) bool {
+	return now.Unix() >= j.ExpiresAt
+}
+
+// ToAPI converts a job into its API representation.
+func (j Job) ToAPI() APIJob {
+	errs := j.Errors
+	if errs == nil {
+		errs = []RowResult{}
+	}
+	return APIJob{
+		ID:              j.ID,
+		Format:          j.Format,
+		Status:          j.Status,
+		Total:           j.Total,
+		Processed:       j.Processed,
+		Created:         j.Created,
+		Skipped:         j.Skipped,
+		Failed:          j.Failed,
+		Errors:          errs,
+		ErrorsTruncated: j.Failed > len(errs),
+		Error:           j.Error,
+		CreatedAt:       j.CreatedAt,
+		UpdatedAt:       j.UpdatedAt,
+	}
+}
+
+// JobKey returns the object store key a job's file is uploaded to.
+func JobKey(userID, jobID string) string {
+	return jobKeyPrefix + userID + "/" + jobID
+}
+
+// ParseJobKey extracts the user and job IDs from a key returned by JobKey.
+func ParseJobKey(key string) (userID, jobID string, ok bool) {
+	rest, ok := strings.CutPrefix(key, jobKeyPrefix)
+	if !ok {
+		return "", "", false
+	}
+	userID, jobID, ok = strings.Cut(rest, "/")
+	if !ok || userID == "" || jobID == "" || strings.Contains(jobID, "/") {
+		return "", "", false
+	}
+	return userID, jobID, true
+}
@@ -0,0 +1,136 @@
+package importer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/bookshelf"
+)
+
+// JobDynamoDBAPI is the subset of the DynamoDB client used by
+// DynamoJobRepository.
+type JobDynamoDBAPI interface {
+	GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error)
+	PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error)
+	UpdateItem(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error)
+}
+
+// DynamoJobRepository is a JobRepository keeping jobs in the books table,
+// next to the books of the user who started them.
+type DynamoJobRepository struct {
+	client JobDynamoDBAPI
+	table  string
+}
+
+// NewDynamoJobRepository returns a repository storing jobs in table.
+func NewDynamoJobRepository(client JobDynamoDBAPI, table string) *DynamoJobRepository {
+	return &DynamoJobRepository{client: client, table: table}
+}
+
+// Put stores the user's job, replacing any job with the same ID.
+func (r *DynamoJobRepository) Put(ctx context.Context, userID string, job Job) error {
+	job.PK = bookshelf.UserPK(userID)
+	job.SK = bookshelf.ImportSK(job.ID)
+	item, err := attributevalue.MarshalMap(job)
+	if err != nil {
+		return fmt.Errorf("failed to marshal import job: %w", err)
+	}
+
+	_, err = r.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(r.table),
+		Item:      item,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to put import job: %w", err)
+	}
+	return nil
+}
+
+// Get returns the user's unexpired job with the given ID, or ErrJobNotFound.
+func (r *DynamoJobRepository) Get(ctx context.Context, userID, jobID string) (Job, error) {
+	result, err := r.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(r.table),
+		Key:            jobKey(userID, jobID),
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return Job{}, fmt.Errorf("failed to get import job: %w", err)
+	}
+	if result.Item == nil {
+		return Job{}, ErrJobNotFound
+	}
+
+	var job Job
+	if err := attributevalue.UnmarshalMap(result.Item, &job); err != nil {
+		return Job{}, fmt.Errorf("failed to unmarshal import job: %w", err)
+	}
+	now := time.Now()
+	if job.expired(now) {
+		return Job{}, ErrJobNotFound
+	}
+	return job.current(now), nil
+}
+
+// Start moves the user's pending job to JobProcessing with one conditional
+// update and returns it, or takes over a job whose lease has expired, as
+// exporter.DynamoJobRepository.Start does.
+func (r *DynamoJobRepository) Start(ctx context.Context, userID, jobID string) (Job, error) {
+	now := time.Now()
+	result, err := r.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:        aws.String(r.table),
+		Key:              jobKey(userID, jobID),
+		UpdateExpression: aws.String("SET #status = :processing, started_at = :now, updated_at = :now"),
+		ConditionExpression: aws.String("attribute_exists(PK) AND expires_at > :unix AND " +
+			"(#status = :pending OR (#status = :processing AND started_at < :stale))"),
+		ExpressionAttributeNames: map[string]string{
+			"#status": "status",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":processing": &types.AttributeValueMemberS{Value: JobProcessing},
+			":pending":    &types.AttributeValueMemberS{Value: JobPending},
+			":now":        &types.AttributeValueMemberS{Value: now.UTC().Format(time.RFC3339)},
+			":stale":      &types.AttributeValueMemberS{Value: now.Add(-JobLease).UTC().Format(time.RFC3339)},
+			":unix":       &types.AttributeValueMemberN{Value: strconv.FormatInt(now.Unix(), 10)},
+		},
+		ReturnValues:                        types.ReturnValueAllNew,
+		ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
+	})
+	var failed *types.ConditionalCheckFailedException
+	if errors.As(err, &failed) {
+		var job Job
+		if failed.Item == nil {
+			return Job{}, ErrJobNotFound
+		}
+		if err := attributevalue.UnmarshalMap(failed.Item, &job); err != nil {
+			return Job{}, fmt.Errorf("failed to unmarshal import job: %w", err)
+		}
+		if job.expired(now) {
+			return Job{}, ErrJobNotFound
+		}
+		return Job{}, ErrJobStarted
+	}
+	if err != nil {
+		return Job{}, fmt.Errorf("failed to start import job: %w", err)
+	}
+
+	var job Job
+	if err := attributevalue.UnmarshalMap(result.Attributes, &job); err != nil {
+		return Job{}, fmt.Errorf("failed to unmarshal import job: %w", err)
+	}
+	return job, nil
+}
+
+// jobKey returns the DynamoDB primary key of a user's import job.
+func jobKey(userID, jobID string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"PK": &types.AttributeValueMemberS{Value: bookshelf.UserPK(userID)},
+		"SK": &types.AttributeValueMemberS{Value: bookshelf.ImportSK(jobID)},
+	}
+}
@@ -0,0 +1,70 @@
+package importer
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+)
+
+// MemoryJobRepository is an in-memory JobRepository for local development
+// and tests. It is safe for concurrent use.
+type MemoryJobRepository struct {
+	mu   sync.Mutex
+	jobs map[string]map[string]Job // user ID -> job ID -> job
+}
+
+// NewMemoryJobRepository returns an empty in-memory job repository.
+func NewMemoryJobRepository() *MemoryJobRepository {
+	return &MemoryJobRepository{jobs: make(map[string]map[string]Job)}
+}
+
+// Put stores the user's job, replacing any job with the same ID.
+func (r *MemoryJobRepository) Put(ctx context.Context, userID string, job Job) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.jobs[userID] == nil {
+		r.jobs[userID] = make(map[string]Job)
+	}
+	r.jobs[userID][job.ID] = cloneJob(job)
+	return nil
+}
+
+// Get returns the user's unexpired job with the given ID, or ErrJobNotFound.
+func (r *MemoryJobRepository) Get(ctx context.Context, userID, jobID string) (Job, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	job, ok := r.jobs[userID][jobID]
+	if !ok || job.expired(now) {
+		return Job{}, ErrJobNotFound
+	}
+	return cloneJob(job).current(now), nil
+}
+
+// Start moves the user's pending job to JobProcessing and returns it, or
+// takes over a job whose lease has expired.
+func (r *MemoryJobRepository) Start(ctx context.Context, userID, jobID string) (Job, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	job, ok := r.jobs[userID][jobID]
+	if !ok || job.expired(now) {
+		return Job{}, ErrJobNotFound
+	}
+	if job.Status != JobPending && !job.leaseExpired(now) {
+		return Job{}, ErrJobStarted
+	}
+	job.start(now)
+	r.jobs[userID][jobID] = job
+	return cloneJob(job), nil
+}
+
+// cloneJob copies job so callers cannot mutate stored state.
+func cloneJob(job Job) Job {
+	job.Errors = slices.Clone(job.Errors)
+	return job
+}
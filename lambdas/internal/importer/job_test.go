@@ -0,0 +1,81 @@
+package importer
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMemoryJobRepositoryStart(t *testing.T) {
+	now := time.Now()
+	ago := func(d time.Duration) string { return now.Add(-d).UTC().Format(time.RFC3339) }
+
+	tests := []struct {
+		name      string
+		job       Job
+		wantGet   string // status Get reports before Start
+		wantStart error
+	}{
+		{
+			name:    "pending for days",
+			job:     Job{Status: JobPending, CreatedAt: ago(72 * time.Hour)},
+			wantGet: JobPending,
+		},
+		{
+			name:      "processing within its lease",
+			job:       Job{Status: JobProcessing, StartedAt: ago(time.Minute), UpdatedAt: ago(time.Minute)},
+			wantGet:   JobProcessing,
+			wantStart: ErrJobStarted,
+		},
+		{
+			name:    "processing past its lease",
+			job:     Job{Status: JobProcessing, StartedAt: ago(JobLease + time.Second), UpdatedAt: ago(time.Minute)},
+			wantGet: JobFailed,
+		},
+		{
+			name:    "processing without started_at, idle past the lease",
+			job:     Job{Status: JobProcessing, UpdatedAt: ago(JobLease + time.Second)},
+			wantGet: JobFailed,
+		},
+		{
+			name:      "failed",
+			job:       Job{Status: JobFailed, StartedAt: ago(time.Hour), Error: "Invalid goodreads file"},
+			wantGet:   JobFailed,
+			wantStart: ErrJobStarted,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			jobs := NewMemoryJobRepository()
+			tt.job.ID = "job-1"
+			tt.job.ExpiresAt = now.Add(JobTTL).Unix()
+			if err := jobs.Put(ctx, "user-1", tt.job); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := jobs.Get(ctx, "user-1", "job-1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Status != tt.wantGet {
+				t.Errorf("Get status = %q, want %q", got.Status, tt.wantGet)
+			}
+
+			started, err := jobs.Start(ctx, "user-1", "job-1")
+			if !errors.Is(err, tt.wantStart) {
+				t.Fatalf("Start error = %v, want %v", err, tt.wantStart)
+			}
+			if err != nil {
+				return
+			}
+			if started.Status != JobProcessing || started.StartedAt == tt.job.StartedAt {
+				t.Errorf("Start = %q started at %q, want a fresh lease", started.Status, started.StartedAt)
+			}
+			if _, err := jobs.Start(ctx, "user-1", "job-1"); !errors.Is(err, ErrJobStarted) {
+				t.Errorf("second Start error = %v, want %v", err, ErrJobStarted)
+			}
+		})
+	}
+}
@@ -0,0 +1,301 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/bookshelf"
+)
+
+// libraryThingCollections are LibraryThing's built-in collections, which
+// set the status. Books in other collections are tagged with them.
+var libraryThingCollections = []string{"Your library", "Wishlist", "Currently reading", "To read", "Read but unowned"}
+
+// libraryThingRequired are the columns a LibraryThing TSV export must have.
+var libraryThingRequired = []string{"Title", "Primary Author"}
+
+// libraryThingSeries matches the volume LibraryThing appends to a series,
+// as in "The Stormlight Archive (1)".
+var libraryThingSeries = regexp.MustCompile(`^(.+?)\s*[(;]\s*[\d.]+\)?$`)
+
+// LibraryThing imports a LibraryThing library export (More > Import/Export),
+// either tab-delimited text or JSON. Besides titles, authors, series, ISBNs,
+// ratings in half stars, reviews and tags, the collections set the status,
+// the media becomes the type, the dates started, read and entered become
+// started_at, finished_at and created_at, and the public and private
+// comments are kept as comments.
+type LibraryThing struct{}
+
+// Format returns "librarything".
+func (LibraryThing) Format() string { return "librarything" }
+
+// Parse reads a LibraryThing export, telling JSON from tab-delimited text
+// by its first character. Tab-delimited exports may be UTF-8 or UTF-16.
+func (LibraryThing) Parse(r io.Reader) ([]Row, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("invalid file: %w", err)
+	}
+	data = decodeUTF16(data)
+
+	trimmed := bytes.TrimLeft(bytes.TrimPrefix(data, []byte("\ufeff")), " \t\r\n")
+	if len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') {
+		return parseLibraryThingJSON(trimmed)
+	}
+	return readTable(newTSVReader(bytes.NewReader(data)), libraryThingRequired, libraryThingBook)
+}
+
+// libraryThingBook converts one book of a LibraryThing export, read
+// through field by TSV column name.
+func libraryThingBook(field func(name string) string) (bookshelf.APIBook, error) {
+	book := bookshelf.APIBook{
+		Title:  field("Title"),
+		Author: firstLast(field("Primary Author")),
+		Type:   mediaType(field("Media")),
+		Review: plainText(field("Review")),
+	}
+	if book.Title == "" {
+		return book, errors.New("Title is required")
+	}
+	if book.Author == "" {
+		return book, errors.New("Primary Author is required")
+	}
+	if series := splitList(field("Series"), ","); len(series) > 0 {
+		book.Series = series[0]
+		if m := libraryThingSeries.FindStringSubmatch(book.Series); m != nil {
+			book.Series = m[1]
+		}
+	}
+
+	// Prefer an ISBN-13 from the list of every ISBN of the work
+	isbns := splitList(field("ISBNs"), ",")
+	slices.SortStableFunc(isbns, func(a, b string) int { return len(cleanISBN(b)) - len(cleanISBN(a)) })
+	for _, isbn := range append(isbns, field("ISBN")) {
+		if book.ISBN = cleanISBN(isbn); book.ISBN != "" {
+			break
+		}
+	}
+
+	rating, err := parseStars(field("Rating"))
+	if err != nil {
+		return book, fmt.Errorf("Invalid Rating %q. Must be between 0 and 5", field("Rating"))
+	}
+	book.Rating = rating
+
+	var comments []string
+	for _, name := range []string{"Comment", "Private Comment"} {
+		if comment := plainText(field(name)); comment != "" {
+			comments = append(comments, comment)
+		}
+	}
+	book.Comments = strings.Join(comments, "\n\n")
+
+	started, err := parseDate(field("Date Started"), time.DateOnly)
+	if err != nil {
+		return book, fmt.Errorf("Invalid Date Started %q", field("Date Started"))
+	}
+	finished, err := parseDate(field("Date Read"), time.DateOnly)
+	if err != nil {
+		return book, fmt.Errorf("Invalid Date Read %q", field("Date Read"))
+	}
+	entered, err := parseDate(field("Entry Date"), time.DateOnly)
+	if err != nil {
+		return book, fmt.Errorf("Invalid Entry Date %q", field("Entry Date"))
+	}
+	book.StartedAt = formatDate(started)
+	book.FinishedAt = formatDate(finished)
+	book.CreatedAt = formatTimestamp(entered)
+
+	collections := splitList(field("Collections"), ",")
+	book.Status = libraryThingStatus(collections, book.StartedAt, book.FinishedAt)
+	for _, collection := range collections {
+		if !slices.Contains(libraryThingCollections, collection) {
+			book.Tags = appendTags(book.Tags, collection)
+		}
+	}
+	book.Tags = appendTags(book.Tags, splitList(field("Tags"), ",")...)
+	return book, nil
+}
+
+// libraryThingStatus picks a book's status from its collections, falling
+// back to its reading dates.
+func libraryThingStatus(collections []string, started, finished string) string {
+	switch {
+	case slices.Contains(collections, "Currently reading"):
+		return bookshelf.StatusReading
+	case finished != "" || slices.Contains(collections, "Read but unowned"):
+		return bookshelf.StatusRead
+	case slices.Contains(collections, "To read") || slices.Contains(collections, "Wishlist"):
+		return bookshelf.StatusWantToRead
+	case started != "":
+		return bookshelf.StatusReading
+	default:
+		return bookshelf.StatusWantToRead
+	}
+}
+
+// firstLast turns LibraryThing's "Last, First" author names around.
+func firstLast(name string) string {
+	last, first, ok := strings.Cut(name, ",")
+	if !ok || strings.Contains(first, ",") || strings.TrimSpace(first) == "" {
+		return name
+	}
+	return strings.TrimSpace(first) + " " + strings.TrimSpace(last)
+}
+
+// parseLibraryThingJSON reads a JSON export: an object of books keyed by
+// LibraryThing book ID, in file order, or an array of books. Books are
+// numbered from 1.
+func parseLibraryThingJSON(data []byte) ([]Row, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	start, err := decoder.Token()
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	var rows []Row
+	for number := 1; decoder.More(); number++ {
+		if start == json.Delim('{') {
+			// Skip the book ID key
+			if _, err := decoder.Token(); err != nil {
+				return nil, fmt.Errorf("invalid JSON: %w", err)
+			}
+		}
+		var entry map[string]json.RawMessage
+		if err := decoder.Decode(&entry); err != nil {
+			return nil, fmt.Errorf("invalid JSON: %w", err)
+		}
+		book, err := libraryThingBook(libraryThingJSONField(entry))
+		rows = append(rows, Row{Number: number, Book: book, Err: err})
+	}
+	if _, err := decoder.Token(); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, errors.New("file is empty")
+	}
+	return rows, nil
+}
+
+// libraryThingJSONField looks up the fields of a JSON export entry by their
+// TSV column names, so both exports are converted alike.
+func libraryThingJSONField(entry map[string]json.RawMessage) func(name string) string {
+	return func(name string) string {
+		switch name {
+		case "Title":
+			return jsonText(entry["title"])
+		case "Primary Author":
+			// Authors carry the name first-last as "fl"
+			var authors []struct {
+				FL string `json:"fl"`
+			}
+			if json.Unmarshal(entry["authors"], &authors) == nil && len(authors) > 0 && authors[0].FL != "" {
+				return strings.TrimSpace(authors[0].FL)
+			}
+			return jsonText(entry["primaryauthor"])
+		case "Series":
+			return jsonText(entry["series"])
+		case "ISBNs":
+			return jsonText(entry["isbn"])
+		case "ISBN":
+			return jsonText(entry["originalisbn"])
+		case "Rating":
+			return jsonText(entry["rating"])
+		case "Review":
+			return jsonText(entry["review"])
+		case "Comment":
+			return jsonText(entry["comment"])
+		case "Private Comment":
+			return jsonText(entry["privatecomment"])
+		case "Media":
+			// Formats are objects like {"code": "1", "text": "Paperback"}
+			var formats []struct {
+				Text string `json:"text"`
+			}
+			if json.Unmarshal(entry["format"], &formats) == nil && len(formats) > 0 {
+				return strings.TrimSpace(formats[0].Text)
+			}
+			return ""
+		case "Date Started":
+			return jsonText(entry["datestarted"])
+		case "Date Read":
+			return jsonText(entry["dateread"])
+		case "Entry Date":
+			return jsonText(entry["entrydate"])
+		case "Tags":
+			return jsonText(entry["tags"])
+		case "Collections":
+			return jsonText(entry["collections"])
+		}
+		return ""
+	}
+}
+
+// jsonText renders a JSON string or number as text, and an array, or the
+// values of an object, as a comma-separated list of them.
+func jsonText(raw json.RawMessage) string {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return strings.TrimSpace(s)
+	}
+	var n json.Number
+	if json.Unmarshal(raw, &n) == nil {
+		return n.String()
+	}
+
+	var items []json.RawMessage
+	if json.Unmarshal(raw, &items) != nil {
+		var values map[string]json.RawMessage
+		if json.Unmarshal(raw, &values) != nil {
+			return ""
+		}
+		keys := make([]string, 0, len(values))
+		for key := range values {
+			keys = append(keys, key)
+		}
+		slices.SortFunc(keys, func(a, b string) int {
+			x, _ := strconv.Atoi(a)
+			y, _ := strconv.Atoi(b)
+			return x - y
+		})
+		for _, key := range keys {
+			items = append(items, values[key])
+		}
+	}
+	var texts []string
+	for _, item := range items {
+		if text := jsonText(item); text != "" {
+			texts = append(texts, text)
+		}
+	}
+	return strings.Join(texts, ", ")
+}
+
+// decodeUTF16 converts UTF-16 text, recognized by its byte order mark, to
+// UTF-8. Anything else is returned as is.
+func decodeUTF16(data []byte) []byte {
+	var order func([]byte) uint16
+	switch {
+	case bytes.HasPrefix(data, []byte{0xff, 0xfe}):
+		order = func(b []byte) uint16 { return uint16(b[0]) | uint16(b[1])<<8 }
+	case bytes.HasPrefix(data, []byte{0xfe, 0xff}):
+		order = func(b []byte) uint16 { return uint16(b[1]) | uint16(b[0])<<8 }
+	default:
+		return data
+	}
+	units := make([]uint16, 0, len(data)/2)
+	for i := 2; i+1 < len(data); i += 2 {
+		units = append(units, order(data[i:i+2]))
+	}
+	return []byte(string(utf16.Decode(units)))
+}
@@ -0,0 +1,224 @@
+package importer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/bookshelf"
+)
+
+// Restore modes.
+const (
+	// RestoreMerge stores every book in the backup, overwriting the user's
+	// book with the same ID, and leaves the user's other books alone.
+	RestoreMerge = "merge"
+	// RestoreReplace is RestoreMerge that also moves the user's books that
+	// are not in the backup to their trash, leaving the shelf as it was
+	// when the backup was taken.
+	RestoreReplace = "replace"
+)
+
+// Outcomes of a restored book.
+const (
+	BookCreated   = "created"
+	BookUpdated   = "updated"
+	BookUnchanged = "unchanged"
+	BookRemoved   = "removed"
+	BookFailed    = "failed"
+)
+
+// ErrFilteredReplace is returned by Restore for a replace of a filtered
+// export, which would trash every book the filters left out.
+var ErrFilteredReplace = errors.New("a filtered export cannot be restored in replace mode")
+
+// ValidRestoreMode reports whether mode is a restore mode.
+func ValidRestoreMode(mode string) bool {
+	return mode == RestoreMerge || mode == RestoreReplace
+}
+
+// RestoreResult reports what happened to one book.
+type RestoreResult struct {
+	// Entry is the book's position in the backup, counting from 1. Books
+	// removed because they are not in the backup have none.
+	Entry  int    `json:"entry,omitempty"`
+	BookID string `json:"book_id,omitempty"`
+	Title  string `json:"title,omitempty"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// RestoreReport summarizes a restore.
+type RestoreReport struct {
+	Mode      string          `json:"mode"`
+	Created   int             `json:"created"`
+	Updated   int             `json:"updated"`
+	Unchanged int             `json:"unchanged"`
+	Removed   int             `json:"removed"`
+	Failed    int             `json:"failed"`
+	Books     []RestoreResult `json:"books"`
+}
+
+func (r *RestoreReport) add(result RestoreResult) {
+	switch result.Status {
+	case BookCreated:
+		r.Created++
+	case BookUpdated:
+		r.Updated++
+	case BookUnchanged:
+		r.Unchanged++
+	case BookRemoved:
+		r.Removed++
+	case BookFailed:
+		r.Failed++
+	}
+	r.Books = append(r.Books, result)
+}
+
+// ParseBackup reads a JSON export written by export-books. Exports from
+// before schema_version was introduced, which are a bare array of books,
+// are read as version 0. Its errors are safe to show to clients.
+func ParseBackup(reader io.Reader) (bookshelf.Export, error) {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return bookshelf.Export{}, err
+	}
+	data = bytes.TrimSpace(bytes.TrimPrefix(data, []byte("\ufeff")))
+
+	var backup bookshelf.Export
+	if bytes.HasPrefix(data, []byte("[")) {
+		if err := json.Unmarshal(data, &backup.Books); err != nil {
+			return bookshelf.Export{}, errors.New("malformed JSON")
+		}
+		backup.BookCount = len(backup.Books)
+		return backup, nil
+	}
+
+	if err := json.Unmarshal(data, &backup); err != nil {
+		return bookshelf.Export{}, errors.New("malformed JSON")
+	}
+	switch {
+	case backup.SchemaVersion < 1:
+		return bookshelf.Export{}, errors.New("schema_version is required")
+	case backup.SchemaVersion > bookshelf.ExportSchemaVersion:
+		return bookshelf.Export{}, fmt.Errorf("unsupported schema_version %d, at most %d can be restored", backup.SchemaVersion, bookshelf.ExportSchemaVersion)
+	case backup.BookCount != len(backup.Books):
+		// A truncated file would otherwise restore, or in replace mode
+		// trash, the wrong books.
+		return bookshelf.Export{}, fmt.Errorf("book_count is %d but the file holds %d books", backup.BookCount, len(backup.Books))
+	}
+	return backup, nil
+}
+
+// Restore stores the books in backup for userID with their IDs, versions
+// and creation times, as mode directs. A new book is stored at the version
+// in the backup, so that exporting a restored shelf reproduces the backup,
+// while a book that differs from the user's book with the same ID replaces
+// it with the version incremented. The error is for a filtered replace and
+// for failing to read the user's books; everything else is reported per
+// book.
+func Restore(ctx context.Context, books bookshelf.BookRepository, userID string, backup bookshelf.Export, mode string) (RestoreReport, error) {
+	if mode == RestoreReplace && len(backup.Filters) > 0 {
+		return RestoreReport{}, ErrFilteredReplace
+	}
+	existing, err := bookshelf.ListAll(ctx, books, userID, bookshelf.ListOptions{})
+	if err != nil {
+		return RestoreReport{}, fmt.Errorf("failed to list books: %w", err)
+	}
+	current := make(map[string]bookshelf.Book, len(existing))
+	for _, book := range existing {
+		current[book.ID] = book
+	}
+
+	results := make([]RestoreResult, len(backup.Books))
+	inBackup := make(map[string]bool, len(backup.Books))
+	var created []bookshelf.Book
+	for i, api := range backup.Books {
+		results[i] = RestoreResult{Entry: i + 1, BookID: api.ID, Title: api.Title}
+		err := validateBackupBook(api)
+		if err == nil && inBackup[api.ID] {
+			err = errors.New("Duplicate book ID")
+		}
+		if api.ID != "" {
+			inBackup[api.ID] = true
+		}
+		if err != nil {
+			results[i].Status = BookFailed
+			results[i].Error = err.Error()
+			continue
+		}
+
+		book := bookshelf.NewBook(userID, api.ID, api)
+		book.Version = max(api.Version, 1)
+		stored, ok := current[api.ID]
+		switch {
+		case !ok:
+			results[i].Status = BookCreated
+			created = append(created, book)
+		case len(bookshelf.DiffBooks(stored, book)) == 0 && stored.CreatedAt == book.CreatedAt:
+			results[i].Status = BookUnchanged
+		default:
+			book.Version = stored.Version
+			if _, err := books.Update(ctx, userID, book); err != nil {
+				log.Printf("Error restoring book %s: %v", book.ID, err)
+				results[i].Status = BookFailed
+				results[i].Error = "Could not be saved"
+				continue
+			}
+			results[i].Status = BookUpdated
+		}
+	}
+
+	failed := books.PutBatch(ctx, userID, created)
+	report := RestoreReport{Mode: mode, Books: []RestoreResult{}}
+	for _, result := range results {
+		if err, ok := failed[result.BookID]; ok && result.Status == BookCreated {
+			log.Printf("Error restoring book %s: %v", result.BookID, err)
+			result.Status = BookFailed
+			result.Error = "Could not be saved"
+		}
+		report.add(result)
+	}
+
+	if mode != RestoreReplace {
+		return report, nil
+	}
+	for _, book := range existing {
+		if inBackup[book.ID] {
+			continue
+		}
+		result := RestoreResult{BookID: book.ID, Title: book.Title, Status: BookRemoved}
+		if _, err := books.Trash(ctx, userID, book); err != nil {
+			log.Printf("Error trashing book %s: %v", book.ID, err)
+			result.Status = BookFailed
+			result.Error = "Could not be moved to the trash"
+		}
+		report.add(result)
+	}
+	return report, nil
+}
+
+// validateBackupBook checks the fields a stored book must have. Its errors
+// are safe to show to clients.
+func validateBackupBook(book bookshelf.APIBook) error {
+	switch {
+	case book.ID == "":
+		return errors.New("ID is required")
+	case strings.Contains(book.ID, "#"):
+		return errors.New("Invalid ID")
+	case book.Title == "":
+		return errors.New("Title is required")
+	case book.Author == "":
+		return errors.New("Author is required")
+	case !bookshelf.ValidStatus(book.Status):
+		return fmt.Errorf("Invalid status %q", book.Status)
+	case book.Rating != nil && (*book.Rating < bookshelf.MinRating || *book.Rating > bookshelf.MaxRating):
+		return fmt.Errorf("Rating must be between %d and %d", bookshelf.MinRating, bookshelf.MaxRating)
+	}
+	return nil
+}
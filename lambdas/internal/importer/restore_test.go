@@ -0,0 +1,178 @@
+package importer
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/bookshelf"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/exporter"
+)
+
+// exportedAt is the clock the round trip exports are taken at.
+var exportedAt = time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func TestRestoreRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	shelf := newShelf(t)
+	backup := exportJSON(t, shelf)
+	if !bytes.Contains(backup, []byte(`"version": 3`)) || !bytes.Contains(backup, []byte(`"book_count": 4`)) {
+		t.Fatalf("backup is missing books or versions:\n%s", backup)
+	}
+
+	tests := []struct {
+		name string
+		mode string
+		// shelf is what the user has when the backup is restored.
+		shelf       func(t *testing.T) *bookshelf.MemoryRepository
+		wantCreated int
+		wantRemoved int
+	}{
+		{"merge into an empty shelf", RestoreMerge, emptyShelf, 4, 0},
+		{"merge into the same shelf", RestoreMerge, newShelf, 0, 0},
+		{"replace an empty shelf", RestoreReplace, emptyShelf, 4, 0},
+		{"replace a shelf with other books", RestoreReplace, shelfWithOthers, 0, 2},
+		{"replace a shelf with books trashed", RestoreReplace, shelfWithTrashed, 2, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed, err := ParseBackup(bytes.NewReader(backup))
+			if err != nil {
+				t.Fatal(err)
+			}
+			repo := tt.shelf(t)
+			report, err := Restore(ctx, repo, "user-1", parsed, tt.mode)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if report.Created != tt.wantCreated || report.Removed != tt.wantRemoved || report.Updated != 0 || report.Failed != 0 {
+				t.Errorf("Restore = %+v, want %d created and %d removed", report, tt.wantCreated, tt.wantRemoved)
+			}
+
+			if got := exportJSON(t, repo); !bytes.Equal(got, backup) {
+				t.Errorf("re-export differs from the backup:\n%s\nwant\n%s", got, backup)
+			}
+		})
+	}
+}
+
+// newShelf returns a user's shelf of books with every field set, some at
+// later versions.
+func newShelf(t *testing.T) *bookshelf.MemoryRepository {
+	t.Helper()
+	ctx := context.Background()
+	repo := bookshelf.NewMemoryRepository()
+	rating := func(n int) *int { return &n }
+	books := []bookshelf.APIBook{
+		{
+			ID:         "book-1",
+			Title:      "The Way of Kings",
+			Author:     "Brandon Sanderson",
+			Series:     "The Stormlight Archive",
+			Status:     bookshelf.StatusRead,
+			Rating:     rating(9),
+			Review:     "Long, and \"worth it\".\nKaladin's arc is the best part.",
+			Tags:       []string{"fantasy", "epic"},
+			StartedAt:  "2024-01-03",
+			FinishedAt: "2024-02-11",
+			Thumbnail:  "https://books.example/way-of-kings.jpg",
+			Type:       "book",
+			Comments:   "Signed copy",
+			CreatedAt:  "2024-01-01T09:30:00Z",
+			VolumeID:   "QVn-CgAAQBAJ",
+			ISBN:       "9780765326355",
+		},
+		{
+			ID:        "book-2",
+			Title:     "Piranesi",
+			Author:    "Susanna Clarke",
+			Status:    bookshelf.StatusReading,
+			StartedAt: "2025-02-20",
+			Type:      "audiobook",
+			CreatedAt: "2025-02-19T18:00:00Z",
+		},
+		{
+			ID:        "book-3",
+			Title:     "Dune",
+			Author:    "Frank Herbert",
+			Status:    bookshelf.StatusWantToRead,
+			Tags:      []string{"classics"},
+			CreatedAt: "2023-07-04T08:00:00Z",
+		},
+		{
+			ID:         "book-4",
+			Title:      "Beowulf",
+			Author:     "Unknown",
+			Status:     bookshelf.StatusRead,
+			Rating:     rating(1),
+			FinishedAt: "2020-08-15",
+			CreatedAt:  "2020-08-01T00:00:00Z",
+		},
+	}
+	for _, api := range books {
+		if err := repo.Put(ctx, "user-1", bookshelf.NewBook("user-1", api.ID, api)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Versions past the first are kept by the backup and by restores
+	for _, id := range []string{"book-1", "book-1", "book-4"} {
+		book, err := repo.Get(ctx, "user-1", id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		book.Comments += "!"
+		if _, err := repo.Update(ctx, "user-1", book); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return repo
+}
+
+// emptyShelf returns a user's shelf with no books.
+func emptyShelf(t *testing.T) *bookshelf.MemoryRepository {
+	return bookshelf.NewMemoryRepository()
+}
+
+// shelfWithOthers returns newShelf with two books that are not in its
+// backup.
+func shelfWithOthers(t *testing.T) *bookshelf.MemoryRepository {
+	t.Helper()
+	repo := newShelf(t)
+	for _, id := range []string{"book-5", "book-6"} {
+		book := bookshelf.NewBook("user-1", id, bookshelf.APIBook{Title: "Added after " + id, Author: "Someone", Status: bookshelf.StatusWantToRead})
+		if err := repo.Put(context.Background(), "user-1", book); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return repo
+}
+
+// shelfWithTrashed returns newShelf with two of its books moved to the
+// trash.
+func shelfWithTrashed(t *testing.T) *bookshelf.MemoryRepository {
+	t.Helper()
+	ctx := context.Background()
+	repo := newShelf(t)
+	for _, id := range []string{"book-2", "book-4"} {
+		book, err := repo.Get(ctx, "user-1", id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := repo.Trash(ctx, "user-1", book); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return repo
+}
+
+// exportJSON returns the user's JSON export of repo, taken at exportedAt.
+func exportJSON(t *testing.T, repo bookshelf.BookRepository) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if _, err := exporter.New(repo, nil).Export(context.Background(), &buf, "user-1", "json", nil, exporter.CSVOptions{}, exportedAt); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,161 @@
+package importer
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/bookshelf"
+)
+
+// storyGraphStatuses maps StoryGraph read statuses to ours. A book the
+// reader did not finish is READ, because they have stopped reading it, and
+// a paused book is READING; both are also tagged with the StoryGraph status.
+var storyGraphStatuses = map[string]string{
+	"to-read":           bookshelf.StatusWantToRead,
+	"currently-reading": bookshelf.StatusReading,
+	"read":              bookshelf.StatusRead,
+	"did-not-finish":    bookshelf.StatusRead,
+	"paused":            bookshelf.StatusReading,
+}
+
+// storyGraphRequired are the columns a StoryGraph export must have.
+var storyGraphRequired = []string{"Title", "Authors", "Read Status"}
+
+// storyGraphDateLayouts are StoryGraph's YYYY/MM/DD dates, also accepting
+// YYYY-MM-DD.
+var storyGraphDateLayouts = []string{"2006/01/02", time.DateOnly}
+
+// StoryGraph imports a StoryGraph library export (Manage Account > Export
+// StoryGraph Library). Besides titles, authors, ISBNs, the read status,
+// ratings in quarter stars, reviews and tags, the format becomes the type,
+// the latest dates read become started_at and finished_at, moods and pace
+// become "mood:" and "pace:" tags, and a read count above one is noted in
+// the comments.
+type StoryGraph struct{}
+
+// Format returns "storygraph".
+func (StoryGraph) Format() string { return "storygraph" }
+
+// Parse reads a StoryGraph CSV export.
+func (StoryGraph) Parse(r io.Reader) ([]Row, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	return readTable(reader, storyGraphRequired, storyGraphBook)
+}
+
+// storyGraphBook converts one row of a StoryGraph export, read through
+// field.
+func storyGraphBook(field func(name string) string) (bookshelf.APIBook, error) {
+	book := bookshelf.APIBook{
+		Title:  field("Title"),
+		Author: field("Authors"),
+		ISBN:   cleanISBN(field("ISBN/UID")),
+		Type:   mediaType(field("Format")),
+		Review: plainText(field("Review")),
+	}
+	if book.Title == "" {
+		return book, errors.New("Title is required")
+	}
+	if book.Author == "" {
+		return book, errors.New("Authors is required")
+	}
+
+	readStatus := field("Read Status")
+	status, ok := storyGraphStatuses[readStatus]
+	if !ok {
+		return book, fmt.Errorf("Invalid Read Status %q", readStatus)
+	}
+	book.Status = status
+	if readStatus == "did-not-finish" || readStatus == "paused" {
+		book.Tags = appendTags(book.Tags, readStatus)
+	}
+
+	rating, err := parseStars(field("Star Rating"))
+	if err != nil {
+		return book, fmt.Errorf("Invalid Star Rating %q. Must be between 0 and 5", field("Star Rating"))
+	}
+	book.Rating = rating
+
+	for _, mood := range splitList(field("Moods"), ",") {
+		book.Tags = appendTags(book.Tags, "mood:"+mood)
+	}
+	if pace := field("Pace"); pace != "" {
+		book.Tags = appendTags(book.Tags, "pace:"+pace)
+	}
+	book.Tags = appendTags(book.Tags, splitList(field("Tags"), ",")...)
+
+	started, finished, err := storyGraphDatesRead(field("Dates Read"))
+	if err != nil {
+		return book, fmt.Errorf("Invalid Dates Read %q", field("Dates Read"))
+	}
+	if finished.IsZero() {
+		finished, err = parseDate(field("Last Date Read"), storyGraphDateLayouts...)
+		if err != nil {
+			return book, fmt.Errorf("Invalid Last Date Read %q", field("Last Date Read"))
+		}
+	}
+	if book.Status != bookshelf.StatusWantToRead {
+		book.StartedAt = formatDate(started)
+	}
+	if book.Status == bookshelf.StatusRead {
+		book.FinishedAt = formatDate(finished)
+	}
+
+	added, err := parseDate(field("Date Added"), storyGraphDateLayouts...)
+	if err != nil {
+		return book, fmt.Errorf("Invalid Date Added %q", field("Date Added"))
+	}
+	book.CreatedAt = formatTimestamp(added)
+
+	if count := field("Read Count"); count != "" {
+		n, err := strconv.Atoi(count)
+		if err != nil || n < 0 {
+			return book, fmt.Errorf("Invalid Read Count %q", count)
+		}
+		if n > 1 {
+			book.Comments = fmt.Sprintf("Read %d times.", n)
+		}
+	}
+	return book, nil
+}
+
+// storyGraphDatesRead returns the start and end of the latest read in a
+// StoryGraph "Dates Read" list, as in "2023/01/05-2023/01/20,
+// 2024/02/01-2024/02/10". A read may have only one of its dates. The dates
+// are always YYYY/MM/DD, since "-" separates them.
+func storyGraphDatesRead(s string) (started, finished time.Time, err error) {
+	reads := splitList(s, ",")
+	if len(reads) == 0 {
+		return time.Time{}, time.Time{}, nil
+	}
+	from, to, isRange := strings.Cut(reads[len(reads)-1], "-")
+	if !isRange {
+		// A single date is when the book was finished
+		from, to = "", from
+	}
+	if started, err = parseDate(strings.TrimSpace(from), "2006/01/02"); err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	if finished, err = parseDate(strings.TrimSpace(to), "2006/01/02"); err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	return started, finished, nil
+}
+
+// mediaType maps the format or media an export records for a book to our
+// type: "audiobook" for audio formats and "book" for anything else.
+func mediaType(format string) string {
+	switch {
+	case format == "":
+		return ""
+	case strings.Contains(strings.ToLower(format), "audio"):
+		return "audiobook"
+	default:
+		return "book"
+	}
+}
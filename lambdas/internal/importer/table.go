@@ -0,0 +1,199 @@
+package importer
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/bookshelf"
+)
+
+// recordReader reads the rows of a delimited file, like csv.Reader.
+type recordReader interface {
+	Read() ([]string, error)
+}
+
+// readTable reads a delimited export whose first row names its columns,
+// converting each following row with convert, which looks up the row's
+// fields by column name. The required columns must be present.
+func readTable(reader recordReader, required []string, convert func(field func(name string) string) (bookshelf.APIBook, error)) ([]Row, error) {
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, errors.New("file is empty")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid file: %w", err)
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		if i == 0 {
+			name = strings.TrimPrefix(name, "\ufeff")
+		}
+		columns[strings.TrimSpace(name)] = i
+	}
+	for _, name := range required {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("missing column %q", name)
+		}
+	}
+
+	var rows []Row
+	for number := 2; ; number++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return rows, nil
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			// The reader resynchronizes at the next line.
+			rows = append(rows, Row{Number: number, Err: errors.New("malformed row")})
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid file: %w", err)
+		}
+
+		field := func(name string) string {
+			i, ok := columns[name]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+		book, err := convert(field)
+		rows = append(rows, Row{Number: number, Book: book, Err: err})
+	}
+}
+
+// tsvReader reads tab-separated rows, one per line, without quoting.
+type tsvReader struct {
+	scanner *bufio.Scanner
+}
+
+// maxLineSize bounds one line of a tab-separated file.
+const maxLineSize = 1 << 20
+
+func newTSVReader(r io.Reader) *tsvReader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	return &tsvReader{scanner: scanner}
+}
+
+// Read returns the fields of the next non-blank line.
+func (t *tsvReader) Read() ([]string, error) {
+	for t.scanner.Scan() {
+		line := strings.TrimSuffix(t.scanner.Text(), "\r")
+		if strings.TrimSpace(line) != "" {
+			return strings.Split(line, "\t"), nil
+		}
+	}
+	if err := t.scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+// parseStars converts a rating of up to 5 stars, in steps as fine as
+// quarter stars, to the 1-10 scale. An empty or zero rating is unrated.
+func parseStars(s string) (*int, error) {
+	if s == "" {
+		return nil, nil
+	}
+	stars, err := strconv.ParseFloat(s, 64)
+	if err != nil || stars < 0 || stars > 5 {
+		return nil, errors.New("not between 0 and 5")
+	}
+	if stars == 0 {
+		return nil, nil
+	}
+	rating := max(int(math.Round(stars*2)), bookshelf.MinRating)
+	return &rating, nil
+}
+
+// parseDate parses a date in one of layouts. An empty date is the zero
+// time.
+func parseDate(s string, layouts ...string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", s)
+}
+
+// formatDate formats t as YYYY-MM-DD, or "" for the zero time.
+func formatDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.DateOnly)
+}
+
+// formatTimestamp formats t as RFC 3339, or "" for the zero time.
+func formatTimestamp(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+// splitList splits a delimited list, dropping blank entries.
+func splitList(s, sep string) []string {
+	var items []string
+	for _, item := range strings.Split(s, sep) {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// appendTags adds tags that are not blank or already present.
+func appendTags(tags []string, more ...string) []string {
+	for _, tag := range more {
+		tag = strings.TrimSpace(tag)
+		if tag != "" && !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// isbnChars matches an ISBN-10 or ISBN-13 once punctuation is removed.
+var isbnChars = regexp.MustCompile(`^(?:\d{9}[\dX]|\d{13})$`)
+
+// cleanISBN returns s without the quoting and punctuation exports wrap
+// ISBNs in, or "" if it is not an ISBN.
+func cleanISBN(s string) string {
+	s = strings.ToUpper(strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`="[]- `, r) {
+			return -1
+		}
+		return r
+	}, s))
+	if !isbnChars.MatchString(s) {
+		return ""
+	}
+	return s
+}
+
+// htmlBreak matches the line breaks services write into reviews.
+var htmlBreak = regexp.MustCompile(`(?i)<br\s*/?>`)
+
+// plainText turns the HTML line breaks and entities that services write
+// into reviews and comments back into plain text.
+func plainText(s string) string {
+	return strings.TrimSpace(html.UnescapeString(htmlBreak.ReplaceAllString(s, "\n")))
+}
@@ -0,0 +1,146 @@
+package objectstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// FileStore is a Store that keeps objects as files under a directory, standing
+// in for S3 during local development. Download and upload URLs are plain
+// links under baseURL; it is up to the caller to serve the directory there
+// and to accept PUTs to it (see Put).
+type FileStore struct {
+	dir     string
+	baseURL string
+}
+
+// NewFileStore returns a Store writing objects below dir and linking to them
+// under baseURL.
+func NewFileStore(dir, baseURL string) *FileStore {
+	return &FileStore{dir: dir, baseURL: strings.TrimSuffix(baseURL, "/")}
+}
+
+// Put writes body to the file for key, creating parent directories as needed.
+// Metadata is not persisted.
+func (s *FileStore) Put(ctx context.Context, key string, body io.Reader, metadata map[string]string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", key, err)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", key, err)
+	}
+	if _, err := io.Copy(f, body); err != nil {
+		// Leave no partial file behind, as S3 would not
+		f.Close()
+		os.Remove(path)
+		return fmt.Errorf("failed to write %s: %w", key, err)
+	}
+	return f.Close()
+}
+
+// Get opens the file for key.
+func (s *FileStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", key, err)
+	}
+	return f, nil
+}
+
+// Stat describes the file for key.
+func (s *FileStore) Stat(ctx context.Context, key string) (Object, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return Object{}, err
+	}
+	info, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) || (err == nil && info.IsDir()) {
+		return Object{}, ErrNotFound
+	}
+	if err != nil {
+		return Object{}, fmt.Errorf("failed to stat %s: %w", key, err)
+	}
+	return Object{Key: key, Size: info.Size(), LastModified: info.ModTime()}, nil
+}
+
+// List walks the store directory for the files whose keys start with
+// prefix.
+func (s *FileStore) List(ctx context.Context, prefix string) ([]Object, error) {
+	var objects []Object
+	err := filepath.WalkDir(s.dir, func(path string, entry fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(s.dir, path)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		objects = append(objects, Object{Key: key, Size: info.Size(), LastModified: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", prefix, err)
+	}
+	return objects, nil
+}
+
+// PresignGet returns the link to key under the base URL. The link does not
+// expire.
+func (s *FileStore) PresignGet(ctx context.Context, key string, expires time.Duration) (string, error) {
+	return s.link(key)
+}
+
+// PresignPut returns the same link as PresignGet, which the caller's server
+// must accept PUTs to. The link does not expire.
+func (s *FileStore) PresignPut(ctx context.Context, key string, expires time.Duration) (string, error) {
+	return s.link(key)
+}
+
+// link returns the URL of key under the base URL.
+func (s *FileStore) link(key string) (string, error) {
+	if _, err := s.path(key); err != nil {
+		return "", err
+	}
+	return s.baseURL + "/" + (&url.URL{Path: key}).EscapedPath(), nil
+}
+
+// path maps key to a file below the store directory, rejecting keys that
+// would escape it.
+func (s *FileStore) path(key string) (string, error) {
+	if !filepath.IsLocal(filepath.FromSlash(key)) {
+		return "", fmt.Errorf("invalid object key %q", key)
+	}
+	return filepath.Join(s.dir, filepath.FromSlash(key)), nil
+}
@@ -0,0 +1,74 @@
+// Package objectstore abstracts the blob storage used for export and import
+// files so the Lambdas can run against S3 in AWS and against the local
+// filesystem in development.
+package objectstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// ErrNotFound is returned by Store.Get and Store.Stat for a key with no
+// object.
+var ErrNotFound = errors.New("object not found")
+
+// Object describes a stored object.
+type Object struct {
+	Key          string
+	Size         int64
+	LastModified time.Time
+}
+
+// Store reads and writes objects and hands out time-limited URLs for
+// downloading and uploading them.
+type Store interface {
+	// Put stores body under key, replacing any existing object. Body is
+	// read as it is stored, so it may be a stream of unknown length.
+	Put(ctx context.Context, key string, body io.Reader, metadata map[string]string) error
+	// Get opens the object stored under key, or returns ErrNotFound. The
+	// caller must close it.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Stat describes the object stored under key, or returns ErrNotFound.
+	Stat(ctx context.Context, key string) (Object, error)
+	// List describes every object whose key starts with prefix, in key
+	// order.
+	List(ctx context.Context, prefix string) ([]Object, error)
+	// PresignGet returns a URL that downloads key until expires has elapsed.
+	PresignGet(ctx context.Context, key string, expires time.Duration) (string, error)
+	// PresignPut returns a URL that accepts an HTTP PUT of the object for
+	// key until expires has elapsed.
+	PresignPut(ctx context.Context, key string, expires time.Duration) (string, error)
+}
+
+// PutStream stores what write writes under key, handing it to store.Put
+// through a pipe as it is written, so the object is never held in memory.
+// If write fails the object is not stored and write's error is returned;
+// if storing fails, write is stopped and the store's error is returned.
+func PutStream(ctx context.Context, store Store, key string, metadata map[string]string, write func(io.Writer) error) error {
+	reader, writer := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		err := write(writer)
+		writer.CloseWithError(err)
+		done <- err
+	}()
+
+	err := store.Put(ctx, key, reader, metadata)
+	// Unblock write if Put stopped reading early
+	reader.CloseWithError(errStopped)
+	writeErr := <-done
+	if writeErr != nil && !errors.Is(writeErr, errStopped) {
+		return writeErr
+	}
+	if err == nil && writeErr != nil {
+		// Put returned without reading everything
+		return fmt.Errorf("failed to store %s: %w", key, writeErr)
+	}
+	return err
+}
+
+// errStopped is what write sees once PutStream's store stops reading.
+var errStopped = errors.New("object store stopped reading")
@@ -0,0 +1,71 @@
+package objectstore
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestPutStream(t *testing.T) {
+	writeFailed := errors.New("write failed")
+	tests := []struct {
+		name string
+		// size bytes are written before write returns err
+		size        int
+		err         error
+		failPart    int32
+		wantErr     error
+		wantStored  bool
+		wantAborted bool
+	}{
+		{
+			name:       "stored",
+			size:       PartSize + 1,
+			wantStored: true,
+		},
+		{
+			name:    "write fails within the first part",
+			size:    10,
+			err:     writeFailed,
+			wantErr: writeFailed,
+		},
+		{
+			name:        "write fails after the first part",
+			size:        PartSize + 10,
+			err:         writeFailed,
+			wantErr:     writeFailed,
+			wantAborted: true,
+		},
+		{
+			name:        "store fails",
+			size:        3 * PartSize,
+			failPart:    2,
+			wantErr:     errPartRejected,
+			wantAborted: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &fakeS3{failPart: tt.failPart}
+			store := NewS3Store(client, nil, "bucket")
+			err := PutStream(context.Background(), store, "exports/books.json", nil, func(w io.Writer) error {
+				if _, err := io.Copy(w, strings.NewReader(strings.Repeat("x", tt.size))); err != nil {
+					return err
+				}
+				return tt.err
+			})
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("PutStream error = %v, want %v", err, tt.wantErr)
+			}
+			if stored := len(client.puts) > 0 || client.completed != nil; stored != tt.wantStored {
+				t.Errorf("stored = %v, want %v", stored, tt.wantStored)
+			}
+			if got := client.aborted == 1; got != tt.wantAborted {
+				t.Errorf("aborted %d times, want aborted %v", client.aborted, tt.wantAborted)
+			}
+		})
+	}
+}
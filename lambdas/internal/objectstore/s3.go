@@ -0,0 +1,251 @@
+package objectstore
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"path"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// S3API is the subset of the S3 client used by S3Store.
+type S3API interface {
+	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+	CreateMultipartUpload(ctx context.Context, params *s3.CreateMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error)
+	UploadPart(ctx context.Context, params *s3.UploadPartInput, optFns ...func(*s3.Options)) (*s3.UploadPartOutput, error)
+	CompleteMultipartUpload(ctx context.Context, params *s3.CompleteMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error)
+	AbortMultipartUpload(ctx context.Context, params *s3.AbortMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error)
+	GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+	HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error)
+	ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error)
+}
+
+// S3PresignAPI is the subset of the S3 presign client used by S3Store.
+type S3PresignAPI interface {
+	PresignGetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.PresignOptions)) (*v4.PresignedHTTPRequest, error)
+	PresignPutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.PresignOptions)) (*v4.PresignedHTTPRequest, error)
+}
+
+// S3Store is a Store backed by an S3 bucket.
+type S3Store struct {
+	client    S3API
+	presigner S3PresignAPI
+	bucket    string
+}
+
+// NewS3Store returns a Store that keeps objects in bucket, signing URLs
+// with presigner, usually s3.NewPresignClient of the same client.
+func NewS3Store(client S3API, presigner S3PresignAPI, bucket string) *S3Store {
+	return &S3Store{
+		client:    client,
+		presigner: presigner,
+		bucket:    bucket,
+	}
+}
+
+// PartSize is the size of the parts S3Store.Put uploads a large body in.
+// S3 allows at most 10,000 parts, so objects up to about 80 GB can be
+// stored.
+const PartSize = 8 << 20
+
+// Put uploads body to the bucket under key, with the content type of the
+// key's extension so that downloads open as the right kind of file. A body
+// that fits in one part is stored with a single PutObject; a larger one is
+// streamed as a multipart upload, holding one part in memory at a time.
+func (s *S3Store) Put(ctx context.Context, key string, body io.Reader, metadata map[string]string) error {
+	buffered := bufio.NewReader(body)
+	part := make([]byte, PartSize)
+	n, err := io.ReadFull(buffered, part)
+	if err == nil {
+		// A body of exactly one part still fits in one PutObject
+		_, err = buffered.Peek(1)
+	}
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return s.putObject(ctx, key, bytes.NewReader(part[:n]), metadata)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read upload: %w", err)
+	}
+
+	created, err := s.client.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(key),
+		Metadata:    metadata,
+		ContentType: contentType(key),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to start multipart upload: %w", err)
+	}
+	if err := s.uploadParts(ctx, key, created.UploadId, part, buffered); err != nil {
+		// Discard the parts uploaded so far, which S3 would otherwise keep
+		_, abortErr := s.client.AbortMultipartUpload(context.WithoutCancel(ctx), &s3.AbortMultipartUploadInput{
+			Bucket:   aws.String(s.bucket),
+			Key:      aws.String(key),
+			UploadId: created.UploadId,
+		})
+		return errors.Join(err, abortErr)
+	}
+	return nil
+}
+
+// uploadParts uploads first and then the rest of body as the parts of a
+// multipart upload, and completes it.
+func (s *S3Store) uploadParts(ctx context.Context, key string, uploadID *string, first []byte, body io.Reader) error {
+	var completed []types.CompletedPart
+	part, n := first, len(first)
+	for number := int32(1); ; number++ {
+		result, err := s.client.UploadPart(ctx, &s3.UploadPartInput{
+			Bucket:     aws.String(s.bucket),
+			Key:        aws.String(key),
+			UploadId:   uploadID,
+			PartNumber: aws.Int32(number),
+			Body:       bytes.NewReader(part[:n]),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to upload part %d: %w", number, err)
+		}
+		completed = append(completed, types.CompletedPart{ETag: result.ETag, PartNumber: aws.Int32(number)})
+
+		var readErr error
+		n, readErr = io.ReadFull(body, part)
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil && readErr != io.ErrUnexpectedEOF {
+			return fmt.Errorf("failed to read upload: %w", readErr)
+		}
+	}
+
+	_, err := s.client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(s.bucket),
+		Key:             aws.String(key),
+		UploadId:        uploadID,
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: completed},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to complete multipart upload: %w", err)
+	}
+	return nil
+}
+
+// putObject uploads body in a single request.
+func (s *S3Store) putObject(ctx context.Context, key string, body io.Reader, metadata map[string]string) error {
+	_, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(key),
+		Body:        body,
+		Metadata:    metadata,
+		ContentType: contentType(key),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upload to S3: %w", err)
+	}
+	return nil
+}
+
+// contentType returns the content type of key's extension, or nil if it
+// has none.
+func contentType(key string) *string {
+	if t := mime.TypeByExtension(path.Ext(key)); t != "" {
+		return aws.String(t)
+	}
+	return nil
+}
+
+// Get downloads the object stored under key.
+func (s *S3Store) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	result, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	var noSuchKey *types.NoSuchKey
+	if errors.As(err, &noSuchKey) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to download from S3: %w", err)
+	}
+	return result.Body, nil
+}
+
+// Stat reads the size and modification time of the object stored under key
+// without downloading it.
+func (s *S3Store) Stat(ctx context.Context, key string) (Object, error) {
+	result, err := s.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	var notFound *types.NotFound
+	if errors.As(err, &notFound) {
+		return Object{}, ErrNotFound
+	}
+	if err != nil {
+		return Object{}, fmt.Errorf("failed to stat S3 object: %w", err)
+	}
+	return Object{
+		Key:          key,
+		Size:         aws.ToInt64(result.ContentLength),
+		LastModified: aws.ToTime(result.LastModified),
+	}, nil
+}
+
+// List pages through the objects in the bucket whose keys start with
+// prefix.
+func (s *S3Store) List(ctx context.Context, prefix string) ([]Object, error) {
+	var objects []Object
+	paginator := s3.NewListObjectsV2Paginator(s.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(prefix),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list S3 objects: %w", err)
+		}
+		for _, object := range page.Contents {
+			objects = append(objects, Object{
+				Key:          aws.ToString(object.Key),
+				Size:         aws.ToInt64(object.Size),
+				LastModified: aws.ToTime(object.LastModified),
+			})
+		}
+	}
+	return objects, nil
+}
+
+// PresignGet returns a pre-signed GET URL for key.
+func (s *S3Store) PresignGet(ctx context.Context, key string, expires time.Duration) (string, error) {
+	request, err := s.presigner.PresignGetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	}, func(opts *s3.PresignOptions) {
+		opts.Expires = expires
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to generate pre-signed URL: %w", err)
+	}
+	return request.URL, nil
+}
+
+// PresignPut returns a pre-signed PUT URL for key.
+func (s *S3Store) PresignPut(ctx context.Context, key string, expires time.Duration) (string, error) {
+	request, err := s.presigner.PresignPutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	}, func(opts *s3.PresignOptions) {
+		opts.Expires = expires
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to generate pre-signed URL: %w", err)
+	}
+	return request.URL, nil
+}
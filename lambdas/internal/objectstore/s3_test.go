@@ -0,0 +1,169 @@
+package objectstore
+
+import (
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// errPartRejected is the error fakeS3.UploadPart fails with.
+var errPartRejected = errors.New("part rejected")
+
+// fakeS3 records the sizes of the objects and parts it is handed.
+// UploadPart fails for part number failPart, if set.
+type fakeS3 struct {
+	failPart     int32
+	puts         []int
+	contentTypes []string
+	parts        []int
+	partNumbers  []int32
+	completed    []types.CompletedPart
+	aborted      int
+}
+
+func (f *fakeS3) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	body, err := io.ReadAll(params.Body)
+	if err != nil {
+		return nil, err
+	}
+	f.puts = append(f.puts, len(body))
+	f.contentTypes = append(f.contentTypes, aws.ToString(params.ContentType))
+	return &s3.PutObjectOutput{}, nil
+}
+
+func (f *fakeS3) CreateMultipartUpload(ctx context.Context, params *s3.CreateMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error) {
+	f.contentTypes = append(f.contentTypes, aws.ToString(params.ContentType))
+	return &s3.CreateMultipartUploadOutput{UploadId: aws.String("upload-1")}, nil
+}
+
+func (f *fakeS3) UploadPart(ctx context.Context, params *s3.UploadPartInput, optFns ...func(*s3.Options)) (*s3.UploadPartOutput, error) {
+	number := aws.ToInt32(params.PartNumber)
+	if number == f.failPart {
+		return nil, errPartRejected
+	}
+	body, err := io.ReadAll(params.Body)
+	if err != nil {
+		return nil, err
+	}
+	f.parts = append(f.parts, len(body))
+	f.partNumbers = append(f.partNumbers, number)
+	return &s3.UploadPartOutput{ETag: aws.String(strings.Repeat("e", int(number)))}, nil
+}
+
+func (f *fakeS3) CompleteMultipartUpload(ctx context.Context, params *s3.CompleteMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error) {
+	f.completed = params.MultipartUpload.Parts
+	return &s3.CompleteMultipartUploadOutput{}, nil
+}
+
+func (f *fakeS3) AbortMultipartUpload(ctx context.Context, params *s3.AbortMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error) {
+	f.aborted++
+	return &s3.AbortMultipartUploadOutput{}, nil
+}
+
+func (f *fakeS3) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	return nil, errors.New("GetObject not implemented")
+}
+
+func (f *fakeS3) HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	return nil, errors.New("HeadObject not implemented")
+}
+
+func (f *fakeS3) ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	return nil, errors.New("ListObjectsV2 not implemented")
+}
+
+func TestS3StorePut(t *testing.T) {
+	tests := []struct {
+		name         string
+		size         int
+		failPart     int32
+		wantPuts     []int
+		wantParts    []int
+		wantComplete bool
+		wantAborted  bool
+	}{
+		{
+			name:     "empty",
+			size:     0,
+			wantPuts: []int{0},
+		},
+		{
+			name:     "less than a part",
+			size:     10,
+			wantPuts: []int{10},
+		},
+		{
+			name:     "exactly one part",
+			size:     PartSize,
+			wantPuts: []int{PartSize},
+		},
+		{
+			name:         "just over one part",
+			size:         PartSize + 1,
+			wantParts:    []int{PartSize, 1},
+			wantComplete: true,
+		},
+		{
+			name:         "exactly two parts",
+			size:         2 * PartSize,
+			wantParts:    []int{PartSize, PartSize},
+			wantComplete: true,
+		},
+		{
+			name:        "failed part",
+			size:        2*PartSize + 1,
+			failPart:    2,
+			wantParts:   []int{PartSize},
+			wantAborted: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &fakeS3{failPart: tt.failPart}
+			store := NewS3Store(client, nil, "bucket")
+			err := store.Put(context.Background(), "exports/books.json", strings.NewReader(strings.Repeat("x", tt.size)), nil)
+
+			var wantErr error
+			if tt.wantAborted {
+				wantErr = errPartRejected
+			}
+			if !errors.Is(err, wantErr) {
+				t.Fatalf("Put error = %v, want %v", err, wantErr)
+			}
+			if !reflect.DeepEqual(client.puts, tt.wantPuts) {
+				t.Errorf("PutObject sizes = %v, want %v", client.puts, tt.wantPuts)
+			}
+			if !reflect.DeepEqual(client.parts, tt.wantParts) {
+				t.Errorf("UploadPart sizes = %v, want %v", client.parts, tt.wantParts)
+			}
+			for i, number := range client.partNumbers {
+				if number != int32(i+1) {
+					t.Errorf("part %d numbered %d", i+1, number)
+				}
+			}
+			if got := client.completed != nil; got != tt.wantComplete {
+				t.Errorf("completed = %v, want %v", got, tt.wantComplete)
+			}
+			for i, part := range client.completed {
+				if aws.ToInt32(part.PartNumber) != int32(i+1) || aws.ToString(part.ETag) != strings.Repeat("e", i+1) {
+					t.Errorf("completed part %d = %d %q", i+1, aws.ToInt32(part.PartNumber), aws.ToString(part.ETag))
+				}
+			}
+			if got := client.aborted == 1; got != tt.wantAborted {
+				t.Errorf("aborted %d times, want aborted %v", client.aborted, tt.wantAborted)
+			}
+			for _, contentType := range client.contentTypes {
+				if contentType != "application/json" {
+					t.Errorf("ContentType = %q, want application/json", contentType)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,259 @@
+// Package handler implements GET /books.
+package handler
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/bookshelf"
+)
+
+const (
+	// DefaultLimit is the page size used when only cursor is given.
+	DefaultLimit = 50
+	// MaxLimit is the largest page size a client may request.
+	MaxLimit = 100
+)
+
+// sortedTokenPrefix marks page tokens that are positions in a sorted list
+// rather than repository tokens.
+const sortedTokenPrefix = "sorted:"
+
+// invalidLimitMessage is returned for a limit outside 1..MaxLimit.
+var invalidLimitMessage = fmt.Sprintf("Invalid limit. Must be an integer between 1 and %d", MaxLimit)
+
+// BookPage is the paged response envelope for GET /books.
+type BookPage struct {
+	Books []bookshelf.APIBook `json:"books"`
+	Count int                 `json:"count"`
+	Limit int                 `json:"limit"`
+	// NextCursor fetches the following page; it is null on the last page.
+	NextCursor *string `json:"next_cursor"`
+}
+
+// Handler serves GET /books from an injected book repository.
+type Handler struct {
+	Books   bookshelf.BookRepository
+	Cursors *bookshelf.CursorCodec
+}
+
+// New returns a Handler backed by books that seals page cursors with cursors.
+func New(books bookshelf.BookRepository, cursors *bookshelf.CursorCodec) *Handler {
+	return &Handler{Books: books, Cursors: cursors}
+}
+
+// Handle is the Lambda function handler.
+func (h *Handler) Handle(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Extract user ID from JWT claims
+	userID, err := bookshelf.UserID(request)
+	if err != nil {
+		log.Printf("Error extracting user ID: %v", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusUnauthorized,
+			Body:       "Unauthorized: Could not extract user ID",
+		}, nil
+	}
+
+	// Filters and sort order come from the query string
+	opts, err := bookshelf.ParseListOptions(request.QueryStringParameters)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Body:       err.Error(),
+		}, nil
+	}
+	sortOpts, err := bookshelf.ParseSortOptions(request.QueryStringParameters)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Body:       err.Error(),
+		}, nil
+	}
+
+	// Requests without limit or cursor keep the original bare-array response
+	// containing every book.
+	limitParam, hasLimit := request.QueryStringParameters["limit"]
+	cursor, hasCursor := request.QueryStringParameters["cursor"]
+	if !hasLimit && !hasCursor {
+		books, err := h.Books.List(ctx, userID, opts)
+		if err != nil {
+			log.Printf("Error listing books: %v", err)
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusInternalServerError,
+				Body:       "Internal Server Error: Could not process book data",
+			}, nil
+		}
+		bookshelf.SortBooks(books, sortOpts)
+		return jsonResponse(bookshelf.ToAPIBooks(books))
+	}
+
+	limit := DefaultLimit
+	if hasLimit {
+		limit, err = strconv.Atoi(limitParam)
+		if err != nil || limit < 1 || limit > MaxLimit {
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusBadRequest,
+				Body:       invalidLimitMessage,
+			}, nil
+		}
+	}
+
+	// Cursors continue the listing they came from; a page token read with
+	// other filters or another order would skip or repeat books, or not be
+	// a key of that listing at all
+	query, err := cursorQuery(opts, sortOpts)
+	if err != nil {
+		log.Printf("Error encoding cursor query: %v", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Body:       "Internal Server Error",
+		}, nil
+	}
+
+	pageOpts := bookshelf.PageOptions{ListOptions: opts, Limit: limit}
+	if cursor != "" {
+		pageOpts.StartToken, err = h.Cursors.Decode(userID, query, cursor)
+		if errors.Is(err, bookshelf.ErrCursorMismatch) {
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusBadRequest,
+				Body:       "Invalid cursor. cursor does not match query",
+			}, nil
+		}
+		if err != nil {
+			log.Printf("Rejected cursor for user %s: %v", userID, err)
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusBadRequest,
+				Body:       "Invalid cursor",
+			}, nil
+		}
+	}
+
+	var page bookshelf.Page
+	if sortOpts.Field != "" {
+		page, err = sortedPage(ctx, h.Books, userID, pageOpts, sortOpts)
+	} else {
+		page, err = h.Books.ListPage(ctx, userID, pageOpts)
+	}
+	if errors.Is(err, bookshelf.ErrInvalidToken) {
+		log.Printf("Rejected page token for user %s: %v", userID, err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Body:       "Invalid cursor",
+		}, nil
+	}
+	if err != nil {
+		log.Printf("Error listing books: %v", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Body:       "Internal Server Error: Could not process book data",
+		}, nil
+	}
+
+	response := BookPage{
+		Books: bookshelf.ToAPIBooks(page.Books),
+		Count: len(page.Books),
+		Limit: limit,
+	}
+	if page.NextToken != "" {
+		next, err := h.Cursors.Encode(userID, query, page.NextToken)
+		if err != nil {
+			log.Printf("Error encoding cursor: %v", err)
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusInternalServerError,
+				Body:       "Internal Server Error",
+			}, nil
+		}
+		response.NextCursor = &next
+	}
+	return jsonResponse(response)
+}
+
+// cursorQuery identifies the listing a cursor continues: the filters and the
+// sort order, but not the page size, which may change from page to page.
+func cursorQuery(opts bookshelf.ListOptions, sortOpts bookshelf.SortOptions) (string, error) {
+	query, err := json.Marshal(struct {
+		Filters bookshelf.ListOptions
+		Sort    bookshelf.SortOptions
+	}{opts, sortOpts})
+	return string(query), err
+}
+
+// sortedPage returns a page of the user's books in sort order. DynamoDB can
+// only return items in key order, so every matching book is loaded and
+// sorted for each page: the cost of a page grows with the library, not the
+// page size. The page token is the sort position of the page's last book,
+// so the next page starts after it however the library has changed since.
+func sortedPage(ctx context.Context, books bookshelf.BookRepository, userID string, opts bookshelf.PageOptions, sortOpts bookshelf.SortOptions) (bookshelf.Page, error) {
+	var after *bookshelf.SortPosition
+	if opts.StartToken != "" {
+		position, ok := strings.CutPrefix(opts.StartToken, sortedTokenPrefix)
+		if !ok {
+			return bookshelf.Page{}, bookshelf.ErrInvalidToken
+		}
+		after = new(bookshelf.SortPosition)
+		if err := json.Unmarshal([]byte(position), after); err != nil || after.ID == "" {
+			return bookshelf.Page{}, bookshelf.ErrInvalidToken
+		}
+	}
+
+	all, err := bookshelf.ListAll(ctx, books, userID, opts.ListOptions)
+	if err != nil {
+		return bookshelf.Page{}, err
+	}
+	bookshelf.SortBooks(all, sortOpts)
+
+	start := 0
+	if after != nil {
+		start = sort.Search(len(all), func(i int) bool {
+			return sortOpts.Compare(sortOpts.Position(all[i]), *after) > 0
+		})
+	}
+	end := min(start+opts.Limit, len(all))
+	page := bookshelf.Page{Books: all[start:end]}
+	if end < len(all) {
+		token, err := json.Marshal(sortOpts.Position(all[end-1]))
+		if err != nil {
+			return bookshelf.Page{}, err
+		}
+		page.NextToken = sortedTokenPrefix + string(token)
+	}
+	return page, nil
+}
+
+// jsonResponse returns v as a 200 JSON response.
+func jsonResponse(v interface{}) (events.APIGatewayProxyResponse, error) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		log.Printf("Error marshalling JSON: %v", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Body:       "Internal Server Error",
+		}, nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Headers: map[string]string{
+			"Content-Type": "application/json",
+			"ETag":         listETag(body),
+		},
+		Body: string(body),
+	}, nil
+}
+
+// listETag returns a weak entity tag for a list response. Every book's
+// version is in the body, so the tag changes whenever any listed book does.
+func listETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `W/"` + hex.EncodeToString(sum[:16]) + `"`
+}
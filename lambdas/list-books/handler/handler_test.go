@@ -0,0 +1,281 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/bookshelf"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/handlertest"
+)
+
+func TestHandle(t *testing.T) {
+	ctx := context.Background()
+	books := handlertest.Books(t,
+		bookshelf.APIBook{ID: "book-1", Title: "Dune", Author: "Frank Herbert", Status: bookshelf.StatusRead},
+		bookshelf.APIBook{ID: "book-2", Title: "Emma", Author: "Jane Austen", Status: bookshelf.StatusRead},
+		bookshelf.APIBook{ID: "book-3", Title: "Beowulf", Author: "Unknown", Status: bookshelf.StatusRead},
+		bookshelf.APIBook{ID: "book-4", Title: "Piranesi", Author: "Susanna Clarke", Status: bookshelf.StatusReading},
+	)
+	cursors, err := bookshelf.NewCursorCodec(make([]byte, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := New(books, cursors)
+	list := func(query map[string]string) events.APIGatewayProxyRequest {
+		return handlertest.Request(events.APIGatewayProxyRequest{QueryStringParameters: query})
+	}
+
+	// The cursor to the second page of read books by title
+	first, err := h.Handle(ctx, list(map[string]string{"status": "READ", "sort": "title", "limit": "2"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var page BookPage
+	if err := json.Unmarshal([]byte(first.Body), &page); err != nil || page.NextCursor == nil {
+		t.Fatalf("first page = %d %s, want a next_cursor", first.StatusCode, first.Body)
+	}
+	cursor := *page.NextCursor
+
+	tests := []struct {
+		name       string
+		request    events.APIGatewayProxyRequest
+		wantStatus int
+		wantBody   string   // contained in the body
+		wantTitles []string // in order
+		wantNext   bool
+	}{
+		{
+			name:       "no claims",
+			request:    events.APIGatewayProxyRequest{},
+			wantStatus: 401,
+			wantBody:   "Unauthorized",
+		},
+		{
+			name:       "invalid status",
+			request:    list(map[string]string{"status": "bogus"}),
+			wantStatus: 400,
+			wantBody:   bookshelf.InvalidStatusMessage,
+		},
+		{
+			name:       "invalid rating range",
+			request:    list(map[string]string{"min_rating": "8", "max_rating": "2"}),
+			wantStatus: 400,
+			wantBody:   "Invalid rating range",
+		},
+		{
+			name:       "invalid sort",
+			request:    list(map[string]string{"sort": "colour"}),
+			wantStatus: 400,
+			wantBody:   "Invalid sort",
+		},
+		{
+			name:       "limit too large",
+			request:    list(map[string]string{"limit": "101"}),
+			wantStatus: 400,
+			wantBody:   "Invalid limit",
+		},
+		{
+			name:       "limit not a number",
+			request:    list(map[string]string{"limit": "ten"}),
+			wantStatus: 400,
+			wantBody:   "Invalid limit",
+		},
+		{
+			name:       "malformed cursor",
+			request:    list(map[string]string{"cursor": "not-a-cursor"}),
+			wantStatus: 400,
+			wantBody:   "Invalid cursor",
+		},
+		{
+			name:       "cursor with other filters",
+			request:    list(map[string]string{"status": "READING", "sort": "title", "cursor": cursor}),
+			wantStatus: 400,
+			wantBody:   "cursor does not match query",
+		},
+		{
+			name:       "cursor with another order",
+			request:    list(map[string]string{"status": "READ", "sort": "title", "order": "desc", "cursor": cursor}),
+			wantStatus: 400,
+			wantBody:   "cursor does not match query",
+		},
+		{
+			name:       "every book",
+			request:    list(nil),
+			wantStatus: 200,
+			wantTitles: []string{"Dune", "Emma", "Beowulf", "Piranesi"},
+		},
+		{
+			name:       "every book sorted",
+			request:    list(map[string]string{"sort": "title", "order": "desc"}),
+			wantStatus: 200,
+			wantTitles: []string{"Piranesi", "Emma", "Dune", "Beowulf"},
+		},
+		{
+			name:       "first page",
+			request:    list(map[string]string{"status": "READ", "sort": "title", "limit": "2"}),
+			wantStatus: 200,
+			wantTitles: []string{"Beowulf", "Dune"},
+			wantNext:   true,
+		},
+		{
+			name:       "next page with another limit",
+			request:    list(map[string]string{"status": "READ", "sort": "title", "limit": "5", "cursor": cursor}),
+			wantStatus: 200,
+			wantTitles: []string{"Emma"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := h.Handle(ctx, tt.request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.wantStatus || !strings.Contains(resp.Body, tt.wantBody) {
+				t.Errorf("Handle = %d %s, want %d containing %q", resp.StatusCode, resp.Body, tt.wantStatus, tt.wantBody)
+			}
+			if tt.wantTitles == nil {
+				return
+			}
+
+			// Requests with limit or cursor are paged
+			var page BookPage
+			if _, paged := tt.request.QueryStringParameters["limit"]; paged {
+				err = json.Unmarshal([]byte(resp.Body), &page)
+			} else {
+				err = json.Unmarshal([]byte(resp.Body), &page.Books)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var titles []string
+			for _, book := range page.Books {
+				titles = append(titles, book.Title)
+			}
+			if strings.Join(titles, ",") != strings.Join(tt.wantTitles, ",") {
+				t.Errorf("titles = %v, want %v", titles, tt.wantTitles)
+			}
+			if next := page.NextCursor != nil; next != tt.wantNext {
+				t.Errorf("next_cursor = %v, want one: %v", page.NextCursor, tt.wantNext)
+			}
+		})
+	}
+}
+
+func TestHandleSortedPages(t *testing.T) {
+	ctx := context.Background()
+	rating := func(n int) *int { return &n }
+	books := handlertest.Books(t,
+		bookshelf.APIBook{ID: "book-1", Title: "Dune", Author: "Frank Herbert", Status: bookshelf.StatusRead, Rating: rating(9)},
+		bookshelf.APIBook{ID: "book-2", Title: "Emma", Author: "Jane Austen", Status: bookshelf.StatusRead, Rating: rating(7)},
+		bookshelf.APIBook{ID: "book-3", Title: "Beowulf", Author: "Unknown", Status: bookshelf.StatusRead},
+		bookshelf.APIBook{ID: "book-4", Title: "Hyperion", Author: "Dan Simmons", Status: bookshelf.StatusRead, Rating: rating(7)},
+		bookshelf.APIBook{ID: "book-5", Title: "Ulysses", Author: "James Joyce", Status: bookshelf.StatusRead},
+	)
+	cursors, err := bookshelf.NewCursorCodec(make([]byte, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := New(books, cursors)
+
+	// next returns the titles on the page after cursor and the cursor to the
+	// page after that
+	next := func(query map[string]string, cursor string) ([]string, string) {
+		t.Helper()
+		params := map[string]string{"limit": "2"}
+		for k, v := range query {
+			params[k] = v
+		}
+		if cursor != "" {
+			params["cursor"] = cursor
+		}
+		resp, err := h.Handle(ctx, handlertest.Request(events.APIGatewayProxyRequest{QueryStringParameters: params}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var page BookPage
+		if err := json.Unmarshal([]byte(resp.Body), &page); err != nil {
+			t.Fatalf("Handle = %d %s, want a page", resp.StatusCode, resp.Body)
+		}
+		var titles []string
+		for _, book := range page.Books {
+			titles = append(titles, book.Title)
+		}
+		if page.NextCursor == nil {
+			return titles, ""
+		}
+		return titles, *page.NextCursor
+	}
+	// walk returns every page of the listing
+	walk := func(query map[string]string) [][]string {
+		t.Helper()
+		var pages [][]string
+		titles, cursor := next(query, "")
+		pages = append(pages, titles)
+		for cursor != "" {
+			titles, cursor = next(query, cursor)
+			pages = append(pages, titles)
+		}
+		return pages
+	}
+
+	t.Run("orders", func(t *testing.T) {
+		tests := []struct {
+			query map[string]string
+			want  [][]string
+		}{
+			{
+				query: map[string]string{"sort": "title", "order": "desc"},
+				want:  [][]string{{"Ulysses", "Hyperion"}, {"Emma", "Dune"}, {"Beowulf"}},
+			},
+			{
+				// Ties keep ID order and unrated books come last, both
+				// across pages
+				query: map[string]string{"sort": "rating", "order": "desc"},
+				want:  [][]string{{"Dune", "Emma"}, {"Hyperion", "Beowulf"}, {"Ulysses"}},
+			},
+		}
+		for _, tt := range tests {
+			if got := walk(tt.query); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("pages of %v = %v, want %v", tt.query, got, tt.want)
+			}
+		}
+	})
+
+	t.Run("library changing between pages", func(t *testing.T) {
+		byTitle := map[string]string{"sort": "title"}
+		titles, cursor := next(byTitle, "")
+		if want := []string{"Beowulf", "Dune"}; !reflect.DeepEqual(titles, want) {
+			t.Fatalf("first page = %v, want %v", titles, want)
+		}
+
+		// A book added before the cursor does not repeat the last page's
+		// last book
+		if err := books.Put(ctx, handlertest.UserID, bookshelf.NewBook(handlertest.UserID, "book-6",
+			bookshelf.APIBook{Title: "Anathem", Author: "Neal Stephenson", Status: bookshelf.StatusRead})); err != nil {
+			t.Fatal(err)
+		}
+		titles, cursor = next(byTitle, cursor)
+		if want := []string{"Emma", "Hyperion"}; !reflect.DeepEqual(titles, want) {
+			t.Fatalf("second page = %v, want %v", titles, want)
+		}
+
+		// Nor do deleted books, including the cursor's own, skip any
+		for _, id := range []string{"book-2", "book-4"} {
+			book, err := books.Get(ctx, handlertest.UserID, id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := books.Trash(ctx, handlertest.UserID, book); err != nil {
+				t.Fatal(err)
+			}
+		}
+		titles, cursor = next(byTitle, cursor)
+		if want := []string{"Ulysses"}; !reflect.DeepEqual(titles, want) || cursor != "" {
+			t.Fatalf("last page = %v (next cursor %q), want %v", titles, cursor, want)
+		}
+	})
+}
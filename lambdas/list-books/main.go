@@ -5,59 +5,19 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
-	"net/http"
 	"os"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
-	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
-	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/bookshelf"
+	"github.com/ericdahl/bookshelf-aws/lambdas/list-books/handler"
 )
 
-const tableName = "books"
-
 // ddbClient is the DynamoDB client.
 var ddbClient *dynamodb.Client
 
-// Book represents a book record from DynamoDB.
-type Book struct {
-	ID         string   `dynamodbav:"id"`
-	PK         string   `dynamodbav:"PK"`
-	SK         string   `dynamodbav:"SK"`
-	Title      string   `dynamodbav:"Title"`
-	Author     string   `dynamodbav:"Author"`
-	Series     string   `dynamodbav:"Series"`
-	Status     string   `dynamodbav:"status"`
-	Rating     *int     `dynamodbav:"rating,omitempty"`
-	Review     string   `dynamodbav:"review,omitempty"`
-	Tags       []string `dynamodbav:"tags,omitempty"`
-	StartedAt  string   `dynamodbav:"started_at,omitempty"`
-	FinishedAt string   `dynamodbav:"finished_at,omitempty"`
-	Thumbnail  string   `dynamodbav:"thumbnail"`
-	Type       string   `dynamodbav:"type,omitempty"`
-	Comments   string   `dynamodbav:"comments,omitempty"`
-}
-
-// APIBook is the structure for the API response.
-type APIBook struct {
-	ID         string   `json:"id"`
-	Title      string   `json:"title"`
-	Author     string   `json:"author"`
-	Series     string   `json:"series"`
-	Status     string   `json:"status"`
-	Rating     *int     `json:"rating,omitempty"`
-	Review     string   `json:"review,omitempty"`
-	Tags       []string `json:"tags,omitempty"`
-	StartedAt  string   `json:"started_at,omitempty"`
-	FinishedAt string   `json:"finished_at,omitempty"`
-	Thumbnail  string   `json:"thumbnail"`
-	Type       string   `json:"type,omitempty"`
-	Comments   string   `json:"comments,omitempty"`
-}
-
 func init() {
 	cfg, err := config.LoadDefaultConfig(context.TODO())
 	if err != nil {
@@ -66,166 +26,20 @@ func init() {
 	ddbClient = dynamodb.NewFromConfig(cfg)
 }
 
-// getUserID extracts the user ID from the JWT claims in the request context
-func getUserID(request events.APIGatewayProxyRequest) (string, error) {
-	// For HTTP API with JWT authorizer, the claims are nested under jwt.claims
-	jwt, ok := request.RequestContext.Authorizer["jwt"].(map[string]interface{})
-	if !ok {
-		log.Printf("Authorizer context: %+v", request.RequestContext.Authorizer)
-		return "", fmt.Errorf("no jwt found in authorizer context")
-	}
-	
-	claims, ok := jwt["claims"].(map[string]interface{})
-	if !ok {
-		log.Printf("JWT context: %+v", jwt)
-		return "", fmt.Errorf("no claims found in jwt context")
-	}
-	
-	// Try accessing the 'sub' claim first (standard JWT subject claim)
-	if sub, ok := claims["sub"].(string); ok {
-		return sub, nil
-	}
-	
-	// Try cognito:username as fallback
-	if cognitoUsername, ok := claims["cognito:username"].(string); ok {
-		return cognitoUsername, nil
-	}
-	
-	// Debug: log the actual claims structure if we can't find the user ID
-	log.Printf("Claims: %+v", claims)
-	
-	return "", fmt.Errorf("no user ID found in JWT claims")
-}
-
-
-// handler is the Lambda function handler.
-func handler(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
-	// Extract user ID from JWT claims
-	userID, err := getUserID(request)
-	if err != nil {
-		log.Printf("Error extracting user ID: %v", err)
-		return events.APIGatewayProxyResponse{
-			StatusCode: http.StatusUnauthorized,
-			Body:       "Unauthorized: Could not extract user ID",
-		}, nil
-	}
-
-	status, statusOK := request.QueryStringParameters["status"]
-	tableNameVar := tableName
-	userPK := "USER#" + userID
-
-	var books []Book
-
-	if statusOK {
-		// If status is provided, we need to query the user's books and filter by status
-		// Since we changed the data model, we query by PK and filter by status
-		queryInput := &dynamodb.QueryInput{
-			TableName:              &tableNameVar,
-			KeyConditionExpression: aws.String("PK = :pk"),
-			FilterExpression:       aws.String("#status = :status"),
-			ExpressionAttributeNames: map[string]string{
-				"#status": "status",
-			},
-			ExpressionAttributeValues: map[string]types.AttributeValue{
-				":pk":     &types.AttributeValueMemberS{Value: userPK},
-				":status": &types.AttributeValueMemberS{Value: status},
-			},
-		}
-
-		var queryOutput *dynamodb.QueryOutput
-		queryOutput, err = ddbClient.Query(context.TODO(), queryInput)
-		if err != nil {
-			log.Printf("Error querying DynamoDB: %v", err)
-		}
-		if err == nil {
-			log.Printf("DynamoDB items: %v", queryOutput.Items)
-			err = attributevalue.UnmarshalListOfMaps(queryOutput.Items, &books)
-			if err != nil {
-				log.Printf("Error unmarshalling items: %v", err)
-			}
-		}
-	} else {
-		// If no status parameter, query all books for this user
-		queryInput := &dynamodb.QueryInput{
-			TableName:              &tableNameVar,
-			KeyConditionExpression: aws.String("PK = :pk"),
-			ExpressionAttributeValues: map[string]types.AttributeValue{
-				":pk": &types.AttributeValueMemberS{Value: userPK},
-			},
-		}
-
-		var queryOutput *dynamodb.QueryOutput
-		queryOutput, err = ddbClient.Query(context.TODO(), queryInput)
-		if err == nil {
-			err = attributevalue.UnmarshalListOfMaps(queryOutput.Items, &books)
-		}
-	}
-
-	if err != nil {
-		log.Printf("Error processing DynamoDB request: %v", err)
-		return events.APIGatewayProxyResponse{
-			StatusCode: http.StatusInternalServerError,
-			Body:       "Internal Server Error: Could not process book data",
-		}, nil
-	}
-
-	if statusOK && len(books) == 0 {
-		body, _ := json.Marshal([]APIBook{})
-		return events.APIGatewayProxyResponse{
-			StatusCode: http.StatusOK,
-			Headers: map[string]string{
-				"Content-Type": "application/json",
-			},
-			Body: string(body),
-		}, nil
-	}
-
-	// Map to API response structure
-	apiBooks := make([]APIBook, len(books))
-	for i, book := range books {
-		apiBooks[i] = APIBook{
-			ID:         book.ID,
-			Title:      book.Title,
-			Author:     book.Author,
-			Series:     book.Series,
-			Status:     book.Status,
-			Rating:     book.Rating,
-			Review:     book.Review,
-			Tags:       book.Tags,
-			StartedAt:  book.StartedAt,
-			FinishedAt: book.FinishedAt,
-			Thumbnail:  book.Thumbnail,
-			Type:       book.Type,
-			Comments:   book.Comments,
-		}
-	}
-
-	body, err := json.Marshal(apiBooks)
+func main() {
+	cursors, err := bookshelf.CursorCodecFromEnv()
 	if err != nil {
-		log.Printf("Error marshalling JSON: %v", err)
-		return events.APIGatewayProxyResponse{
-			StatusCode: http.StatusInternalServerError,
-			Body:       "Internal Server Error",
-		}, nil
+		log.Fatalf("unable to create cursor codec, %v", err)
 	}
+	h := handler.New(bookshelf.NewDynamoRepository(ddbClient, bookshelf.TableName), cursors)
 
-	return events.APIGatewayProxyResponse{
-		StatusCode: http.StatusOK,
-		Headers: map[string]string{
-			"Content-Type": "application/json",
-		},
-		Body: string(body),
-	}, nil
-}
-
-func main() {
 	// If the LAMBDA_TASK_ROOT environment variable is not set, we're running locally.
 	if os.Getenv("LAMBDA_TASK_ROOT") == "" {
 		// Create a dummy request for local testing.
 		request := events.APIGatewayProxyRequest{}
 
 		// Call the handler directly.
-		response, err := handler(request)
+		response, err := h.Handle(context.Background(), request)
 		if err != nil {
 			log.Fatalf("FATAL: handler failed: %v", err)
 		}
@@ -240,6 +54,6 @@ func main() {
 
 	} else {
 		// Start the Lambda handler in the AWS environment.
-		lambda.Start(handler)
+		lambda.Start(h.Handle)
 	}
 }
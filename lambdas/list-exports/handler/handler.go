@@ -0,0 +1,80 @@
+// Package handler implements GET /exports.
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/bookshelf"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/exporter"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/objectstore"
+)
+
+// ListExportsResponse is the user's exports, newest first.
+type ListExportsResponse struct {
+	Exports []exporter.APIExport `json:"exports"`
+}
+
+// Handler serves GET /exports against an injected job repository and the
+// injected object store export files are written to.
+type Handler struct {
+	Jobs  exporter.JobRepository
+	Store objectstore.Store
+}
+
+// New returns a Handler listing the jobs in jobs and the files in store.
+func New(jobs exporter.JobRepository, store objectstore.Store) *Handler {
+	return &Handler{Jobs: jobs, Store: store}
+}
+
+// Handle is the Lambda function handler.
+func (h *Handler) Handle(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Extract user ID from JWT claims
+	userID, err := bookshelf.UserID(request)
+	if err != nil {
+		log.Printf("Error extracting user ID: %v", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusUnauthorized,
+			Body:       "Unauthorized: Could not extract user ID",
+		}, nil
+	}
+
+	if h.Store == nil {
+		log.Printf("Error listing exports: no export store configured")
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Body:       "Internal Server Error",
+		}, nil
+	}
+
+	exports, err := exporter.History(ctx, h.Jobs, h.Store, userID)
+	if err != nil {
+		log.Printf("Error listing exports: %v", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Body:       "Internal Server Error",
+		}, nil
+	}
+
+	body, err := json.Marshal(ListExportsResponse{Exports: exports})
+	if err != nil {
+		log.Printf("Error marshalling JSON: %v", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Body:       "Internal Server Error",
+		}, nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Headers: map[string]string{
+			"Content-Type": "application/json",
+			// Jobs change while their exports run
+			"Cache-Control": "no-store",
+		},
+		Body: string(body),
+	}, nil
+}
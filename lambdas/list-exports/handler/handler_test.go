@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/exporter"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/exporter/exportertest"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/handlertest"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/objectstore"
+)
+
+func TestHandle(t *testing.T) {
+	exports := exportertest.New(t)
+	otherUser := events.APIGatewayProxyRequest{}
+	otherUser.RequestContext.Authorizer = map[string]interface{}{
+		"jwt": map[string]interface{}{"claims": map[string]interface{}{"sub": "user-2"}},
+	}
+
+	tests := []struct {
+		name       string
+		jobs       exporter.JobRepository
+		store      objectstore.Store
+		request    events.APIGatewayProxyRequest
+		wantStatus int
+		wantBody   string   // contained in the body
+		wantIDs    []string // in order
+	}{
+		{
+			name:       "no claims",
+			jobs:       exports.Jobs,
+			store:      exports.Store,
+			request:    events.APIGatewayProxyRequest{},
+			wantStatus: 401,
+			wantBody:   "Unauthorized",
+		},
+		{
+			name:       "no exports",
+			jobs:       exporter.NewMemoryJobRepository(),
+			store:      objectstore.NewFileStore(t.TempDir(), "https://exports.example.com"),
+			request:    handlertest.Request(events.APIGatewayProxyRequest{}),
+			wantStatus: 200,
+			wantBody:   `{"exports":[]}`,
+			wantIDs:    []string{},
+		},
+		{
+			name:       "another user's exports",
+			jobs:       exports.Jobs,
+			store:      exports.Store,
+			request:    otherUser,
+			wantStatus: 200,
+			wantBody:   `{"exports":[]}`,
+			wantIDs:    []string{},
+		},
+		{
+			// Newest first, without the completed job whose file is gone
+			name:       "exports",
+			jobs:       exports.Jobs,
+			store:      exports.Store,
+			request:    handlertest.Request(events.APIGatewayProxyRequest{}),
+			wantStatus: 200,
+			wantIDs:    []string{exports.File, exports.Pending, exports.Failed, exports.Completed, exports.Stalled},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := New(tt.jobs, tt.store).Handle(context.Background(), tt.request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.wantStatus || !strings.Contains(resp.Body, tt.wantBody) {
+				t.Errorf("Handle = %d %s, want %d containing %q", resp.StatusCode, resp.Body, tt.wantStatus, tt.wantBody)
+			}
+			if tt.wantIDs == nil {
+				return
+			}
+
+			var response ListExportsResponse
+			if err := json.Unmarshal([]byte(resp.Body), &response); err != nil {
+				t.Fatal(err)
+			}
+			var ids []string
+			for _, export := range response.Exports {
+				ids = append(ids, export.ID)
+			}
+			if strings.Join(ids, ",") != strings.Join(tt.wantIDs, ",") {
+				t.Errorf("exports = %v, want %v", ids, tt.wantIDs)
+			}
+		})
+	}
+}
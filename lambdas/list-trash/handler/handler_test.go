@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/bookshelf"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/handlertest"
+)
+
+func TestHandle(t *testing.T) {
+	ctx := context.Background()
+	books := handlertest.Books(t,
+		bookshelf.APIBook{ID: "book-1", Title: "Dune", Author: "Frank Herbert", Status: bookshelf.StatusRead},
+		bookshelf.APIBook{ID: "book-2", Title: "Emma", Author: "Jane Austen", Status: bookshelf.StatusRead},
+	)
+	book, err := books.Get(ctx, handlertest.UserID, "book-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := books.Trash(ctx, handlertest.UserID, book); err != nil {
+		t.Fatal(err)
+	}
+
+	otherUser := events.APIGatewayProxyRequest{}
+	otherUser.RequestContext.Authorizer = map[string]interface{}{
+		"jwt": map[string]interface{}{"claims": map[string]interface{}{"sub": "user-2"}},
+	}
+
+	tests := []struct {
+		name       string
+		books      bookshelf.BookRepository
+		request    events.APIGatewayProxyRequest
+		wantStatus int
+		wantBody   string // contained in the body
+	}{
+		{
+			name:       "no claims",
+			books:      books,
+			request:    events.APIGatewayProxyRequest{},
+			wantStatus: 401,
+			wantBody:   "Unauthorized",
+		},
+		{
+			name:       "empty trash",
+			books:      handlertest.Books(t),
+			request:    handlertest.Request(events.APIGatewayProxyRequest{}),
+			wantStatus: 200,
+			wantBody:   "[]",
+		},
+		{
+			name:       "another user's trash",
+			books:      books,
+			request:    otherUser,
+			wantStatus: 200,
+			wantBody:   "[]",
+		},
+		{
+			name:       "trashed book",
+			books:      books,
+			request:    handlertest.Request(events.APIGatewayProxyRequest{}),
+			wantStatus: 200,
+			wantBody:   `"title":"Dune"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := New(tt.books).Handle(ctx, tt.request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.wantStatus || !strings.Contains(resp.Body, tt.wantBody) {
+				t.Errorf("Handle = %d %s, want %d containing %q", resp.StatusCode, resp.Body, tt.wantStatus, tt.wantBody)
+			}
+			if strings.Contains(resp.Body, "Emma") {
+				t.Errorf("Handle = %s, want only trashed books", resp.Body)
+			}
+			if resp.StatusCode != 200 {
+				return
+			}
+
+			// Each book says when it was deleted and will be purged
+			var trash []bookshelf.APITrashedBook
+			if err := json.Unmarshal([]byte(resp.Body), &trash); err != nil {
+				t.Fatal(err)
+			}
+			for _, book := range trash {
+				deleted, err := time.Parse(time.RFC3339, book.DeletedAt)
+				if err != nil {
+					t.Fatal(err)
+				}
+				expires, err := time.Parse(time.RFC3339, book.ExpiresAt)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !expires.Equal(deleted.Add(bookshelf.TrashTTL)) || book.Version != 1 {
+					t.Errorf("trashed %+v, want version 1 expiring TrashTTL after deletion", book)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,165 @@
+// Package handler implements POST /books/{id}/merge.
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/bookshelf"
+)
+
+// maxAttempts bounds the retries for a request without If-Match that keeps
+// racing other writers to either book.
+const maxAttempts = 3
+
+// MergeRequest represents the request payload for merging a duplicate into a
+// book.
+type MergeRequest struct {
+	// SourceID is the duplicate, which is deleted once merged.
+	SourceID string `json:"source_id"`
+}
+
+// Handler serves POST /books/{id}/merge against an injected book repository.
+type Handler struct {
+	Books bookshelf.BookRepository
+}
+
+// New returns a Handler backed by books.
+func New(books bookshelf.BookRepository) *Handler {
+	return &Handler{Books: books}
+}
+
+// Handle is the Lambda function handler.
+func (h *Handler) Handle(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Extract user ID from JWT claims
+	userID, err := bookshelf.UserID(request)
+	if err != nil {
+		log.Printf("Error extracting user ID: %v", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusUnauthorized,
+			Body:       "Unauthorized: Could not extract user ID",
+		}, nil
+	}
+
+	// Record the request ID with the change in the book's history
+	ctx = bookshelf.WithRequestID(ctx, request.RequestContext.RequestID)
+
+	// Get the target book ID from path parameters
+	targetID := request.PathParameters["id"]
+	if targetID == "" {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Body:       "Book ID is required",
+		}, nil
+	}
+
+	// Parse the request body
+	var mergeRequest MergeRequest
+	if err := json.Unmarshal([]byte(request.Body), &mergeRequest); err != nil {
+		log.Printf("Error parsing request body: %v", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Body:       "Invalid request body",
+		}, nil
+	}
+	if mergeRequest.SourceID == "" {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Body:       "source_id is required",
+		}, nil
+	}
+	if mergeRequest.SourceID == targetID {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Body:       "A book cannot be merged into itself",
+		}, nil
+	}
+
+	// Read both books, merge them and write the result only if neither has
+	// changed since. With If-Match the client's version of the target must
+	// match; without it, a concurrent change is retried.
+	ifMatch := bookshelf.ParseIfMatch(request)
+	var mergedBook bookshelf.Book
+	for attempt := 1; ; attempt++ {
+		target, err := h.Books.Get(ctx, userID, targetID)
+		if errors.Is(err, bookshelf.ErrNotFound) {
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusNotFound,
+				Body:       "Book not found",
+			}, nil
+		}
+		if err != nil {
+			log.Printf("Error getting book: %v", err)
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusInternalServerError,
+				Body:       "Internal Server Error",
+			}, nil
+		}
+		if !ifMatch.Matches(target.Version) {
+			return bookshelf.ConflictResponse(http.StatusPreconditionFailed, target), nil
+		}
+
+		source, err := h.Books.Get(ctx, userID, mergeRequest.SourceID)
+		if errors.Is(err, bookshelf.ErrNotFound) {
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusNotFound,
+				Body:       "Source book not found",
+			}, nil
+		}
+		if err != nil {
+			log.Printf("Error getting source book: %v", err)
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusInternalServerError,
+				Body:       "Internal Server Error",
+			}, nil
+		}
+
+		// Store the merged book and delete the duplicate in one transaction
+		mergedBook, err = h.Books.Merge(ctx, userID, bookshelf.MergeBooks(target, source), source)
+		var conflict *bookshelf.ConflictError
+		if errors.As(err, &conflict) {
+			if ifMatch.Present && conflict.Current.ID == targetID {
+				return bookshelf.ConflictResponse(http.StatusPreconditionFailed, conflict.Current), nil
+			}
+			if attempt < maxAttempts {
+				continue
+			}
+			return bookshelf.ConflictResponse(http.StatusConflict, conflict.Current), nil
+		}
+		if errors.Is(err, bookshelf.ErrNotFound) && attempt < maxAttempts {
+			// One of the books was deleted between the read and the write;
+			// reading again reports which.
+			continue
+		}
+		if err != nil {
+			log.Printf("Error merging books: %v", err)
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusInternalServerError,
+				Body:       "Internal Server Error",
+			}, nil
+		}
+		break
+	}
+
+	body, err := json.Marshal(mergedBook.ToAPI())
+	if err != nil {
+		log.Printf("Error marshalling JSON: %v", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Body:       "Internal Server Error",
+		}, nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Headers: map[string]string{
+			"Content-Type": "application/json",
+			"ETag":         bookshelf.ETag(mergedBook.Version),
+		},
+		Body: string(body),
+	}, nil
+}
@@ -0,0 +1,166 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/bookshelf"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/handlertest"
+)
+
+func TestHandle(t *testing.T) {
+	merge := func(id, ifMatch, body string) events.APIGatewayProxyRequest {
+		request := events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": id}, Body: body}
+		if ifMatch != "" {
+			request.Headers = map[string]string{"If-Match": ifMatch}
+		}
+		return handlertest.Request(request)
+	}
+	rating := 8
+
+	tests := []struct {
+		name       string
+		request    events.APIGatewayProxyRequest
+		racing     bool // another writer changes the target before every write
+		racesOnce  bool // another writer changes the target before the first write
+		wantStatus int
+		wantBody   string // contained in the body
+		wantMerged bool
+	}{
+		{
+			name:       "no claims",
+			request:    events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "book-1"}, Body: `{"source_id":"book-2"}`},
+			wantStatus: 401,
+			wantBody:   "Unauthorized",
+		},
+		{
+			name:       "no ID",
+			request:    merge("", "", `{"source_id":"book-2"}`),
+			wantStatus: 400,
+			wantBody:   "Book ID is required",
+		},
+		{
+			name:       "invalid JSON",
+			request:    merge("book-1", "", `{"source_id":`),
+			wantStatus: 400,
+			wantBody:   "Invalid request body",
+		},
+		{
+			name:       "no source",
+			request:    merge("book-1", "", `{}`),
+			wantStatus: 400,
+			wantBody:   "source_id is required",
+		},
+		{
+			name:       "into itself",
+			request:    merge("book-1", "", `{"source_id":"book-1"}`),
+			wantStatus: 400,
+			wantBody:   "cannot be merged into itself",
+		},
+		{
+			name:       "target not found",
+			request:    merge("book-3", "", `{"source_id":"book-2"}`),
+			wantStatus: 404,
+			wantBody:   "Book not found",
+		},
+		{
+			name:       "source not found",
+			request:    merge("book-1", "", `{"source_id":"book-3"}`),
+			wantStatus: 404,
+			wantBody:   "Source book not found",
+		},
+		{
+			name:       "stale If-Match",
+			request:    merge("book-1", bookshelf.ETag(2), `{"source_id":"book-2"}`),
+			wantStatus: 412,
+			wantBody:   `"version":1`,
+		},
+		{
+			name:       "If-Match losing a race",
+			request:    merge("book-1", bookshelf.ETag(1), `{"source_id":"book-2"}`),
+			racing:     true,
+			wantStatus: 412,
+			wantBody:   `"version":2`,
+		},
+		{
+			name:       "losing every retry",
+			request:    merge("book-1", "", `{"source_id":"book-2"}`),
+			racing:     true,
+			wantStatus: 409,
+			wantBody:   `"version":4`,
+		},
+		{
+			name:       "retried after a concurrent change",
+			request:    merge("book-1", "", `{"source_id":"book-2"}`),
+			racesOnce:  true,
+			wantStatus: 200,
+			wantBody:   `"version":3`,
+			wantMerged: true,
+		},
+		{
+			name:       "merged",
+			request:    merge("book-1", bookshelf.ETag(1), `{"source_id":"book-2"}`),
+			wantStatus: 200,
+			wantBody:   `"rating":8`,
+			wantMerged: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			memory := handlertest.Books(t,
+				bookshelf.APIBook{ID: "book-1", Title: "Dune", Author: "Frank Herbert", Status: bookshelf.StatusReading, Tags: []string{"sci-fi"}},
+				bookshelf.APIBook{ID: "book-2", Title: "Dune", Author: "Frank Herbert", Status: bookshelf.StatusRead, Rating: &rating, Tags: []string{"classics"}},
+			)
+			var books bookshelf.BookRepository = memory
+			switch {
+			case tt.racing:
+				books = handlertest.Racing{MemoryRepository: memory}
+			case tt.racesOnce:
+				books = &handlertest.RacingOnce{MemoryRepository: memory}
+			}
+			resp, err := New(books).Handle(ctx, tt.request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.wantStatus || !strings.Contains(resp.Body, tt.wantBody) {
+				t.Errorf("Handle = %d %s, want %d containing %q", resp.StatusCode, resp.Body, tt.wantStatus, tt.wantBody)
+			}
+
+			_, err = memory.Get(ctx, handlertest.UserID, "book-2")
+			if merged := errors.Is(err, bookshelf.ErrNotFound); merged != tt.wantMerged {
+				t.Errorf("source deleted = %v, want %v", merged, tt.wantMerged)
+			}
+			if !tt.wantMerged {
+				return
+			}
+
+			// The target takes the source's rating, furthest status and tags
+			target, err := memory.Get(ctx, handlertest.UserID, "book-1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if target.Rating == nil || *target.Rating != rating || target.Status != bookshelf.StatusRead ||
+				strings.Join(target.Tags, ",") != "sci-fi,classics" {
+				t.Errorf("merged book = %+v, want book-2's rating, status and tags folded in", target.ToAPI())
+			}
+
+			// Each book's history records the other
+			for _, want := range []bookshelf.HistoryEntry{
+				{BookID: "book-1", Action: bookshelf.HistoryMerge, MergedFrom: "book-2"},
+				{BookID: "book-2", Action: bookshelf.HistoryDelete, MergedInto: "book-1"},
+			} {
+				history, err := memory.History(ctx, handlertest.UserID, want.BookID)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := history[0]; got.Action != want.Action || got.MergedFrom != want.MergedFrom || got.MergedInto != want.MergedInto {
+					t.Errorf("%s history starts %+v, want a %s from %q into %q", want.BookID, got, want.Action, want.MergedFrom, want.MergedInto)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,162 @@
+// Package handler implements PATCH /books/{id}.
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"mime"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/bookshelf"
+)
+
+// Handler serves PATCH /books/{id} against an injected book repository.
+type Handler struct {
+	Books bookshelf.BookRepository
+}
+
+// New returns a Handler backed by books.
+func New(books bookshelf.BookRepository) *Handler {
+	return &Handler{Books: books}
+}
+
+// Handle is the Lambda function handler.
+func (h *Handler) Handle(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Extract user ID from JWT claims
+	userID, err := bookshelf.UserID(request)
+	if err != nil {
+		log.Printf("Error extracting user ID: %v", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusUnauthorized,
+			Body:       "Unauthorized: Could not extract user ID",
+		}, nil
+	}
+
+	// Record the request ID with the change in the book's history
+	ctx = bookshelf.WithRequestID(ctx, request.RequestContext.RequestID)
+
+	// Get the book ID from path parameters
+	bookID := request.PathParameters["id"]
+	if bookID == "" {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Body:       "Book ID is required",
+		}, nil
+	}
+
+	// Parse the patch in the format named by Content-Type
+	contentType, _ := bookshelf.Header(request, "Content-Type")
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	var patch bookshelf.Patch
+	switch mediaType {
+	case bookshelf.MergePatchContentType:
+		patch, err = bookshelf.ParseMergePatch([]byte(request.Body))
+	case bookshelf.JSONPatchContentType:
+		patch, err = bookshelf.ParseJSONPatch([]byte(request.Body))
+	default:
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusUnsupportedMediaType,
+			Headers: map[string]string{
+				"Accept-Patch": bookshelf.MergePatchContentType + ", " + bookshelf.JSONPatchContentType,
+			},
+			Body: "Unsupported Content-Type. Use " + bookshelf.MergePatchContentType + " or " + bookshelf.JSONPatchContentType,
+		}, nil
+	}
+	var patchErr *bookshelf.PatchError
+	if errors.As(err, &patchErr) {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusUnprocessableEntity,
+			Body:       patchErr.Error(),
+		}, nil
+	}
+	if err != nil {
+		log.Printf("Error parsing request body: %v", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Body:       "Invalid request body",
+		}, nil
+	}
+
+	// With If-Match, only patch the version the client last saw
+	ifMatch := bookshelf.ParseIfMatch(request)
+	var version *int
+	var current bookshelf.Book
+	if ifMatch.Present || patch.Empty() {
+		current, err = h.Books.Get(ctx, userID, bookID)
+		if errors.Is(err, bookshelf.ErrNotFound) {
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusNotFound,
+				Body:       "Book not found",
+			}, nil
+		}
+		if err != nil {
+			log.Printf("Error getting book: %v", err)
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusInternalServerError,
+				Body:       "Internal Server Error",
+			}, nil
+		}
+		if !ifMatch.Matches(current.Version) {
+			return bookshelf.ConflictResponse(http.StatusPreconditionFailed, current), nil
+		}
+		// A patch of only test operations is answered here, as nothing
+		// is written
+		if patch.Empty() && !patch.TestsPass(current) {
+			return bookshelf.ConflictResponse(http.StatusConflict, current), nil
+		}
+		version = &current.Version
+	}
+
+	// Apply the patch in one conditional update; a patch that changes
+	// nothing leaves the book and its version alone
+	patchedBook := current
+	if !patch.Empty() {
+		patchedBook, err = h.Books.Patch(ctx, userID, bookID, patch, version)
+	}
+	var conflict *bookshelf.ConflictError
+	if errors.As(err, &conflict) {
+		if !ifMatch.Present {
+			// The book kept changing while the repository retried
+			return bookshelf.ConflictResponse(http.StatusConflict, conflict.Current), nil
+		}
+		return bookshelf.ConflictResponse(http.StatusPreconditionFailed, conflict.Current), nil
+	}
+	var testFailed *bookshelf.TestFailedError
+	if errors.As(err, &testFailed) {
+		return bookshelf.ConflictResponse(http.StatusConflict, testFailed.Current), nil
+	}
+	if errors.Is(err, bookshelf.ErrNotFound) {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusNotFound,
+			Body:       "Book not found",
+		}, nil
+	}
+	if err != nil {
+		log.Printf("Error patching book: %v", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Body:       "Internal Server Error",
+		}, nil
+	}
+
+	body, err := json.Marshal(patchedBook.ToAPI())
+	if err != nil {
+		log.Printf("Error marshalling JSON: %v", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Body:       "Internal Server Error",
+		}, nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Headers: map[string]string{
+			"Content-Type": "application/json",
+			"ETag":         bookshelf.ETag(patchedBook.Version),
+		},
+		Body: string(body),
+	}, nil
+}
@@ -0,0 +1,203 @@
+package handler
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/bookshelf"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/handlertest"
+)
+
+func TestHandle(t *testing.T) {
+	patch := func(id, contentType, ifMatch, body string) events.APIGatewayProxyRequest {
+		headers := map[string]string{"content-type": contentType}
+		if ifMatch != "" {
+			headers["if-match"] = ifMatch
+		}
+		return handlertest.Request(events.APIGatewayProxyRequest{
+			PathParameters: map[string]string{"id": id},
+			Headers:        headers,
+			Body:           body,
+		})
+	}
+	const merge, jsonPatch = bookshelf.MergePatchContentType, bookshelf.JSONPatchContentType
+
+	tests := []struct {
+		name       string
+		request    events.APIGatewayProxyRequest
+		wantStatus int
+		wantBody   string // contained in the body
+		wantETag   string
+		// wantPatched changes the book as first stored into the book stored
+		// afterwards; nil if the request must not change it
+		wantPatched func(*bookshelf.APIBook)
+	}{
+		{
+			name: "no claims",
+			request: events.APIGatewayProxyRequest{
+				PathParameters: map[string]string{"id": "book-1"},
+				Headers:        map[string]string{"Content-Type": merge},
+				Body:           `{"rating":8}`,
+			},
+			wantStatus: 401,
+			wantBody:   "Unauthorized",
+		},
+		{
+			name:       "no ID",
+			request:    patch("", merge, "", `{"rating":8}`),
+			wantStatus: 400,
+			wantBody:   "Book ID is required",
+		},
+		{
+			name:       "unsupported Content-Type",
+			request:    patch("book-1", "application/json", "", `{"rating":8}`),
+			wantStatus: 415,
+			wantBody:   "Unsupported Content-Type",
+		},
+		{
+			name:       "invalid JSON",
+			request:    patch("book-1", merge, "", `{"rating":`),
+			wantStatus: 400,
+			wantBody:   "Invalid request body",
+		},
+		{
+			name:       "unknown field",
+			request:    patch("book-1", merge, "", `{"colour":"red"}`),
+			wantStatus: 422,
+			wantBody:   "colour",
+		},
+		{
+			name:       "not found",
+			request:    patch("book-2", merge, "", `{"rating":8}`),
+			wantStatus: 404,
+			wantBody:   "Book not found",
+		},
+		{
+			name:       "stale If-Match",
+			request:    patch("book-1", merge, bookshelf.ETag(2), `{"rating":8}`),
+			wantStatus: 412,
+			wantBody:   `"version":1`,
+			wantETag:   `"1"`,
+		},
+		{
+			name:       "failed test",
+			request:    patch("book-1", jsonPatch, "", `[{"op":"test","path":"/status","value":"READ"},{"op":"replace","path":"/rating","value":8}]`),
+			wantStatus: 409,
+			wantBody:   `"status":"READING"`,
+			wantETag:   `"1"`,
+		},
+		{
+			name:       "failed test without other operations",
+			request:    patch("book-1", jsonPatch, "", `[{"op":"test","path":"/title","value":"Emma"}]`),
+			wantStatus: 409,
+			wantBody:   `"title":"Dune"`,
+			wantETag:   `"1"`,
+		},
+		{
+			name:       "tag index out of range",
+			request:    patch("book-1", jsonPatch, "", `[{"op":"replace","path":"/tags/2","value":"classics"}]`),
+			wantStatus: 409,
+			wantBody:   `"tags":["sci-fi","desert"]`,
+			wantETag:   `"1"`,
+		},
+		{
+			name:       "same path twice",
+			request:    patch("book-1", jsonPatch, "", `[{"op":"replace","path":"/rating","value":8},{"op":"remove","path":"/rating"}]`),
+			wantStatus: 422,
+			wantBody:   "Path /rating is changed more than once",
+		},
+		{
+			name:       "tags removed by index twice",
+			request:    patch("book-1", jsonPatch, "", `[{"op":"remove","path":"/tags/0"},{"op":"remove","path":"/tags/1"}]`),
+			wantStatus: 422,
+			wantBody:   "Only one tag can be changed by index in a patch",
+		},
+		{
+			name:       "test after a change",
+			request:    patch("book-1", jsonPatch, "", `[{"op":"replace","path":"/title","value":"X"},{"op":"test","path":"/title","value":"X"}]`),
+			wantStatus: 422,
+			wantBody:   "must come before the operations that change the book",
+		},
+		{
+			name:       "unknown path",
+			request:    patch("book-1", jsonPatch, "", `[{"op":"add","path":"/colour","value":"red"}]`),
+			wantStatus: 422,
+			wantBody:   "colour",
+		},
+		{
+			name:       "read-only path",
+			request:    patch("book-1", jsonPatch, "", `[{"op":"replace","path":"/version","value":7}]`),
+			wantStatus: 422,
+			wantBody:   "Field version cannot be changed",
+		},
+		{
+			name:       "merge patch",
+			request:    patch("book-1", merge+"; charset=utf-8", bookshelf.ETag(1), `{"rating":8,"series":null}`),
+			wantStatus: 200,
+			wantBody:   `"rating":8`,
+			wantETag:   `"2"`,
+			wantPatched: func(b *bookshelf.APIBook) {
+				rating := 8
+				b.Rating, b.Series = &rating, ""
+			},
+		},
+		{
+			name:        "JSON patch",
+			request:     patch("book-1", jsonPatch, "", `[{"op":"test","path":"/status","value":"READING"},{"op":"add","path":"/tags/-","value":"classics"}]`),
+			wantStatus:  200,
+			wantBody:    `"tags":["sci-fi","desert","classics"]`,
+			wantETag:    `"2"`,
+			wantPatched: func(b *bookshelf.APIBook) { b.Tags = append(b.Tags, "classics") },
+		},
+		{
+			name:        "JSON patch by tag index",
+			request:     patch("book-1", jsonPatch, bookshelf.ETag(1), `[{"op":"test","path":"/tags/1","value":"desert"},{"op":"remove","path":"/tags/0"},{"op":"replace","path":"/status","value":"READ"}]`),
+			wantStatus:  200,
+			wantBody:    `"tags":["desert"]`,
+			wantETag:    `"2"`,
+			wantPatched: func(b *bookshelf.APIBook) { b.Tags, b.Status = []string{"desert"}, bookshelf.StatusRead },
+		},
+		{
+			name:       "passed test without other operations",
+			request:    patch("book-1", jsonPatch, "", `[{"op":"test","path":"/title","value":"Dune"}]`),
+			wantStatus: 200,
+			wantBody:   `"title":"Dune"`,
+			wantETag:   `"1"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			books := handlertest.Books(t, bookshelf.APIBook{ID: "book-1", Title: "Dune", Author: "Frank Herbert", Series: "Dune", Status: bookshelf.StatusReading, Tags: []string{"sci-fi", "desert"}})
+			before, err := books.Get(context.Background(), handlertest.UserID, "book-1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := New(books).Handle(context.Background(), tt.request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.wantStatus || !strings.Contains(resp.Body, tt.wantBody) {
+				t.Errorf("Handle = %d %s, want %d containing %q", resp.StatusCode, resp.Body, tt.wantStatus, tt.wantBody)
+			}
+			if resp.Headers["ETag"] != tt.wantETag {
+				t.Errorf("ETag = %q, want %q", resp.Headers["ETag"], tt.wantETag)
+			}
+
+			after, err := books.Get(context.Background(), handlertest.UserID, "book-1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := before.ToAPI()
+			if tt.wantPatched != nil {
+				tt.wantPatched(&want)
+				want.Version++
+			}
+			if got := after.ToAPI(); !reflect.DeepEqual(got, want) {
+				t.Errorf("stored %+v, want %+v", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+// Package handler writes the files of export jobs started by POST /export
+// with "async": true, invoked asynchronously by export-books.
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/bookshelf"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/exporter"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/objectstore"
+)
+
+// deadlineMargin is how long before the Lambda timeout an unfinished export
+// is abandoned, leaving time to record that it failed.
+const deadlineMargin = 30 * time.Second
+
+// Handler exports books from an injected book repository to an injected
+// object store, tracking progress in an injected job repository.
+type Handler struct {
+	Books bookshelf.BookRepository
+	Jobs  exporter.JobRepository
+	Store objectstore.Store
+}
+
+// New returns a Handler exporting books to store.
+func New(books bookshelf.BookRepository, jobs exporter.JobRepository, store objectstore.Store) *Handler {
+	return &Handler{Books: books, Jobs: jobs, Store: store}
+}
+
+// Handle is the Lambda function handler. Only errors that leave the job
+// pending are returned, so that Lambda retries the invocation; anything that
+// goes wrong once the job has started is recorded on the job instead.
+func (h *Handler) Handle(ctx context.Context, task exporter.Task) error {
+	// Claim the job so a retried invocation does not export twice
+	job, err := h.Jobs.Start(ctx, task.UserID, task.ExportID)
+	if errors.Is(err, exporter.ErrJobNotFound) || errors.Is(err, exporter.ErrJobStarted) {
+		log.Printf("Ignoring export %s for user %s: %v", task.ExportID, task.UserID, err)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to start export %s: %w", task.ExportID, err)
+	}
+
+	if h.Store == nil {
+		log.Printf("Error exporting %s: no export store configured", job.ID)
+		h.fail(ctx, task.UserID, job, "The export file could not be saved")
+		return nil
+	}
+
+	// Give up before Lambda stops the worker, so that the job fails instead
+	// of being left processing
+	exportCtx := ctx
+	if deadline, ok := ctx.Deadline(); ok {
+		var cancel context.CancelFunc
+		exportCtx, cancel = context.WithDeadline(ctx, deadline.Add(-deadlineMargin))
+		defer cancel()
+	}
+
+	key := exporter.Key(task.UserID, job.ID)
+	var opts exporter.CSVOptions
+	if job.Options != nil {
+		opts = *job.Options
+	}
+	var bookCount int
+	var generateErr error
+	size, err := exporter.Upload(exportCtx, h.Store, key, func(w io.Writer) error {
+		bookCount, generateErr = exporter.New(h.Books, nil).Export(exportCtx, w, task.UserID, job.Format, job.Filters, opts, time.Now().UTC())
+		return generateErr
+	})
+	if err != nil && exportCtx.Err() != nil && ctx.Err() == nil {
+		log.Printf("Error exporting %s: out of time: %v", job.ID, err)
+		h.fail(ctx, task.UserID, job, exporter.JobTimedOut)
+		return nil
+	}
+	if errors.Is(err, exporter.ErrListBooks) {
+		log.Printf("Error getting user books: %v", err)
+		h.fail(ctx, task.UserID, job, "Failed to retrieve books")
+		return nil
+	}
+	if err != nil && errors.Is(err, generateErr) {
+		log.Printf("Error generating export data: %v", err)
+		h.fail(ctx, task.UserID, job, "Failed to generate export data")
+		return nil
+	}
+	if err != nil {
+		log.Printf("Error uploading export: %v", err)
+		h.fail(ctx, task.UserID, job, "Failed to upload export file")
+		return nil
+	}
+
+	job.Complete(bookCount, size)
+	if err := h.Jobs.Put(ctx, task.UserID, job); err != nil {
+		log.Printf("Error completing export job %s: %v", job.ID, err)
+	}
+	log.Printf("Exported %d books for user %s to %q", bookCount, task.UserID, key)
+	return nil
+}
+
+// fail records why the job could not write its file.
+func (h *Handler) fail(ctx context.Context, userID string, job exporter.Job, reason string) {
+	job.Fail(reason)
+	if err := h.Jobs.Put(ctx, userID, job); err != nil {
+		log.Printf("Error failing export job %s: %v", job.ID, err)
+	}
+}
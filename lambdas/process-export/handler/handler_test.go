@@ -0,0 +1,189 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/bookshelf"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/exporter"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/exporter/exportertest"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/handlertest"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/objectstore"
+)
+
+// contextBooks is a repository that stops reading books once its context is
+// done, as DynamoDB does.
+type contextBooks struct {
+	*bookshelf.MemoryRepository
+}
+
+func (r contextBooks) ListPage(ctx context.Context, userID string, opts bookshelf.PageOptions) (bookshelf.Page, error) {
+	if err := ctx.Err(); err != nil {
+		return bookshelf.Page{}, err
+	}
+	return r.MemoryRepository.ListPage(ctx, userID, opts)
+}
+
+// unreadable is a repository whose books cannot be read.
+type unreadable struct {
+	*bookshelf.MemoryRepository
+}
+
+func (r unreadable) ListPage(ctx context.Context, userID string, opts bookshelf.PageOptions) (bookshelf.Page, error) {
+	return bookshelf.Page{}, errors.New("throttled")
+}
+
+func TestHandle(t *testing.T) {
+	books := contextBooks{handlertest.Books(t,
+		bookshelf.APIBook{ID: "book-1", Title: "Dune", Author: "Frank Herbert", Status: bookshelf.StatusRead},
+		bookshelf.APIBook{ID: "book-2", Title: "Emma", Author: "Jane Austen", Status: bookshelf.StatusWantToRead},
+	)}
+
+	tests := []struct {
+		name       string
+		exportID   func(exportertest.Exports) string
+		noStore    bool
+		unreadable bool
+		deadline   time.Duration // from now, for the invocation
+		wantStatus string
+		wantError  string
+		wantCount  int
+		wantFile   bool
+		wantTitles []string // in the file
+		wantOmits  []string // titles not in the file
+	}{
+		{
+			name:     "unknown export",
+			exportID: func(exportertest.Exports) string { return "books-20250301-120000-3f9a1c.csv" },
+		},
+		{
+			name:       "already failed",
+			exportID:   func(e exportertest.Exports) string { return e.Failed },
+			wantStatus: exporter.JobFailed,
+			wantError:  "The books could not be read",
+		},
+		{
+			// A retried invocation leaves the file alone
+			name:       "already completed",
+			exportID:   func(e exportertest.Exports) string { return e.Completed },
+			wantStatus: exporter.JobCompleted,
+			wantCount:  2,
+			wantFile:   true,
+		},
+		{
+			name:       "no store",
+			exportID:   func(e exportertest.Exports) string { return e.Pending },
+			noStore:    true,
+			wantStatus: exporter.JobFailed,
+			wantError:  "The export file could not be saved",
+		},
+		{
+			name:       "out of time",
+			exportID:   func(e exportertest.Exports) string { return e.Pending },
+			deadline:   deadlineMargin,
+			wantStatus: exporter.JobFailed,
+			wantError:  exporter.JobTimedOut,
+		},
+		{
+			name:       "books unreadable",
+			exportID:   func(e exportertest.Exports) string { return e.Pending },
+			unreadable: true,
+			wantStatus: exporter.JobFailed,
+			wantError:  "Failed to retrieve books",
+		},
+		{
+			name:       "exported",
+			exportID:   func(e exportertest.Exports) string { return e.Pending },
+			deadline:   15 * time.Minute,
+			wantStatus: exporter.JobCompleted,
+			wantCount:  2,
+			wantFile:   true,
+			wantTitles: []string{"Dune", "Emma"},
+		},
+		{
+			name: "filtered",
+			exportID: func(e exportertest.Exports) string {
+				job := exporter.NewJob(handlertest.UserID, "books-20250301-120000-3f9a1c.csv", "csv", map[string]string{"status": bookshelf.StatusRead}, exporter.CSVOptions{}, time.Now())
+				if err := e.Jobs.Put(context.Background(), handlertest.UserID, job); err != nil {
+					t.Fatal(err)
+				}
+				return job.ID
+			},
+			wantStatus: exporter.JobCompleted,
+			wantCount:  1,
+			wantFile:   true,
+			wantTitles: []string{"Dune"},
+			wantOmits:  []string{"Emma"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			exports := exportertest.New(t)
+			exportID := tt.exportID(exports)
+			var store objectstore.Store = exports.Store
+			if tt.noStore {
+				store = nil
+			}
+			var repo bookshelf.BookRepository = books
+			if tt.unreadable {
+				repo = unreadable{books.MemoryRepository}
+			}
+
+			invocation := ctx
+			if tt.deadline != 0 {
+				var cancel context.CancelFunc
+				invocation, cancel = context.WithTimeout(ctx, tt.deadline)
+				defer cancel()
+			}
+			if err := New(repo, exports.Jobs, store).Handle(invocation, exporter.Task{UserID: handlertest.UserID, ExportID: exportID}); err != nil {
+				t.Fatal(err)
+			}
+
+			job, err := exports.Jobs.Get(ctx, handlertest.UserID, exportID)
+			if errors.Is(err, exporter.ErrJobNotFound) && tt.wantStatus == "" {
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if job.Status != tt.wantStatus || job.Error != tt.wantError || job.BookCount != tt.wantCount {
+				t.Errorf("job = %s %q with %d books, want %s %q with %d", job.Status, job.Error, job.BookCount, tt.wantStatus, tt.wantError, tt.wantCount)
+			}
+			_, err = exports.Store.Stat(ctx, exporter.Key(handlertest.UserID, exportID))
+			if written := err == nil; written != tt.wantFile {
+				t.Errorf("file written = %v, want %v", written, tt.wantFile)
+			}
+			if !tt.wantFile {
+				return
+			}
+
+			file, err := exports.Store.Get(ctx, exporter.Key(handlertest.UserID, exportID))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer file.Close()
+			data, err := io.ReadAll(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if job.Size != int64(len(data)) {
+				t.Errorf("job size = %d, want the file's %d bytes", job.Size, len(data))
+			}
+			for _, title := range tt.wantTitles {
+				if !strings.Contains(string(data), title) {
+					t.Errorf("file does not list %s:\n%s", title, data)
+				}
+			}
+			for _, title := range tt.wantOmits {
+				if strings.Contains(string(data), title) {
+					t.Errorf("file lists %s:\n%s", title, data)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/bookshelf"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/exporter"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/objectstore"
+	"github.com/ericdahl/bookshelf-aws/lambdas/process-export/handler"
+)
+
+var (
+	ddbClient  *dynamodb.Client
+	s3Client   *s3.Client
+	bucketName = os.Getenv("EXPORTS_BUCKET_NAME")
+)
+
+func init() {
+	cfg, err := config.LoadDefaultConfig(context.TODO())
+	if err != nil {
+		log.Fatalf("unable to load SDK config, %v", err)
+	}
+	ddbClient = dynamodb.NewFromConfig(cfg)
+	s3Client = s3.NewFromConfig(cfg)
+}
+
+func main() {
+	var store objectstore.Store
+	if bucketName != "" {
+		store = objectstore.NewS3Store(s3Client, s3.NewPresignClient(s3Client), bucketName)
+	} else {
+		log.Printf("EXPORTS_BUCKET_NAME environment variable not set")
+	}
+	jobs := exporter.NewDynamoJobRepository(ddbClient, bookshelf.TableName)
+	h := handler.New(bookshelf.NewDynamoRepository(ddbClient, bookshelf.TableName), jobs, store)
+
+	if os.Getenv("LAMBDA_TASK_ROOT") == "" {
+		// Local testing
+		fmt.Println("--- Local execution mode ---")
+		fmt.Println("Set EXPORTS_BUCKET_NAME environment variable for S3 operations")
+
+		// Create a pending job and run it
+		ctx := context.Background()
+		job := exporter.NewJob("test-user-id", exporter.NewID("csv", time.Now()), "csv", nil, exporter.CSVOptions{}, time.Now())
+		if err := jobs.Put(ctx, "test-user-id", job); err != nil {
+			log.Fatalf("FATAL: failed to create job: %v", err)
+		}
+
+		if err := h.Handle(ctx, exporter.Task{UserID: "test-user-id", ExportID: job.ID}); err != nil {
+			log.Fatalf("FATAL: handler failed: %v", err)
+		}
+		fmt.Println("Processed", job.ID)
+	} else {
+		lambda.Start(h.Handle)
+	}
+}
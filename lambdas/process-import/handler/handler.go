@@ -0,0 +1,181 @@
+// Package handler imports the files uploaded for import jobs, triggered by
+// S3 event notifications for the imports bucket.
+package handler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/bookshelf"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/importer"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/objectstore"
+	"github.com/google/uuid"
+)
+
+const (
+	// MaxFileSize is the largest file that is imported, in bytes.
+	MaxFileSize = 20 << 20
+
+	// ProgressBatchSize is how many rows are written between updates to the
+	// job's progress.
+	ProgressBatchSize = 250
+
+	// deadlineMargin is how long before the Lambda timeout the import stops
+	// between batches, leaving time to record how far it got.
+	deadlineMargin = 30 * time.Second
+)
+
+// errOutOfTime stops an import that would not finish before the Lambda
+// timeout.
+var errOutOfTime = errors.New("out of time")
+
+// Handler imports uploaded files into an injected book repository, reading
+// them from an injected object store and tracking progress in an injected
+// job repository.
+type Handler struct {
+	Books bookshelf.BookRepository
+	Jobs  importer.JobRepository
+	Store objectstore.Store
+}
+
+// New returns a Handler importing files from store into books.
+func New(books bookshelf.BookRepository, jobs importer.JobRepository, store objectstore.Store) *Handler {
+	return &Handler{Books: books, Jobs: jobs, Store: store}
+}
+
+// Handle is the Lambda function handler. Only errors that leave the job
+// pending are returned, so that Lambda retries the event; anything that
+// goes wrong once the job has started is recorded on the job instead.
+func (h *Handler) Handle(ctx context.Context, event events.S3Event) error {
+	var errs []error
+	for _, record := range event.Records {
+		if err := h.process(ctx, record.S3.Object.URLDecodedKey); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// process imports the file uploaded under key.
+func (h *Handler) process(ctx context.Context, key string) error {
+	userID, jobID, ok := importer.ParseJobKey(key)
+	if !ok {
+		log.Printf("Ignoring upload %q outside the imports folder", key)
+		return nil
+	}
+
+	// Claim the job so a redelivered event does not import the file twice
+	job, err := h.Jobs.Start(ctx, userID, jobID)
+	if errors.Is(err, importer.ErrJobNotFound) || errors.Is(err, importer.ErrJobStarted) {
+		log.Printf("Ignoring upload %q: %v", key, err)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to start import %s: %w", jobID, err)
+	}
+
+	data, err := h.read(ctx, key)
+	if errors.Is(err, errTooLarge) {
+		h.fail(ctx, userID, job, fmt.Sprintf("File is too large. At most %d MB can be imported at once", MaxFileSize>>20))
+		return nil
+	}
+	if err != nil {
+		log.Printf("Error reading upload %q: %v", key, err)
+		h.fail(ctx, userID, job, "The uploaded file could not be read")
+		return nil
+	}
+
+	imp, ok := importer.Lookup(job.Format)
+	if !ok {
+		h.fail(ctx, userID, job, importer.InvalidFormatMessage())
+		return nil
+	}
+	rows, err := imp.Parse(bytes.NewReader(data))
+	if err != nil {
+		h.fail(ctx, userID, job, fmt.Sprintf("Invalid %s file: %v", job.Format, err))
+		return nil
+	}
+	job.Total = len(rows)
+	if err := h.Jobs.Put(ctx, userID, job); err != nil {
+		log.Printf("Error updating import job %s: %v", jobID, err)
+	}
+
+	// How long an import takes depends on the library it is checked for
+	// duplicates against as much as on the file, so rather than limit the
+	// rows, stop between batches while there is time to say how far it got
+	var stopAt time.Time
+	if deadline, ok := ctx.Deadline(); ok {
+		stopAt = deadline.Add(-deadlineMargin)
+	}
+
+	// Store the books a batch at a time, reporting progress after each
+	report, err := importer.ImportBatches(ctx, h.Books, userID, rows, uuid.NewString, ProgressBatchSize, func(report importer.Report) error {
+		job.Progress(report)
+		if err := h.Jobs.Put(ctx, userID, job); err != nil {
+			// Keep importing; the final update may still succeed
+			log.Printf("Error updating import job %s: %v", jobID, err)
+		}
+		if !stopAt.IsZero() && time.Now().After(stopAt) {
+			return errOutOfTime
+		}
+		return nil
+	})
+	if errors.Is(err, errOutOfTime) {
+		log.Printf("Error importing upload %q: out of time after %d of %d rows", key, len(report.Rows), len(rows))
+		job.Progress(report)
+		h.fail(ctx, userID, job, fmt.Sprintf("%s: %d of %d rows were processed. Upload the file to a new import to add the rest; the books already imported are skipped", importer.JobTimedOut, len(report.Rows), len(rows)))
+		return nil
+	}
+	if err != nil {
+		log.Printf("Error importing upload %q: %v", key, err)
+		job.Progress(report)
+		h.fail(ctx, userID, job, "The import stopped before every row was processed")
+		return nil
+	}
+
+	job.Progress(report)
+	job.Complete()
+	if err := h.Jobs.Put(ctx, userID, job); err != nil {
+		log.Printf("Error completing import job %s: %v", jobID, err)
+	}
+	log.Printf("Imported %q: %d created, %d skipped, %d failed", key, job.Created, job.Skipped, job.Failed)
+	return nil
+}
+
+// errTooLarge is returned by read for a file over MaxFileSize.
+var errTooLarge = errors.New("file too large")
+
+// read returns the contents of the file uploaded under key.
+func (h *Handler) read(ctx context.Context, key string) ([]byte, error) {
+	if h.Store == nil {
+		return nil, errors.New("no import store configured")
+	}
+	body, err := h.Store.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(body, MaxFileSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > MaxFileSize {
+		return nil, errTooLarge
+	}
+	return data, nil
+}
+
+// fail records why the job could not import its file.
+func (h *Handler) fail(ctx context.Context, userID string, job importer.Job, reason string) {
+	job.Fail(reason)
+	if err := h.Jobs.Put(ctx, userID, job); err != nil {
+		log.Printf("Error failing import job %s: %v", job.ID, err)
+	}
+}
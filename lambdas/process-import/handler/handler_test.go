@@ -0,0 +1,233 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/bookshelf"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/handlertest"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/importer"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/objectstore"
+)
+
+func TestHandle(t *testing.T) {
+	// Dune is already on the shelf and the third row has no author
+	const goodreads = "Title,Author,Exclusive Shelf\n" +
+		"Dune,Frank Herbert,read\n" +
+		"Emma,Jane Austen,to-read\n" +
+		"Beowulf,,read\n"
+	var large strings.Builder
+	large.WriteString("Title,Author\n")
+	for i := range ProgressBatchSize + 50 {
+		fmt.Fprintf(&large, "Book %d,Author %d\n", i, i)
+	}
+
+	tests := []struct {
+		name          string
+		key           string // of the upload; the job's file by default
+		file          string // uploaded, if not empty
+		format        string // of the job; goodreads by default
+		jobStatus     string // of the job before the upload, if it exists
+		startedAgo    time.Duration
+		deliveries    int // of the upload event; once by default
+		deadline      time.Duration
+		wantStatus    string // of the job after the upload, if it exists
+		wantError     string // prefix of the job's error
+		wantProcessed int
+		wantCreated   int
+		wantErrors    []importer.RowResult
+		wantTitles    []string // of the books created, in title order
+	}{
+		{
+			name:       "outside the imports folder",
+			key:        "exports/user-1/books.csv",
+			file:       goodreads,
+			jobStatus:  importer.JobPending,
+			wantStatus: importer.JobPending,
+		},
+		{
+			name: "unknown job",
+			file: goodreads,
+		},
+		{
+			name:       "already started",
+			file:       goodreads,
+			jobStatus:  importer.JobProcessing,
+			wantStatus: importer.JobProcessing,
+		},
+		{
+			name:       "still running",
+			file:       goodreads,
+			jobStatus:  importer.JobProcessing,
+			startedAgo: importer.JobLease - time.Minute,
+			wantStatus: importer.JobProcessing,
+		},
+		{
+			name:       "no file",
+			jobStatus:  importer.JobPending,
+			wantStatus: importer.JobFailed,
+			wantError:  "The uploaded file could not be read",
+		},
+		{
+			name:       "invalid file",
+			file:       "Title,Exclusive Shelf\nDune,read\n",
+			jobStatus:  importer.JobPending,
+			wantStatus: importer.JobFailed,
+			wantError:  `Invalid goodreads file: missing column "Author"`,
+		},
+		{
+			name:       "too large",
+			file:       "Title,Author\n" + strings.Repeat("Dune,Frank Herbert\n", MaxFileSize/19+1),
+			jobStatus:  importer.JobPending,
+			wantStatus: importer.JobFailed,
+			wantError:  "File is too large. At most 20 MB can be imported at once",
+		},
+		{
+			name:          "imported",
+			file:          goodreads,
+			jobStatus:     importer.JobPending,
+			deadline:      15 * time.Minute,
+			wantStatus:    importer.JobCompleted,
+			wantProcessed: 3,
+			wantCreated:   1,
+			wantErrors:    []importer.RowResult{{Row: 4, Title: "Beowulf", Status: importer.RowFailed, Error: "Author is required"}},
+			wantTitles:    []string{"Emma"},
+		},
+		{
+			name:          "imported in the job's format",
+			file:          "Title,Authors,Read Status\nPiranesi,Susanna Clarke,read\n",
+			format:        "storygraph",
+			jobStatus:     importer.JobPending,
+			wantStatus:    importer.JobCompleted,
+			wantProcessed: 1,
+			wantCreated:   1,
+			wantTitles:    []string{"Piranesi"},
+		},
+		{
+			name:          "redelivered",
+			file:          goodreads,
+			jobStatus:     importer.JobPending,
+			deliveries:    2,
+			wantStatus:    importer.JobCompleted,
+			wantProcessed: 3,
+			wantCreated:   1,
+			wantErrors:    []importer.RowResult{{Row: 4, Title: "Beowulf", Status: importer.RowFailed, Error: "Author is required"}},
+			wantTitles:    []string{"Emma"},
+		},
+		{
+			// The worker that started it died, so the import starts again
+			name:          "lease expired",
+			file:          goodreads,
+			jobStatus:     importer.JobProcessing,
+			startedAgo:    importer.JobLease + time.Minute,
+			wantStatus:    importer.JobCompleted,
+			wantProcessed: 3,
+			wantCreated:   1,
+			wantErrors:    []importer.RowResult{{Row: 4, Title: "Beowulf", Status: importer.RowFailed, Error: "Author is required"}},
+			wantTitles:    []string{"Emma"},
+		},
+		{
+			name:          "out of time",
+			file:          large.String(),
+			jobStatus:     importer.JobPending,
+			deadline:      deadlineMargin,
+			wantStatus:    importer.JobFailed,
+			wantError:     fmt.Sprintf("%s: %d of %d rows were processed.", importer.JobTimedOut, ProgressBatchSize, ProgressBatchSize+50),
+			wantProcessed: ProgressBatchSize,
+			wantCreated:   ProgressBatchSize,
+			wantTitles: func() []string {
+				// The first batch of books, as sorted by title
+				var titles []string
+				for i := range ProgressBatchSize {
+					titles = append(titles, fmt.Sprintf("Book %d", i))
+				}
+				slices.Sort(titles)
+				return titles
+			}(),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			books := handlertest.Books(t, bookshelf.APIBook{ID: "book-1", Title: "Dune", Author: "Frank Herbert", Status: bookshelf.StatusRead})
+			jobs := importer.NewMemoryJobRepository()
+			store := objectstore.NewFileStore(t.TempDir(), "https://imports.example.com")
+
+			if tt.jobStatus != "" {
+				format := tt.format
+				if format == "" {
+					format = "goodreads"
+				}
+				job := importer.NewJob(handlertest.UserID, "import-1", format, time.Now().Add(-tt.startedAgo))
+				job.Status = tt.jobStatus
+				job.StartedAt = job.CreatedAt
+				if err := jobs.Put(ctx, handlertest.UserID, job); err != nil {
+					t.Fatal(err)
+				}
+			}
+			key := tt.key
+			if key == "" {
+				key = importer.JobKey(handlertest.UserID, "import-1")
+			}
+			if tt.file != "" {
+				if err := store.Put(ctx, key, strings.NewReader(tt.file), nil); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			invocation := ctx
+			if tt.deadline != 0 {
+				var cancel context.CancelFunc
+				invocation, cancel = context.WithTimeout(ctx, tt.deadline)
+				defer cancel()
+			}
+			event := events.S3Event{Records: []events.S3EventRecord{
+				{S3: events.S3Entity{Object: events.S3Object{Key: key, URLDecodedKey: key}}},
+			}}
+			for range max(tt.deliveries, 1) {
+				if err := New(books, jobs, store).Handle(invocation, event); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			all, err := books.List(ctx, handlertest.UserID, bookshelf.ListOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var titles []string
+			for _, book := range all {
+				if book.ID != "book-1" {
+					titles = append(titles, book.Title)
+				}
+			}
+			slices.Sort(titles)
+			if !slices.Equal(titles, tt.wantTitles) {
+				t.Errorf("created %d books %v, want %v", len(titles), titles, tt.wantTitles)
+			}
+
+			job, err := jobs.Get(ctx, handlertest.UserID, "import-1")
+			if errors.Is(err, importer.ErrJobNotFound) && tt.wantStatus == "" {
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if job.Status != tt.wantStatus || !strings.HasPrefix(job.Error, tt.wantError) || (tt.wantError == "") != (job.Error == "") {
+				t.Errorf("job = %s %q, want %s %q", job.Status, job.Error, tt.wantStatus, tt.wantError)
+			}
+			if job.Processed != tt.wantProcessed || job.Created != tt.wantCreated {
+				t.Errorf("job processed %d rows and created %d books, want %d and %d", job.Processed, job.Created, tt.wantProcessed, tt.wantCreated)
+			}
+			if wantErrors := append([]importer.RowResult{}, tt.wantErrors...); !reflect.DeepEqual(job.Errors, wantErrors) {
+				t.Errorf("job errors = %+v, want %+v", job.Errors, wantErrors)
+			}
+		})
+	}
+}
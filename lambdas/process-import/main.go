@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/bookshelf"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/importer"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/objectstore"
+	"github.com/ericdahl/bookshelf-aws/lambdas/process-import/handler"
+)
+
+var (
+	ddbClient  *dynamodb.Client
+	s3Client   *s3.Client
+	bucketName = os.Getenv("IMPORTS_BUCKET_NAME")
+)
+
+func init() {
+	cfg, err := config.LoadDefaultConfig(context.TODO())
+	if err != nil {
+		log.Fatalf("unable to load SDK config, %v", err)
+	}
+	ddbClient = dynamodb.NewFromConfig(cfg)
+	s3Client = s3.NewFromConfig(cfg)
+}
+
+func main() {
+	var store objectstore.Store
+	if bucketName != "" {
+		store = objectstore.NewS3Store(s3Client, s3.NewPresignClient(s3Client), bucketName)
+	} else {
+		log.Printf("IMPORTS_BUCKET_NAME environment variable not set")
+	}
+	h := handler.New(
+		bookshelf.NewDynamoRepository(ddbClient, bookshelf.TableName),
+		importer.NewDynamoJobRepository(ddbClient, bookshelf.TableName),
+		store,
+	)
+
+	if os.Getenv("LAMBDA_TASK_ROOT") == "" {
+		// Local testing
+		fmt.Println("--- Local execution mode ---")
+		fmt.Println("Set IMPORTS_BUCKET_NAME environment variable for S3 operations")
+
+		// Create a test event for a file uploaded for a pending job
+		key := importer.JobKey("test-user-id", "test-import-id")
+		testEvent := events.S3Event{
+			Records: []events.S3EventRecord{{
+				EventName: "ObjectCreated:Put",
+				S3: events.S3Entity{
+					Bucket: events.S3Bucket{Name: bucketName},
+					Object: events.S3Object{Key: key, URLDecodedKey: key},
+				},
+			}},
+		}
+
+		if err := h.Handle(context.Background(), testEvent); err != nil {
+			log.Fatalf("FATAL: handler failed: %v", err)
+		}
+		fmt.Println("Processed", key)
+	} else {
+		lambda.Start(h.Handle)
+	}
+}
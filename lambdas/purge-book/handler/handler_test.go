@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/bookshelf"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/handlertest"
+)
+
+func TestHandle(t *testing.T) {
+	purge := func(id string) events.APIGatewayProxyRequest {
+		return handlertest.Request(events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": id}})
+	}
+
+	tests := []struct {
+		name       string
+		request    events.APIGatewayProxyRequest
+		wantStatus int
+		wantBody   string // contained in the body
+		wantTrash  int    // books left in the trash
+	}{
+		{
+			name:       "no claims",
+			request:    events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "book-1"}},
+			wantStatus: 401,
+			wantBody:   "Unauthorized",
+			wantTrash:  1,
+		},
+		{
+			name:       "no ID",
+			request:    purge(""),
+			wantStatus: 400,
+			wantBody:   "Book ID is required",
+			wantTrash:  1,
+		},
+		{
+			name:       "on the shelf",
+			request:    purge("book-2"),
+			wantStatus: 404,
+			wantBody:   "Book not found in trash",
+			wantTrash:  1,
+		},
+		{
+			name:       "purged",
+			request:    purge("book-1"),
+			wantStatus: 204,
+			wantTrash:  0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			books := handlertest.Books(t,
+				bookshelf.APIBook{ID: "book-1", Title: "Dune", Author: "Frank Herbert", Status: bookshelf.StatusRead},
+				bookshelf.APIBook{ID: "book-2", Title: "Emma", Author: "Jane Austen", Status: bookshelf.StatusRead},
+			)
+			book, err := books.Get(ctx, handlertest.UserID, "book-1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := books.Trash(ctx, handlertest.UserID, book); err != nil {
+				t.Fatal(err)
+			}
+
+			resp, err := New(books).Handle(ctx, tt.request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.wantStatus || !strings.Contains(resp.Body, tt.wantBody) {
+				t.Errorf("Handle = %d %s, want %d containing %q", resp.StatusCode, resp.Body, tt.wantStatus, tt.wantBody)
+			}
+			trash, err := books.ListTrash(ctx, handlertest.UserID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(trash) != tt.wantTrash {
+				t.Errorf("trash holds %d books, want %d", len(trash), tt.wantTrash)
+			}
+
+			// Purging only ever touches the trash, and keeps the history
+			if _, err := books.Get(ctx, handlertest.UserID, "book-2"); err != nil {
+				t.Errorf("book on the shelf: %v", err)
+			}
+			_, err = books.Restore(ctx, handlertest.UserID, "book-1")
+			if restorable := err == nil; restorable != (tt.wantTrash == 1) {
+				t.Errorf("book-1 restorable = %v, want %v", restorable, tt.wantTrash == 1)
+			}
+			history, err := books.History(ctx, handlertest.UserID, "book-1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(history) < 2 {
+				t.Errorf("book-1 history = %v, want its create and delete", history)
+			}
+		})
+	}
+}
@@ -0,0 +1,457 @@
+// Package handler implements GET /recommendations.
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/bookshelf"
+)
+
+// Logger is the structured logger used by the handler. It writes JSON to
+// stdout so CloudWatch can index the fields.
+var Logger = slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+	Level: slog.LevelInfo,
+}))
+
+var logger = Logger
+
+// Recommendation represents a book recommendation
+type Recommendation struct {
+	Title  string `json:"title"`
+	Author string `json:"author"`
+	Genre  string `json:"genre"`
+	Reason string `json:"reason"`
+}
+
+// RecommendationResponse is the API response structure
+type RecommendationResponse struct {
+	Recommendations []Recommendation `json:"recommendations"`
+	// BookCount is the exact number of the user's books included in the
+	// Bedrock prompt.
+	BookCount int `json:"book_count"`
+}
+
+// Titan request/response structures for Bedrock
+type TitanRequest struct {
+	InputText            string               `json:"inputText"`
+	TextGenerationConfig TextGenerationConfig `json:"textGenerationConfig"`
+}
+
+type TextGenerationConfig struct {
+	MaxTokenCount int      `json:"maxTokenCount"`
+	StopSequences []string `json:"stopSequences,omitempty"`
+	Temperature   float64  `json:"temperature,omitempty"`
+	TopP          float64  `json:"topP,omitempty"`
+}
+
+type TitanResponse struct {
+	InputTextTokenCount int                     `json:"inputTextTokenCount"`
+	Results             []TitanGenerationResult `json:"results"`
+}
+
+type TitanGenerationResult struct {
+	TokenCount       int    `json:"tokenCount"`
+	OutputText       string `json:"outputText"`
+	CompletionReason string `json:"completionReason"`
+}
+
+// BedrockAPI is the subset of the Bedrock runtime client used to generate
+// recommendations.
+type BedrockAPI interface {
+	InvokeModel(ctx context.Context, params *bedrockruntime.InvokeModelInput, optFns ...func(*bedrockruntime.Options)) (*bedrockruntime.InvokeModelOutput, error)
+}
+
+// Handler serves GET /recommendations, reading books from an injected
+// repository. When Bedrock is nil the default recommendations are returned.
+type Handler struct {
+	Books   bookshelf.BookRepository
+	Bedrock BedrockAPI
+}
+
+// New returns a Handler reading from books and prompting bedrock.
+func New(books bookshelf.BookRepository, bedrock BedrockAPI) *Handler {
+	return &Handler{Books: books, Bedrock: bedrock}
+}
+
+// getUserBooks fetches every page of the user's books from the repository
+func (h *Handler) getUserBooks(ctx context.Context, userID string) ([]bookshelf.Book, error) {
+	startTime := time.Now()
+
+	logger.Info("fetching user books",
+		"user_id", userID,
+		"user_pk", bookshelf.UserPK(userID),
+		"table", bookshelf.TableName)
+
+	books, err := bookshelf.ListAll(ctx, h.Books, userID, bookshelf.ListOptions{})
+	duration := time.Since(startTime)
+
+	if err != nil {
+		logger.Error("error listing books",
+			"error", err,
+			"duration_ms", duration.Milliseconds(),
+			"user_id", userID)
+		return nil, fmt.Errorf("error listing books: %v", err)
+	}
+
+	logger.Info("successfully fetched user books",
+		"user_id", userID,
+		"books_count", len(books),
+		"duration_ms", duration.Milliseconds())
+
+	return books, nil
+}
+
+// generateRecommendations uses Amazon Titan Text to generate book
+// recommendations. It also returns how many of the books were included in the
+// prompt, which is zero when the defaults are returned.
+func (h *Handler) generateRecommendations(ctx context.Context, books []bookshelf.Book) ([]Recommendation, int, error) {
+	startTime := time.Now()
+
+	logger.Info("generating book recommendations",
+		"books_count", len(books))
+
+	if len(books) == 0 {
+		logger.Info("no books found, returning default recommendations")
+		return getDefaultRecommendations(), 0, nil
+	}
+
+	// Build prompt from user's reading history
+	var readBooks []string
+	var currentlyReading []string
+	var statusCounts = make(map[string]int)
+
+	for _, book := range books {
+		bookStr := fmt.Sprintf("%s by %s", book.Title, book.Author)
+		statusCounts[book.Status]++
+
+		switch strings.ToUpper(book.Status) {
+		case bookshelf.StatusRead, "FINISHED":
+			readBooks = append(readBooks, bookStr)
+		case bookshelf.StatusReading, "CURRENTLY-READING", "CURRENTLY_READING":
+			currentlyReading = append(currentlyReading, bookStr)
+		}
+	}
+
+	logger.Info("book status breakdown",
+		"status_counts", statusCounts,
+		"matched_read_books", len(readBooks),
+		"matched_currently_reading", len(currentlyReading))
+
+	prompt := "Based on the following reading history, recommend 5 books with their genres. Return the response as a valid JSON array with objects containing 'title', 'author', 'genre', and 'reason' fields.\n\n"
+
+	if len(readBooks) > 0 {
+		prompt += "Books read: " + strings.Join(readBooks, ", ") + "\n"
+	}
+
+	if len(currentlyReading) > 0 {
+		prompt += "Currently reading: " + strings.Join(currentlyReading, ", ") + "\n"
+	}
+
+	prompt += "\nPlease provide exactly 5 book recommendations in valid JSON format. Return only the JSON array, no additional text."
+
+	logger.Info("built prompt for Bedrock",
+		"read_books_count", len(readBooks),
+		"currently_reading_count", len(currentlyReading),
+		"prompt_length", len(prompt))
+
+	// Call Bedrock Titan model
+	recommendations, err := h.callBedrockTitan(ctx, prompt)
+	duration := time.Since(startTime)
+
+	if err != nil {
+		logger.Warn("error calling Bedrock, falling back to defaults",
+			"error", err,
+			"duration_ms", duration.Milliseconds())
+		// Fallback to default recommendations if Bedrock fails
+		return getDefaultRecommendations(), 0, nil
+	}
+
+	logger.Info("successfully generated recommendations",
+		"recommendations_count", len(recommendations),
+		"duration_ms", duration.Milliseconds())
+
+	return recommendations, len(readBooks) + len(currentlyReading), nil
+}
+
+// callBedrockTitan makes a request to Amazon Titan Text model
+func (h *Handler) callBedrockTitan(ctx context.Context, prompt string) ([]Recommendation, error) {
+	startTime := time.Now()
+
+	if h.Bedrock == nil {
+		return nil, fmt.Errorf("no Bedrock client configured")
+	}
+
+	// Prepare Titan request
+	titanReq := TitanRequest{
+		InputText: prompt,
+		TextGenerationConfig: TextGenerationConfig{
+			MaxTokenCount: 1000,
+			Temperature:   0.7,
+			TopP:          0.9,
+		},
+	}
+
+	requestBody, err := json.Marshal(titanReq)
+	if err != nil {
+		logger.Error("error marshalling Titan request", "error", err)
+		return nil, fmt.Errorf("error marshalling Titan request: %v", err)
+	}
+
+	// Call Bedrock with Titan model
+	modelID := "amazon.titan-text-express-v1"
+
+	logger.Info("calling Bedrock Titan model",
+		"model_id", modelID,
+		"prompt", prompt,
+		"max_tokens", 1000,
+		"temperature", 0.7,
+		"top_p", 0.9)
+
+	output, err := h.Bedrock.InvokeModel(ctx, &bedrockruntime.InvokeModelInput{
+		ModelId:     aws.String(modelID),
+		Body:        requestBody,
+		ContentType: aws.String("application/json"),
+	})
+
+	bedrockDuration := time.Since(startTime)
+
+	if err != nil {
+		logger.Error("error calling Bedrock",
+			"error", err,
+			"model_id", modelID,
+			"duration_ms", bedrockDuration.Milliseconds())
+		return nil, fmt.Errorf("error calling Bedrock: %v", err)
+	}
+
+	logger.Info("received response from Bedrock",
+		"model_id", modelID,
+		"duration_ms", bedrockDuration.Milliseconds(),
+		"response_size_bytes", len(output.Body))
+
+	// Parse Titan response
+	var titanResp TitanResponse
+	err = json.Unmarshal(output.Body, &titanResp)
+	if err != nil {
+		logger.Error("error unmarshalling Titan response",
+			"error", err,
+			"response_body", string(output.Body))
+		return nil, fmt.Errorf("error unmarshalling Titan response: %v", err)
+	}
+
+	if len(titanResp.Results) == 0 {
+		logger.Error("no results in Titan response", "response", titanResp)
+		return nil, fmt.Errorf("no results in Titan response")
+	}
+
+	// Extract JSON from Titan's text response
+	text := titanResp.Results[0].OutputText
+
+	logger.Info("received text output from Titan",
+		"output_text", text,
+		"input_token_count", titanResp.InputTextTokenCount,
+		"output_token_count", titanResp.Results[0].TokenCount,
+		"completion_reason", titanResp.Results[0].CompletionReason)
+
+	// Clean up the text by removing markdown code blocks and extra formatting
+	text = strings.ReplaceAll(text, "```json", "")
+	text = strings.ReplaceAll(text, "```tabular-data-json", "")
+	text = strings.ReplaceAll(text, "```", "")
+	text = strings.TrimSpace(text)
+
+	// Try to find the first complete JSON array in the response
+	startIdx := strings.Index(text, "[")
+	if startIdx == -1 {
+		logger.Error("could not find JSON array start in Titan response", "text", text)
+		return nil, fmt.Errorf("could not find JSON array start in Titan response: %s", text)
+	}
+
+	// Find the matching closing bracket for the array
+	bracketCount := 0
+	endIdx := -1
+	for i := startIdx; i < len(text); i++ {
+		if text[i] == '[' {
+			bracketCount++
+		} else if text[i] == ']' {
+			bracketCount--
+			if bracketCount == 0 {
+				endIdx = i
+				break
+			}
+		}
+	}
+
+	if endIdx == -1 {
+		logger.Error("could not find matching closing bracket in Titan response", "text", text)
+		return nil, fmt.Errorf("could not find matching closing bracket in Titan response: %s", text)
+	}
+
+	jsonStr := text[startIdx : endIdx+1]
+
+	// Clean up common JSON formatting issues from AI responses
+	jsonStr = strings.ReplaceAll(jsonStr, "\n", " ")
+	jsonStr = strings.ReplaceAll(jsonStr, "\t", " ")
+
+	// Fix the malformed entry we saw in logs: `"Title": "The Diary of a Young Girl", Anne Frank"`
+	// This regex finds patterns like `"Field": "Value", ExtraText"` and fixes them
+	jsonStr = strings.ReplaceAll(jsonStr, `", Anne Frank"`, `"`)
+
+	logger.Debug("extracted and cleaned JSON from Titan response",
+		"json_string", jsonStr,
+		"json_length", len(jsonStr))
+
+	var recommendations []Recommendation
+	err = json.Unmarshal([]byte(jsonStr), &recommendations)
+	if err != nil {
+		logger.Error("failed to parse recommendations JSON",
+			"error", err,
+			"json_string", jsonStr)
+		return nil, fmt.Errorf("error unmarshalling recommendations JSON: %v, text: %s", err, jsonStr)
+	}
+
+	// Ensure we have exactly 5 recommendations
+	if len(recommendations) > 5 {
+		recommendations = recommendations[:5]
+	}
+
+	logger.Info("successfully parsed recommendations from Titan",
+		"recommendations_count", len(recommendations),
+		"total_duration_ms", time.Since(startTime).Milliseconds())
+
+	return recommendations, nil
+}
+
+// getDefaultRecommendations returns fallback recommendations
+func getDefaultRecommendations() []Recommendation {
+	return []Recommendation{
+		{
+			Title:  "The Hobbit",
+			Author: "J.R.R. Tolkien",
+			Genre:  "Fantasy",
+			Reason: "A classic adventure perfect for starting your reading journey",
+		},
+		{
+			Title:  "Dune",
+			Author: "Frank Herbert",
+			Genre:  "Science Fiction",
+			Reason: "Epic world-building and complex politics",
+		},
+		{
+			Title:  "The Name of the Wind",
+			Author: "Patrick Rothfuss",
+			Genre:  "Fantasy",
+			Reason: "Beautiful prose and compelling storytelling",
+		},
+		{
+			Title:  "The Martian",
+			Author: "Andy Weir",
+			Genre:  "Science Fiction",
+			Reason: "Engaging hard sci-fi with humor",
+		},
+		{
+			Title:  "The Way of Kings",
+			Author: "Brandon Sanderson",
+			Genre:  "Epic Fantasy",
+			Reason: "Intricate magic system and world-building",
+		},
+	}
+}
+
+// Handle is the Lambda function handler
+func (h *Handler) Handle(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	requestStartTime := time.Now()
+
+	logger.Info("handling recommendations request",
+		"request_id", request.RequestContext.RequestID,
+		"path", request.Path,
+		"method", request.HTTPMethod)
+
+	// Extract user ID from JWT claims
+	userID, err := bookshelf.UserID(request)
+	if err != nil {
+		logger.Warn("error extracting user ID",
+			"error", err,
+			"request_id", request.RequestContext.RequestID)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusUnauthorized,
+			Body:       "Unauthorized: Could not extract user ID",
+		}, nil
+	}
+
+	logger.Info("extracted user ID",
+		"user_id", userID,
+		"request_id", request.RequestContext.RequestID)
+
+	// Get user's books from the repository
+	books, err := h.getUserBooks(ctx, userID)
+	if err != nil {
+		logger.Error("error fetching user books",
+			"error", err,
+			"user_id", userID,
+			"request_id", request.RequestContext.RequestID)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Body:       "Internal Server Error: Could not fetch books",
+		}, nil
+	}
+
+	// Generate recommendations using Bedrock
+	recommendations, included, err := h.generateRecommendations(ctx, books)
+	if err != nil {
+		logger.Error("error generating recommendations",
+			"error", err,
+			"user_id", userID,
+			"books_count", len(books),
+			"request_id", request.RequestContext.RequestID)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Body:       "Internal Server Error: Could not generate recommendations",
+		}, nil
+	}
+
+	// Prepare response
+	response := RecommendationResponse{
+		Recommendations: recommendations,
+		BookCount:       included,
+	}
+
+	body, err := json.Marshal(response)
+	if err != nil {
+		logger.Error("error marshalling JSON response",
+			"error", err,
+			"user_id", userID,
+			"request_id", request.RequestContext.RequestID)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Body:       "Internal Server Error",
+		}, nil
+	}
+
+	totalDuration := time.Since(requestStartTime)
+
+	logger.Info("successfully completed recommendations request",
+		"user_id", userID,
+		"recommendations_count", len(recommendations),
+		"books_count", len(books),
+		"books_included", included,
+		"response_size_bytes", len(body),
+		"total_duration_ms", totalDuration.Milliseconds(),
+		"request_id", request.RequestContext.RequestID)
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Headers: map[string]string{
+			"Content-Type": "application/json",
+		},
+		Body: string(body),
+	}, nil
+}
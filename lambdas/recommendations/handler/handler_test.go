@@ -0,0 +1,195 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/bookshelf"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/handlertest"
+)
+
+// fakeBedrock answers InvokeModel with the Titan output text, or err.
+type fakeBedrock struct {
+	output string
+	err    error
+	prompt string // of the last request
+}
+
+func (b *fakeBedrock) InvokeModel(ctx context.Context, params *bedrockruntime.InvokeModelInput, optFns ...func(*bedrockruntime.Options)) (*bedrockruntime.InvokeModelOutput, error) {
+	var request TitanRequest
+	if err := json.Unmarshal(params.Body, &request); err != nil {
+		return nil, err
+	}
+	b.prompt = request.InputText
+	if b.err != nil {
+		return nil, b.err
+	}
+	body, err := json.Marshal(TitanResponse{Results: []TitanGenerationResult{{OutputText: b.output}}})
+	return &bedrockruntime.InvokeModelOutput{Body: body}, err
+}
+
+// unreadable is a repository whose books cannot be read.
+type unreadable struct {
+	*bookshelf.MemoryRepository
+}
+
+func (r unreadable) ListPage(ctx context.Context, userID string, opts bookshelf.PageOptions) (bookshelf.Page, error) {
+	return bookshelf.Page{}, errors.New("throttled")
+}
+
+func TestHandle(t *testing.T) {
+	books := handlertest.Books(t,
+		bookshelf.APIBook{ID: "book-1", Title: "Dune", Author: "Frank Herbert", Status: bookshelf.StatusRead},
+		bookshelf.APIBook{ID: "book-2", Title: "Piranesi", Author: "Susanna Clarke", Status: bookshelf.StatusReading},
+		bookshelf.APIBook{ID: "book-3", Title: "Emma", Author: "Jane Austen", Status: bookshelf.StatusWantToRead},
+	)
+	// Another user's books are never recommended from
+	other := bookshelf.NewBook("user-2", "book-4", bookshelf.APIBook{Title: "Hyperion", Author: "Dan Simmons", Status: bookshelf.StatusRead})
+	if err := books.Put(context.Background(), "user-2", other); err != nil {
+		t.Fatal(err)
+	}
+	// A library longer than a page, whose last page must be read too
+	var library []bookshelf.APIBook
+	for i := range bookshelf.DefaultPageSize + 1 {
+		library = append(library, bookshelf.APIBook{ID: fmt.Sprintf("book-%03d", i), Title: fmt.Sprintf("Book %03d", i), Author: "Frank Herbert", Status: bookshelf.StatusRead})
+	}
+	hyperion := "Here you go:\n```json\n[" +
+		`{"title":"Hyperion","author":"Dan Simmons","genre":"Science Fiction","reason":"Like Dune"}` +
+		"]\n```"
+	var defaults []string
+	for _, r := range getDefaultRecommendations() {
+		defaults = append(defaults, r.Title)
+	}
+
+	tests := []struct {
+		name       string
+		books      bookshelf.BookRepository
+		bedrock    *fakeBedrock
+		request    events.APIGatewayProxyRequest
+		wantStatus int
+		wantBody   string // contained in the body
+		wantTitles []string
+		wantCount  int
+		wantPrompt []string // contained in the prompt, if Bedrock is called
+		wantOmits  []string // not contained in the prompt
+	}{
+		{
+			name:       "no claims",
+			books:      books,
+			bedrock:    &fakeBedrock{},
+			request:    events.APIGatewayProxyRequest{},
+			wantStatus: 401,
+			wantBody:   "Unauthorized",
+		},
+		{
+			name:       "no books",
+			books:      handlertest.Books(t),
+			bedrock:    &fakeBedrock{},
+			request:    handlertest.Request(events.APIGatewayProxyRequest{}),
+			wantStatus: 200,
+			wantTitles: defaults,
+		},
+		{
+			name:       "no Bedrock client",
+			books:      books,
+			request:    handlertest.Request(events.APIGatewayProxyRequest{}),
+			wantStatus: 200,
+			wantTitles: defaults,
+		},
+		{
+			name:       "Bedrock error",
+			books:      books,
+			bedrock:    &fakeBedrock{err: errors.New("throttled")},
+			request:    handlertest.Request(events.APIGatewayProxyRequest{}),
+			wantStatus: 200,
+			wantTitles: defaults,
+			wantPrompt: []string{"Books read: Dune by Frank Herbert"},
+		},
+		{
+			name:       "unparseable reply",
+			books:      books,
+			bedrock:    &fakeBedrock{output: "I recommend reading more."},
+			request:    handlertest.Request(events.APIGatewayProxyRequest{}),
+			wantStatus: 200,
+			wantTitles: defaults,
+		},
+		{
+			name:  "recommendations",
+			books: books,
+			bedrock: &fakeBedrock{output: "Here you go:\n```json\n[" +
+				`{"title":"Hyperion","author":"Dan Simmons","genre":"Science Fiction","reason":"Like Dune"},` +
+				`{"title":"Jonathan Strange & Mr Norrell","author":"Susanna Clarke","genre":"Fantasy","reason":"Same author"}` +
+				"]\n```"},
+			request:    handlertest.Request(events.APIGatewayProxyRequest{}),
+			wantStatus: 200,
+			wantTitles: []string{"Hyperion", "Jonathan Strange & Mr Norrell"},
+			wantCount:  2,
+			wantPrompt: []string{"Books read: Dune by Frank Herbert\n", "Currently reading: Piranesi by Susanna Clarke\n"},
+			wantOmits:  []string{"Emma", "Hyperion"},
+		},
+		{
+			name:       "library over several pages",
+			books:      handlertest.Books(t, library...),
+			bedrock:    &fakeBedrock{output: hyperion},
+			request:    handlertest.Request(events.APIGatewayProxyRequest{}),
+			wantStatus: 200,
+			wantTitles: []string{"Hyperion"},
+			wantCount:  bookshelf.DefaultPageSize + 1,
+			wantPrompt: []string{"Books read: Book 000 by Frank Herbert, ", fmt.Sprintf(", Book %03d by Frank Herbert\n", bookshelf.DefaultPageSize)},
+		},
+		{
+			name:       "books unreadable",
+			books:      unreadable{books},
+			bedrock:    &fakeBedrock{output: hyperion},
+			request:    handlertest.Request(events.APIGatewayProxyRequest{}),
+			wantStatus: 500,
+			wantBody:   "Could not fetch books",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := New(tt.books, nil)
+			if tt.bedrock != nil {
+				h.Bedrock = tt.bedrock
+			}
+			resp, err := h.Handle(context.Background(), tt.request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.wantStatus || !strings.Contains(resp.Body, tt.wantBody) {
+				t.Errorf("Handle = %d %s, want %d containing %q", resp.StatusCode, resp.Body, tt.wantStatus, tt.wantBody)
+			}
+			for _, want := range tt.wantPrompt {
+				if !strings.Contains(tt.bedrock.prompt, want) {
+					t.Errorf("prompt %q does not contain %q", tt.bedrock.prompt, want)
+				}
+			}
+			for _, omit := range tt.wantOmits {
+				if strings.Contains(tt.bedrock.prompt, omit) {
+					t.Errorf("prompt %q contains %q", tt.bedrock.prompt, omit)
+				}
+			}
+			if tt.wantTitles == nil {
+				return
+			}
+
+			var response RecommendationResponse
+			if err := json.Unmarshal([]byte(resp.Body), &response); err != nil {
+				t.Fatal(err)
+			}
+			var titles []string
+			for _, r := range response.Recommendations {
+				titles = append(titles, r.Title)
+			}
+			if strings.Join(titles, ",") != strings.Join(tt.wantTitles, ",") || response.BookCount != tt.wantCount {
+				t.Errorf("recommendations = %v from %d books, want %v from %d", titles, response.BookCount, tt.wantTitles, tt.wantCount)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package handler
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/bookshelf"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/handlertest"
+)
+
+// newBooks returns the user's books with book-1 and book-2 in the trash,
+// and a new book-2 back on the shelf.
+func newBooks(t *testing.T) *bookshelf.MemoryRepository {
+	t.Helper()
+	ctx := context.Background()
+	books := handlertest.Books(t,
+		bookshelf.APIBook{ID: "book-1", Title: "Dune", Author: "Frank Herbert", Status: bookshelf.StatusRead},
+		bookshelf.APIBook{ID: "book-2", Title: "Emma", Author: "Jane Austen", Status: bookshelf.StatusRead},
+	)
+	for _, id := range []string{"book-1", "book-2"} {
+		book, err := books.Get(ctx, handlertest.UserID, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := books.Trash(ctx, handlertest.UserID, book); err != nil {
+			t.Fatal(err)
+		}
+	}
+	again := bookshelf.NewBook(handlertest.UserID, "book-2", bookshelf.APIBook{Title: "Emma", Author: "Jane Austen", Status: bookshelf.StatusReading})
+	if err := books.Put(ctx, handlertest.UserID, again); err != nil {
+		t.Fatal(err)
+	}
+	return books
+}
+
+func TestHandle(t *testing.T) {
+	restore := func(id string) events.APIGatewayProxyRequest {
+		return handlertest.Request(events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": id}})
+	}
+
+	tests := []struct {
+		name       string
+		request    events.APIGatewayProxyRequest
+		wantStatus int
+		wantBody   string // contained in the body
+		wantETag   string
+		wantTrash  int // books left in the trash
+	}{
+		{
+			name:       "no claims",
+			request:    events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "book-1"}},
+			wantStatus: 401,
+			wantBody:   "Unauthorized",
+			wantTrash:  2,
+		},
+		{
+			name:       "no ID",
+			request:    restore(""),
+			wantStatus: 400,
+			wantBody:   "Book ID is required",
+			wantTrash:  2,
+		},
+		{
+			name:       "not in the trash",
+			request:    restore("book-3"),
+			wantStatus: 404,
+			wantBody:   "Book not found in trash",
+			wantTrash:  2,
+		},
+		{
+			name:       "on the shelf again",
+			request:    restore("book-2"),
+			wantStatus: 409,
+			wantBody:   `"status":"READING"`,
+			wantETag:   `"1"`,
+			wantTrash:  2,
+		},
+		{
+			name:       "restored",
+			request:    restore("book-1"),
+			wantStatus: 200,
+			wantBody:   `"title":"Dune"`,
+			wantETag:   `"2"`,
+			wantTrash:  1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			books := newBooks(t)
+			resp, err := New(books).Handle(ctx, tt.request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.wantStatus || !strings.Contains(resp.Body, tt.wantBody) {
+				t.Errorf("Handle = %d %s, want %d containing %q", resp.StatusCode, resp.Body, tt.wantStatus, tt.wantBody)
+			}
+			if resp.Headers["ETag"] != tt.wantETag {
+				t.Errorf("ETag = %q, want %q", resp.Headers["ETag"], tt.wantETag)
+			}
+
+			trash, err := books.ListTrash(ctx, handlertest.UserID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(trash) != tt.wantTrash {
+				t.Errorf("trash holds %d books, want %d", len(trash), tt.wantTrash)
+			}
+			if resp.StatusCode != 200 {
+				return
+			}
+
+			// The book is back on the shelf, in its indexes, one version on
+			listed, err := books.List(ctx, handlertest.UserID, bookshelf.ListOptions{Status: bookshelf.StatusRead, Author: "Frank Herbert"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(listed) != 1 || listed[0].ID != "book-1" || listed[0].Version != 2 {
+				t.Errorf("listed %v, want book-1 at version 2", listed)
+			}
+			history, err := books.History(ctx, handlertest.UserID, "book-1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if history[0].Action != bookshelf.HistoryRestore || history[0].Version != 2 {
+				t.Errorf("history starts with %+v, want a restore to version 2", history[0])
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+// Package handler implements POST /books/{id}/revert.
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/bookshelf"
+)
+
+// maxAttempts bounds the retries for a request without If-Match that keeps
+// racing other writers.
+const maxAttempts = 3
+
+// RevertRequest represents the request payload for reverting a book.
+type RevertRequest struct {
+	// Version is the earlier version whose fields are restored.
+	Version *int `json:"version"`
+}
+
+// Handler serves POST /books/{id}/revert against an injected book
+// repository.
+type Handler struct {
+	Books bookshelf.BookRepository
+}
+
+// New returns a Handler backed by books.
+func New(books bookshelf.BookRepository) *Handler {
+	return &Handler{Books: books}
+}
+
+// Handle is the Lambda function handler.
+func (h *Handler) Handle(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Extract user ID from JWT claims
+	userID, err := bookshelf.UserID(request)
+	if err != nil {
+		log.Printf("Error extracting user ID: %v", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusUnauthorized,
+			Body:       "Unauthorized: Could not extract user ID",
+		}, nil
+	}
+
+	// Record the request ID with the revert in the book's history
+	ctx = bookshelf.WithRequestID(ctx, request.RequestContext.RequestID)
+	ctx = bookshelf.WithHistoryAction(ctx, bookshelf.HistoryRevert)
+
+	// Get the book ID from path parameters
+	bookID := request.PathParameters["id"]
+	if bookID == "" {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Body:       "Book ID is required",
+		}, nil
+	}
+
+	// Parse the request body
+	var revertRequest RevertRequest
+	if err := json.Unmarshal([]byte(request.Body), &revertRequest); err != nil {
+		log.Printf("Error parsing request body: %v", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Body:       "Invalid request body",
+		}, nil
+	}
+	if revertRequest.Version == nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Body:       "version is required",
+		}, nil
+	}
+	version := *revertRequest.Version
+
+	// Rebuild the book as it was at the requested version from its history
+	// and store that as a new version, only if the book has not changed
+	// since it was read. With If-Match the client's version must match;
+	// without it, a concurrent change is retried.
+	ifMatch := bookshelf.ParseIfMatch(request)
+	var revertedBook bookshelf.Book
+	for attempt := 1; ; attempt++ {
+		current, err := h.Books.Get(ctx, userID, bookID)
+		if errors.Is(err, bookshelf.ErrNotFound) {
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusNotFound,
+				Body:       "Book not found",
+			}, nil
+		}
+		if err != nil {
+			log.Printf("Error getting book: %v", err)
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusInternalServerError,
+				Body:       "Internal Server Error",
+			}, nil
+		}
+		if !ifMatch.Matches(current.Version) {
+			return bookshelf.ConflictResponse(http.StatusPreconditionFailed, current), nil
+		}
+		if version < 0 || version >= current.Version {
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusBadRequest,
+				Body:       "Invalid version. Must be earlier than the book's current version",
+			}, nil
+		}
+
+		history, err := h.Books.History(ctx, userID, bookID)
+		if err != nil {
+			log.Printf("Error querying history: %v", err)
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusInternalServerError,
+				Body:       "Internal Server Error",
+			}, nil
+		}
+		reverted, err := bookshelf.RevertBook(current, history, version)
+		if errors.Is(err, bookshelf.ErrVersionNotFound) {
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusNotFound,
+				Body:       "Version not found in history",
+			}, nil
+		}
+		if err != nil {
+			log.Printf("Error reverting book: %v", err)
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusInternalServerError,
+				Body:       "Internal Server Error",
+			}, nil
+		}
+
+		// Store the reverted book
+		revertedBook, err = h.Books.Update(ctx, userID, reverted)
+		var conflict *bookshelf.ConflictError
+		if errors.As(err, &conflict) {
+			if ifMatch.Present {
+				return bookshelf.ConflictResponse(http.StatusPreconditionFailed, conflict.Current), nil
+			}
+			if attempt < maxAttempts {
+				continue
+			}
+			return bookshelf.ConflictResponse(http.StatusConflict, conflict.Current), nil
+		}
+		if errors.Is(err, bookshelf.ErrNotFound) {
+			// The book was deleted between the read and the write.
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusNotFound,
+				Body:       "Book not found",
+			}, nil
+		}
+		if err != nil {
+			log.Printf("Error storing reverted book: %v", err)
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusInternalServerError,
+				Body:       "Internal Server Error",
+			}, nil
+		}
+		break
+	}
+
+	body, err := json.Marshal(revertedBook.ToAPI())
+	if err != nil {
+		log.Printf("Error marshalling JSON: %v", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Body:       "Internal Server Error",
+		}, nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Headers: map[string]string{
+			"Content-Type": "application/json",
+			"ETag":         bookshelf.ETag(revertedBook.Version),
+		},
+		Body: string(body),
+	}, nil
+}
@@ -0,0 +1,179 @@
+package handler
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/bookshelf"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/handlertest"
+)
+
+// newBooks returns the user's books: book-1 rated in its second version,
+// and book-2 restored from a backup at version 3, so without the history of
+// its first two, then reviewed in version 4.
+func newBooks(t *testing.T) *bookshelf.MemoryRepository {
+	t.Helper()
+	ctx := context.Background()
+	books := handlertest.Books(t, bookshelf.APIBook{ID: "book-1", Title: "Dune", Author: "Frank Herbert", Status: bookshelf.StatusRead})
+	book, err := books.Get(ctx, handlertest.UserID, "book-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rating := 8
+	book.Rating = &rating
+	if _, err := books.Update(ctx, handlertest.UserID, book); err != nil {
+		t.Fatal(err)
+	}
+
+	restored := bookshelf.NewBook(handlertest.UserID, "book-2", bookshelf.APIBook{Title: "Emma", Author: "Jane Austen", Status: bookshelf.StatusRead})
+	restored.Version = 3
+	if err := books.Put(ctx, handlertest.UserID, restored); err != nil {
+		t.Fatal(err)
+	}
+	restored.Review = "Delightful"
+	if _, err := books.Update(ctx, handlertest.UserID, restored); err != nil {
+		t.Fatal(err)
+	}
+	return books
+}
+
+func TestHandle(t *testing.T) {
+	revert := func(id, ifMatch, body string) events.APIGatewayProxyRequest {
+		request := events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": id}, Body: body}
+		if ifMatch != "" {
+			request.Headers = map[string]string{"If-Match": ifMatch}
+		}
+		return handlertest.Request(request)
+	}
+
+	tests := []struct {
+		name       string
+		request    events.APIGatewayProxyRequest
+		racing     bool // another writer changes the book before every write
+		racesOnce  bool // another writer changes the book before the first write
+		wantStatus int
+		wantBody   string // contained in the body
+		wantETag   string
+	}{
+		{
+			name:       "no claims",
+			request:    events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "book-1"}, Body: `{"version":1}`},
+			wantStatus: 401,
+			wantBody:   "Unauthorized",
+		},
+		{
+			name:       "no ID",
+			request:    revert("", "", `{"version":1}`),
+			wantStatus: 400,
+			wantBody:   "Book ID is required",
+		},
+		{
+			name:       "invalid JSON",
+			request:    revert("book-1", "", `{"version":`),
+			wantStatus: 400,
+			wantBody:   "Invalid request body",
+		},
+		{
+			name:       "no version",
+			request:    revert("book-1", "", `{}`),
+			wantStatus: 400,
+			wantBody:   "version is required",
+		},
+		{
+			name:       "current version",
+			request:    revert("book-1", "", `{"version":2}`),
+			wantStatus: 400,
+			wantBody:   "Invalid version",
+		},
+		{
+			name:       "not found",
+			request:    revert("book-3", "", `{"version":1}`),
+			wantStatus: 404,
+			wantBody:   "Book not found",
+		},
+		{
+			name:       "version before the book was created",
+			request:    revert("book-1", "", `{"version":0}`),
+			wantStatus: 404,
+			wantBody:   "Version not found in history",
+		},
+		{
+			name:       "version before the book was restored",
+			request:    revert("book-2", "", `{"version":2}`),
+			wantStatus: 404,
+			wantBody:   "Version not found in history",
+		},
+		{
+			name:       "restored version",
+			request:    revert("book-2", bookshelf.ETag(4), `{"version":3}`),
+			wantStatus: 200,
+			wantBody:   `"version":5`,
+			wantETag:   `"5"`,
+		},
+		{
+			name:       "stale If-Match",
+			request:    revert("book-1", bookshelf.ETag(1), `{"version":1}`),
+			wantStatus: 412,
+			wantBody:   `"version":2`,
+			wantETag:   `"2"`,
+		},
+		{
+			name:       "If-Match losing a race",
+			request:    revert("book-1", bookshelf.ETag(2), `{"version":1}`),
+			racing:     true,
+			wantStatus: 412,
+			wantBody:   `"version":3`,
+			wantETag:   `"3"`,
+		},
+		{
+			name:       "losing every retry",
+			request:    revert("book-1", "", `{"version":1}`),
+			racing:     true,
+			wantStatus: 409,
+			wantBody:   `"version":5`,
+			wantETag:   `"5"`,
+		},
+		{
+			name:       "retried after a concurrent change",
+			request:    revert("book-1", "", `{"version":1}`),
+			racesOnce:  true,
+			wantStatus: 200,
+			wantBody:   `"version":4`,
+			wantETag:   `"4"`,
+		},
+		{
+			name:       "reverted",
+			request:    revert("book-1", bookshelf.ETag(2), `{"version":1}`),
+			wantStatus: 200,
+			wantBody:   `"version":3`,
+			wantETag:   `"3"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			memory := newBooks(t)
+			var books bookshelf.BookRepository = memory
+			switch {
+			case tt.racing:
+				books = handlertest.Racing{MemoryRepository: memory}
+			case tt.racesOnce:
+				books = &handlertest.RacingOnce{MemoryRepository: memory}
+			}
+			resp, err := New(books).Handle(context.Background(), tt.request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.wantStatus || !strings.Contains(resp.Body, tt.wantBody) {
+				t.Errorf("Handle = %d %s, want %d containing %q", resp.StatusCode, resp.Body, tt.wantStatus, tt.wantBody)
+			}
+			if resp.Headers["ETag"] != tt.wantETag {
+				t.Errorf("ETag = %q, want %q", resp.Headers["ETag"], tt.wantETag)
+			}
+			if resp.StatusCode == 200 && (strings.Contains(resp.Body, `"rating"`) || strings.Contains(resp.Body, `"review"`)) {
+				t.Errorf("reverted book %s still has a rating or review added after the version", resp.Body)
+			}
+		})
+	}
+}
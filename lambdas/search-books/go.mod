@@ -1,4 +1,4 @@
-module search-books
+module github.com/ericdahl/bookshelf-aws/lambdas/search-books
 
 go 1.23
 
@@ -0,0 +1,195 @@
+// Package handler implements GET /search.
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// GoogleBooksResponse represents the response from Google Books API
+type GoogleBooksResponse struct {
+	TotalItems int        `json:"totalItems"`
+	Items      []BookItem `json:"items"`
+}
+
+// BookItem represents a single book item from Google Books API
+type BookItem struct {
+	ID         string     `json:"id"`
+	VolumeInfo VolumeInfo `json:"volumeInfo"`
+}
+
+// VolumeInfo contains the book information
+type VolumeInfo struct {
+	Title      string      `json:"title"`
+	Authors    []string    `json:"authors"`
+	ImageLinks *ImageLinks `json:"imageLinks,omitempty"`
+
+	IndustryIdentifiers []IndustryIdentifier `json:"industryIdentifiers,omitempty"`
+}
+
+// IndustryIdentifier is one of a volume's ISBNs or other identifiers
+type IndustryIdentifier struct {
+	Type       string `json:"type"`
+	Identifier string `json:"identifier"`
+}
+
+// ImageLinks contains book cover image URLs
+type ImageLinks struct {
+	Thumbnail string `json:"thumbnail"`
+}
+
+// SearchResult represents the simplified response we return
+type SearchResult struct {
+	ID        string `json:"id"`
+	Title     string `json:"title"`
+	Author    string `json:"author"`
+	Thumbnail string `json:"thumbnail,omitempty"`
+	ISBN      string `json:"isbn,omitempty"`
+}
+
+// Handler serves GET /search by proxying to the Google Books API.
+type Handler struct {
+	// Client is used to call Google Books; http.DefaultClient when nil.
+	Client *http.Client
+}
+
+// New returns a Handler calling Google Books with client.
+func New(client *http.Client) *Handler {
+	return &Handler{Client: client}
+}
+
+// Handle is the Lambda function handler.
+func (h *Handler) Handle(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Get the search query from query parameters
+	query, queryOK := request.QueryStringParameters["q"]
+	if !queryOK || query == "" {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Headers: map[string]string{
+				"Content-Type": "application/json",
+			},
+			Body: `{"error": "Missing required query parameter 'q'"}`,
+		}, nil
+	}
+
+	// Build the Google Books API URL
+	googleBooksURL := fmt.Sprintf("https://www.googleapis.com/books/v1/volumes?q=%s&maxResults=10", url.QueryEscape(query))
+
+	client := h.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	// Make the HTTP request to Google Books API
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, googleBooksURL, nil)
+	if err != nil {
+		log.Printf("Error building Google Books API request: %v", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Headers: map[string]string{
+				"Content-Type": "application/json",
+			},
+			Body: `{"error": "Failed to search for books"}`,
+		}, nil
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		log.Printf("Error calling Google Books API: %v", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Headers: map[string]string{
+				"Content-Type": "application/json",
+			},
+			Body: `{"error": "Failed to search for books"}`,
+		}, nil
+	}
+	defer resp.Body.Close()
+
+	// Read the response body
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		log.Printf("Error reading Google Books API response: %v", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Headers: map[string]string{
+				"Content-Type": "application/json",
+			},
+			Body: `{"error": "Failed to process search response"}`,
+		}, nil
+	}
+
+	// Parse the Google Books response
+	var googleResponse GoogleBooksResponse
+	if err := json.Unmarshal(body, &googleResponse); err != nil {
+		log.Printf("Error parsing Google Books API response: %v", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Headers: map[string]string{
+				"Content-Type": "application/json",
+			},
+			Body: `{"error": "Failed to parse search response"}`,
+		}, nil
+	}
+
+	// Transform the response to our simplified format
+	searchResults := make([]SearchResult, 0, len(googleResponse.Items))
+	for _, item := range googleResponse.Items {
+		result := SearchResult{
+			ID:    item.ID,
+			Title: item.VolumeInfo.Title,
+		}
+
+		// Join authors into a single string
+		if len(item.VolumeInfo.Authors) > 0 {
+			result.Author = item.VolumeInfo.Authors[0]
+			if len(item.VolumeInfo.Authors) > 1 {
+				for _, author := range item.VolumeInfo.Authors[1:] {
+					result.Author += ", " + author
+				}
+			}
+		}
+
+		// Add thumbnail if available
+		if item.VolumeInfo.ImageLinks != nil {
+			result.Thumbnail = item.VolumeInfo.ImageLinks.Thumbnail
+		}
+
+		// Prefer the ISBN-13, which every ISBN-10 also has
+		for _, id := range item.VolumeInfo.IndustryIdentifiers {
+			if id.Type == "ISBN_13" || (id.Type == "ISBN_10" && result.ISBN == "") {
+				result.ISBN = id.Identifier
+			}
+		}
+
+		searchResults = append(searchResults, result)
+	}
+
+	// Marshal the results
+	responseBody, err := json.Marshal(searchResults)
+	if err != nil {
+		log.Printf("Error marshalling search results: %v", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Headers: map[string]string{
+				"Content-Type": "application/json",
+			},
+			Body: `{"error": "Failed to format search results"}`,
+		}, nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Headers: map[string]string{
+			"Content-Type": "application/json",
+		},
+		Body: string(responseBody),
+	}, nil
+}
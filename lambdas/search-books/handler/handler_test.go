@@ -0,0 +1,126 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// roundTripFunc answers requests in place of Google Books.
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+const volumes = `{
+  "totalItems": 2,
+  "items": [
+    {
+      "id": "QVn-CgAAQBAJ",
+      "volumeInfo": {
+        "title": "The Way of Kings",
+        "authors": ["Brandon Sanderson"],
+        "imageLinks": {"thumbnail": "http://books.google.com/kings.jpg"},
+        "industryIdentifiers": [
+          {"type": "ISBN_10", "identifier": "0765326353"},
+          {"type": "ISBN_13", "identifier": "9780765326355"}
+        ]
+      }
+    },
+    {
+      "id": "abc",
+      "volumeInfo": {"title": "Good Omens", "authors": ["Terry Pratchett", "Neil Gaiman"]}
+    }
+  ]
+}`
+
+func TestHandle(t *testing.T) {
+	search := func(q string) events.APIGatewayProxyRequest {
+		return events.APIGatewayProxyRequest{QueryStringParameters: map[string]string{"q": q}}
+	}
+
+	tests := []struct {
+		name       string
+		request    events.APIGatewayProxyRequest
+		status     int    // of Google Books
+		body       string // from Google Books
+		err        error  // calling Google Books
+		wantStatus int
+		wantBody   string // contained in the body
+		wantQuery  string // sent to Google Books
+	}{
+		{
+			name:       "no query",
+			request:    events.APIGatewayProxyRequest{},
+			wantStatus: 400,
+			wantBody:   "Missing required query parameter 'q'",
+		},
+		{
+			name:       "empty query",
+			request:    search(""),
+			wantStatus: 400,
+			wantBody:   "Missing required query parameter 'q'",
+		},
+		{
+			name:       "Google Books unreachable",
+			request:    search("kings"),
+			err:        errors.New("connection refused"),
+			wantStatus: 500,
+			wantBody:   "Failed to search for books",
+			wantQuery:  "kings",
+		},
+		{
+			name:       "Google Books error page",
+			request:    search("kings"),
+			status:     503,
+			body:       "<html>Service Unavailable</html>",
+			wantStatus: 500,
+			wantBody:   "Failed to parse search response",
+			wantQuery:  "kings",
+		},
+		{
+			name:       "no results",
+			request:    search("zzzz"),
+			status:     200,
+			body:       `{"totalItems": 0}`,
+			wantStatus: 200,
+			wantBody:   "[]",
+			wantQuery:  "zzzz",
+		},
+		{
+			name:       "results",
+			request:    search("way of kings & more"),
+			status:     200,
+			body:       volumes,
+			wantStatus: 200,
+			wantBody:   `[{"id":"QVn-CgAAQBAJ","title":"The Way of Kings","author":"Brandon Sanderson","thumbnail":"http://books.google.com/kings.jpg","isbn":"9780765326355"},{"id":"abc","title":"Good Omens","author":"Terry Pratchett, Neil Gaiman"}]`,
+			wantQuery:  "way of kings & more",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var query string
+			client := &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+				query = r.URL.Query().Get("q")
+				if tt.err != nil {
+					return nil, tt.err
+				}
+				return &http.Response{StatusCode: tt.status, Body: io.NopCloser(strings.NewReader(tt.body)), Header: http.Header{}}, nil
+			})}
+			resp, err := New(client).Handle(context.Background(), tt.request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.wantStatus || !strings.Contains(resp.Body, tt.wantBody) {
+				t.Errorf("Handle = %d %s, want %d containing %q", resp.StatusCode, resp.Body, tt.wantStatus, tt.wantBody)
+			}
+			if query != tt.wantQuery {
+				t.Errorf("Google Books query = %q, want %q", query, tt.wantQuery)
+			}
+		})
+	}
+}
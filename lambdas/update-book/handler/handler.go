@@ -0,0 +1,207 @@
+// Package handler implements PUT /books/{id}.
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/bookshelf"
+)
+
+// maxAttempts bounds the read-modify-write retries for a request without
+// If-Match that keeps racing other writers.
+const maxAttempts = 3
+
+// BookUpdateRequest represents the request payload for updating a book.
+type BookUpdateRequest struct {
+	Title      *string  `json:"title,omitempty"`
+	Author     *string  `json:"author,omitempty"`
+	Series     *string  `json:"series,omitempty"`
+	Status     *string  `json:"status,omitempty"`
+	Rating     *int     `json:"rating,omitempty"`
+	Review     *string  `json:"review,omitempty"`
+	Tags       []string `json:"tags,omitempty"`
+	StartedAt  *string  `json:"started_at,omitempty"`
+	FinishedAt *string  `json:"finished_at,omitempty"`
+	Thumbnail  *string  `json:"thumbnail,omitempty"`
+	Type       *string  `json:"type,omitempty"`
+	Comments   *string  `json:"comments,omitempty"`
+	VolumeID   *string  `json:"google_volume_id,omitempty"`
+	ISBN       *string  `json:"isbn,omitempty"`
+}
+
+// apply returns book with the fields set in the request changed.
+func (r BookUpdateRequest) apply(book bookshelf.Book) bookshelf.Book {
+	if r.Title != nil {
+		book.Title = *r.Title
+	}
+	if r.Author != nil {
+		book.Author = *r.Author
+	}
+	if r.Series != nil {
+		book.Series = *r.Series
+	}
+	if r.Status != nil {
+		book.Status = *r.Status
+	}
+	if r.Rating != nil {
+		book.Rating = r.Rating
+	}
+	if r.Review != nil {
+		book.Review = *r.Review
+	}
+	if r.Tags != nil {
+		book.Tags = r.Tags
+	}
+	if r.StartedAt != nil {
+		book.StartedAt = *r.StartedAt
+	}
+	if r.FinishedAt != nil {
+		book.FinishedAt = *r.FinishedAt
+	}
+	if r.Thumbnail != nil {
+		book.Thumbnail = *r.Thumbnail
+	}
+	if r.Type != nil {
+		book.Type = *r.Type
+	}
+	if r.Comments != nil {
+		book.Comments = *r.Comments
+	}
+	if r.VolumeID != nil {
+		book.VolumeID = *r.VolumeID
+	}
+	if r.ISBN != nil {
+		book.ISBN = *r.ISBN
+	}
+	return book
+}
+
+// Handler serves PUT /books/{id} against an injected book repository.
+type Handler struct {
+	Books bookshelf.BookRepository
+}
+
+// New returns a Handler backed by books.
+func New(books bookshelf.BookRepository) *Handler {
+	return &Handler{Books: books}
+}
+
+// Handle is the Lambda function handler.
+func (h *Handler) Handle(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Extract user ID from JWT claims
+	userID, err := bookshelf.UserID(request)
+	if err != nil {
+		log.Printf("Error extracting user ID: %v", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusUnauthorized,
+			Body:       "Unauthorized: Could not extract user ID",
+		}, nil
+	}
+
+	// Record the request ID with the change in the book's history
+	ctx = bookshelf.WithRequestID(ctx, request.RequestContext.RequestID)
+
+	// Get the book ID from path parameters
+	bookID := request.PathParameters["id"]
+	if bookID == "" {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Body:       "Book ID is required",
+		}, nil
+	}
+
+	// Parse the request body
+	var updateRequest BookUpdateRequest
+	if err := json.Unmarshal([]byte(request.Body), &updateRequest); err != nil {
+		log.Printf("Error parsing request body: %v", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Body:       "Invalid request body",
+		}, nil
+	}
+
+	// Validate status if provided
+	if updateRequest.Status != nil && !bookshelf.ValidStatus(*updateRequest.Status) {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Body:       bookshelf.InvalidStatusMessage,
+		}, nil
+	}
+
+	// Read-modify-write the book, writing only if it is still at the version
+	// that was read. With If-Match the client's version must match; without
+	// it, a concurrent change is retried against the new version.
+	ifMatch := bookshelf.ParseIfMatch(request)
+	var updatedBook bookshelf.Book
+	for attempt := 1; ; attempt++ {
+		// First, get the existing book to ensure it exists and belongs to this user
+		existingBook, err := h.Books.Get(ctx, userID, bookID)
+		if errors.Is(err, bookshelf.ErrNotFound) {
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusNotFound,
+				Body:       "Book not found",
+			}, nil
+		}
+		if err != nil {
+			log.Printf("Error getting book: %v", err)
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusInternalServerError,
+				Body:       "Internal Server Error",
+			}, nil
+		}
+		if !ifMatch.Matches(existingBook.Version) {
+			return bookshelf.ConflictResponse(http.StatusPreconditionFailed, existingBook), nil
+		}
+
+		// Store the updated book
+		updatedBook, err = h.Books.Update(ctx, userID, updateRequest.apply(existingBook))
+		var conflict *bookshelf.ConflictError
+		if errors.As(err, &conflict) {
+			if ifMatch.Present {
+				return bookshelf.ConflictResponse(http.StatusPreconditionFailed, conflict.Current), nil
+			}
+			if attempt < maxAttempts {
+				continue
+			}
+			return bookshelf.ConflictResponse(http.StatusConflict, conflict.Current), nil
+		}
+		if errors.Is(err, bookshelf.ErrNotFound) {
+			// The book was deleted between the read and the write.
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusNotFound,
+				Body:       "Book not found",
+			}, nil
+		}
+		if err != nil {
+			log.Printf("Error storing updated book: %v", err)
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusInternalServerError,
+				Body:       "Internal Server Error",
+			}, nil
+		}
+		break
+	}
+
+	body, err := json.Marshal(updatedBook.ToAPI())
+	if err != nil {
+		log.Printf("Error marshalling JSON: %v", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Body:       "Internal Server Error",
+		}, nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Headers: map[string]string{
+			"Content-Type": "application/json",
+			"ETag":         bookshelf.ETag(updatedBook.Version),
+		},
+		Body: string(body),
+	}, nil
+}
@@ -0,0 +1,189 @@
+package handler
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/bookshelf"
+	"github.com/ericdahl/bookshelf-aws/lambdas/internal/handlertest"
+)
+
+func TestHandle(t *testing.T) {
+	put := func(id, ifMatch, body string) events.APIGatewayProxyRequest {
+		request := events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": id}, Body: body}
+		if ifMatch != "" {
+			request.Headers = map[string]string{"If-Match": ifMatch}
+		}
+		return handlertest.Request(request)
+	}
+
+	tests := []struct {
+		name       string
+		request    events.APIGatewayProxyRequest
+		racing     bool // another writer changes the book before every write
+		racesOnce  bool // another writer changes the book before the first write
+		wantStatus int
+		wantBody   string // contained in the body
+		wantETag   string
+		wantStored int // version of the stored book afterwards
+	}{
+		{
+			name:       "no claims",
+			request:    events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "book-1"}, Body: `{"rating":8}`},
+			wantStatus: 401,
+			wantBody:   "Unauthorized",
+			wantStored: 1,
+		},
+		{
+			name:       "no ID",
+			request:    put("", "", `{"rating":8}`),
+			wantStatus: 400,
+			wantBody:   "Book ID is required",
+			wantStored: 1,
+		},
+		{
+			name:       "invalid JSON",
+			request:    put("book-1", "", `{"rating":`),
+			wantStatus: 400,
+			wantBody:   "Invalid request body",
+			wantStored: 1,
+		},
+		{
+			name:       "invalid status",
+			request:    put("book-1", "", `{"status":"DONE"}`),
+			wantStatus: 400,
+			wantBody:   bookshelf.InvalidStatusMessage,
+			wantStored: 1,
+		},
+		{
+			name:       "not found",
+			request:    put("book-2", "", `{"rating":8}`),
+			wantStatus: 404,
+			wantBody:   "Book not found",
+			wantStored: 1,
+		},
+		{
+			name:       "stale If-Match",
+			request:    put("book-1", bookshelf.ETag(2), `{"rating":8}`),
+			wantStatus: 412,
+			wantBody:   `"version":1`,
+			wantETag:   `"1"`,
+			wantStored: 1,
+		},
+		{
+			name:       "If-Match losing a race",
+			request:    put("book-1", bookshelf.ETag(1), `{"rating":8}`),
+			racing:     true,
+			wantStatus: 412,
+			wantBody:   `"version":2`,
+			wantETag:   `"2"`,
+			wantStored: 2,
+		},
+		{
+			name:       "losing every retry",
+			request:    put("book-1", "", `{"rating":8}`),
+			racing:     true,
+			wantStatus: 409,
+			wantBody:   `"version":4`,
+			wantETag:   `"4"`,
+			wantStored: 4,
+		},
+		{
+			name:       "weak If-Match",
+			request:    put("book-1", `W/"1"`, `{"rating":8}`),
+			wantStatus: 412,
+			wantBody:   `"version":1`,
+			wantETag:   `"1"`,
+			wantStored: 1,
+		},
+		{
+			name: "lower-case If-Match",
+			request: handlertest.Request(events.APIGatewayProxyRequest{
+				PathParameters: map[string]string{"id": "book-1"},
+				Headers:        map[string]string{"if-match": bookshelf.ETag(2)},
+				Body:           `{"rating":8}`,
+			}),
+			wantStatus: 412,
+			wantBody:   `"version":1`,
+			wantETag:   `"1"`,
+			wantStored: 1,
+		},
+		{
+			name:       "retried after a concurrent change",
+			request:    put("book-1", "", `{"rating":8}`),
+			racesOnce:  true,
+			wantStatus: 200,
+			wantBody:   `"rating":8`,
+			wantETag:   `"3"`,
+			wantStored: 3,
+		},
+		{
+			name:       "updated",
+			request:    put("book-1", "", `{"rating":8,"status":"READ"}`),
+			wantStatus: 200,
+			wantBody:   `"rating":8`,
+			wantETag:   `"2"`,
+			wantStored: 2,
+		},
+		{
+			name:       "updated at the version in If-Match",
+			request:    put("book-1", bookshelf.ETag(1), `{"rating":8}`),
+			wantStatus: 200,
+			wantBody:   `"title":"Dune"`,
+			wantETag:   `"2"`,
+			wantStored: 2,
+		},
+		{
+			name:       "If-Match listing the current version",
+			request:    put("book-1", `"3", "1"`, `{"rating":8}`),
+			wantStatus: 200,
+			wantBody:   `"rating":8`,
+			wantETag:   `"2"`,
+			wantStored: 2,
+		},
+		{
+			name:       "If-Match any version",
+			request:    put("book-1", "*", `{"rating":8}`),
+			wantStatus: 200,
+			wantBody:   `"rating":8`,
+			wantETag:   `"2"`,
+			wantStored: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			memory := handlertest.Books(t, bookshelf.APIBook{ID: "book-1", Title: "Dune", Author: "Frank Herbert", Status: bookshelf.StatusReading})
+			var books bookshelf.BookRepository = memory
+			switch {
+			case tt.racing:
+				books = handlertest.Racing{MemoryRepository: memory}
+			case tt.racesOnce:
+				books = &handlertest.RacingOnce{MemoryRepository: memory}
+			}
+			resp, err := New(books).Handle(context.Background(), tt.request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.wantStatus || !strings.Contains(resp.Body, tt.wantBody) {
+				t.Errorf("Handle = %d %s, want %d containing %q", resp.StatusCode, resp.Body, tt.wantStatus, tt.wantBody)
+			}
+			if resp.Headers["ETag"] != tt.wantETag {
+				t.Errorf("ETag = %q, want %q", resp.Headers["ETag"], tt.wantETag)
+			}
+
+			// Nothing is written when a precondition fails
+			stored, err := memory.Get(context.Background(), handlertest.UserID, "book-1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stored.Version != tt.wantStored {
+				t.Errorf("stored version %d, want %d", stored.Version, tt.wantStored)
+			}
+			if rated := stored.Rating != nil; rated != (tt.wantStatus == 200) {
+				t.Errorf("stored rating %v, want one: %v", stored.Rating, tt.wantStatus == 200)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+# S3 bucket the web app uploads import files to, through pre-signed URLs
+resource "aws_s3_bucket" "imports" {
+  bucket_prefix = "bookshelf-imports-"
+  force_destroy = true
+}
+
+# Configure bucket to be private by default
+resource "aws_s3_bucket_public_access_block" "imports" {
+  bucket = aws_s3_bucket.imports.id
+
+  block_public_acls       = true
+  block_public_policy     = true
+  ignore_public_acls      = true
+  restrict_public_buckets = true
+}
+
+# Allow the browser to PUT files to pre-signed URLs
+resource "aws_s3_bucket_cors_configuration" "imports" {
+  bucket = aws_s3_bucket.imports.id
+
+  cors_rule {
+    allowed_headers = ["*"]
+    allowed_methods = ["PUT"]
+    allowed_origins = ["*"]
+    max_age_seconds = 3000
+  }
+}
+
+# Uploaded files are only needed until they have been imported
+resource "aws_s3_bucket_lifecycle_configuration" "imports" {
+  bucket = aws_s3_bucket.imports.id
+
+  rule {
+    id     = "delete_old_imports"
+    status = "Enabled"
+
+    expiration {
+      days = 1
+    }
+  }
+}
+
+# Import each file as soon as it has been uploaded
+resource "aws_s3_bucket_notification" "imports" {
+  bucket = aws_s3_bucket.imports.id
+
+  lambda_function {
+    lambda_function_arn = aws_lambda_function.process_import_lambda.arn
+    events              = ["s3:ObjectCreated:*"]
+    filter_prefix       = "imports/"
+  }
+
+  depends_on = [aws_lambda_permission.process_import_s3_permission]
+}
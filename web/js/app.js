@@ -12,6 +12,11 @@ document.addEventListener('DOMContentLoaded', function() {
         SEARCH: `${API_BASE_URL}/search`, // Google Books search endpoint
         RECOMMENDATIONS: `${API_BASE_URL}/recommendations`,
         EXPORT: `${API_BASE_URL}/export`,
+        EXPORT_JOB: (id) => `${API_BASE_URL}/exports/${encodeURIComponent(id)}`,
+        EXPORT_URL: (id) => `${API_BASE_URL}/exports/${encodeURIComponent(id)}/url`,
+        IMPORTS: `${API_BASE_URL}/imports`,
+        IMPORT_JOB: (id) => `${API_BASE_URL}/imports/${id}`,
+        IMPORT_BACKUP: `${API_BASE_URL}/import/backup`,
         BOOK_STATUS: (id) => `${API_BASE_URL}/books/${id}`,
         BOOK_DETAILS: (id) => `${API_BASE_URL}/books/${id}`,
         DELETE_BOOK: (id) => `${API_BASE_URL}/books/${id}`
@@ -37,9 +42,14 @@ document.addEventListener('DOMContentLoaded', function() {
     const refreshRecommendationsButton = document.getElementById('refresh-recommendations');
     const recommendationsContainer = document.getElementById('recommendations-container');
     const exportButton = document.getElementById('export-button');
+    const importButton = document.getElementById('import-button');
+    const importFile = document.getElementById('import-file');
 
     // Current book being viewed/edited
     let currentBook = null;
+    // Latest known version of each book, sent as If-Match so edits made in
+    // another tab are not silently overwritten
+    const bookVersions = {};
     let currentRating = null;
 
     // Initialize the application
@@ -121,6 +131,7 @@ document.addEventListener('DOMContentLoaded', function() {
                 };
                 
                 books.forEach(book => {
+                    bookVersions[book.id] = book.version;
                     const frontendStatus = statusMapping[book.status] || book.status;
                     if (booksByStatus[frontendStatus]) {
                         // Update the book object with the frontend status
@@ -282,6 +293,17 @@ document.addEventListener('DOMContentLoaded', function() {
         if (exportButton) {
             exportButton.addEventListener('click', handleExport);
         }
+
+        // Import functionality
+        if (importButton && importFile) {
+            importButton.addEventListener('click', () => importFile.click());
+            importFile.addEventListener('change', () => {
+                if (importFile.files.length > 0) {
+                    importBooks(importFile.files[0]);
+                }
+                importFile.value = '';
+            });
+        }
         
         // Close search results
         closeSearch.addEventListener('click', () => {
@@ -791,7 +813,8 @@ document.addEventListener('DOMContentLoaded', function() {
     }
 
     // Add a new book from Google Books search to the shelf
-    function addGoogleBook(book, buttonElement) {
+    // Pass force to add the book even if it looks like a duplicate
+    function addGoogleBook(book, buttonElement, force = false) {
         // If book already exists in a shelf, just show a notification
         if (book.existing_shelf) {
             alert(`This book is already in your "${book.existing_shelf}" shelf`);
@@ -804,21 +827,35 @@ document.addEventListener('DOMContentLoaded', function() {
             title: book.title,
             author: book.author,
             status: 'WANT_TO_READ', // Use backend status format
-            thumbnail: book.thumbnail || ''
+            thumbnail: book.thumbnail || '',
+            google_volume_id: book.id || '',
+            isbn: book.isbn || ''
         };
         
-        fetch(API.BOOKS, {
+        fetch(force ? `${API.BOOKS}?force=true` : API.BOOKS, {
             method: 'POST',
             headers: getAuthHeaders(),
             body: JSON.stringify(newBook)
         })
         .then(response => {
+            if (response.status === 409) {
+                // The server found a likely duplicate; let the user decide
+                return response.json().then(duplicate => {
+                    hideLoading();
+                    if (confirm(`"${duplicate.title}" by ${duplicate.author} is already on your shelves. Add it anyway?`)) {
+                        addGoogleBook(book, buttonElement, true);
+                    }
+                    return null;
+                });
+            }
             if (!response.ok) {
                 throw new Error('Failed to add book');
             }
             return response.json();
         })
         .then(addedBook => {
+            if (!addedBook) return;
+            
             // Map the status to frontend format for display
             const statusMapping = {
                 'WANT_TO_READ': 'Want to Read',
@@ -874,6 +911,7 @@ document.addEventListener('DOMContentLoaded', function() {
             return response.json();
         })
         .then(updatedBook => {
+            bookVersions[updatedBook.id] = updatedBook.version;
             // Update the book in the UI
             updatedBook.status = newStatus; // Use frontend status for UI
             updateBookCardInShelf(updatedBook);
@@ -1009,16 +1047,21 @@ document.addEventListener('DOMContentLoaded', function() {
         
         fetch(`${API_BASE_URL}/books/${currentBook.id}`, {
             method: 'PUT',
-            headers: getAuthHeaders(),
+            headers: getVersionedHeaders(currentBook.id),
             body: JSON.stringify(updatedBook)
         })
         .then(response => {
+            if (response.status === 412) {
+                return handleVersionConflict(response);
+            }
             if (!response.ok) {
                 throw new Error(`HTTP error! status: ${response.status}`);
             }
             return response.json();
         })
         .then(updatedBookData => {
+            if (!updatedBookData) return;
+            bookVersions[updatedBookData.id] = updatedBookData.version;
             // Map backend status to frontend status for UI
             const statusMapping = {
                 'WANT_TO_READ': 'Want to Read',
@@ -1043,22 +1086,26 @@ document.addEventListener('DOMContentLoaded', function() {
 
     // Delete a book
     function deleteBook() {
-        if (!currentBook || !confirm(`Are you sure you want to delete "${currentBook.title}"? This action cannot be undone.`)) return;
+        if (!currentBook || !confirm(`Are you sure you want to delete "${currentBook.title}"? You can restore it from the trash for 30 days.`)) return;
         
         showLoading();
         
         fetch(`${API_BASE_URL}/books/${currentBook.id}`, {
             method: 'DELETE',
-            headers: getAuthHeaders()
+            headers: getVersionedHeaders(currentBook.id)
         })
         .then(response => {
+            if (response.status === 412) {
+                return handleVersionConflict(response);
+            }
             if (!response.ok) {
                 throw new Error(`HTTP error! status: ${response.status}`);
             }
             // No need to parse JSON for 204 No Content response
             return response.status;
         })
-        .then(() => {
+        .then(status => {
+            if (!status) return;
             // Remove the book from the UI
             const bookCard = document.querySelector(`.book-card[data-id="${currentBook.id}"]`);
             if (bookCard) {
@@ -1363,6 +1410,27 @@ document.addEventListener('DOMContentLoaded', function() {
         };
     }
 
+    // Auth headers plus If-Match for the last version of the book we saw
+    function getVersionedHeaders(bookId) {
+        const headers = getAuthHeaders();
+        if (bookVersions[bookId] !== undefined) {
+            headers['If-Match'] = `"${bookVersions[bookId]}"`;
+        }
+        return headers;
+    }
+
+    // The book changed elsewhere since it was loaded: show the server copy
+    function handleVersionConflict(response) {
+        return response.json().then(current => {
+            bookVersions[current.id] = current.version;
+            hideLoading();
+            bookDetails.classList.add('hidden');
+            alert('This book was changed in another tab or device, so your changes were not saved. The latest version has been loaded.');
+            loadBooks();
+            return null;
+        });
+    }
+
     // Load recommendations from the API
     function loadRecommendations() {
         const refreshIcon = refreshRecommendationsButton.querySelector('i');
@@ -1480,15 +1548,18 @@ document.addEventListener('DOMContentLoaded', function() {
         });
     }
 
-    // Export books function
+    // Export books: start an export job, poll it until the file is written,
+    // then download it from a freshly signed URL
     function exportBooks(format, filters = {}) {
         showLoading();
         
         const requestBody = {
             format: format,
-            filters: filters
+            filters: filters,
+            async: true
         };
         
+        let bookCount = 0;
         fetch(API.EXPORT, {
             method: 'POST',
             headers: getAuthHeaders(),
@@ -1500,6 +1571,20 @@ document.addEventListener('DOMContentLoaded', function() {
             }
             return response.json();
         })
+        .then(job => waitForExport(job.id))
+        .then(job => {
+            if (job.status === 'failed') {
+                throw new Error(job.error);
+            }
+            bookCount = job.book_count;
+            return fetch(API.EXPORT_URL(job.id), { headers: getAuthHeaders() });
+        })
+        .then(response => {
+            if (!response.ok) {
+                throw new Error(`Export failed: ${response.status}`);
+            }
+            return response.json();
+        })
         .then(data => {
             hideLoading();
             
@@ -1513,7 +1598,7 @@ document.addEventListener('DOMContentLoaded', function() {
             document.body.removeChild(link);
             
             // Show success message
-            alert(`Export successful! Your ${format.toUpperCase()} file is downloading.\n\nFile: ${data.filename}\nExpires: ${new Date(data.expires_at).toLocaleString()}`);
+            alert(`Export successful! Your ${format.toUpperCase()} file is downloading.\n\nFile: ${data.filename}\nBooks: ${bookCount}\nExpires: ${new Date(data.expires_at).toLocaleString()}`);
         })
         .catch(error => {
             console.error('Error exporting books:', error);
@@ -1522,6 +1607,134 @@ document.addEventListener('DOMContentLoaded', function() {
         });
     }
 
+    // Poll an export job until it has completed or failed
+    function waitForExport(id) {
+        return new Promise(resolve => setTimeout(resolve, EXPORT_POLL_INTERVAL_MS))
+        .then(() => fetch(API.EXPORT_JOB(id), { headers: getAuthHeaders() }))
+        .then(response => {
+            if (!response.ok) {
+                throw new Error(`Could not check export: ${response.status}`);
+            }
+            return response.json();
+        })
+        .then(job => {
+            if (job.status === 'completed' || job.status === 'failed') {
+                return job;
+            }
+            return waitForExport(id);
+        });
+    }
+
+    // How often to check on a running export or import
+    const EXPORT_POLL_INTERVAL_MS = 1000;
+    const IMPORT_POLL_INTERVAL_MS = 1000;
+
+    // Import a library export: start an import job, upload the file to its
+    // signed URL, then poll the job until it finishes. Our own JSON exports
+    // are restored as backups instead.
+    function importBooks(file) {
+        showLoading();
+
+        detectImportFormat(file)
+        .then(format => format === 'backup' ? restoreBackup(file) : startImport(file, format))
+        .catch(error => {
+            console.error('Error importing books:', error);
+            hideLoading();
+            alert(`Failed to import books. ${error.message}`);
+        });
+    }
+
+    function startImport(file, format) {
+        return fetch(API.IMPORTS, {
+            method: 'POST',
+            headers: { ...getAuthHeaders(), 'Content-Type': 'application/json' },
+            body: JSON.stringify({ format })
+        })
+        .then(response => checkImportResponse(response, 'Import failed'))
+        .then(job => fetch(job.upload_url, {
+            method: 'PUT',
+            headers: { 'Content-Type': file.type || 'text/csv' },
+            body: file
+        }).then(response => {
+            if (!response.ok) {
+                throw new Error(`Upload failed: ${response.status}`);
+            }
+            return waitForImport(job.id);
+        }))
+        .then(job => {
+            hideLoading();
+            loadBooks();
+
+            if (job.status === 'failed') {
+                alert(`Failed to import books. ${job.error}`);
+                return;
+            }
+            let message = `Import finished.\n\nAdded: ${job.created}\nSkipped as duplicates: ${job.skipped}\nFailed: ${job.failed}`;
+            const failures = job.errors.slice(0, 10);
+            if (failures.length > 0) {
+                message += '\n\n' + failures.map(row => `Row ${row.row}: ${row.error}`).join('\n');
+            }
+            alert(message);
+        });
+    }
+
+    // Restore a JSON export, keeping any books added since it was taken
+    function restoreBackup(file) {
+        return fetch(`${API.IMPORT_BACKUP}?mode=merge`, {
+            method: 'POST',
+            headers: { ...getAuthHeaders(), 'Content-Type': 'application/json' },
+            body: file
+        })
+        .then(response => checkImportResponse(response, 'Restore failed'))
+        .then(report => {
+            hideLoading();
+            loadBooks();
+
+            let message = `Backup restored.\n\nAdded: ${report.created}\nUpdated: ${report.updated}\nUnchanged: ${report.unchanged}\nFailed: ${report.failed}`;
+            const failures = report.books.filter(book => book.status === 'failed').slice(0, 10);
+            if (failures.length > 0) {
+                message += '\n\n' + failures.map(book => `Book ${book.entry}: ${book.error}`).join('\n');
+            }
+            alert(message);
+        });
+    }
+
+    // Tell which service an export came from: our own JSON exports start
+    // with a schema_version, LibraryThing exports JSON or tab-separated text,
+    // and StoryGraph's CSV has a "Read Status" column
+    function detectImportFormat(file) {
+        return file.slice(0, 4096).text().then(head => {
+            const start = head.replace(/^\uFEFF/, '').trimStart();
+            if (start.startsWith('{') && start.includes('"schema_version"')) {
+                return 'backup';
+            }
+            if (start.startsWith('{') || start.startsWith('[') || head.split('\n')[0].includes('\t') || head.includes('\u0000')) {
+                return 'librarything';
+            }
+            return head.split('\n')[0].includes('Read Status') ? 'storygraph' : 'goodreads';
+        });
+    }
+
+    // Poll an import job until it has completed or failed
+    function waitForImport(id) {
+        return new Promise(resolve => setTimeout(resolve, IMPORT_POLL_INTERVAL_MS))
+        .then(() => fetch(API.IMPORT_JOB(id), { headers: getAuthHeaders() }))
+        .then(response => checkImportResponse(response, 'Could not check import'))
+        .then(job => {
+            if (job.status === 'completed' || job.status === 'failed') {
+                return job;
+            }
+            return waitForImport(id);
+        });
+    }
+
+    function checkImportResponse(response, message) {
+        if (!response.ok) {
+            return response.text().then(text => { throw new Error(text || `${message}: ${response.status}`); });
+        }
+        return response.json();
+    }
+
     // Make functions available globally
     window.searchForBook = searchForBook;
     window.signOut = signOut;